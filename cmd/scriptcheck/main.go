@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/milk9111/sidescroller/ecs/script"
+)
+
+// scriptcheck checks every script set the prefabs and levels reference for
+// unknown module functions, wrong argument counts and unknown state names.
+func main() {
+	issues, err := script.CheckReferencedScripts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		fmt.Printf("%d script issues\n", len(issues))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/replay"
+	"github.com/milk9111/sidescroller/levels"
+	"github.com/milk9111/sidescroller/scenes"
+)
+
+func main() {
+	levelName := flag.String("level", "long_fall.json", "level name in levels/ (basename, .json optional), or \"all\" to run every level")
+	frames := flag.Int("frames", 600, "number of frames to step each level")
+	inputPath := flag.String("input", "", "optional input script to feed (see replay.ParseScript for the format)")
+	replayPath := flag.String("replay", "", "optional recording made with the game's -record flag")
+	allAbilities := flag.Bool("ab", false, "start with all abilities unlocked")
+	flag.Parse()
+
+	if *frames <= 0 {
+		log.Fatal("-frames must be greater than zero")
+	}
+	if *inputPath != "" && *replayPath != "" {
+		log.Fatal("-input and -replay cannot be used together")
+	}
+
+	var recorded *replay.Player
+	if *replayPath != "" {
+		player, err := replay.Load(*replayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		recorded = player
+	}
+
+	var scripted []replay.Frame
+	if *inputPath != "" {
+		file, err := os.Open(*inputPath)
+		if err != nil {
+			log.Fatalf("open input script: %v", err)
+		}
+		scripted, err = replay.ParseScript(file)
+		_ = file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	names := []string{*levelName}
+	if recorded != nil {
+		names = []string{recorded.Header().Level}
+	} else if strings.EqualFold(*levelName, "all") {
+		listed, err := fs.Glob(levels.LevelsFS, "*.json")
+		if err != nil {
+			log.Fatalf("list levels: %v", err)
+		}
+		sort.Strings(listed)
+		names = listed
+	}
+
+	failed := 0
+	for _, name := range names {
+		player := recorded
+		if player == nil {
+			player = replay.NewPlayer(replay.Header{Level: name, AllAbilities: *allAbilities}, scripted)
+		}
+
+		result := simulate(player, *frames)
+		result.print(os.Stdout)
+		if !result.ok() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d levels failed\n", failed, len(names))
+		os.Exit(1)
+	}
+}
+
+type result struct {
+	level        string
+	frames       int
+	panic        any
+	entities     int
+	scripted     int
+	enemies      int
+	player       *playerSummary
+	scriptErrors map[string]int
+}
+
+type playerSummary struct {
+	x, y    float64
+	health  component.Health
+	state   string
+	hasBody bool
+}
+
+func (r result) ok() bool {
+	return r.panic == nil && len(r.scriptErrors) == 0
+}
+
+// simulate steps a fresh game scene through the replayed frames without
+// opening a window. Level load failures panic inside PersistenceSystem, so
+// they are recovered here and reported as a failed run.
+func simulate(player *replay.Player, frames int) (res result) {
+	header := player.Header()
+	level := header.Level
+	if filepath.Ext(level) == "" {
+		level += ".json"
+	}
+	res.level = level
+	res.scriptErrors = map[string]int{}
+
+	cfg := scenes.GameConfig{
+		LevelName:        level,
+		Mute:             true,
+		AllAbilities:     header.AllAbilities,
+		InitialAbilities: header.InitialAbilities,
+		LoadedSave:       header.Save,
+		Replay:           player,
+		ScriptErrorHandler: func(_ ecs.Entity, err error) {
+			res.scriptErrors[err.Error()]++
+		},
+	}
+
+	var scene *scenes.GameScene
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			res.panic = recovered
+		}
+		if scene != nil {
+			res.summarize(scene.World())
+		}
+	}()
+
+	scene = scenes.NewGameScene(cfg)
+	for res.frames < frames {
+		if _, err := scene.Update(); err != nil {
+			panic(err)
+		}
+		res.frames++
+	}
+
+	return res
+}
+
+func (r *result) summarize(w *ecs.World) {
+	if w == nil {
+		return
+	}
+
+	r.entities = len(ecs.Entities(w))
+	ecs.ForEach(w, component.ScriptComponent.Kind(), func(ecs.Entity, *component.Script) { r.scripted++ })
+	ecs.ForEach(w, component.AITagComponent.Kind(), func(ecs.Entity, *component.AITag) { r.enemies++ })
+
+	ent, ok := ecs.First(w, component.PlayerTagComponent.Kind())
+	if !ok {
+		return
+	}
+
+	summary := &playerSummary{}
+	if transform, ok := ecs.Get(w, ent, component.TransformComponent.Kind()); ok && transform != nil {
+		summary.x = transform.X
+		summary.y = transform.Y
+	}
+	if health, ok := ecs.Get(w, ent, component.HealthComponent.Kind()); ok && health != nil {
+		summary.health = *health
+	}
+	if stateMachine, ok := ecs.Get(w, ent, component.PlayerStateMachineComponent.Kind()); ok && stateMachine != nil && stateMachine.State != nil {
+		summary.state = stateMachine.State.Name()
+	}
+	summary.hasBody = ecs.Has(w, ent, component.PhysicsBodyComponent.Kind())
+	r.player = summary
+}
+
+func (r result) print(out *os.File) {
+	status := "ok"
+	if !r.ok() {
+		status = "FAIL"
+	}
+	fmt.Fprintf(out, "%s %s: %d frames, %d entities, %d scripted, %d enemies\n", status, r.level, r.frames, r.entities, r.scripted, r.enemies)
+
+	if r.panic != nil {
+		fmt.Fprintf(out, "  panic: %v\n", r.panic)
+	}
+
+	if r.player == nil {
+		fmt.Fprintln(out, "  player: none")
+	} else {
+		state := r.player.state
+		if state == "" {
+			state = "-"
+		}
+		fmt.Fprintf(out, "  player: pos=(%.1f, %.1f) health=%d/%d state=%s body=%t\n", r.player.x, r.player.y, r.player.health.Current, r.player.health.Initial, state, r.player.hasBody)
+	}
+
+	messages := make([]string, 0, len(r.scriptErrors))
+	for message := range r.scriptErrors {
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+	for _, message := range messages {
+		fmt.Fprintf(out, "  script error (x%d): %s\n", r.scriptErrors[message], message)
+	}
+}
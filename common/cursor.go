@@ -0,0 +1,19 @@
+package common
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+var cursorOffsetX, cursorOffsetY int
+
+// SetCursorOffset records where the game screen's top-left corner sits inside
+// the window's logical screen. It is non-zero while integer scaling
+// letterboxes the game.
+func SetCursorOffset(x, y int) {
+	cursorOffsetX = x
+	cursorOffsetY = y
+}
+
+// CursorPosition returns the cursor in game screen coordinates.
+func CursorPosition() (int, int) {
+	x, y := ebiten.CursorPosition()
+	return x - cursorOffsetX, y - cursorOffsetY
+}
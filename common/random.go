@@ -0,0 +1,20 @@
+package common
+
+import (
+	"math/rand"
+	"time"
+)
+
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Rand returns the shared gameplay random source. Gameplay code should draw
+// from it instead of the math/rand globals so recorded sessions replay with
+// the same rolls.
+func Rand() *rand.Rand {
+	return rng
+}
+
+// SeedRand reseeds the shared gameplay random source.
+func SeedRand(seed int64) {
+	rng.Seed(seed)
+}
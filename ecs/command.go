@@ -0,0 +1,66 @@
+package ecs
+
+import "github.com/milk9111/sidescroller/ecs/component"
+
+// CommandBuffer records structural changes, creating and destroying entities
+// and adding and removing components, to make at a sync point instead of
+// while a ForEach or Query is walking the stores they would change. Changing
+// a walked store moves entities around under the loop, which then skips some
+// and visits others twice.
+//
+// Commands run in the order they were recorded. Those aimed at an entity
+// destroyed in the meantime do nothing. The zero value is ready to use.
+type CommandBuffer struct {
+	commands []func(w *World)
+}
+
+// Len reports how many commands are waiting.
+func (b *CommandBuffer) Len() int {
+	return len(b.commands)
+}
+
+// Create records creating an entity. build runs right after, at the sync
+// point, to give it its components.
+func (b *CommandBuffer) Create(build func(w *World, e Entity)) {
+	b.commands = append(b.commands, func(w *World) {
+		e := CreateEntity(w)
+		if build != nil {
+			build(w, e)
+		}
+	})
+}
+
+// Destroy records destroying e.
+func (b *CommandBuffer) Destroy(e Entity) {
+	b.commands = append(b.commands, func(w *World) {
+		DestroyEntity(w, e)
+	})
+}
+
+// DeferAdd records adding value to e as kind, or replacing e's current one.
+func DeferAdd[T any](b *CommandBuffer, e Entity, kind component.ComponentKind[T], value *T) {
+	b.commands = append(b.commands, func(w *World) {
+		_ = Add(w, e, kind, value)
+	})
+}
+
+// DeferRemove records removing e's component of kind.
+func DeferRemove[T any](b *CommandBuffer, e Entity, kind component.ComponentKind[T]) {
+	b.commands = append(b.commands, func(w *World) {
+		Remove(w, e, kind)
+	})
+}
+
+// Apply makes the recorded changes to w and empties the buffer. Commands
+// recorded while it runs, say by a Create's build, are applied too.
+func (b *CommandBuffer) Apply(w *World) {
+	if w == nil {
+		return
+	}
+	for i := 0; i < len(b.commands); i++ {
+		command := b.commands[i]
+		b.commands[i] = nil
+		command(w)
+	}
+	b.commands = b.commands[:0]
+}
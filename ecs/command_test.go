@@ -0,0 +1,106 @@
+package ecs
+
+import (
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestCommandBufferApply(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	name := component.NewComponentKind[string]()
+
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+	_ = Add(w, b, name, stringPtr("b"))
+
+	var buffer CommandBuffer
+	var created Entity
+	buffer.Create(func(w *World, e Entity) {
+		created = e
+		_ = Add(w, e, pos, intPtr(3))
+	})
+	DeferAdd(&buffer, a, name, stringPtr("a"))
+	DeferRemove(&buffer, b, name)
+	buffer.Destroy(b)
+	// Aimed at an entity destroyed by an earlier command.
+	DeferAdd(&buffer, b, pos, intPtr(20))
+
+	if buffer.Len() != 5 {
+		t.Fatalf("expected 5 commands, got %d", buffer.Len())
+	}
+	if Has(w, a, name) || !IsAlive(w, b) {
+		t.Fatalf("recording should not change the world")
+	}
+
+	buffer.Apply(w)
+	if buffer.Len() != 0 {
+		t.Fatalf("expected Apply to empty the buffer, got %d", buffer.Len())
+	}
+	if v, ok := Get(w, created, pos); !ok || *v != 3 {
+		t.Fatalf("expected created entity with pos 3, got %v %v", v, ok)
+	}
+	if v, ok := Get(w, a, name); !ok || *v != "a" {
+		t.Fatalf("expected a to get its name, got %v %v", v, ok)
+	}
+	if IsAlive(w, b) {
+		t.Fatalf("expected b destroyed")
+	}
+	if len(Entities(w)) != 2 {
+		t.Fatalf("expected 2 entities, got %v", Entities(w))
+	}
+}
+
+func TestCommandBufferAppliesCommandsRecordedWhileApplying(t *testing.T) {
+	tag := component.NewComponentKind[int]()
+	w := NewWorld()
+
+	var buffer CommandBuffer
+	buffer.Create(func(w *World, parent Entity) {
+		_ = Add(w, parent, tag, intPtr(1))
+		buffer.Create(func(w *World, child Entity) {
+			_ = Add(w, child, tag, intPtr(2))
+		})
+	})
+	buffer.Apply(w)
+
+	if got := NewQuery(tag).Entities(w); len(got) != 2 {
+		t.Fatalf("expected parent and child, got %v", got)
+	}
+}
+
+func TestDeferredRemovalVisitsEveryEntityOnce(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	w := NewWorld()
+	var all []Entity
+	for i := 0; i < 6; i++ {
+		e := CreateEntity(w)
+		_ = Add(w, e, pos, intPtr(i))
+		all = append(all, e)
+	}
+
+	var buffer CommandBuffer
+	visits := map[Entity]int{}
+	ForEach(w, pos, func(e Entity, v *int) {
+		visits[e]++
+		// Removing other entities directly would move the last one into
+		// their slot ahead of the loop.
+		if *v == 0 {
+			DeferRemove(&buffer, all[2], pos)
+			buffer.Destroy(all[4])
+		}
+	})
+	buffer.Apply(w)
+
+	for _, e := range all {
+		if visits[e] != 1 {
+			t.Fatalf("expected every entity visited once, got %v", visits)
+		}
+	}
+	if Has(w, all[2], pos) || IsAlive(w, all[4]) {
+		t.Fatalf("expected deferred changes applied after the loop")
+	}
+}
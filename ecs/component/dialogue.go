@@ -2,10 +2,85 @@ package component
 
 import "github.com/hajimehoshi/ebiten/v2"
 
+// DialogueEnd is the node id that ends a conversation. An empty next does
+// the same.
+const DialogueEnd = "end"
+
+// Dialogue is a conversation attached to a speaker. Simple signs only set
+// Lines; branching conversations set Nodes, and Lines is then ignored.
 type Dialogue struct {
 	Lines    []string
 	Range    float64
 	Portrait *ebiten.Image
+	Speaker  string
+	// Start is the id of the first node. Empty starts at the first node.
+	Start string
+	Nodes []DialogueNode
+}
+
+// DialogueNode is a run of lines from one speaker. After its last line the
+// player picks one of the visible choices, or the conversation follows the
+// first passing branch, or Next.
+type DialogueNode struct {
+	ID string
+	// Speaker and Portrait override the dialogue's own when set.
+	Speaker  string
+	Portrait *ebiten.Image
+	Lines    []string
+	// Actions run when the node is entered.
+	Actions  []DialogueAction
+	Choices  []DialogueChoice
+	Branches []DialogueBranch
+	Next     string
+}
+
+type DialogueChoice struct {
+	Text string
+	Next string
+	// Conditions hide the choice unless they all pass.
+	Conditions []DialogueCondition
+	Actions    []DialogueAction
+}
+
+type DialogueBranch struct {
+	Conditions []DialogueCondition
+	Next       string
+}
+
+// DialogueCondition checks player state. Every field that is set must pass.
+type DialogueCondition struct {
+	// Item passes when the inventory holds at least ItemCount of the prefab.
+	Item      string
+	ItemCount int
+	NoItem    string
+	Ability   string
+	NoAbility string
+	// Entity is a game entity id in the current level, or a "level#id" key,
+	// whose persisted state must equal EntityState. An empty EntityState
+	// passes for any recorded state.
+	Entity      string
+	EntityState PersistedLevelEntityState
+	// Flag must be truthy unless FlagValue or FlagAtLeast narrow the check
+	// to an exact value or an int minimum.
+	Flag        string
+	FlagValue   *FlagValue
+	FlagAtLeast *int
+	NoFlag      string
+}
+
+// DialogueAction runs when a node is entered or a choice is picked.
+type DialogueAction struct {
+	// Signal is emitted to the script on Target, a game entity id. An empty
+	// target means the speaker and "*" broadcasts to every script.
+	Signal string
+	Target string
+	// SetFlag stores FlagValue, or true when FlagValue is nil.
+	SetFlag   string
+	FlagValue *FlagValue
+	ClearFlag string
+	// OpenShop opens the shop on Target, or on the speaker when Target is
+	// empty, once the dialogue closes.
+	OpenShop bool
 }
 
 var DialogueComponent = NewComponent[Dialogue]()
@@ -3,6 +3,9 @@ package component
 type DialogueInput struct {
 	Pressed      bool
 	UsingGamepad bool
+	// Move is -1 or 1 on the frame the player steps up or down through
+	// dialogue choices.
+	Move int
 }
 
 var DialogueInputComponent = NewComponent[DialogueInput]()
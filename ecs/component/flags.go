@@ -0,0 +1,61 @@
+package component
+
+type FlagKind string
+
+const (
+	FlagKindBool   FlagKind = "bool"
+	FlagKindInt    FlagKind = "int"
+	FlagKindString FlagKind = "string"
+)
+
+// FlagValue is a typed story flag. Only the field matching Kind is used.
+type FlagValue struct {
+	Kind   FlagKind
+	Bool   bool
+	Int    int
+	String string
+}
+
+func BoolFlag(value bool) FlagValue { return FlagValue{Kind: FlagKindBool, Bool: value} }
+
+func IntFlag(value int) FlagValue { return FlagValue{Kind: FlagKindInt, Int: value} }
+
+func StringFlag(value string) FlagValue { return FlagValue{Kind: FlagKindString, String: value} }
+
+// Truthy reports whether the flag counts as set for flag/no_flag checks:
+// true, a non-zero int or a non-empty string.
+func (v FlagValue) Truthy() bool {
+	switch v.Kind {
+	case FlagKindBool:
+		return v.Bool
+	case FlagKindInt:
+		return v.Int != 0
+	case FlagKindString:
+		return v.String != ""
+	}
+	return false
+}
+
+// Equal reports whether both flags have the same kind and value.
+func (v FlagValue) Equal(other FlagValue) bool {
+	if v.Kind != other.Kind {
+		return false
+	}
+	switch v.Kind {
+	case FlagKindBool:
+		return v.Bool == other.Bool
+	case FlagKindInt:
+		return v.Int == other.Int
+	case FlagKindString:
+		return v.String == other.String
+	}
+	return true
+}
+
+// Flags holds named story flags on the player singleton so they follow the
+// player across levels.
+type Flags struct {
+	Values map[string]FlagValue
+}
+
+var FlagsComponent = NewComponent[Flags]()
@@ -21,6 +21,10 @@ type Input struct {
 	AnchorReleasePressed bool
 	MenuPressed          bool
 	UsingGamepad         bool
+	// CursorX and CursorY hold the mouse cursor in screen space so aiming
+	// reads the same position during recording and replay.
+	CursorX float64
+	CursorY float64
 
 	MouseDoubleClickPressedTimer int
 }
@@ -1,10 +1,10 @@
 package component
 
-// LevelChangeRequest is a one-shot request emitted by gameplay systems (e.g.
-// TransitionSystem) to ask the outer game loop to load a different level.
+// LevelChangeRequest is an event emitted by gameplay systems (e.g.
+// TransitionSystem) to ask the persistence system to load a different level.
 //
-// This keeps systems independent: systems only emit data; the Game loop owns
-// IO/world reinitialization.
+// This keeps systems independent: systems only emit data; the persistence
+// system owns IO/world reinitialization.
 type LevelChangeRequest struct {
 	TargetLevel       string
 	SpawnTransitionID string
@@ -19,5 +19,3 @@ type LevelChangeRequest struct {
 	// entry and a candidate for the pop impulse after spawn.
 	EntryFromBelow bool
 }
-
-var LevelChangeRequestComponent = NewComponent[LevelChangeRequest]()
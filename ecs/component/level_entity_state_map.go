@@ -13,6 +13,9 @@ const (
 // survives level transitions with the player singleton.
 type LevelEntityStateMap struct {
 	States map[string]PersistedLevelEntityState
+	// Purchased counts items bought from shops, keyed by "level#id#index"
+	// with the index of the entry in the shop's item list.
+	Purchased map[string]int
 }
 
 var LevelEntityStateMapComponent = NewComponent[LevelEntityStateMap]()
@@ -1,7 +1,8 @@
 package component
 
+// LeverHitRequest is an event telling the lever system that an attack hit
+// Lever.
 type LeverHitRequest struct {
+	Lever        uint64
 	SourceEntity uint64
 }
-
-var LeverHitRequestComponent = NewComponent[LeverHitRequest]()
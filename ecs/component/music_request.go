@@ -1,6 +1,6 @@
 package component
 
-// MusicRequest is a one-shot request for global music playback.
+// MusicRequest is an event requesting global music playback.
 //
 // The music system guarantees only one active song at a time. When a new
 // request arrives while another song is active, the current song fades out to
@@ -11,5 +11,3 @@ type MusicRequest struct {
 	Loop          bool
 	FadeOutFrames int
 }
-
-var MusicRequestComponent = NewComponent[MusicRequest]()
@@ -4,8 +4,8 @@ import "github.com/jakecoffman/cp"
 
 // PhysicsBody stores Chipmunk2D runtime data and collider configuration.
 type PhysicsBody struct {
-	Body                   *cp.Body
-	Shape                  *cp.Shape
+	Body                   *cp.Body  `json:"-"`
+	Shape                  *cp.Shape `json:"-"`
 	Disabled               bool
 	LockRotation           bool
 	AutoSizeFromAreaBounds bool
@@ -0,0 +1,10 @@
+package component
+
+// PrefabSource records the prefab an entity was built from, and the
+// overrides it was built with, so a snapshot restore can build it again.
+type PrefabSource struct {
+	Path      string
+	Overrides map[string]any
+}
+
+var PrefabSourceComponent = NewComponent[PrefabSource]()
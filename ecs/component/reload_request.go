@@ -1,8 +1,5 @@
 package component
 
-// ReloadRequest is a marker component used to signal the game loop to reload
-// the current level/world. Systems may create a short-lived entity with this
-// component to request a reload.
+// ReloadRequest is an event asking the persistence system to reload the
+// current level/world. Emit it with ecs.Emit.
 type ReloadRequest struct{}
-
-var ReloadRequestComponent = NewComponent[ReloadRequest]()
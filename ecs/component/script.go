@@ -4,6 +4,9 @@ type Script struct {
 	Path    string
 	Paths   []string
 	Modules []string
+	// Stdlib narrows the sandbox's Tengo stdlib allowlist, which leaves out
+	// os, to the modules listed. Empty uses the sandbox's.
+	Stdlib []string
 }
 
 var ScriptComponent = NewComponent[Script]()
@@ -21,6 +24,28 @@ type ScriptSignalEvent struct {
 	HasPosition      bool
 	PositionX        float64
 	PositionY        float64
+	// Payload is handed to signals.on callbacks that take a second argument.
+	Payload SignalPayload
+}
+
+// SignalPayload is data attached to a script signal. Go systems attach typed
+// structs; maps emitted from scripts arrive as SignalData.
+type SignalPayload interface {
+	SignalData() map[string]any
+}
+
+type SignalData map[string]any
+
+func (d SignalData) SignalData() map[string]any { return d }
+
+// HitSignalPayload rides on on_hit signals from combat.
+type HitSignalPayload struct {
+	Damage int
+	Health int
+}
+
+func (p HitSignalPayload) SignalData() map[string]any {
+	return map[string]any{"damage": p.Damage, "health": p.Health}
 }
 
 type ScriptSignalQueue struct {
@@ -42,3 +67,22 @@ type ScriptState struct {
 }
 
 var ScriptStateComponent = NewComponent[ScriptState]()
+
+// ScriptErrorLog keeps the latest script failures, newest first, for the
+// debug overlay.
+type ScriptErrorLog struct {
+	Entries []ScriptErrorEntry
+}
+
+type ScriptErrorEntry struct {
+	GameEntityID string
+	// File and Line point into the original script file; Line is 0 when the
+	// failure has no position.
+	File    string
+	Line    int
+	Message string
+	// Count is how many times the same failure has been reported.
+	Count int
+}
+
+var ScriptErrorLogComponent = NewComponent[ScriptErrorLog]()
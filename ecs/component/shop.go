@@ -0,0 +1,21 @@
+package component
+
+// Shop lists the items a merchant sells for gears.
+type Shop struct {
+	// Title heads the buy screen. Empty falls back to the speaker of the
+	// entity's dialogue.
+	Title string
+	Items []ShopItem
+}
+
+type ShopItem struct {
+	Prefab string
+	Price  int
+	// Stock is how many can be bought. Zero means no limit.
+	Stock int
+	// Sold counts purchases when the shop has no game entity id to record
+	// them under.
+	Sold int
+}
+
+var ShopComponent = NewComponent[Shop]()
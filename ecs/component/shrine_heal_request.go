@@ -1,6 +1,5 @@
 package component
 
-// ShrineHealRequest asks the player controller to enter the shrine heal state.
+// ShrineHealRequest is an event asking the player controller to enter the
+// shrine heal state.
 type ShrineHealRequest struct{}
-
-var ShrineHealRequestComponent = NewComponent[ShrineHealRequest]()
@@ -8,7 +8,7 @@ import (
 
 type Sprite struct {
 	Disabled   bool
-	Image      *ebiten.Image
+	Image      *ebiten.Image `json:"-"`
 	Source     image.Rectangle
 	UseSource  bool
 	TileX      bool
@@ -4,6 +4,11 @@ type Trigger struct {
 	Bounds   AABB
 	Name     string
 	Disabled bool
+	// Flag and NoFlag gate the trigger on player story flags; it waits,
+	// still armed, until they pass. SetFlag is set to true when it fires.
+	Flag    string
+	NoFlag  string
+	SetFlag string
 }
 
 var TriggerComponent = NewComponent[Trigger]()
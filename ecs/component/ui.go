@@ -20,7 +20,10 @@ type DialogueUI struct {
 	Panel        *widget.Container
 	PortraitBox  *widget.Container
 	Portrait     *widget.Graphic
+	Speaker      *widget.Text
 	Text         *widget.Text
+	// Choices are fixed rows under the text; unused rows stay hidden.
+	Choices []*widget.Text
 }
 
 var DialogueUIComponent = NewComponent[DialogueUI]()
@@ -28,7 +31,12 @@ var DialogueUIComponent = NewComponent[DialogueUI]()
 type DialogueState struct {
 	Active         bool
 	DialogueEntity uint64
+	NodeIndex      int
 	LineIndex      int
+	// Choices holds the indexes of the node's choices that passed their
+	// conditions once its last line was reached.
+	Choices     []int
+	ChoiceIndex int
 }
 
 var DialogueStateComponent = NewComponent[DialogueState]()
@@ -72,6 +80,33 @@ type InventoryState struct {
 
 var InventoryStateComponent = NewComponent[InventoryState]()
 
+type ShopUI struct {
+	Root        *widget.Container
+	Overlay     *widget.Container
+	Panel       *widget.Container
+	Title       *widget.Text
+	Gears       *widget.Text
+	GridHost    *widget.Container
+	DetailPanel *widget.Container
+	DetailImage *widget.Graphic
+	DetailText  *widget.Text
+	Message     *widget.Text
+}
+
+var ShopUIComponent = NewComponent[ShopUI]()
+
+type ShopState struct {
+	Active        bool
+	ShopEntity    uint64
+	SelectedIndex int
+	LastMoveX     int
+	LastMoveY     int
+	// Message reports the outcome of the last purchase attempt.
+	Message string
+}
+
+var ShopStateComponent = NewComponent[ShopState]()
+
 type TutorialUI struct {
 	Root    *widget.Container
 	Overlay *widget.Container
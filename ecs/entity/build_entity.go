@@ -88,6 +88,7 @@ var componentRegistry = map[string]componentBuildFn{
 	"item_reference":       addItemReference,
 	"item":                 addItem,
 	"dialogue":             addDialogue,
+	"shop":                 addShop,
 	"shrine":               addShrine,
 	"item_popup":           addItemPopup,
 	"dialogue_popup":       addDialoguePopup,
@@ -155,6 +156,7 @@ var componentBuildOrder = []string{
 	"item_reference",
 	"item",
 	"dialogue",
+	"shop",
 	"shrine",
 	"item_popup",
 	"dialogue_popup",
@@ -172,15 +174,46 @@ func BuildEntityWithOverrides(w *ecs.World, prefabPath string, componentOverride
 		return 0, fmt.Errorf("build entity: world is nil")
 	}
 
+	spec, err := loadBuildSpec(prefabPath, componentOverrides)
+	if err != nil {
+		return 0, err
+	}
+
+	e := ecs.CreateEntity(w)
+	if err := buildComponents(w, e, prefabPath, componentOverrides, spec); err != nil {
+		ecs.DestroyEntity(w, e)
+		return 0, err
+	}
+	return e, nil
+}
+
+// RebuildEntity builds a prefab onto e, an entity that is alive but has none
+// of the prefab's components, such as one a snapshot restore brings back.
+// On error e is left with the components built so far.
+func RebuildEntity(w *ecs.World, e ecs.Entity, prefabPath string, componentOverrides map[string]any) error {
+	if w == nil {
+		return fmt.Errorf("build entity: world is nil")
+	}
+
+	spec, err := loadBuildSpec(prefabPath, componentOverrides)
+	if err != nil {
+		return err
+	}
+	return buildComponents(w, e, prefabPath, componentOverrides, spec)
+}
+
+func loadBuildSpec(prefabPath string, componentOverrides map[string]any) (prefabs.EntityBuildSpec, error) {
 	spec, err := prefabs.LoadEntityBuildSpecWithOverrides(prefabPath, componentOverrides)
 	if err != nil {
-		return 0, fmt.Errorf("build entity: load %q: %w", prefabPath, err)
+		return spec, fmt.Errorf("build entity: load %q: %w", prefabPath, err)
 	}
 	if len(spec.Components) == 0 {
-		return 0, fmt.Errorf("build entity: prefab %q does not define components", prefabPath)
+		return spec, fmt.Errorf("build entity: prefab %q does not define components", prefabPath)
 	}
+	return spec, nil
+}
 
-	e := ecs.CreateEntity(w)
+func buildComponents(w *ecs.World, e ecs.Entity, prefabPath string, componentOverrides map[string]any, spec prefabs.EntityBuildSpec) error {
 	ctx := &buildContext{PrefabPath: prefabPath}
 
 	remaining := make(map[string]any, len(spec.Components))
@@ -195,12 +228,10 @@ func BuildEntityWithOverrides(w *ecs.World, prefabPath string, componentOverride
 		}
 		builder, ok := componentRegistry[name]
 		if !ok {
-			ecs.DestroyEntity(w, e)
-			return 0, fmt.Errorf("build entity: %q: no builder for component %q", prefabPath, name)
+			return fmt.Errorf("build entity: %q: no builder for component %q", prefabPath, name)
 		}
 		if err := builder(w, e, raw, ctx); err != nil {
-			ecs.DestroyEntity(w, e)
-			return 0, fmt.Errorf("build entity: %q: add %q: %w", prefabPath, name, err)
+			return fmt.Errorf("build entity: %q: add %q: %w", prefabPath, name, err)
 		}
 		delete(remaining, name)
 	}
@@ -214,17 +245,15 @@ func BuildEntityWithOverrides(w *ecs.World, prefabPath string, componentOverride
 		for _, name := range names {
 			builder, ok := componentRegistry[name]
 			if !ok {
-				ecs.DestroyEntity(w, e)
-				return 0, fmt.Errorf("build entity: %q: no builder for component %q", prefabPath, name)
+				return fmt.Errorf("build entity: %q: no builder for component %q", prefabPath, name)
 			}
 			if err := builder(w, e, remaining[name], ctx); err != nil {
-				ecs.DestroyEntity(w, e)
-				return 0, fmt.Errorf("build entity: %q: add %q: %w", prefabPath, name, err)
+				return fmt.Errorf("build entity: %q: add %q: %w", prefabPath, name, err)
 			}
 		}
 	}
 
-	return e, nil
+	return ecs.Add(w, e, component.PrefabSourceComponent.Kind(), &component.PrefabSource{Path: prefabPath, Overrides: componentOverrides})
 }
 
 func SetEntityTransform(w *ecs.World, e ecs.Entity, x, y, rotation float64) error {
@@ -309,22 +338,12 @@ func addDialogue(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
 		return fmt.Errorf("decode dialogue spec: %w", err)
 	}
 
-	lines := append([]string(nil), spec.Lines...)
-
-	var portrait *ebiten.Image
-	if spec.Portrait != "" {
-		img, err := assets.LoadImage(spec.Portrait)
-		if err != nil {
-			return fmt.Errorf("decode dialogue spec: load portrait image %q: %w", spec.Portrait, err)
-		}
-		portrait = scaleImage(img, 3)
+	dialogue, err := buildDialogue(spec)
+	if err != nil {
+		return fmt.Errorf("decode dialogue spec: %w", err)
 	}
 
-	return ecs.Add(w, e, component.DialogueComponent.Kind(), &component.Dialogue{
-		Lines:    lines,
-		Range:    spec.Range,
-		Portrait: portrait,
-	})
+	return ecs.Add(w, e, component.DialogueComponent.Kind(), dialogue)
 }
 
 func addShrine(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
@@ -394,6 +413,37 @@ func addInventory(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
 	return ecs.Add(w, e, component.InventoryComponent.Kind(), &component.Inventory{Items: items})
 }
 
+func addShop(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
+	spec, err := prefabs.DecodeComponentSpec[prefabs.ShopComponentSpec](raw)
+	if err != nil {
+		return fmt.Errorf("decode shop spec: %w", err)
+	}
+
+	items := make([]component.ShopItem, 0, len(spec.Items))
+	for i, entry := range spec.Items {
+		prefabPath := strings.TrimSpace(entry.Prefab)
+		if prefabPath == "" {
+			return fmt.Errorf("decode shop spec: item %d has no prefab", i)
+		}
+		if entry.Price < 0 {
+			return fmt.Errorf("decode shop spec: item %q has negative price %d", prefabPath, entry.Price)
+		}
+		if entry.Stock < 0 {
+			return fmt.Errorf("decode shop spec: item %q has negative stock %d", prefabPath, entry.Stock)
+		}
+		items = append(items, component.ShopItem{
+			Prefab: prefabPath,
+			Price:  entry.Price,
+			Stock:  entry.Stock,
+		})
+	}
+
+	return ecs.Add(w, e, component.ShopComponent.Kind(), &component.Shop{
+		Title: strings.TrimSpace(spec.Title),
+		Items: items,
+	})
+}
+
 func scaleImage(src *ebiten.Image, factor float64) *ebiten.Image {
 	if src == nil || factor <= 0 || factor == 1 {
 		return src
@@ -1125,7 +1175,7 @@ func addScript(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
 		// Prepend legacy single path to keep ordering predictable.
 		paths = append([]string{spec.Path}, paths...)
 	}
-	return ecs.Add(w, e, component.ScriptComponent.Kind(), &component.Script{Path: spec.Path, Paths: paths, Modules: append([]string(nil), spec.Modules...)})
+	return ecs.Add(w, e, component.ScriptComponent.Kind(), &component.Script{Path: spec.Path, Paths: paths, Modules: append([]string(nil), spec.Modules...), Stdlib: append([]string(nil), spec.Stdlib...)})
 }
 
 func addTrigger(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
@@ -1142,6 +1192,9 @@ func addTrigger(w *ecs.World, e ecs.Entity, raw any, _ *buildContext) error {
 		},
 		Name:     spec.Name,
 		Disabled: spec.Disabled,
+		Flag:     strings.TrimSpace(spec.Flag),
+		NoFlag:   strings.TrimSpace(spec.NoFlag),
+		SetFlag:  strings.TrimSpace(spec.SetFlag),
 	})
 }
 
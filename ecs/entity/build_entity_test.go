@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/milk9111/sidescroller/ecs"
@@ -67,3 +69,83 @@ func TestBuildEntityPreservesExplicitZeroInitialHealth(t *testing.T) {
 		t.Fatalf("expected current health to default from explicit zero initial health, got %d", health.Current)
 	}
 }
+
+func TestBuildEntityBuildsMerchantDialogueGraph(t *testing.T) {
+	w := ecs.NewWorld()
+	e, err := BuildEntity(w, "merchant.yaml")
+	if err != nil {
+		t.Fatalf("build entity: %v", err)
+	}
+
+	dialogue, ok := ecs.Get(w, e, component.DialogueComponent.Kind())
+	if !ok || dialogue == nil {
+		t.Fatal("expected dialogue component")
+	}
+	if dialogue.Start != "greeting" || len(dialogue.Nodes) == 0 {
+		t.Fatalf("expected a dialogue graph starting at greeting, got start=%q nodes=%d", dialogue.Start, len(dialogue.Nodes))
+	}
+}
+
+func TestBuildEntityRejectsDialogueWithUnknownNode(t *testing.T) {
+	w := ecs.NewWorld()
+	_, err := BuildEntityWithOverrides(w, "merchant.yaml", map[string]any{
+		"dialogue": map[string]any{
+			"start": "greeting",
+			"nodes": []any{
+				map[string]any{"id": "greeting", "lines": []any{"hi"}, "next": "missing"},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected a next pointing at a missing node to be rejected")
+	}
+}
+
+func TestBuildEntityRejectsDialogueWithTooManyChoices(t *testing.T) {
+	choices := make([]any, 0, dialogueMaxChoices+1)
+	for i := 0; i <= dialogueMaxChoices; i++ {
+		choices = append(choices, map[string]any{"text": fmt.Sprintf("choice %d", i)})
+	}
+	w := ecs.NewWorld()
+	_, err := BuildEntityWithOverrides(w, "merchant.yaml", map[string]any{
+		"dialogue": map[string]any{
+			"start": "greeting",
+			"nodes": []any{
+				map[string]any{"id": "greeting", "lines": []any{"hi"}, "choices": choices},
+			},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "choices") {
+		t.Fatalf("expected more choices than the panel has rows for to be rejected, got %v", err)
+	}
+}
+
+func TestBuildEntityBuildsMerchantShop(t *testing.T) {
+	w := ecs.NewWorld()
+	e, err := BuildEntity(w, "merchant.yaml")
+	if err != nil {
+		t.Fatalf("build entity: %v", err)
+	}
+
+	shop, ok := ecs.Get(w, e, component.ShopComponent.Kind())
+	if !ok || shop == nil {
+		t.Fatal("expected shop component")
+	}
+	if len(shop.Items) == 0 || shop.Items[0].Prefab != "item_healing_flask.yaml" || shop.Items[0].Price <= 0 {
+		t.Fatalf("expected the merchant to sell healing flasks, got %+v", shop.Items)
+	}
+}
+
+func TestBuildEntityRejectsShopItemWithoutPrefab(t *testing.T) {
+	w := ecs.NewWorld()
+	_, err := BuildEntityWithOverrides(w, "merchant.yaml", map[string]any{
+		"shop": map[string]any{
+			"items": []any{
+				map[string]any{"price": 5},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected a shop item without a prefab to be rejected")
+	}
+}
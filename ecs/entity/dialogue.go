@@ -0,0 +1,279 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/milk9111/sidescroller/assets"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/prefabs"
+)
+
+var dialogueAbilities = map[string]bool{
+	"anchor":      true,
+	"double_jump": true,
+	"wall_grab":   true,
+	"heal":        true,
+}
+
+var dialogueEntityStates = map[component.PersistedLevelEntityState]bool{
+	component.PersistedLevelEntityStateActive:    true,
+	component.PersistedLevelEntityStateDefeated:  true,
+	component.PersistedLevelEntityStateCollected: true,
+	component.PersistedLevelEntityStateUsed:      true,
+}
+
+// buildDialogue converts a dialogue spec into its component and checks that
+// every node reference resolves.
+func buildDialogue(spec prefabs.DialogueComponentSpec) (*component.Dialogue, error) {
+	portraits := map[string]*ebiten.Image{}
+	loadPortrait := func(path string) (*ebiten.Image, error) {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, nil
+		}
+		if img, ok := portraits[path]; ok {
+			return img, nil
+		}
+		img, err := assets.LoadImage(path)
+		if err != nil {
+			return nil, fmt.Errorf("load portrait image %q: %w", path, err)
+		}
+		portraits[path] = scaleImage(img, 3)
+		return portraits[path], nil
+	}
+
+	portrait, err := loadPortrait(spec.Portrait)
+	if err != nil {
+		return nil, err
+	}
+
+	dialogue := &component.Dialogue{
+		Lines:    append([]string(nil), spec.Lines...),
+		Range:    spec.Range,
+		Portrait: portrait,
+		Speaker:  strings.TrimSpace(spec.Speaker),
+		Start:    strings.TrimSpace(spec.Start),
+	}
+
+	for i, nodeSpec := range spec.Nodes {
+		nodePortrait, err := loadPortrait(nodeSpec.Portrait)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		node := component.DialogueNode{
+			ID:       strings.TrimSpace(nodeSpec.ID),
+			Speaker:  strings.TrimSpace(nodeSpec.Speaker),
+			Portrait: nodePortrait,
+			Lines:    append([]string(nil), nodeSpec.Lines...),
+			Actions:  buildDialogueActions(nodeSpec.Actions),
+			Next:     strings.TrimSpace(nodeSpec.Next),
+		}
+		for _, choiceSpec := range nodeSpec.Choices {
+			node.Choices = append(node.Choices, component.DialogueChoice{
+				Text:       strings.TrimSpace(choiceSpec.Text),
+				Next:       strings.TrimSpace(choiceSpec.Next),
+				Conditions: buildDialogueConditions(choiceSpec.If),
+				Actions:    buildDialogueActions(choiceSpec.Actions),
+			})
+		}
+		for _, branchSpec := range nodeSpec.Branches {
+			node.Branches = append(node.Branches, component.DialogueBranch{
+				Conditions: buildDialogueConditions(branchSpec.If),
+				Next:       strings.TrimSpace(branchSpec.Next),
+			})
+		}
+		dialogue.Nodes = append(dialogue.Nodes, node)
+	}
+
+	if err := validateDialogue(dialogue); err != nil {
+		return nil, err
+	}
+	return dialogue, nil
+}
+
+func buildDialogueConditions(specs []prefabs.DialogueConditionSpec) []component.DialogueCondition {
+	if len(specs) == 0 {
+		return nil
+	}
+	conditions := make([]component.DialogueCondition, 0, len(specs))
+	for _, spec := range specs {
+		conditions = append(conditions, component.DialogueCondition{
+			Item:        strings.TrimSpace(spec.Item),
+			ItemCount:   spec.Count,
+			NoItem:      strings.TrimSpace(spec.NoItem),
+			Ability:     strings.TrimSpace(spec.Ability),
+			NoAbility:   strings.TrimSpace(spec.NoAbility),
+			Entity:      strings.TrimSpace(spec.Entity),
+			EntityState: component.PersistedLevelEntityState(strings.TrimSpace(spec.State)),
+			Flag:        strings.TrimSpace(spec.Flag),
+			FlagValue:   flagValueFromSpec(spec.Value),
+			FlagAtLeast: spec.AtLeast,
+			NoFlag:      strings.TrimSpace(spec.NoFlag),
+		})
+	}
+	return conditions
+}
+
+func buildDialogueActions(specs []prefabs.DialogueActionSpec) []component.DialogueAction {
+	if len(specs) == 0 {
+		return nil
+	}
+	actions := make([]component.DialogueAction, 0, len(specs))
+	for _, spec := range specs {
+		actions = append(actions, component.DialogueAction{
+			Signal:    strings.TrimSpace(spec.Signal),
+			Target:    strings.TrimSpace(spec.Target),
+			SetFlag:   strings.TrimSpace(spec.SetFlag),
+			FlagValue: flagValueFromSpec(spec.Value),
+			ClearFlag: strings.TrimSpace(spec.ClearFlag),
+			OpenShop:  spec.OpenShop,
+		})
+	}
+	return actions
+}
+
+// flagValueFromSpec converts a YAML scalar into a flag value. Unsupported
+// types come back without a kind so validation can report them.
+func flagValueFromSpec(raw any) *component.FlagValue {
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case bool:
+		value := component.BoolFlag(v)
+		return &value
+	case int:
+		value := component.IntFlag(v)
+		return &value
+	case float64:
+		if v == math.Trunc(v) {
+			value := component.IntFlag(int(v))
+			return &value
+		}
+	case string:
+		value := component.StringFlag(v)
+		return &value
+	}
+	return &component.FlagValue{}
+}
+
+func validateDialogue(dialogue *component.Dialogue) error {
+	if len(dialogue.Nodes) == 0 {
+		if dialogue.Start != "" {
+			return fmt.Errorf("start %q set without nodes", dialogue.Start)
+		}
+		return nil
+	}
+
+	ids := make(map[string]bool, len(dialogue.Nodes))
+	for i, node := range dialogue.Nodes {
+		if node.ID == "" {
+			return fmt.Errorf("node %d has no id", i)
+		}
+		if node.ID == component.DialogueEnd {
+			return fmt.Errorf("node %d: id %q is reserved", i, node.ID)
+		}
+		if ids[node.ID] {
+			return fmt.Errorf("duplicate node id %q", node.ID)
+		}
+		ids[node.ID] = true
+	}
+
+	checkNext := func(where, next string) error {
+		if next == "" || next == component.DialogueEnd || ids[next] {
+			return nil
+		}
+		return fmt.Errorf("%s: unknown node %q", where, next)
+	}
+
+	if dialogue.Start != "" && !ids[dialogue.Start] {
+		return fmt.Errorf("start: unknown node %q", dialogue.Start)
+	}
+	for _, node := range dialogue.Nodes {
+		where := fmt.Sprintf("node %q", node.ID)
+		if err := checkNext(where+" next", node.Next); err != nil {
+			return err
+		}
+		if err := validateDialogueActions(where, node.Actions); err != nil {
+			return err
+		}
+		// The dialogue panel only has rows for this many choices.
+		if len(node.Choices) > dialogueMaxChoices {
+			return fmt.Errorf("%s has %d choices, at most %d fit", where, len(node.Choices), dialogueMaxChoices)
+		}
+		for i, choice := range node.Choices {
+			choiceWhere := fmt.Sprintf("%s choice %d", where, i)
+			if choice.Text == "" {
+				return fmt.Errorf("%s has no text", choiceWhere)
+			}
+			if err := checkNext(choiceWhere, choice.Next); err != nil {
+				return err
+			}
+			if err := validateDialogueConditions(choiceWhere, choice.Conditions); err != nil {
+				return err
+			}
+			if err := validateDialogueActions(choiceWhere, choice.Actions); err != nil {
+				return err
+			}
+		}
+		for i, branch := range node.Branches {
+			branchWhere := fmt.Sprintf("%s branch %d", where, i)
+			if err := checkNext(branchWhere, branch.Next); err != nil {
+				return err
+			}
+			if err := validateDialogueConditions(branchWhere, branch.Conditions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateDialogueConditions(where string, conditions []component.DialogueCondition) error {
+	for _, condition := range conditions {
+		for _, ability := range []string{condition.Ability, condition.NoAbility} {
+			if ability != "" && !dialogueAbilities[ability] {
+				return fmt.Errorf("%s: unknown ability %q", where, ability)
+			}
+		}
+		if (condition.FlagValue != nil || condition.FlagAtLeast != nil) && condition.Flag == "" {
+			return fmt.Errorf("%s: value or at_least needs a flag", where)
+		}
+		if condition.FlagValue != nil && condition.FlagValue.Kind == "" {
+			return fmt.Errorf("%s: flag %q value must be a bool, int or string", where, condition.Flag)
+		}
+		if condition.EntityState != "" {
+			if condition.Entity == "" {
+				return fmt.Errorf("%s: state %q needs an entity", where, condition.EntityState)
+			}
+			if !dialogueEntityStates[condition.EntityState] {
+				return fmt.Errorf("%s: unknown entity state %q", where, condition.EntityState)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDialogueActions(where string, actions []component.DialogueAction) error {
+	for i, action := range actions {
+		if action.Signal == "" && action.SetFlag == "" && action.ClearFlag == "" && !action.OpenShop {
+			return fmt.Errorf("%s action %d does nothing", where, i)
+		}
+		if action.FlagValue != nil && action.SetFlag == "" {
+			return fmt.Errorf("%s action %d: value without set_flag", where, i)
+		}
+		if action.FlagValue != nil && action.FlagValue.Kind == "" {
+			return fmt.Errorf("%s action %d: flag %q value must be a bool, int or string", where, i, action.SetFlag)
+		}
+		if action.Target != "" && action.Signal == "" && !action.OpenShop {
+			return fmt.Errorf("%s action %d: target %q without a signal or shop", where, i, action.Target)
+		}
+		if action.Target == "*" && action.OpenShop {
+			return fmt.Errorf("%s action %d: cannot open a shop on every target", where, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// PlayerFlags returns the story flag store on the player, adding an empty one
+// when the player has none yet.
+func PlayerFlags(w *ecs.World) *component.Flags {
+	if w == nil {
+		return nil
+	}
+
+	player, ok := ecs.First(w, component.PlayerTagComponent.Kind())
+	if !ok {
+		return nil
+	}
+
+	flags, ok := ecs.Get(w, player, component.FlagsComponent.Kind())
+	if ok && flags != nil {
+		if flags.Values == nil {
+			flags.Values = make(map[string]component.FlagValue)
+		}
+		return flags
+	}
+
+	flags = &component.Flags{Values: make(map[string]component.FlagValue)}
+	_ = ecs.Add(w, player, component.FlagsComponent.Kind(), flags)
+	return flags
+}
+
+// PlayerFlag looks up a story flag without creating the store.
+func PlayerFlag(w *ecs.World, name string) (component.FlagValue, bool) {
+	if w == nil || name == "" {
+		return component.FlagValue{}, false
+	}
+
+	player, ok := ecs.First(w, component.PlayerTagComponent.Kind())
+	if !ok {
+		return component.FlagValue{}, false
+	}
+
+	flags, ok := ecs.Get(w, player, component.FlagsComponent.Kind())
+	if !ok || flags == nil {
+		return component.FlagValue{}, false
+	}
+
+	value, ok := flags.Values[name]
+	return value, ok
+}
+
+func SetPlayerFlag(w *ecs.World, name string, value component.FlagValue) bool {
+	if name == "" {
+		return false
+	}
+
+	flags := PlayerFlags(w)
+	if flags == nil {
+		return false
+	}
+
+	flags.Values[name] = value
+	return true
+}
+
+func ClearPlayerFlag(w *ecs.World, name string) bool {
+	if name == "" {
+		return false
+	}
+
+	flags := PlayerFlags(w)
+	if flags == nil {
+		return false
+	}
+
+	delete(flags.Values, name)
+	return true
+}
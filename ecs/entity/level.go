@@ -353,6 +353,15 @@ func LoadLevelToWorld(world *ecs.World, lvl *levels.Level) error {
 			}
 			triggerComp.Bounds = bounds
 			triggerComp.Disabled = getBool("disabled", triggerComp.Disabled)
+			if flag := strings.TrimSpace(getString("flag")); flag != "" {
+				triggerComp.Flag = flag
+			}
+			if noFlag := strings.TrimSpace(getString("no_flag")); noFlag != "" {
+				triggerComp.NoFlag = noFlag
+			}
+			if setFlag := strings.TrimSpace(getString("set_flag")); setFlag != "" {
+				triggerComp.SetFlag = setFlag
+			}
 			if err := ecs.Add(world, te, component.TriggerComponent.Kind(), triggerComp); err != nil {
 				return err
 			}
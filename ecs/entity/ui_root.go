@@ -21,6 +21,7 @@ const dialoguePanelMinHeight = 144
 const dialoguePortraitSize = 192
 const dialoguePortraitOverlap = 96
 const dialoguePortraitFramePadding = 8
+const dialogueMaxChoices = 4
 const tutorialPanelMaxWidth = 620
 const tutorialPanelMinHeight = 176
 const itemPanelMaxWidth = 420
@@ -124,12 +125,29 @@ func NewUIRoot(w *ecs.World) (ecs.Entity, error) {
 	portrait.GetWidget().MinHeight = dialoguePortraitSize
 	portrait.GetWidget().Visibility = widget.Visibility_Hide
 
+	speakerText := widget.NewText(
+		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 255, G: 214, B: 120, A: 255}),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Stretch: true})),
+	)
+	speakerText.GetWidget().Visibility = widget.Visibility_Hide
+
 	text := widget.NewText(
 		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 236, G: 240, B: 250, A: 255}),
 		widget.TextOpts.MaxWidth(dialoguePanelMaxWidth-dialoguePortraitOverlap-36),
 		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Stretch: true})),
 	)
 
+	choiceTexts := make([]*widget.Text, 0, dialogueMaxChoices)
+	for range dialogueMaxChoices {
+		choiceText := widget.NewText(
+			widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 168, G: 176, B: 196, A: 255}),
+			widget.TextOpts.MaxWidth(dialoguePanelMaxWidth-dialoguePortraitOverlap-36),
+			widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Stretch: true})),
+		)
+		choiceText.GetWidget().Visibility = widget.Visibility_Hide
+		choiceTexts = append(choiceTexts, choiceText)
+	}
+
 	tutorialOverlay := widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
 	)
@@ -281,8 +299,115 @@ func NewUIRoot(w *ecs.World) (ecs.Entity, error) {
 		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter, Stretch: true})),
 	)
 
+	shopOverlay := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(euiimage.NewNineSliceColor(color.NRGBA{R: 5, G: 8, B: 12, A: 196})),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+	shopOverlay.GetWidget().LayoutData = widget.AnchorLayoutData{StretchHorizontal: true, StretchVertical: true}
+	shopOverlay.GetWidget().Visibility = widget.Visibility_Hide
+
+	shopPanel := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(euiimage.NewNineSliceColor(color.NRGBA{R: 17, G: 23, B: 30, A: 244})),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+	shopPanel.GetWidget().LayoutData = widget.AnchorLayoutData{
+		StretchHorizontal: true,
+		StretchVertical:   true,
+	}
+
+	shopTitle := widget.NewText(
+		widget.TextOpts.Text("Shop", &titleFace, color.NRGBA{R: 240, G: 232, B: 214, A: 255}),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{HorizontalPosition: widget.AnchorLayoutPositionCenter, VerticalPosition: widget.AnchorLayoutPositionStart})),
+	)
+	shopTitle.GetWidget().MinHeight = inventoryTitleHeight
+
+	shopGears := widget.NewText(
+		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 236, G: 191, B: 99, A: 255}),
+		widget.TextOpts.Position(widget.TextPositionEnd, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+			HorizontalPosition: widget.AnchorLayoutPositionEnd,
+			VerticalPosition:   widget.AnchorLayoutPositionStart,
+			Padding:            &widget.Insets{Right: inventoryPaneOuterPadding},
+		})),
+	)
+	shopGears.GetWidget().MinHeight = inventoryTitleHeight
+
+	shopBody := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+	shopBody.GetWidget().LayoutData = widget.AnchorLayoutData{
+		StretchHorizontal: true,
+		StretchVertical:   true,
+		Padding:           &widget.Insets{Top: inventoryTitleHeight + inventoryPanelSpacing, Left: inventoryPanelPadding, Right: inventoryPanelPadding, Bottom: inventoryPanelPadding},
+	}
+	shopBody.GetWidget().MinHeight = inventoryBodyMinHeight
+
+	shopGridPanel := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(euiimage.NewNineSliceColor(color.NRGBA{R: 23, G: 31, B: 40, A: 248})),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(&widget.Insets{Left: inventorySectionPadding, Right: inventorySectionPadding, Top: inventorySectionPadding, Bottom: inventorySectionPadding}),
+		)),
+	)
+	shopGridPanel.GetWidget().LayoutData = widget.AnchorLayoutData{
+		HorizontalPosition: widget.AnchorLayoutPositionStart,
+		StretchVertical:    true,
+		Padding:            &widget.Insets{Left: inventoryPaneOuterPadding, Bottom: inventoryPaneOuterPadding},
+	}
+	shopGridPanel.GetWidget().MinWidth = inventoryGridPanelMinWidth
+	shopGridPanel.GetWidget().MinHeight = inventoryGridPanelMinHeight
+
+	shopGridHost := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+	shopGridHost.GetWidget().LayoutData = widget.RowLayoutData{Stretch: true}
+	shopGridHost.GetWidget().MinWidth = inventoryGridPanelMinWidth - inventorySectionPadding*2
+	shopGridHost.GetWidget().MinHeight = inventoryGridPanelMinHeight - inventorySectionPadding*2
+
+	shopDetailPanel := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(euiimage.NewNineSliceColor(color.NRGBA{R: 29, G: 38, B: 48, A: 248})),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(&widget.Insets{Left: 24, Right: 24, Top: 24, Bottom: 24}),
+			widget.RowLayoutOpts.Spacing(18),
+		)),
+	)
+	shopDetailPanel.GetWidget().LayoutData = widget.AnchorLayoutData{
+		StretchHorizontal: true,
+		StretchVertical:   true,
+		Padding:           &widget.Insets{Left: inventoryPaneOuterPadding + inventoryGridPanelMinWidth + inventoryBodySpacing, Right: inventoryPaneOuterPadding, Bottom: inventoryPaneOuterPadding},
+	}
+	shopDetailPanel.GetWidget().MinHeight = inventoryBodyMinHeight
+
+	shopDetailImage := widget.NewGraphic(
+		widget.GraphicOpts.Image(ebiten.NewImage(1, 1)),
+		widget.GraphicOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	shopDetailImage.GetWidget().MinWidth = inventoryDetailImageMinSize
+	shopDetailImage.GetWidget().MinHeight = inventoryDetailImageMinSize
+	shopDetailImage.GetWidget().Visibility = widget.Visibility_Hide
+
+	shopDetailText := widget.NewText(
+		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 231, G: 236, B: 242, A: 255}),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
+		widget.TextOpts.MaxWidth(360),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter, Stretch: true})),
+	)
+
+	shopMessage := widget.NewText(
+		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 255, G: 214, B: 120, A: 255}),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
+		widget.TextOpts.MaxWidth(360),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter, Stretch: true})),
+	)
+
 	portraitBox.AddChild(portrait)
+	textPanel.AddChild(speakerText)
 	textPanel.AddChild(text)
+	for _, choiceText := range choiceTexts {
+		textPanel.AddChild(choiceText)
+	}
 	panel.AddChild(textPanel)
 	panel.AddChild(portraitBox)
 	overlay.AddChild(panel)
@@ -299,10 +424,21 @@ func NewUIRoot(w *ecs.World) (ecs.Entity, error) {
 	inventoryPanel.AddChild(inventoryTitle)
 	inventoryPanel.AddChild(inventoryBody)
 	inventoryOverlay.AddChild(inventoryPanel)
+	shopGridPanel.AddChild(shopGridHost)
+	shopDetailPanel.AddChild(shopDetailImage)
+	shopDetailPanel.AddChild(shopDetailText)
+	shopDetailPanel.AddChild(shopMessage)
+	shopBody.AddChild(shopGridPanel)
+	shopBody.AddChild(shopDetailPanel)
+	shopPanel.AddChild(shopTitle)
+	shopPanel.AddChild(shopGears)
+	shopPanel.AddChild(shopBody)
+	shopOverlay.AddChild(shopPanel)
 	overlayLayer.AddChild(overlay)
 	overlayLayer.AddChild(tutorialOverlay)
 	overlayLayer.AddChild(itemOverlay)
 	overlayLayer.AddChild(inventoryOverlay)
+	overlayLayer.AddChild(shopOverlay)
 	root.AddChild(hudLayer)
 	root.AddChild(overlayLayer)
 
@@ -313,7 +449,7 @@ func NewUIRoot(w *ecs.World) (ecs.Entity, error) {
 	if err := ecs.Add(w, ent, component.UIRootComponent.Kind(), &component.UIRoot{UI: &ebitenui.UI{Container: root}}); err != nil {
 		return 0, fmt.Errorf("ui root: add root: %w", err)
 	}
-	if err := ecs.Add(w, ent, component.DialogueUIComponent.Kind(), &component.DialogueUI{Root: root, HUDLayer: hudLayer, OverlayLayer: overlayLayer, Overlay: overlay, Panel: panel, PortraitBox: portraitBox, Portrait: portrait, Text: text}); err != nil {
+	if err := ecs.Add(w, ent, component.DialogueUIComponent.Kind(), &component.DialogueUI{Root: root, HUDLayer: hudLayer, OverlayLayer: overlayLayer, Overlay: overlay, Panel: panel, PortraitBox: portraitBox, Portrait: portrait, Speaker: speakerText, Text: text, Choices: choiceTexts}); err != nil {
 		return 0, fmt.Errorf("ui root: add dialogue ui: %w", err)
 	}
 	if err := ecs.Add(w, ent, component.DialogueStateComponent.Kind(), &component.DialogueState{}); err != nil {
@@ -331,6 +467,12 @@ func NewUIRoot(w *ecs.World) (ecs.Entity, error) {
 	if err := ecs.Add(w, ent, component.InventoryStateComponent.Kind(), &component.InventoryState{}); err != nil {
 		return 0, fmt.Errorf("ui root: add inventory state: %w", err)
 	}
+	if err := ecs.Add(w, ent, component.ShopUIComponent.Kind(), &component.ShopUI{Root: root, Overlay: shopOverlay, Panel: shopPanel, Title: shopTitle, Gears: shopGears, GridHost: shopGridHost, DetailPanel: shopDetailPanel, DetailImage: shopDetailImage, DetailText: shopDetailText, Message: shopMessage}); err != nil {
+		return 0, fmt.Errorf("ui root: add shop ui: %w", err)
+	}
+	if err := ecs.Add(w, ent, component.ShopStateComponent.Kind(), &component.ShopState{}); err != nil {
+		return 0, fmt.Errorf("ui root: add shop state: %w", err)
+	}
 	if err := ecs.Add(w, ent, component.TutorialUIComponent.Kind(), &component.TutorialUI{Root: root, Overlay: tutorialOverlay, Panel: tutorialPanel, Text: tutorialText}); err != nil {
 		return 0, fmt.Errorf("ui root: add tutorial ui: %w", err)
 	}
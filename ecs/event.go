@@ -0,0 +1,120 @@
+package ecs
+
+import "reflect"
+
+// Events are one-shot messages between systems, like a reload or a camera
+// shake, kept outside entities so nothing has to clean them up. Each type has
+// its own queue in emission order. An event lives for the frame it was
+// emitted in and the next one, so an EventReader sees it once whether its
+// system runs before or after the emitter. Frames are counted by
+// AdvanceEvents, which the game calls once per gameplay frame, not by
+// schedulers.
+
+type eventQueue[T any] struct {
+	// events holds last frame's events, then this frame's from split on.
+	events []T
+	split  int
+	// first is the sequence number of events[0].
+	first uint64
+}
+
+type eventBuffer interface {
+	advance()
+}
+
+func (q *eventQueue[T]) advance() {
+	var zero T
+	kept := copy(q.events, q.events[q.split:])
+	for i := kept; i < len(q.events); i++ {
+		q.events[i] = zero
+	}
+	q.first += uint64(q.split)
+	q.events = q.events[:kept]
+	q.split = kept
+}
+
+func queueFor[T any](w *World, create bool) *eventQueue[T] {
+	key := reflect.TypeFor[T]()
+	if buffer, ok := w.events[key]; ok {
+		return buffer.(*eventQueue[T])
+	}
+	if !create {
+		return nil
+	}
+	if w.events == nil {
+		w.events = map[reflect.Type]eventBuffer{}
+	}
+	q := &eventQueue[T]{}
+	w.events[key] = q
+	return q
+}
+
+// Emit queues event after every other event of its type.
+func Emit[T any](w *World, event T) {
+	if w == nil {
+		return
+	}
+	q := queueFor[T](w, true)
+	q.events = append(q.events, event)
+}
+
+// Events returns every live event of type T, oldest first, whoever has read
+// them. That is this frame's and last frame's, so a system calling it every
+// frame sees each event twice; systems read with an EventReader, and Events
+// is for tests and debugging. The slice is only valid until the next
+// AdvanceEvents.
+func Events[T any](w *World) []T {
+	if w == nil {
+		return nil
+	}
+	q := queueFor[T](w, false)
+	if q == nil {
+		return nil
+	}
+	return q.events
+}
+
+// AdvanceEvents starts a new event frame, dropping the events emitted before
+// the one that just ended. Whatever owns the world's frame calls it before
+// running the frame's systems; with several schedulers on one world, that
+// is once per frame, not once per Scheduler.Update.
+func AdvanceEvents(w *World) {
+	if w == nil {
+		return
+	}
+	for _, buffer := range w.events {
+		buffer.advance()
+	}
+}
+
+// EventReader reads each event of type T once. A system keeps one per type
+// it listens to; the zero value is ready to use.
+type EventReader[T any] struct {
+	queue *eventQueue[T]
+	next  uint64
+}
+
+// Read returns the events emitted since the last Read, oldest first. The
+// slice is only valid until the next AdvanceEvents.
+func (r *EventReader[T]) Read(w *World) []T {
+	if w == nil {
+		return nil
+	}
+	q := queueFor[T](w, false)
+	if q == nil {
+		return nil
+	}
+	if r.queue != q {
+		// A new world starts the reader over.
+		r.queue = q
+		r.next = q.first
+	}
+
+	from := q.first
+	if r.next > from {
+		from = r.next
+	}
+	end := q.first + uint64(len(q.events))
+	r.next = end
+	return q.events[from-q.first:]
+}
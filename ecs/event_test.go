@@ -0,0 +1,89 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+)
+
+type shakeEvent struct {
+	Frames int
+}
+
+func TestEventReaderSeesEachEventOnceWhateverItsOrder(t *testing.T) {
+	w := NewWorld()
+	var early, late EventReader[shakeEvent]
+
+	// Frame 1: early runs before the emitter, late after it.
+	AdvanceEvents(w)
+	if got := early.Read(w); len(got) != 0 {
+		t.Fatalf("expected nothing before the first emit, got %v", got)
+	}
+	Emit(w, shakeEvent{Frames: 1})
+	Emit(w, shakeEvent{Frames: 2})
+	if got := late.Read(w); !reflect.DeepEqual(got, []shakeEvent{{1}, {2}}) {
+		t.Fatalf("expected late to read both events in order, got %v", got)
+	}
+
+	// Frame 2: early catches up, late has nothing new.
+	AdvanceEvents(w)
+	if got := early.Read(w); !reflect.DeepEqual(got, []shakeEvent{{1}, {2}}) {
+		t.Fatalf("expected early to read last frame's events, got %v", got)
+	}
+	Emit(w, shakeEvent{Frames: 3})
+	if got := late.Read(w); !reflect.DeepEqual(got, []shakeEvent{{3}}) {
+		t.Fatalf("expected late to read only the new event, got %v", got)
+	}
+	if got := Events[shakeEvent](w); !reflect.DeepEqual(got, []shakeEvent{{1}, {2}, {3}}) {
+		t.Fatalf("expected every live event, got %v", got)
+	}
+
+	// Frame 3: frame 1's events are gone even for a reader that never read.
+	AdvanceEvents(w)
+	var fresh EventReader[shakeEvent]
+	if got := fresh.Read(w); !reflect.DeepEqual(got, []shakeEvent{{3}}) {
+		t.Fatalf("expected only frame 2's event to be live, got %v", got)
+	}
+
+	AdvanceEvents(w)
+	if got := Events[shakeEvent](w); len(got) != 0 {
+		t.Fatalf("expected every event to have expired, got %v", got)
+	}
+}
+
+func TestEventReaderStartsOverInANewWorld(t *testing.T) {
+	var reader EventReader[shakeEvent]
+	first := NewWorld()
+	for i := 0; i < 3; i++ {
+		Emit(first, shakeEvent{Frames: i})
+	}
+	reader.Read(first)
+
+	second := NewWorld()
+	Emit(second, shakeEvent{Frames: 9})
+	if got := reader.Read(second); !reflect.DeepEqual(got, []shakeEvent{{9}}) {
+		t.Fatalf("expected the new world's event, got %v", got)
+	}
+}
+
+func TestSchedulerLeavesEventsToTheFrame(t *testing.T) {
+	w := NewWorld()
+	gameplay, dialogue := NewScheduler(), NewScheduler()
+	var reader EventReader[shakeEvent]
+
+	// Gameplay emits as the game switches to dialogue for a few updates.
+	AdvanceEvents(w)
+	gameplay.Update(w)
+	Emit(w, shakeEvent{Frames: 1})
+	for i := 0; i < 3; i++ {
+		dialogue.Update(w)
+	}
+	if got := reader.Read(w); !reflect.DeepEqual(got, []shakeEvent{{1}}) {
+		t.Fatalf("expected the event to outlast the dialogue updates, got %v", got)
+	}
+
+	AdvanceEvents(w)
+	AdvanceEvents(w)
+	if got := Events[shakeEvent](w); len(got) != 0 {
+		t.Fatalf("expected the event to expire after two frames, got %v", got)
+	}
+}
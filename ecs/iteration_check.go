@@ -0,0 +1,7 @@
+//go:build ecsdebug
+
+package ecs
+
+// checkIteration makes a store panic when it gains or loses an entity while a
+// ForEach or Query walks it. Build or test with -tags ecsdebug to turn it on.
+const checkIteration = true
@@ -0,0 +1,75 @@
+//go:build ecsdebug
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestStoreChangedDuringIterationPanics(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	other := component.NewComponentKind[int]()
+
+	tests := []struct {
+		name   string
+		change func(w *World, self, next Entity)
+		panics bool
+	}{
+		{"remove_walked", func(w *World, self, next Entity) { Remove(w, next, pos) }, true},
+		{"destroy", func(w *World, self, next Entity) { DestroyEntity(w, next) }, true},
+		{"add_walked", func(w *World, self, next Entity) {
+			e := CreateEntity(w)
+			_ = Add(w, e, pos, intPtr(9))
+		}, true},
+		{"replace_walked", func(w *World, self, next Entity) { _ = Add(w, self, pos, intPtr(7)) }, false},
+		{"other_store", func(w *World, self, next Entity) {
+			_ = Add(w, self, other, intPtr(1))
+			Remove(w, next, other)
+		}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWorld()
+			a := CreateEntity(w)
+			_ = Add(w, a, pos, intPtr(1))
+			b := CreateEntity(w)
+			_ = Add(w, b, pos, intPtr(2))
+			_ = Add(w, b, other, intPtr(2))
+
+			panicked := func() (panicked bool) {
+				defer func() { panicked = recover() != nil }()
+				ForEach(w, pos, func(e Entity, _ *int) {
+					if e == a {
+						tc.change(w, a, b)
+					}
+				})
+				return false
+			}()
+			if panicked != tc.panics {
+				t.Fatalf("expected panic=%v, got %v", tc.panics, panicked)
+			}
+
+			// Even a loop that panicked stops counting as walking the store.
+			Remove(w, a, pos)
+		})
+	}
+}
+
+func TestQueryEachChecksWalkedSet(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected creating an entity under a query with no With terms to panic")
+		}
+	}()
+	NewQuery().Each(w, func(Entity) {
+		CreateEntity(w)
+	})
+}
@@ -0,0 +1,5 @@
+//go:build !ecsdebug
+
+package ecs
+
+const checkIteration = false
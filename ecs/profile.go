@@ -0,0 +1,235 @@
+package ecs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultProfileWindow is how many recent frames a SystemProfile keeps for
+// its percentiles, two seconds at 60 updates a second.
+const DefaultProfileWindow = 120
+
+const allocsMetric = "/gc/heap/allocs:objects"
+
+// SystemProfile times each system a scheduler runs. It keeps the wall time
+// and heap allocation count of the last few runs of every system for
+// rolling percentiles, plus totals for the whole session. Schedulers sharing
+// one profile add to the same entries for systems with the same name.
+type SystemProfile struct {
+	window  int
+	entries map[string]*profileEntry
+	order   []string
+	sample  []metrics.Sample
+}
+
+type profileEntry struct {
+	name      string
+	durations []time.Duration
+	allocs    []uint64
+	next      int
+	runs      int
+	total     time.Duration
+	allocsSum uint64
+}
+
+// SystemStats summarizes one system's runs. The percentiles and Mean cover
+// the rolling window; Runs, Total and Allocs cover the whole session.
+type SystemStats struct {
+	Name        string        `json:"name"`
+	Runs        int           `json:"runs"`
+	Last        time.Duration `json:"last_ns"`
+	Mean        time.Duration `json:"mean_ns"`
+	P50         time.Duration `json:"p50_ns"`
+	P95         time.Duration `json:"p95_ns"`
+	P99         time.Duration `json:"p99_ns"`
+	Max         time.Duration `json:"max_ns"`
+	Total       time.Duration `json:"total_ns"`
+	LastAllocs  uint64        `json:"last_allocs"`
+	MeanAllocs  float64       `json:"mean_allocs"`
+	TotalAllocs uint64        `json:"total_allocs"`
+}
+
+// NewSystemProfile returns a profile keeping the last window runs of each
+// system, or DefaultProfileWindow when window is not positive.
+func NewSystemProfile(window int) *SystemProfile {
+	if window <= 0 {
+		window = DefaultProfileWindow
+	}
+	return &SystemProfile{
+		window:  window,
+		entries: map[string]*profileEntry{},
+		sample:  []metrics.Sample{{Name: allocsMetric}},
+	}
+}
+
+// Measure runs fn and records its wall time and allocations under name. It
+// is how the scheduler times systems, and can time work that runs outside a
+// scheduler, like drawing.
+func (p *SystemProfile) Measure(name string, fn func()) {
+	if p == nil {
+		fn()
+		return
+	}
+	startAllocs := p.allocs()
+	start := time.Now()
+	fn()
+	elapsed := time.Since(start)
+	p.record(name, elapsed, p.allocs()-startAllocs)
+}
+
+// allocs reads the heap allocation count without stopping the world, unlike
+// runtime.ReadMemStats. The runtime counts small objects a span at a time, so
+// one run's count is coarse and the mean over the window is the figure to
+// trust.
+func (p *SystemProfile) allocs() uint64 {
+	metrics.Read(p.sample)
+	if p.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return p.sample[0].Value.Uint64()
+}
+
+func (p *SystemProfile) record(name string, elapsed time.Duration, allocs uint64) {
+	entry, ok := p.entries[name]
+	if !ok {
+		entry = &profileEntry{
+			name:      name,
+			durations: make([]time.Duration, 0, p.window),
+			allocs:    make([]uint64, 0, p.window),
+		}
+		p.entries[name] = entry
+		p.order = append(p.order, name)
+	}
+
+	if len(entry.durations) < p.window {
+		entry.durations = append(entry.durations, elapsed)
+		entry.allocs = append(entry.allocs, allocs)
+	} else {
+		entry.durations[entry.next] = elapsed
+		entry.allocs[entry.next] = allocs
+	}
+	entry.next = (entry.next + 1) % p.window
+	entry.runs++
+	entry.total += elapsed
+	entry.allocsSum += allocs
+}
+
+// Stats lists every system in the order it first ran.
+func (p *SystemProfile) Stats() []SystemStats {
+	if p == nil {
+		return nil
+	}
+	stats := make([]SystemStats, 0, len(p.order))
+	for _, name := range p.order {
+		stats = append(stats, p.entries[name].stats())
+	}
+	return stats
+}
+
+func (e *profileEntry) stats() SystemStats {
+	last := (e.next - 1 + len(e.durations)) % len(e.durations)
+	sorted := append([]time.Duration(nil), e.durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	var allocs uint64
+	for _, a := range e.allocs {
+		allocs += a
+	}
+
+	return SystemStats{
+		Name:        e.name,
+		Runs:        e.runs,
+		Last:        e.durations[last],
+		Mean:        sum / time.Duration(len(sorted)),
+		P50:         percentile(sorted, 0.50),
+		P95:         percentile(sorted, 0.95),
+		P99:         percentile(sorted, 0.99),
+		Max:         sorted[len(sorted)-1],
+		Total:       e.total,
+		LastAllocs:  e.allocs[last],
+		MeanAllocs:  float64(allocs) / float64(len(e.allocs)),
+		TotalAllocs: e.allocsSum,
+	}
+}
+
+// percentile picks the nearest-rank value from sorted durations.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(q*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// WriteJSON writes Stats as a JSON array with durations in nanoseconds.
+func (p *SystemProfile) WriteJSON(w io.Writer) error {
+	stats := p.Stats()
+	if stats == nil {
+		stats = []SystemStats{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+// WriteCSV writes Stats with one row per system and durations in
+// microseconds.
+func (p *SystemProfile) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"system", "runs", "last_us", "mean_us", "p50_us", "p95_us", "p99_us", "max_us", "total_ms", "last_allocs", "mean_allocs", "total_allocs"}); err != nil {
+		return err
+	}
+	micros := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Microsecond), 'f', 1, 64)
+	}
+	for _, s := range p.Stats() {
+		row := []string{
+			s.Name,
+			strconv.Itoa(s.Runs),
+			micros(s.Last),
+			micros(s.Mean),
+			micros(s.P50),
+			micros(s.P95),
+			micros(s.P99),
+			micros(s.Max),
+			strconv.FormatFloat(float64(s.Total)/float64(time.Millisecond), 'f', 2, 64),
+			strconv.FormatUint(s.LastAllocs, 10),
+			strconv.FormatFloat(s.MeanAllocs, 'f', 1, 64),
+			strconv.FormatUint(s.TotalAllocs, 10),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// SystemName names a system by its type, like PhysicsSystem.
+func SystemName(system System) string {
+	t := reflect.TypeOf(system)
+	if t == nil {
+		return "<nil>"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return fmt.Sprintf("%T", system)
+	}
+	return t.Name()
+}
@@ -0,0 +1,93 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingSystem struct {
+	updates int
+	garbage [][]byte
+}
+
+func (s *countingSystem) Update(w *World) {
+	s.updates++
+	// Large objects are counted as they are made, unlike small ones.
+	s.garbage = append(s.garbage[:0], make([]byte, 64<<10), make([]byte, 64<<10))
+}
+
+type idleSystem struct{}
+
+func (idleSystem) Update(w *World) {}
+
+func TestSchedulerProfilesEachSystemByName(t *testing.T) {
+	counting := &countingSystem{}
+	scheduler := NewScheduler(counting, idleSystem{})
+	scheduler.SetProfile(NewSystemProfile(4))
+
+	for i := 0; i < 6; i++ {
+		scheduler.Update(nil)
+	}
+
+	stats := scheduler.Profile().Stats()
+	if len(stats) != 2 || stats[0].Name != "countingSystem" || stats[1].Name != "idleSystem" {
+		t.Fatalf("expected countingSystem then idleSystem, got %+v", stats)
+	}
+	if counting.updates != 6 || stats[0].Runs != 6 {
+		t.Fatalf("expected 6 updates and runs, got %d and %d", counting.updates, stats[0].Runs)
+	}
+	if stats[0].LastAllocs < 2 || stats[0].TotalAllocs < 6*2 {
+		t.Fatalf("expected at least 2 allocations a run, got %+v", stats[0])
+	}
+}
+
+func TestSystemProfileKeepsRollingPercentiles(t *testing.T) {
+	profile := NewSystemProfile(10)
+	// The first ten runs fall out of the window.
+	for i := 0; i < 10; i++ {
+		profile.record("PhysicsSystem", time.Second, 100)
+	}
+	for i := 1; i <= 10; i++ {
+		profile.record("PhysicsSystem", time.Duration(i)*time.Millisecond, uint64(i))
+	}
+
+	stats := profile.Stats()[0]
+	if stats.P50 != 5*time.Millisecond || stats.P95 != 10*time.Millisecond || stats.Max != 10*time.Millisecond {
+		t.Fatalf("expected p50 5ms and p95 and max 10ms, got %+v", stats)
+	}
+	if stats.Last != 10*time.Millisecond || stats.LastAllocs != 10 || stats.MeanAllocs != 5.5 {
+		t.Fatalf("expected the last run and mean allocations of the window, got %+v", stats)
+	}
+	if stats.Runs != 20 || stats.Total != 10*time.Second+55*time.Millisecond || stats.TotalAllocs != 1055 {
+		t.Fatalf("expected session totals over all 20 runs, got %+v", stats)
+	}
+}
+
+func TestSystemProfileWritesCSVAndJSON(t *testing.T) {
+	profile := NewSystemProfile(0)
+	profile.record("PhysicsSystem", 1500*time.Microsecond, 3)
+
+	var csvOut bytes.Buffer
+	if err := profile.WriteCSV(&csvOut); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	if len(lines) != 2 || lines[1] != "PhysicsSystem,1,1500.0,1500.0,1500.0,1500.0,1500.0,1500.0,1.50,3,3.0,3" {
+		t.Fatalf("unexpected csv:\n%s", csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	if err := profile.WriteJSON(&jsonOut); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	var decoded []SystemStats
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].P99 != 1500*time.Microsecond || decoded[0].TotalAllocs != 3 {
+		t.Fatalf("unexpected json: %s", jsonOut.String())
+	}
+}
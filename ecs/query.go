@@ -0,0 +1,209 @@
+package ecs
+
+import "github.com/milk9111/sidescroller/ecs/component"
+
+// Kind is any component kind, whatever its component type. Query terms take
+// kinds of several types at once.
+type Kind interface {
+	ID() component.ComponentID
+	Valid() bool
+}
+
+// Query matches the entities that have every With component and no Without
+// component. Optional components don't change what matches; they name what
+// the caller reads besides, and Fetch reads them like With components.
+//
+// A system builds its queries once and keeps them: the query remembers the
+// stores it found in the last world it ran on and reuses its entity slice, so
+// running it every frame doesn't look them up again or allocate.
+type Query struct {
+	with     []component.ComponentID
+	without  []component.ComponentID
+	optional []component.ComponentID
+	invalid  bool
+
+	world         *World
+	stores        map[component.ComponentID]componentStore
+	withStores    []componentStore
+	withoutStores []componentStore
+	entities      []Entity
+}
+
+// NewQuery returns a query matching entities that have every kind in with.
+func NewQuery(with ...Kind) *Query {
+	return (&Query{}).With(with...)
+}
+
+// With adds kinds the matched entities must have.
+func (q *Query) With(kinds ...Kind) *Query {
+	q.with = q.appendKinds(q.with, kinds)
+	return q
+}
+
+// Without adds kinds the matched entities must not have.
+func (q *Query) Without(kinds ...Kind) *Query {
+	q.without = q.appendKinds(q.without, kinds)
+	return q
+}
+
+// Optional adds kinds the matched entities may have, for Fetch to read.
+func (q *Query) Optional(kinds ...Kind) *Query {
+	q.optional = q.appendKinds(q.optional, kinds)
+	return q
+}
+
+func (q *Query) appendKinds(ids []component.ComponentID, kinds []Kind) []component.ComponentID {
+	for _, kind := range kinds {
+		if kind == nil || !kind.Valid() {
+			// Like ForEach with an invalid kind, the query matches nothing.
+			q.invalid = true
+			continue
+		}
+		ids = append(ids, kind.ID())
+	}
+	// Terms added after the query ran need their stores looked up.
+	q.world = nil
+	return ids
+}
+
+// Each calls fn for every matching entity, walking the smallest With store,
+// or every entity when the query has no With terms. Like ForEach, fn must not
+// add entities to or remove them from the walked set; record those changes in
+// a CommandBuffer, or iterate Entities instead.
+func (q *Query) Each(w *World, fn func(Entity)) {
+	base, ok := q.prepare(w)
+	if !ok {
+		return
+	}
+	base.beginWalk()
+	defer base.endWalk()
+	for _, id := range base.ids() {
+		if q.matches(w, id) {
+			fn(makeEntity(id, w.generations[id]))
+		}
+	}
+}
+
+// Entities returns every matching entity. The slice is reused by the next
+// call, so it is only valid until then.
+func (q *Query) Entities(w *World) []Entity {
+	q.entities = q.entities[:0]
+	base, ok := q.prepare(w)
+	if !ok {
+		return q.entities
+	}
+	for _, id := range base.ids() {
+		if q.matches(w, id) {
+			q.entities = append(q.entities, makeEntity(id, w.generations[id]))
+		}
+	}
+	return q.entities
+}
+
+// Matches reports whether e is alive and matches the query.
+func (q *Query) Matches(w *World, e Entity) bool {
+	if _, ok := q.prepare(w); !ok {
+		return false
+	}
+	return IsAlive(w, e) && q.matches(w, e.id())
+}
+
+// Fetch reads e's component of kind through the stores q last ran on. kind
+// should be one of q's With or Optional terms; other kinds fall back to Get.
+func Fetch[T any](q *Query, e Entity, kind component.ComponentKind[T]) (*T, bool) {
+	w := q.world
+	if w == nil || !IsAlive(w, e) || !kind.Valid() {
+		return nil, false
+	}
+	store, ok := q.stores[kind.ID()]
+	if !ok {
+		return Get(w, e, kind)
+	}
+	typed, ok := store.(*sparseComponentStore[T])
+	if !ok {
+		return nil, false
+	}
+	return typed.get(e.id())
+}
+
+// walkable is a set of entity ids a query can walk: a store, or the world's
+// live entities.
+type walkable interface {
+	ids() []entityID
+	beginWalk()
+	endWalk()
+}
+
+// prepare looks up the query's stores in w and returns the set to walk, or
+// false when nothing can match.
+func (q *Query) prepare(w *World) (walkable, bool) {
+	if w == nil || q.invalid {
+		return nil, false
+	}
+	if q.world != w {
+		q.world = w
+		q.stores = make(map[component.ComponentID]componentStore, len(q.with)+len(q.without)+len(q.optional))
+	}
+
+	var base componentStore
+	q.withStores = q.withStores[:0]
+	for _, id := range q.with {
+		store := q.store(w, id)
+		if store == nil || store.len() == 0 {
+			return nil, false
+		}
+		if base == nil || store.len() < base.len() {
+			base = store
+		}
+		q.withStores = append(q.withStores, store)
+	}
+	q.withoutStores = q.withoutStores[:0]
+	for _, id := range q.without {
+		if store := q.store(w, id); store != nil {
+			q.withoutStores = append(q.withoutStores, store)
+		}
+	}
+	for _, id := range q.optional {
+		q.store(w, id)
+	}
+
+	if base == nil {
+		return &w.alive, true
+	}
+	return base, true
+}
+
+// store returns the store for id, caching it once the world has one. Worlds
+// never replace a store, so a cached one stays valid.
+func (q *Query) store(w *World, id component.ComponentID) componentStore {
+	if store, ok := q.stores[id]; ok {
+		return store
+	}
+	v, ok := w.components[id]
+	if !ok {
+		return nil
+	}
+	store, ok := v.(componentStore)
+	if !ok {
+		panic("ecs: component store type mismatch")
+	}
+	q.stores[id] = store
+	return store
+}
+
+func (q *Query) matches(w *World, id entityID) bool {
+	if !w.alive.has(id) {
+		return false
+	}
+	for _, store := range q.withStores {
+		if !store.has(id) {
+			return false
+		}
+	}
+	for _, store := range q.withoutStores {
+		if store.has(id) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package ecs
+
+import (
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestQueryFilters(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	tag := component.NewComponentKind[string]()
+	skip := component.NewComponentKind[struct{}]()
+	extra := component.NewComponentKind[float64]()
+
+	w := NewWorld()
+	plain := CreateEntity(w)
+	_ = Add(w, plain, pos, intPtr(1))
+	tagged := CreateEntity(w)
+	_ = Add(w, tagged, pos, intPtr(2))
+	_ = Add(w, tagged, tag, stringPtr("a"))
+	skipped := CreateEntity(w)
+	_ = Add(w, skipped, pos, intPtr(3))
+	_ = Add(w, skipped, tag, stringPtr("b"))
+	_ = Add(w, skipped, skip, &struct{}{})
+	withExtra := CreateEntity(w)
+	_ = Add(w, withExtra, pos, intPtr(4))
+	_ = Add(w, withExtra, extra, float64Ptr(0.5))
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  []Entity
+	}{
+		{"with", NewQuery(pos), []Entity{plain, tagged, skipped, withExtra}},
+		{"with_two", NewQuery(pos, tag), []Entity{tagged, skipped}},
+		{"without", NewQuery(pos).Without(skip), []Entity{plain, tagged, withExtra}},
+		{"with_and_without", NewQuery(tag).Without(skip), []Entity{tagged}},
+		{"optional_does_not_filter", NewQuery(pos).Optional(extra, tag), []Entity{plain, tagged, skipped, withExtra}},
+		{"no_with_matches_every_entity", NewQuery().Without(tag), []Entity{plain, withExtra}},
+		{"missing_store", NewQuery(pos, component.NewComponentKind[bool]()), nil},
+		{"without_missing_store", NewQuery(tag).Without(component.NewComponentKind[bool]()), []Entity{tagged, skipped}},
+		{"invalid_kind", NewQuery(pos).Without(component.ComponentKind[int]{}), nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.query.Entities(w)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d entities, got %v", len(tc.want), got)
+			}
+			gotSet := toSet(got)
+			for _, e := range tc.want {
+				if _, ok := gotSet[e]; !ok {
+					t.Fatalf("expected %v in %v", e, got)
+				}
+				if !tc.query.Matches(w, e) {
+					t.Fatalf("Matches(%v) = false for a listed entity", e)
+				}
+			}
+
+			var each []Entity
+			tc.query.Each(w, func(e Entity) { each = append(each, e) })
+			if len(each) != len(got) {
+				t.Fatalf("Each visited %v, Entities returned %v", each, got)
+			}
+		})
+	}
+}
+
+func TestQueryWalksSmallestStore(t *testing.T) {
+	common := component.NewComponentKind[int]()
+	rare := component.NewComponentKind[int]()
+
+	w := NewWorld()
+	var want Entity
+	for i := 0; i < 50; i++ {
+		e := CreateEntity(w)
+		_ = Add(w, e, common, intPtr(i))
+		if i == 20 {
+			_ = Add(w, e, rare, intPtr(i))
+			want = e
+		}
+	}
+
+	q := NewQuery(common, rare)
+	base, ok := q.prepare(w)
+	if !ok || len(base.ids()) != 1 {
+		t.Fatalf("expected to walk the one-entity store, got %d ids", len(base.ids()))
+	}
+	if got := q.Entities(w); len(got) != 1 || got[0] != want {
+		t.Fatalf("expected [%v], got %v", want, got)
+	}
+}
+
+func TestQueryReusedAcrossChanges(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	dead := component.NewComponentKind[bool]()
+
+	w := NewWorld()
+	q := NewQuery(pos).Without(dead)
+	if got := q.Entities(w); len(got) != 0 {
+		t.Fatalf("expected no entities before any store exists, got %v", got)
+	}
+
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+	if got := q.Entities(w); len(got) != 2 {
+		t.Fatalf("expected both entities once their store exists, got %v", got)
+	}
+
+	// The Without store appears after the query first ran.
+	_ = Add(w, b, dead, boolPtr(true))
+	if got := q.Entities(w); len(got) != 1 || got[0] != a {
+		t.Fatalf("expected [%v] after excluding %v, got %v", a, b, got)
+	}
+
+	DestroyEntity(w, a)
+	if got := q.Entities(w); len(got) != 0 {
+		t.Fatalf("expected destroyed entity to drop out, got %v", got)
+	}
+	if q.Matches(w, a) {
+		t.Fatalf("destroyed entity should not match")
+	}
+
+	other := NewWorld()
+	c := CreateEntity(other)
+	_ = Add(other, c, pos, intPtr(3))
+	if got := q.Entities(other); len(got) != 1 || got[0] != c {
+		t.Fatalf("expected the query to follow a new world, got %v", got)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	name := component.NewComponentKind[string]()
+	other := component.NewComponentKind[float64]()
+
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	_ = Add(w, a, name, stringPtr("a"))
+	_ = Add(w, a, other, float64Ptr(2))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+
+	q := NewQuery(pos).Optional(name)
+	if _, ok := Fetch(q, a, pos); ok {
+		t.Fatalf("Fetch before the query ran should find nothing")
+	}
+
+	names := map[Entity]string{}
+	q.Each(w, func(e Entity) {
+		p, ok := Fetch(q, e, pos)
+		if !ok {
+			t.Fatalf("expected With component for %v", e)
+		}
+		if n, ok := Fetch(q, e, name); ok {
+			names[e] = *n
+		} else if *p != 2 {
+			t.Fatalf("expected only b to lack a name, got pos %d", *p)
+		}
+	})
+	if len(names) != 1 || names[a] != "a" {
+		t.Fatalf("expected only a's name, got %v", names)
+	}
+
+	if v, ok := Fetch(q, a, other); !ok || *v != 2 {
+		t.Fatalf("expected Fetch of a kind outside the query to fall back to Get, got %v %v", v, ok)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
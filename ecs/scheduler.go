@@ -1,32 +1,279 @@
 package ecs
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type System interface {
 	Update(w *World)
 }
 
+// Phase is a coarse slot in the frame. A scheduler runs every system of one
+// phase before any system of the next.
+type Phase int
+
+const (
+	// PhaseInput reads input and plays what the last frame requested.
+	PhaseInput Phase = iota
+	// PhasePrePhysics decides what entities do and pushes forces.
+	PhasePrePhysics
+	// PhasePhysics steps the physics space.
+	PhasePhysics
+	// PhasePostPhysics reacts to where things ended up: contacts,
+	// triggers, pickups and scripts.
+	PhasePostPhysics
+	// PhaseLate spends the frame's results, like deaths, transitions and
+	// saves.
+	PhaseLate
+	// PhaseRenderPrep settles the camera and anything drawn relative to it.
+	PhaseRenderPrep
+)
+
+var phaseNames = [...]string{"input", "pre-physics", "physics", "post-physics", "late", "render-prep"}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return fmt.Sprintf("phase(%d)", int(p))
+	}
+	return phaseNames[p]
+}
+
+// SystemSpec places a system in a scheduler: in its phase, and within the
+// phase after the systems it names in After and before those in Before.
+// Systems with nothing between them keep the order they were registered in.
+type SystemSpec struct {
+	System System
+	// Name is what other specs' Before and After refer to. Empty uses
+	// SystemName.
+	Name  string
+	Phase Phase
+	// Before and After name other systems of the same scheduler. A name
+	// the scheduler doesn't have is an error, unless Shared is set.
+	Before []string
+	After  []string
+	// Shared marks a spec registered in several schedulers, letting Before
+	// and After name systems only some of them run; the others ignore
+	// those names.
+	Shared bool
+}
+
 type Scheduler struct {
-	systems []System
+	specs   []SystemSpec
+	ordered []int
+	dirty   bool
+	err     error
+	profile *SystemProfile
 }
 
 func NewScheduler(systems ...System) *Scheduler {
-	copied := append([]System(nil), systems...)
-	return &Scheduler{systems: copied}
+	s := &Scheduler{}
+	for _, system := range systems {
+		s.Add(system)
+	}
+	return s
 }
 
+// Add runs system in PhaseLate, after the systems added before it.
 func (s *Scheduler) Add(system System) {
-	if system == nil {
+	s.Register(SystemSpec{System: system, Phase: PhaseLate})
+}
+
+// Register adds a system as spec places it. The order is worked out on the
+// next Update.
+func (s *Scheduler) Register(spec SystemSpec) {
+	if spec.System == nil {
 		return
 	}
-	s.systems = append(s.systems, system)
+	if spec.Name == "" {
+		spec.Name = SystemName(spec.System)
+	}
+	s.specs = append(s.specs, spec)
+	s.dirty = true
+}
+
+// Register adds one spec to every scheduler given, for systems that run in
+// more than one, like music in both gameplay and dialogue.
+func Register(spec SystemSpec, schedulers ...*Scheduler) {
+	for _, s := range schedulers {
+		s.Register(spec)
+	}
+}
+
+// SetProfile times every system into profile on each update, or stops
+// timing when profile is nil.
+func (s *Scheduler) SetProfile(profile *SystemProfile) {
+	s.profile = profile
+}
+
+// Profile returns the profile set with SetProfile.
+func (s *Scheduler) Profile() *SystemProfile {
+	return s.profile
+}
+
+// Validate works out the run order. It reports cycles, Before or After
+// asking a system of another phase to run on the wrong side of its phase,
+// and Before or After naming more than one system or, unless the spec is
+// Shared, none.
+func (s *Scheduler) Validate() error {
+	s.resolve()
+	return s.err
 }
 
+// Update runs every system in order. It leaves events alone; see
+// AdvanceEvents. It panics if the order can't be worked out, since a
+// scheduler that runs systems in the wrong order fails in subtler ways later.
 func (s *Scheduler) Update(w *World) {
-	for _, system := range s.systems {
-		system.Update(w)
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+
+	for _, i := range s.ordered {
+		spec := s.specs[i]
+		if s.profile == nil {
+			spec.System.Update(w)
+		} else {
+			s.profile.Measure(spec.Name, func() {
+				spec.System.Update(w)
+			})
+		}
 	}
 }
 
+// Systems lists the systems in the order Update runs them, or in the order
+// they were registered if that can't be worked out.
 func (s *Scheduler) Systems() []System {
-	systems := make([]System, 0, len(s.systems))
-	return append(systems, s.systems...)
+	s.resolve()
+	systems := make([]System, 0, len(s.specs))
+	if s.err != nil {
+		for _, spec := range s.specs {
+			systems = append(systems, spec.System)
+		}
+		return systems
+	}
+	for _, i := range s.ordered {
+		systems = append(systems, s.specs[i].System)
+	}
+	return systems
+}
+
+func (s *Scheduler) resolve() {
+	if !s.dirty {
+		return
+	}
+	s.dirty = false
+	s.ordered, s.err = orderSpecs(s.specs)
+}
+
+// orderSpecs sorts specs by phase and, within a phase, topologically by
+// their Before and After, preferring registration order wherever the
+// constraints allow either.
+func orderSpecs(specs []SystemSpec) ([]int, error) {
+	byName := make(map[string]int, len(specs))
+	shared := map[string]bool{}
+	for i, spec := range specs {
+		if _, ok := byName[spec.Name]; ok {
+			shared[spec.Name] = true
+		}
+		byName[spec.Name] = i
+	}
+	lookup := func(spec SystemSpec, name string) (int, bool, error) {
+		if shared[name] {
+			return 0, false, fmt.Errorf("ecs: more than one system is named %s; give them Names to order against", name)
+		}
+		i, ok := byName[name]
+		if !ok && !spec.Shared {
+			return 0, false, fmt.Errorf("ecs: %s is ordered against %s, which the scheduler doesn't have", spec.Name, name)
+		}
+		return i, ok, nil
+	}
+
+	// edges[i] are the systems that must run after i.
+	edges := make([][]int, len(specs))
+	indegree := make([]int, len(specs))
+	link := func(first, then int) error {
+		a, b := specs[first], specs[then]
+		if a.Phase > b.Phase {
+			return fmt.Errorf("ecs: %s (%s) cannot run before %s (%s)", a.Name, a.Phase, b.Name, b.Phase)
+		}
+		if a.Phase == b.Phase {
+			edges[first] = append(edges[first], then)
+			indegree[then]++
+		}
+		return nil
+	}
+	for i, spec := range specs {
+		for _, name := range spec.Before {
+			j, ok, err := lookup(spec, name)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				if err := link(i, j); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, name := range spec.After {
+			j, ok, err := lookup(spec, name)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				if err := link(j, i); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	ordered := make([]int, 0, len(specs))
+	for _, phase := range distinctPhases(specs) {
+		// Kahn's algorithm, always taking the earliest registered system
+		// that is ready.
+		var ready []int
+		for i, spec := range specs {
+			if spec.Phase == phase && indegree[i] == 0 {
+				ready = append(ready, i)
+			}
+		}
+		for len(ready) > 0 {
+			sort.Ints(ready)
+			next := ready[0]
+			ready = ready[1:]
+			ordered = append(ordered, next)
+			for _, then := range edges[next] {
+				indegree[then]--
+				if indegree[then] == 0 {
+					ready = append(ready, then)
+				}
+			}
+		}
+	}
+
+	if len(ordered) < len(specs) {
+		var stuck []string
+		for i, spec := range specs {
+			if indegree[i] > 0 {
+				stuck = append(stuck, spec.Name)
+			}
+		}
+		return nil, fmt.Errorf("ecs: Before and After form a cycle among systems %s", strings.Join(stuck, ", "))
+	}
+	return ordered, nil
+}
+
+// distinctPhases lists the phases specs use, lowest first.
+func distinctPhases(specs []SystemSpec) []Phase {
+	seen := map[Phase]bool{}
+	var phases []Phase
+	for _, spec := range specs {
+		if !seen[spec.Phase] {
+			seen[spec.Phase] = true
+			phases = append(phases, spec.Phase)
+		}
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i] < phases[j] })
+	return phases
 }
@@ -0,0 +1,134 @@
+package ecs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordingSystem struct {
+	name string
+	log  *[]string
+}
+
+func (s *recordingSystem) Update(w *World) {
+	*s.log = append(*s.log, s.name)
+}
+
+func TestSchedulerOrdersByPhaseThenConstraints(t *testing.T) {
+	var log []string
+	system := func(name string) System { return &recordingSystem{name: name, log: &log} }
+
+	s := NewScheduler()
+	s.Register(SystemSpec{System: system("camera"), Name: "camera", Phase: PhaseRenderPrep})
+	s.Register(SystemSpec{System: system("scripts"), Name: "scripts", Phase: PhasePostPhysics, After: []string{"triggers"}})
+	s.Register(SystemSpec{System: system("triggers"), Name: "triggers", Phase: PhasePostPhysics})
+	s.Register(SystemSpec{System: system("physics"), Name: "physics", Phase: PhasePhysics, After: []string{"controller"}})
+	s.Register(SystemSpec{System: system("controller"), Name: "controller", Phase: PhaseInput})
+	s.Register(SystemSpec{System: system("pickups"), Name: "pickups", Phase: PhasePostPhysics, Before: []string{"triggers"}})
+	s.Register(SystemSpec{System: system("gates"), Name: "gates", Phase: PhasePostPhysics})
+
+	s.Update(nil)
+	want := []string{"controller", "physics", "pickups", "triggers", "scripts", "gates", "camera"}
+	if !reflect.DeepEqual(log, want) {
+		t.Fatalf("expected %v, got %v", want, log)
+	}
+}
+
+func TestSchedulerAddKeepsInsertionOrder(t *testing.T) {
+	var log []string
+	s := NewScheduler(&recordingSystem{name: "a", log: &log})
+	s.Add(&recordingSystem{name: "b", log: &log})
+	s.Register(SystemSpec{System: &recordingSystem{name: "c", log: &log}, Name: "c", Phase: PhaseLate})
+
+	// Without Names both recordingSystems share one, which is fine until
+	// something orders against it.
+	s.Update(nil)
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(log, want) {
+		t.Fatalf("expected %v, got %v", want, log)
+	}
+}
+
+func TestRegisterSharesOneSpecAcrossSchedulers(t *testing.T) {
+	var log []string
+	gameplay, dialogue := NewScheduler(), NewScheduler()
+	music := &recordingSystem{name: "music", log: &log}
+
+	gameplay.Register(SystemSpec{System: &recordingSystem{name: "audio", log: &log}, Name: "audio", Phase: PhaseInput})
+	// audio only exists in gameplay, so dialogue ignores the constraint.
+	Register(SystemSpec{System: music, Name: "music", Phase: PhaseInput, After: []string{"audio"}, Shared: true}, gameplay, dialogue)
+	dialogue.Register(SystemSpec{System: &recordingSystem{name: "ui", log: &log}, Name: "ui", Phase: PhaseRenderPrep})
+
+	gameplay.Update(nil)
+	dialogue.Update(nil)
+	if want := []string{"audio", "music", "music", "ui"}; !reflect.DeepEqual(log, want) {
+		t.Fatalf("expected %v, got %v", want, log)
+	}
+}
+
+func TestSchedulerRejectsCyclesAndConflictingPhases(t *testing.T) {
+	var log []string
+	system := func(name string) System { return &recordingSystem{name: name, log: &log} }
+
+	cases := []struct {
+		name  string
+		specs []SystemSpec
+		want  string
+	}{
+		{
+			name: "cycle",
+			specs: []SystemSpec{
+				{System: system("a"), Name: "a", Phase: PhaseLate, After: []string{"c"}},
+				{System: system("b"), Name: "b", Phase: PhaseLate, After: []string{"a"}},
+				{System: system("c"), Name: "c", Phase: PhaseLate, After: []string{"b"}},
+				{System: system("d"), Name: "d", Phase: PhaseLate},
+			},
+			want: "cycle among systems a, b, c",
+		},
+		{
+			name: "phase",
+			specs: []SystemSpec{
+				{System: system("camera"), Name: "camera", Phase: PhaseRenderPrep, Before: []string{"physics"}},
+				{System: system("physics"), Name: "physics", Phase: PhasePhysics},
+			},
+			want: "camera (render-prep) cannot run before physics (physics)",
+		},
+		{
+			name: "duplicate",
+			specs: []SystemSpec{
+				{System: system("a"), Name: "a"},
+				{System: system("a"), Name: "a"},
+				{System: system("b"), Name: "b", After: []string{"a"}},
+			},
+			want: "more than one system is named a",
+		},
+		{
+			name: "unknown",
+			specs: []SystemSpec{
+				{System: system("a"), Name: "a"},
+				{System: system("b"), Name: "b", Before: []string{"c"}},
+			},
+			want: "b is ordered against c, which the scheduler doesn't have",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewScheduler()
+			for _, spec := range c.specs {
+				s.Register(spec)
+			}
+			err := s.Validate()
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("expected an error containing %q, got %v", c.want, err)
+			}
+
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected Update to panic")
+				}
+			}()
+			s.Update(nil)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/levels"
+	"github.com/milk9111/sidescroller/prefabs"
+)
+
+// CheckScripts loads a script set the way the runtime would, without running
+// it, and reports what would only fail once the bad line ran: module
+// functions that don't exist, calls with the wrong number of arguments where
+// that is known, and state names that aren't in the states map. A set that
+// fails to load or compile reports just that.
+func (r *Runtime) CheckScripts(scriptComp *component.Script) []Issue {
+	if r == nil || scriptComp == nil {
+		return nil
+	}
+	paths := scriptPaths(scriptComp)
+	if _, err := r.compileScript(paths, scriptComp.Modules, scriptComp.Stdlib); err != nil {
+		return []Issue{loadIssue(paths, err)}
+	}
+
+	scripts := make([]parsedScript, 0, len(paths))
+	for _, path := range paths {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		src, err := prefabs.LoadScript(path)
+		if err != nil {
+			return []Issue{loadIssue(paths, err)}
+		}
+		file := parser.NewFileSet().AddFile(path, -1, len(src))
+		parsed, err := parser.NewParser(file, src, nil).ParseFile()
+		if err != nil {
+			return []Issue{{File: path, Message: err.Error()}}
+		}
+		scripts = append(scripts, parsedScript{path: path, file: parsed})
+	}
+
+	issues := checkSources(scripts, r.moduleFunctions())
+	sortIssues(issues)
+	return issues
+}
+
+// CheckReferencedScripts checks every script set a prefab or a level entity
+// uses, each once.
+func CheckReferencedScripts() ([]Issue, error) {
+	sets, err := referencedScripts()
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRuntime()
+	seen := map[string]bool{}
+	var issues []Issue
+	for _, set := range sets {
+		for _, issue := range r.CheckScripts(set) {
+			// Files shared between sets would report the same issue again.
+			if key := issue.String(); !seen[key] {
+				seen[key] = true
+				issues = append(issues, issue)
+			}
+		}
+	}
+	sortIssues(issues)
+	return issues, nil
+}
+
+// referencedScripts lists the script components of the prefabs and the
+// script overrides of level entities, without repeats.
+func referencedScripts() ([]*component.Script, error) {
+	var sets []*component.Script
+	seen := map[string]bool{}
+	add := func(raw any, source string) error {
+		spec, err := prefabs.DecodeComponentSpec[prefabs.ScriptComponentSpec](raw)
+		if err != nil {
+			return fmt.Errorf("%s: decode script: %w", source, err)
+		}
+		paths := append([]string(nil), spec.Paths...)
+		if spec.Path != "" {
+			paths = append([]string{spec.Path}, paths...)
+		}
+		key := compileCacheKey(paths, spec.Modules, spec.Stdlib)
+		if len(paths) == 0 || seen[key] {
+			return nil
+		}
+		seen[key] = true
+		sets = append(sets, &component.Script{Paths: paths, Modules: spec.Modules, Stdlib: spec.Stdlib})
+		return nil
+	}
+
+	prefabNames, err := fs.Glob(prefabs.PrefabsFS, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range prefabNames {
+		spec, err := prefabs.LoadEntityBuildSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		if raw, ok := spec.Components["script"]; ok {
+			if err := add(raw, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	levelNames, err := fs.Glob(levels.LevelsFS, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range levelNames {
+		level, err := levels.LoadLevelFromFS(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, ent := range level.Entities {
+			components, _ := ent.Props["components"].(map[string]interface{})
+			if raw, ok := components["script"]; ok {
+				if err := add(raw, name+" "+ent.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return sets, nil
+}
+
+// moduleFunctions lists the names each module a script can import exposes.
+func (r *Runtime) moduleFunctions() map[string]map[string]bool {
+	functions := map[string]map[string]bool{}
+	add := func(module string, values map[string]tengo.Object) {
+		names := map[string]bool{}
+		for name := range values {
+			names[name] = true
+		}
+		functions[module] = names
+	}
+
+	for name, module := range stdlib.BuiltinModules {
+		add(name, module)
+	}
+	add("rand", seededRandModule())
+	// Building a module only creates its functions, so no entity is needed.
+	for name, plugin := range r.modules {
+		add(name, r.buildPluginModule(plugin, 0, 0))
+	}
+	add(scriptSignalsModule, r.buildSignalsModule(0, nil))
+	add(scriptTimerModule, r.buildTimerModule(nil))
+	return functions
+}
+
+// loadIssue locates an error loading or compiling a script set in its
+// original files when it has a position.
+func loadIssue(paths []string, err error) Issue {
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	issue := Issue{File: strings.Join(paths, ";"), Message: message}
+
+	var compileErr *compileError
+	if !errors.As(err, &compileErr) {
+		return issue
+	}
+	match := scriptPositionPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return issue
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	if file, fileLine, ok := sourceLine(compileErr.sources, line); ok {
+		issue.File, issue.Line, issue.Column = file, fileLine, column
+	}
+	return issue
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+}
@@ -0,0 +1,465 @@
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/token"
+)
+
+// Issue is a problem found in a script file without running it.
+type Issue struct {
+	File string
+	// Line is 0 when the problem is with the script set as a whole, like a
+	// missing file.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+}
+
+// arity is how many arguments a function takes; max is -1 when there is no
+// limit.
+type arity struct {
+	min, max int
+}
+
+func (a arity) accepts(n int) bool {
+	return n >= a.min && (a.max < 0 || n <= a.max)
+}
+
+func (a arity) String() string {
+	switch {
+	case a.max < 0:
+		return fmt.Sprintf("at least %d", a.min)
+	case a.min == a.max:
+		return fmt.Sprintf("%d", a.min)
+	default:
+		return fmt.Sprintf("%d to %d", a.min, a.max)
+	}
+}
+
+// knownArities are the module functions whose argument counts are checked.
+// Registered modules only expose names, so these are the ones the runtime
+// defines itself.
+var knownArities = map[string]map[string]arity{
+	scriptSignalsModule: {"on": {2, 3}, "off": {1, 1}, "emit": {1, 3}},
+	scriptTimerModule:   {"after": {2, 2}, "every": {2, 2}, "cancel": {1, 1}},
+}
+
+// forEntityArity applies to the for_entity every registered module has.
+var forEntityArity = arity{1, 1}
+
+// Callbacks the runtime calls are handed the state, and signal callbacks
+// the payload too when they take it.
+var (
+	stateCallbackArity  = arity{1, 1}
+	signalCallbackArity = arity{1, 2}
+)
+
+// stateKeys are the state entries that hold a state name.
+var stateKeys = map[string]bool{"next_state": true, "current_state": true}
+
+type parsedScript struct {
+	path string
+	file *parser.File
+}
+
+// stateTable is a top-level map that may hold a state's methods.
+type stateTable struct {
+	script  parsedScript
+	methods *parser.MapLit
+}
+
+// scriptSet is what the files of a script set define at the top level,
+// which every file can see once they are joined.
+type scriptSet struct {
+	// functions are the exported names of each module a script can import;
+	// a module missing here is not checked.
+	functions map[string]map[string]bool
+	// aliases maps the names modules are imported as to the modules.
+	aliases map[string]string
+	// topFuncs are top-level functions defined once and never shadowed.
+	topFuncs map[string]*parser.FuncLit
+	// states are the keys of the top-level states map, if there is one.
+	states map[string]bool
+	issues []Issue
+}
+
+// checkSources reports calls to module functions that don't exist, calls
+// with the wrong number of arguments where that is known, and state names
+// that aren't in the states map.
+func checkSources(scripts []parsedScript, functions map[string]map[string]bool) []Issue {
+	set := &scriptSet{
+		functions: functions,
+		aliases:   map[string]string{},
+		topFuncs:  map[string]*parser.FuncLit{},
+	}
+
+	defined := map[string]int{}
+	var states *parser.MapLit
+	tables := map[string]stateTable{}
+	for _, script := range scripts {
+		for _, stmt := range script.file.Stmts {
+			assign, ok := stmt.(*parser.AssignStmt)
+			if !ok || len(assign.LHS) != 1 || len(assign.RHS) != 1 {
+				continue
+			}
+			ident, ok := assign.LHS[0].(*parser.Ident)
+			if !ok {
+				continue
+			}
+			switch value := assign.RHS[0].(type) {
+			case *parser.ImportExpr:
+				if _, ok := set.aliases[ident.Name]; !ok {
+					set.aliases[ident.Name] = value.ModuleName
+				}
+			case *parser.FuncLit:
+				set.topFuncs[ident.Name] = value
+			case *parser.MapLit:
+				if ident.Name == "states" {
+					states = value
+				}
+				tables[ident.Name] = stateTable{script: script, methods: value}
+			}
+		}
+		for _, stmt := range script.file.Stmts {
+			walkNode(stmt, func(node parser.Node) {
+				switch node := node.(type) {
+				case *parser.AssignStmt:
+					if node.Token != token.Define {
+						return
+					}
+					for _, lhs := range node.LHS {
+						if ident, ok := lhs.(*parser.Ident); ok {
+							defined[ident.Name]++
+						}
+					}
+				case *parser.FuncType:
+					for _, param := range node.Params.List {
+						defined[param.Name]++
+					}
+				case *parser.ForInStmt:
+					if node.Key != nil {
+						defined[node.Key.Name]++
+					}
+					if node.Value != nil {
+						defined[node.Value.Name]++
+					}
+				}
+			})
+		}
+	}
+	for name := range set.topFuncs {
+		if defined[name] != 1 {
+			delete(set.topFuncs, name)
+		}
+	}
+
+	if states != nil {
+		set.states = map[string]bool{}
+		for _, element := range states.Elements {
+			set.states[element.Key] = true
+			// The runtime calls each state's methods with the state.
+			if ident, ok := element.Value.(*parser.Ident); ok {
+				if table, ok := tables[ident.Name]; ok {
+					set.checkStateTable(element.Key, table)
+				}
+			}
+		}
+	}
+
+	for _, script := range scripts {
+		for _, stmt := range script.file.Stmts {
+			set.checkLifecycle(script, stmt)
+			walkNode(stmt, func(node parser.Node) {
+				set.check(script, node)
+			})
+		}
+	}
+
+	return set.issues
+}
+
+func (s *scriptSet) report(script parsedScript, pos parser.Pos, format string, args ...any) {
+	position := script.file.InputFile.Position(pos)
+	s.issues = append(s.issues, Issue{
+		File:    script.path,
+		Line:    position.Line,
+		Column:  position.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkLifecycle checks the functions the runtime calls each frame.
+func (s *scriptSet) checkLifecycle(script parsedScript, stmt parser.Stmt) {
+	assign, ok := stmt.(*parser.AssignStmt)
+	if !ok || len(assign.LHS) != 1 || len(assign.RHS) != 1 {
+		return
+	}
+	ident, ok := assign.LHS[0].(*parser.Ident)
+	if !ok || (ident.Name != "on_start" && ident.Name != "on_update") {
+		return
+	}
+	if fn, ok := assign.RHS[0].(*parser.FuncLit); ok && !funcAccepts(fn, stateCallbackArity) {
+		s.report(script, fn.Pos(), "%s must take 1 argument (state)", ident.Name)
+	}
+}
+
+// checkStateTable checks the enter, update and exit of a state.
+func (s *scriptSet) checkStateTable(state string, table stateTable) {
+	for _, element := range table.methods.Elements {
+		if fn, ok := element.Value.(*parser.FuncLit); ok && !funcAccepts(fn, stateCallbackArity) {
+			s.report(table.script, fn.Pos(), "state %q %s must take 1 argument (state)", state, element.Key)
+		}
+	}
+}
+
+func (s *scriptSet) check(script parsedScript, node parser.Node) {
+	switch node := node.(type) {
+	case *parser.SelectorExpr:
+		module, ok := s.moduleOf(node.Expr)
+		if !ok {
+			return
+		}
+		name, ok := node.Sel.(*parser.StringLit)
+		if !ok {
+			return
+		}
+		functions, known := s.functions[module]
+		if !known || functions[name.Value] {
+			return
+		}
+		message := fmt.Sprintf("%s.%s is not defined", module, name.Value)
+		if suggestion := closestName(name.Value, functions); suggestion != "" {
+			message += fmt.Sprintf("; did you mean %s?", suggestion)
+		}
+		s.report(script, name.Pos(), "%s", message)
+
+	case *parser.CallExpr:
+		if node.Ellipsis != parser.NoPos {
+			return
+		}
+		s.checkCall(script, node)
+
+	case *parser.AssignStmt:
+		if len(node.LHS) != 1 || len(node.RHS) != 1 {
+			return
+		}
+		if key, ok := stateKey(node.LHS[0]); ok {
+			s.checkStateName(script, key, node.RHS[0])
+		}
+
+	case *parser.BinaryExpr:
+		if node.Token != token.Equal && node.Token != token.NotEqual {
+			return
+		}
+		if key, ok := stateKey(node.LHS); ok {
+			s.checkStateName(script, key, node.RHS)
+		}
+		if key, ok := stateKey(node.RHS); ok {
+			s.checkStateName(script, key, node.LHS)
+		}
+	}
+}
+
+func (s *scriptSet) checkCall(script parsedScript, call *parser.CallExpr) {
+	switch fn := call.Func.(type) {
+	case *parser.Ident:
+		lit, ok := s.topFuncs[fn.Name]
+		if !ok {
+			return
+		}
+		if want := funcArity(lit); !want.accepts(len(call.Args)) {
+			s.report(script, call.Pos(), "%s takes %s %s, got %d", fn.Name, want, pluralArguments(want), len(call.Args))
+		}
+
+	case *parser.SelectorExpr:
+		module, ok := s.moduleOf(fn.Expr)
+		if !ok {
+			return
+		}
+		name, ok := fn.Sel.(*parser.StringLit)
+		if !ok {
+			return
+		}
+		want, ok := knownArities[module][name.Value]
+		if !ok && name.Value == "for_entity" && module != scriptSignalsModule && module != scriptTimerModule {
+			want, ok = forEntityArity, true
+		}
+		if ok && !want.accepts(len(call.Args)) {
+			s.report(script, call.Pos(), "%s.%s takes %s %s, got %d", module, name.Value, want, pluralArguments(want), len(call.Args))
+			return
+		}
+
+		switch {
+		case module == scriptSignalsModule && name.Value == "on" && len(call.Args) > 1:
+			s.checkCallback(script, call.Args[1], signalCallbackArity, "signal callback must take 1 or 2 arguments (state, payload)")
+		case module == scriptTimerModule && (name.Value == "after" || name.Value == "every") && len(call.Args) > 1:
+			s.checkCallback(script, call.Args[1], stateCallbackArity, "timer callback must take 1 argument (state)")
+		}
+	}
+}
+
+// checkCallback checks a callback the runtime will call, when it is a
+// function literal or a top-level function.
+func (s *scriptSet) checkCallback(script parsedScript, callback parser.Expr, want arity, message string) {
+	var fn *parser.FuncLit
+	switch callback := callback.(type) {
+	case *parser.FuncLit:
+		fn = callback
+	case *parser.Ident:
+		fn = s.topFuncs[callback.Name]
+	}
+	if fn != nil && !funcAccepts(fn, want) {
+		s.report(script, callback.Pos(), "%s", message)
+	}
+}
+
+func (s *scriptSet) checkStateName(script parsedScript, key string, value parser.Expr) {
+	lit, ok := value.(*parser.StringLit)
+	if !ok || s.states == nil || s.states[lit.Value] {
+		return
+	}
+	s.report(script, lit.Pos(), "%s %q is not a state in states", key, lit.Value)
+}
+
+// moduleOf resolves a module alias, or a module's for_entity(...) call, to
+// the module.
+func (s *scriptSet) moduleOf(expr parser.Expr) (string, bool) {
+	if call, ok := expr.(*parser.CallExpr); ok {
+		selector, ok := call.Func.(*parser.SelectorExpr)
+		if !ok {
+			return "", false
+		}
+		name, ok := selector.Sel.(*parser.StringLit)
+		if !ok || name.Value != "for_entity" {
+			return "", false
+		}
+		expr = selector.Expr
+	}
+	ident, ok := expr.(*parser.Ident)
+	if !ok {
+		return "", false
+	}
+	module, ok := s.aliases[ident.Name]
+	return module, ok
+}
+
+// stateKey reports whether expr is an index of a state name entry, like
+// state["next_state"].
+func stateKey(expr parser.Expr) (string, bool) {
+	index, ok := expr.(*parser.IndexExpr)
+	if !ok {
+		return "", false
+	}
+	key, ok := index.Index.(*parser.StringLit)
+	if !ok || !stateKeys[key.Value] {
+		return "", false
+	}
+	return key.Value, true
+}
+
+func funcArity(fn *parser.FuncLit) arity {
+	params := len(fn.Type.Params.List)
+	if fn.Type.Params.VarArgs {
+		return arity{params - 1, -1}
+	}
+	return arity{params, params}
+}
+
+// funcAccepts reports whether fn can be called with every argument count
+// want allows.
+func funcAccepts(fn *parser.FuncLit, want arity) bool {
+	for n := want.min; n <= want.max; n++ {
+		if funcArity(fn).accepts(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func pluralArguments(a arity) string {
+	if a.min == 1 && a.max == 1 {
+		return "argument"
+	}
+	return "arguments"
+}
+
+// closestName returns the name in names at most two edits from name, if
+// there is one.
+func closestName(name string, names map[string]bool) string {
+	candidates := make([]string, 0, len(names))
+	for candidate := range names {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// walkNode calls visit on node and every node under it.
+func walkNode(node parser.Node, visit func(parser.Node)) {
+	value := reflect.ValueOf(node)
+	if node == nil || (value.Kind() == reflect.Pointer && value.IsNil()) {
+		return
+	}
+	visit(node)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	fields := value.Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		walkValue(fields.Field(i), visit)
+	}
+}
+
+func walkValue(value reflect.Value, visit func(parser.Node)) {
+	switch value.Kind() {
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walkValue(value.Index(i), visit)
+		}
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() || !value.CanInterface() {
+			return
+		}
+		if node, ok := value.Interface().(parser.Node); ok {
+			walkNode(node, visit)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package script
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestCheckScriptsReportsBadCallsAndStateNames(t *testing.T) {
+	writeTestScripts(t, map[string]string{
+		"guard/constants.tengo": "SPEED := 2\n",
+		"guard/main.tengo": `entity := import("entity")
+signals := import("signals")
+timer := import("timer")
+
+on_hit := func(state, payload, extra) {
+    state["next_state"] = "hurt"
+}
+
+patrol := func(state, speed) {
+    entity.destory()
+}
+
+states := {
+    idle: {
+        enter: func(state) {
+            timer.after(30)
+            timer.every(10, func() {})
+        },
+        update: func(state) {
+            patrol(state)
+            if state["current_state"] == "walk" {
+                state["next_state"] = "idle"
+            }
+        }
+    }
+}
+
+on_start := func(state) {
+    signals.on("hit", on_hit)
+    entity.for_entity().exists()
+}
+`,
+	})
+
+	issues := NewRuntime().CheckScripts(&component.Script{Paths: []string{"guard/constants.tengo", "guard/main.tengo"}})
+	got := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"guard/main.tengo:6:27: next_state \"hurt\" is not a state in states",
+		"guard/main.tengo:10:12: entity.destory is not defined; did you mean destroy?",
+		"guard/main.tengo:16:13: timer.after takes 2 arguments, got 1",
+		"guard/main.tengo:17:29: timer callback must take 1 argument (state)",
+		"guard/main.tengo:20:13: patrol takes 2 arguments, got 1",
+		"guard/main.tengo:21:42: current_state \"walk\" is not a state in states",
+		"guard/main.tengo:29:23: signal callback must take 1 or 2 arguments (state, payload)",
+		"guard/main.tengo:30:5: entity.for_entity takes 1 argument, got 0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckScriptsReportsCompileErrorsInTheirFile(t *testing.T) {
+	writeTestScripts(t, map[string]string{
+		"broken/constants.tengo": "SPEED := 2\n",
+		"broken/main.tengo":      "on_update := func(state) {\n    count := missing\n}\n",
+	})
+
+	issues := NewRuntime().CheckScripts(&component.Script{Paths: []string{"broken/constants.tengo", "broken/main.tengo"}})
+	if len(issues) != 1 {
+		t.Fatalf("expected one compile issue, got %v", issues)
+	}
+	if issues[0].File != "broken/main.tengo" || issues[0].Line != 2 || !strings.Contains(issues[0].Message, "missing") {
+		t.Fatalf("expected the unresolved reference at broken/main.tengo:2, got %v", issues[0])
+	}
+}
+
+func TestReferencedScriptsPassCheck(t *testing.T) {
+	issues, err := CheckReferencedScripts()
+	if err != nil {
+		t.Fatalf("check referenced scripts: %v", err)
+	}
+	for _, issue := range issues {
+		t.Error(issue)
+	}
+}
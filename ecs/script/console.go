@@ -0,0 +1,284 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+// maxConsoleLines caps the output a console keeps.
+const maxConsoleLines = 200
+
+// consoleTimeout stops console input that never returns, like a bare
+// for {} the allocation cap doesn't catch. Console input isn't replayed, so
+// unlike script runs it can go by wall time.
+const consoleTimeout = time.Second
+
+const consoleHelp = `commands:
+  select <id>           bind modules to an entity by game entity id
+  select                bind modules to the player again
+  emit <signal> [id]    send a signal to the selected or given entity
+  spawn <prefab> [x y]  instantiate a prefab, optionally at x, y
+  layer <name> on|off   activate or deactivate a level layer
+  tp <x> <y>            teleport the player
+  snapshot [path]       keep the world's state, or write it to path
+  restore [path]        put the world back to the kept or written state
+  clear                 clear the output
+anything else is evaluated as Tengo with every builtin module imported`
+
+// Console runs developer commands and Tengo source against a world. Builtin
+// modules are bound to the player, or to the selected entity through
+// for_entity.
+type Console struct {
+	runtime *Runtime
+	// Target is the game entity id modules are bound to; empty is the player.
+	Target string
+	Lines  []string
+	// snapshot is the state the last snapshot without a path kept.
+	snapshot []byte
+}
+
+func NewConsole(r *Runtime) *Console {
+	return &Console{runtime: r}
+}
+
+// Exec runs one console line and appends it and its result to Lines.
+func (c *Console) Exec(w *ecs.World, line string) {
+	line = strings.TrimSpace(line)
+	if c == nil || line == "" {
+		return
+	}
+	c.print("> " + line)
+
+	result, err := c.exec(w, line)
+	if err != nil {
+		c.print("error: " + err.Error())
+		return
+	}
+	if result != "" {
+		c.print(result)
+	}
+}
+
+func (c *Console) exec(w *ecs.World, line string) (string, error) {
+	if c.runtime == nil || w == nil {
+		return "", errors.New("no script runtime")
+	}
+	c.runtime.rebuildEntityIndex(w)
+
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "help":
+		return consoleHelp, nil
+	case "clear":
+		c.Lines = nil
+		return "", nil
+	case "select":
+		if len(fields) < 2 {
+			c.Target = ""
+			return "modules bound to the player", nil
+		}
+		if _, ok := c.runtime.byGameEntityID[fields[1]]; !ok {
+			return "", fmt.Errorf("could not find entity %s", fields[1])
+		}
+		c.Target = fields[1]
+		return "modules bound to " + c.Target, nil
+	case "emit":
+		if len(fields) < 2 {
+			return "", errors.New("usage: emit <signal> [id]")
+		}
+		targetID := c.Target
+		if len(fields) > 2 {
+			targetID = fields[2]
+		}
+		target, ok := c.resolve(w, targetID)
+		if !ok {
+			return "", fmt.Errorf("could not find entity %s", targetID)
+		}
+		// The entity signals itself, so subscriptions that only listen to
+		// their own entity hear it too.
+		if !EmitSignalEvent(w, target, target, component.ScriptSignalEvent{Name: fields[1]}) {
+			return "", fmt.Errorf("could not emit %s", fields[1])
+		}
+		return "emitted " + fields[1], nil
+	case "spawn":
+		if len(fields) != 2 && len(fields) != 4 {
+			return "", errors.New("usage: spawn <prefab> [x y]")
+		}
+		src := fmt.Sprintf("__id := prefab.instantiate(%s)\n", strconv.Quote(fields[1]))
+		if len(fields) == 4 {
+			x, y, err := consoleCoords(fields[2], fields[3])
+			if err != nil {
+				return "", err
+			}
+			src += fmt.Sprintf("transform.for_entity(__id).set_position(%v, %v)\n", x, y)
+		}
+		return c.eval(w, c.Target, src+"__id")
+	case "layer":
+		if len(fields) != 3 || (fields[2] != "on" && fields[2] != "off") {
+			return "", errors.New("usage: layer <name> on|off")
+		}
+		fn := "activate"
+		if fields[2] == "off" {
+			fn = "deactivate"
+		}
+		return c.eval(w, c.Target, fmt.Sprintf("level.%s(%s)", fn, strconv.Quote(fields[1])))
+	case "tp":
+		if len(fields) != 3 {
+			return "", errors.New("usage: tp <x> <y>")
+		}
+		x, y, err := consoleCoords(fields[1], fields[2])
+		if err != nil {
+			return "", err
+		}
+		return c.eval(w, "", fmt.Sprintf("physics.set_position(%v, %v)", x, y))
+	case "snapshot":
+		if len(fields) > 2 {
+			return "", errors.New("usage: snapshot [path]")
+		}
+		data, err := savegame.SnapshotWorld(w, c.runtime)
+		if err != nil {
+			return "", err
+		}
+		if len(fields) == 1 {
+			c.snapshot = data
+			return fmt.Sprintf("kept %d entities", len(ecs.Entities(w))), nil
+		}
+		if err := os.WriteFile(fields[1], data, 0o644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("wrote %d entities to %s", len(ecs.Entities(w)), fields[1]), nil
+	case "restore":
+		if len(fields) > 2 {
+			return "", errors.New("usage: restore [path]")
+		}
+		data := c.snapshot
+		if len(fields) == 2 {
+			var err error
+			if data, err = os.ReadFile(fields[1]); err != nil {
+				return "", err
+			}
+		} else if data == nil {
+			return "", errors.New("no snapshot kept; run snapshot first")
+		}
+		if err := savegame.RestoreWorld(w, data, c.runtime); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("restored %d entities", len(ecs.Entities(w))), nil
+	}
+	return c.eval(w, c.Target, line)
+}
+
+func (c *Console) eval(w *ecs.World, target, src string) (string, error) {
+	player, _ := ecs.First(w, component.PlayerTagComponent.Kind())
+	result, err := c.runtime.Eval(w, player, target, src)
+	if err != nil {
+		return "", err
+	}
+	if result == nil || result == tengo.UndefinedValue {
+		return "", nil
+	}
+	return result.String(), nil
+}
+
+func (c *Console) resolve(w *ecs.World, id string) (ecs.Entity, bool) {
+	if id == "" {
+		return ecs.First(w, component.PlayerTagComponent.Kind())
+	}
+	ent, ok := c.runtime.byGameEntityID[id]
+	return ent, ok
+}
+
+func (c *Console) print(text string) {
+	c.Lines = append(c.Lines, strings.Split(text, "\n")...)
+	if len(c.Lines) > maxConsoleLines {
+		c.Lines = c.Lines[len(c.Lines)-maxConsoleLines:]
+	}
+}
+
+func consoleCoords(rawX, rawY string) (float64, float64, error) {
+	x, err := strconv.ParseFloat(rawX, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad x %q", rawX)
+	}
+	y, err := strconv.ParseFloat(rawY, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad y %q", rawY)
+	}
+	return x, y, nil
+}
+
+// Eval runs Tengo source with every registered module bound to owner, or to
+// target through for_entity when a game entity id is given. An expression
+// returns its value; statements return the value of __result, if they set
+// it.
+func (r *Runtime) Eval(w *ecs.World, owner ecs.Entity, target, src string) (tengo.Object, error) {
+	if r == nil || w == nil {
+		return nil, errors.New("no world to evaluate against")
+	}
+	r.rebuildEntityIndex(w)
+
+	names := r.entityModuleNames(nil)
+	var prelude strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&prelude, "%s := __modules[%q]", name, name)
+		if target != "" {
+			fmt.Fprintf(&prelude, ".for_entity(%s)", strconv.Quote(target))
+		}
+		prelude.WriteString("\n")
+	}
+	modules := r.buildEntityModules(owner, nil, names)
+	allowedStdlib := r.stdlibAllowlist(nil)
+
+	compile := func(body string) (*tengo.Compiled, error) {
+		script := tengo.NewScript([]byte(prelude.String() + body))
+		_ = script.Add("__modules", modules)
+		_ = script.Add("__result", tengo.UndefinedValue)
+		script.SetImports(buildModuleMap(allowedStdlib))
+		if r.sandbox.MaxAllocs > 0 {
+			script.SetMaxAllocs(r.sandbox.MaxAllocs)
+		}
+		return script.Compile()
+	}
+
+	// The last line is tried as an expression first, so `health.current()`
+	// prints without an assignment.
+	body := strings.TrimSpace(src)
+	lines := strings.Split(body, "\n")
+	last := len(lines) - 1
+	expression := strings.Join(append(lines[:last:last], "__result = ("+lines[last]+")"), "\n")
+	compiled, err := compile(expression)
+	if err != nil {
+		var statementErr error
+		if compiled, statementErr = compile(body); statementErr != nil {
+			return nil, consoleError(statementErr, len(names))
+		}
+	}
+
+	if err := runWithTimeout(compiled, consoleTimeout); err != nil {
+		return nil, consoleError(err, len(names))
+	}
+	return compiled.Get("__result").Object(), nil
+}
+
+// consoleError points an error's positions at the console input instead of
+// the prelude-prefixed source.
+func consoleError(err error, preludeLines int) error {
+	message := scriptPositionPattern.ReplaceAllStringFunc(err.Error(), func(pos string) string {
+		match := scriptPositionPattern.FindStringSubmatch(pos)
+		line, _ := strconv.Atoi(match[1])
+		if line -= preludeLines; line < 1 {
+			return "(prelude)"
+		}
+		return fmt.Sprintf("line %d:%s", line, match[2])
+	})
+	return errors.New(strings.NewReplacer("\n\t", " ", "\n", " ").Replace(message))
+}
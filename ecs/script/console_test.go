@@ -0,0 +1,86 @@
+package script
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestConsoleEvaluatesAgainstPlayerAndSelectedEntity(t *testing.T) {
+	w := ecs.NewWorld()
+	player := ecs.CreateEntity(w)
+	_ = ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{})
+	_ = ecs.Add(w, player, component.TransformComponent.Kind(), &component.Transform{X: 3, Y: 4, ScaleX: 1, ScaleY: 1})
+	crate := ecs.CreateEntity(w)
+	_ = ecs.Add(w, crate, component.GameEntityIDComponent.Kind(), &component.GameEntityID{Value: "crate_1"})
+	_ = ecs.Add(w, crate, component.TransformComponent.Kind(), &component.Transform{ScaleX: 1, ScaleY: 1})
+
+	console := NewConsole(NewRuntime())
+	last := func() string {
+		return console.Lines[len(console.Lines)-1]
+	}
+
+	console.Exec(w, "transform.position()")
+	if got := last(); got != "[3, 4]" {
+		t.Fatalf("expected the player's position, got %q", got)
+	}
+
+	console.Exec(w, "select crate_1")
+	console.Exec(w, "transform.set_position(5, 6)")
+	if tf, _ := ecs.Get(w, crate, component.TransformComponent.Kind()); tf.X != 5 || tf.Y != 6 {
+		t.Fatalf("expected the selected entity to move, got %+v", tf)
+	}
+
+	console.Exec(w, "emit ping")
+	queue, ok := ecs.Get(w, crate, component.ScriptSignalQueueComponent.Kind())
+	if !ok || len(queue.Events) != 1 || queue.Events[0].Name != "ping" || queue.Events[0].SourceGameEntity != "crate_1" {
+		t.Fatalf("expected ping queued on the selected entity, got %+v", queue)
+	}
+
+	console.Exec(w, "x := ")
+	if got := last(); !strings.HasPrefix(got, "error: ") || strings.Contains(got, "(main)") {
+		t.Fatalf("expected an error pointing at the console input, got %q", got)
+	}
+}
+
+func TestConsoleSnapshotAndRestore(t *testing.T) {
+	w := ecs.NewWorld()
+	player := ecs.CreateEntity(w)
+	_ = ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{})
+	_ = ecs.Add(w, player, component.TransformComponent.Kind(), &component.Transform{X: 3, Y: 4, ScaleX: 1, ScaleY: 1})
+	_ = ecs.Add(w, player, component.HealthComponent.Kind(), &component.Health{Initial: 5, Current: 5})
+
+	console := NewConsole(NewRuntime())
+	last := func() string {
+		return console.Lines[len(console.Lines)-1]
+	}
+
+	console.Exec(w, "restore")
+	if got := last(); !strings.HasPrefix(got, "error: ") {
+		t.Fatalf("expected restore without a snapshot to fail, got %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	console.Exec(w, "snapshot")
+	console.Exec(w, "snapshot "+path)
+	health, _ := ecs.Get(w, player, component.HealthComponent.Kind())
+	health.Current = 1
+
+	console.Exec(w, "restore")
+	if health, _ := ecs.Get(w, player, component.HealthComponent.Kind()); health.Current != 5 {
+		t.Fatalf("expected the kept snapshot restored, got %+v", health)
+	}
+
+	health, _ = ecs.Get(w, player, component.HealthComponent.Kind())
+	health.Current = 2
+	console.Exec(w, "restore "+path)
+	if got := last(); got != "restored 1 entities" {
+		t.Fatalf("expected the written snapshot restored, got %q", got)
+	}
+	if health, _ := ecs.Get(w, player, component.HealthComponent.Kind()); health.Current != 5 {
+		t.Fatalf("expected the written snapshot's health, got %+v", health)
+	}
+}
@@ -0,0 +1,184 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// ErrorLogInterval is how many updates pass before the log prints the same
+// script failure again.
+const ErrorLogInterval = 120
+
+// maxErrorLogEntries caps the failures kept in the world for the overlay.
+const maxErrorLogEntries = 8
+
+// scriptPositionPattern matches the positions Tengo reports in the compiled
+// source, which is named (main).
+var scriptPositionPattern = regexp.MustCompile(`\(main\):(\d+):(\d+)`)
+
+// Error is a script failure located in the entity's original script files.
+type Error struct {
+	Entity       ecs.Entity
+	GameEntityID string
+	// ScriptPath is the entity's script set, joined with ";".
+	ScriptPath string
+	// File, Line and Column locate the failure in the original script file.
+	// Line is 0 when the failure has no position, like a missing file.
+	File   string
+	Line   int
+	Column int
+	Err    error
+	// message is Err on one line with its positions mapped to files.
+	message string
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "entity=%d", e.Entity)
+	if e.GameEntityID != "" {
+		fmt.Fprintf(&sb, " id=%s", e.GameEntityID)
+	}
+	if e.ScriptPath != "" {
+		fmt.Fprintf(&sb, " script=%s", e.ScriptPath)
+	}
+	sb.WriteString(" ")
+	sb.WriteString(e.message)
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// scriptSource is where one file of a script set sits in the compiled source.
+type scriptSource struct {
+	path string
+	// first is the 1-based line of the compiled source the file starts on.
+	first int
+	lines int
+}
+
+// sourceLine maps a line of the compiled source back to its file and line.
+func sourceLine(sources []scriptSource, line int) (string, int, bool) {
+	for _, src := range sources {
+		if line >= src.first && line < src.first+src.lines {
+			return src.path, line - src.first + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// compileError keeps the layout of a script set that failed to compile, so
+// its position maps back like a runtime error's.
+type compileError struct {
+	err     error
+	sources []scriptSource
+}
+
+func (e *compileError) Error() string { return e.err.Error() }
+
+func (e *compileError) Unwrap() error { return e.err }
+
+// newError locates err in the original files of ent's scripts.
+func (r *Runtime) newError(ent ecs.Entity, err error) *Error {
+	diag := &Error{Entity: ent, GameEntityID: r.gameEntityID(ent), Err: err}
+	if r.world != nil {
+		if scriptComp, ok := ecs.Get(r.world, ent, component.ScriptComponent.Kind()); ok && scriptComp != nil {
+			diag.ScriptPath = strings.Join(scriptPaths(scriptComp), ";")
+		}
+	}
+
+	var sources []scriptSource
+	if rt := r.runtimes[ent]; rt != nil && rt.scriptPath == diag.ScriptPath {
+		sources = rt.sources
+	}
+	var compileErr *compileError
+	if errors.As(err, &compileErr) {
+		sources = compileErr.sources
+	}
+
+	message := scriptPositionPattern.ReplaceAllStringFunc(err.Error(), func(pos string) string {
+		match := scriptPositionPattern.FindStringSubmatch(pos)
+		line, _ := strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		file, fileLine, ok := sourceLine(sources, line)
+		if !ok {
+			// The import block and lifecycle dispatch the runtime adds.
+			return "(generated)"
+		}
+		// Tengo reports the innermost frame first.
+		if diag.Line == 0 {
+			diag.File, diag.Line, diag.Column = file, fileLine, column
+		}
+		return fmt.Sprintf("%s:%d:%d", file, fileLine, column)
+	})
+	diag.message = strings.NewReplacer("\n\t", " ", "\n", " ").Replace(message)
+	return diag
+}
+
+type loggedError struct {
+	frame      uint64
+	suppressed int
+}
+
+// logError prints a script failure unless the same one was printed within
+// the last ErrorLogInterval updates, counting the ones it holds back.
+func (r *Runtime) logError(diag *Error) {
+	if r.loggedErrors == nil {
+		r.loggedErrors = map[string]*loggedError{}
+	}
+	logged, ok := r.loggedErrors[diag.message]
+	if ok && r.frame-logged.frame < ErrorLogInterval {
+		logged.suppressed++
+		return
+	}
+
+	repeated := ""
+	if ok && logged.suppressed > 0 {
+		repeated = fmt.Sprintf(" (repeated %d times)", logged.suppressed)
+	}
+	r.loggedErrors[diag.message] = &loggedError{frame: r.frame}
+	fmt.Printf("script: %v%s\n", diag, repeated)
+}
+
+// recordError adds a script failure to the world's error log, moving a
+// repeat of an earlier one to the front.
+func recordError(w *ecs.World, diag *Error) {
+	if w == nil || diag == nil {
+		return
+	}
+	ent, ok := ecs.First(w, component.ScriptErrorLogComponent.Kind())
+	if !ok {
+		ent = ecs.CreateEntity(w)
+	}
+	log, _ := ecs.Get(w, ent, component.ScriptErrorLogComponent.Kind())
+	if log == nil {
+		log = &component.ScriptErrorLog{}
+	}
+
+	entry := component.ScriptErrorEntry{
+		GameEntityID: diag.GameEntityID,
+		File:         diag.File,
+		Line:         diag.Line,
+		Message:      diag.message,
+		Count:        1,
+	}
+	for i, existing := range log.Entries {
+		if existing.GameEntityID == entry.GameEntityID && existing.Message == entry.Message {
+			entry.Count = existing.Count + 1
+			log.Entries = append(log.Entries[:i], log.Entries[i+1:]...)
+			break
+		}
+	}
+	log.Entries = append([]component.ScriptErrorEntry{entry}, log.Entries...)
+	if len(log.Entries) > maxErrorLogEntries {
+		log.Entries = log.Entries[:maxErrorLogEntries]
+	}
+	if err := ecs.Add(w, ent, component.ScriptErrorLogComponent.Kind(), log); err != nil {
+		panic("script: add script error log: " + err.Error())
+	}
+}
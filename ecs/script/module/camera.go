@@ -34,18 +34,14 @@ func CameraModule() Module {
 					return tengo.FalseValue, fmt.Errorf("intensity must be non-negative")
 				}
 
-				camEnt, ok := ecs.First(world, component.CameraTagComponent.Kind())
-				if !ok {
+				if _, ok := ecs.First(world, component.CameraTagComponent.Kind()); !ok {
 					return tengo.FalseValue, fmt.Errorf("camera entity not found")
 				}
 
-				err := ecs.Add(world, camEnt, component.CameraShakeRequestComponent.Kind(), &component.CameraShakeRequest{
+				ecs.Emit(world, component.CameraShakeRequest{
 					Frames:    duration,
 					Intensity: intensity,
 				})
-				if err != nil {
-					return tengo.FalseValue, fmt.Errorf("failed to add CameraShakeRequest component to camera entity: %w", err)
-				}
 
 				return tengo.TrueValue, nil
 			}}
@@ -0,0 +1,138 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	levelentity "github.com/milk9111/sidescroller/ecs/entity"
+)
+
+func FlagsModule() Module {
+	return Module{
+		Name: "flags",
+		Build: func(world *ecs.World, _ map[string]ecs.Entity, _ ecs.Entity, _ ecs.Entity) map[string]tengo.Object {
+			values := map[string]tengo.Object{}
+
+			values["get"] = &tengo.UserFunction{Name: "get", Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) < 1 {
+					return tengo.UndefinedValue, fmt.Errorf("get requires 1 argument: name")
+				}
+
+				value, ok := levelentity.PlayerFlag(world, strings.TrimSpace(objectAsString(args[0])))
+				if !ok {
+					if len(args) > 1 {
+						return args[1], nil
+					}
+					return tengo.UndefinedValue, nil
+				}
+
+				return flagValueObject(value), nil
+			}}
+
+			values["has"] = &tengo.UserFunction{Name: "has", Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) < 1 {
+					return tengo.FalseValue, fmt.Errorf("has requires 1 argument: name")
+				}
+
+				if _, ok := levelentity.PlayerFlag(world, strings.TrimSpace(objectAsString(args[0]))); ok {
+					return tengo.TrueValue, nil
+				}
+				return tengo.FalseValue, nil
+			}}
+
+			values["set"] = &tengo.UserFunction{Name: "set", Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) < 1 {
+					return tengo.FalseValue, fmt.Errorf("set requires at least 1 argument: name")
+				}
+
+				name := strings.TrimSpace(objectAsString(args[0]))
+				if name == "" {
+					return tengo.FalseValue, fmt.Errorf("name must not be empty")
+				}
+
+				value := component.BoolFlag(true)
+				if len(args) > 1 {
+					var err error
+					if value, err = flagValueFromObject(args[1]); err != nil {
+						return tengo.FalseValue, err
+					}
+				}
+
+				if !levelentity.SetPlayerFlag(world, name, value) {
+					return tengo.FalseValue, nil
+				}
+				return tengo.TrueValue, nil
+			}}
+
+			values["add"] = &tengo.UserFunction{Name: "add", Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) < 1 {
+					return tengo.UndefinedValue, fmt.Errorf("add requires at least 1 argument: name")
+				}
+
+				name := strings.TrimSpace(objectAsString(args[0]))
+				if name == "" {
+					return tengo.UndefinedValue, fmt.Errorf("name must not be empty")
+				}
+
+				delta := 1
+				if len(args) > 1 {
+					delta = objectAsInt(args[1])
+				}
+
+				current, ok := levelentity.PlayerFlag(world, name)
+				if ok && current.Kind != component.FlagKindInt {
+					return tengo.UndefinedValue, fmt.Errorf("flag %q is a %s, not an int", name, current.Kind)
+				}
+
+				next := component.IntFlag(current.Int + delta)
+				if !levelentity.SetPlayerFlag(world, name, next) {
+					return tengo.UndefinedValue, nil
+				}
+				return &tengo.Int{Value: int64(next.Int)}, nil
+			}}
+
+			values["clear"] = &tengo.UserFunction{Name: "clear", Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) < 1 {
+					return tengo.FalseValue, fmt.Errorf("clear requires 1 argument: name")
+				}
+
+				if !levelentity.ClearPlayerFlag(world, strings.TrimSpace(objectAsString(args[0]))) {
+					return tengo.FalseValue, nil
+				}
+				return tengo.TrueValue, nil
+			}}
+
+			return values
+		},
+	}
+}
+
+func flagValueObject(value component.FlagValue) tengo.Object {
+	switch value.Kind {
+	case component.FlagKindBool:
+		if value.Bool {
+			return tengo.TrueValue
+		}
+		return tengo.FalseValue
+	case component.FlagKindInt:
+		return &tengo.Int{Value: int64(value.Int)}
+	case component.FlagKindString:
+		return &tengo.String{Value: value.String}
+	}
+	return tengo.UndefinedValue
+}
+
+func flagValueFromObject(obj tengo.Object) (component.FlagValue, error) {
+	switch v := obj.(type) {
+	case *tengo.Bool:
+		return component.BoolFlag(!v.IsFalsy()), nil
+	case *tengo.Int:
+		return component.IntFlag(int(v.Value)), nil
+	case *tengo.String:
+		return component.StringFlag(v.Value), nil
+	}
+	return component.FlagValue{}, fmt.Errorf("flag value must be a bool, int or string, got %s", obj.TypeName())
+}
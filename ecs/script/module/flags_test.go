@@ -0,0 +1,57 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	levelentity "github.com/milk9111/sidescroller/ecs/entity"
+)
+
+func TestFlagsModuleSetsTypedFlagsOnPlayer(t *testing.T) {
+	w := ecs.NewWorld()
+	player := ecs.CreateEntity(w)
+	_ = ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{})
+
+	mod := FlagsModule().Build(w, nil, 0, 0)
+	call := func(name string, args ...tengo.Object) tengo.Object {
+		t.Helper()
+		fn, ok := mod[name].(*tengo.UserFunction)
+		if !ok || fn == nil {
+			t.Fatalf("expected flags %s function", name)
+		}
+		result, err := fn.Value(args...)
+		if err != nil {
+			t.Fatalf("flags %s: %v", name, err)
+		}
+		return result
+	}
+
+	call("set", &tengo.String{Value: "met_merchant"})
+	call("set", &tengo.String{Value: "rival_name"}, &tengo.String{Value: "Rust"})
+	call("add", &tengo.String{Value: "quest_stage"})
+	if result := call("add", &tengo.String{Value: "quest_stage"}, &tengo.Int{Value: 2}); result.(*tengo.Int).Value != 3 {
+		t.Fatalf("expected quest_stage 3, got %#v", result)
+	}
+
+	if value, ok := levelentity.PlayerFlag(w, "met_merchant"); !ok || value != component.BoolFlag(true) {
+		t.Fatalf("unexpected met_merchant %+v", value)
+	}
+	if result := call("get", &tengo.String{Value: "rival_name"}); result.(*tengo.String).Value != "Rust" {
+		t.Fatalf("unexpected rival_name %#v", result)
+	}
+	if result := call("get", &tengo.String{Value: "missing"}, &tengo.Int{Value: 7}); result.(*tengo.Int).Value != 7 {
+		t.Fatalf("expected fallback for missing flag, got %#v", result)
+	}
+
+	call("clear", &tengo.String{Value: "met_merchant"})
+	if call("has", &tengo.String{Value: "met_merchant"}) != tengo.FalseValue {
+		t.Fatal("expected met_merchant to be cleared")
+	}
+
+	addFn := mod["add"].(*tengo.UserFunction)
+	if _, err := addFn.Value(&tengo.String{Value: "rival_name"}); err == nil {
+		t.Fatal("expected add on a string flag to fail")
+	}
+}
@@ -36,5 +36,6 @@ func Builtins() []Module {
 		BreakableWallModule(),
 		HazardModule(),
 		ArenaModule(),
+		FlagsModule(),
 	}
 }
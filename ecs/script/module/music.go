@@ -56,8 +56,7 @@ func RequestMusicWithOptions(w *ecs.World, req *component.MusicRequest) {
 	if w == nil || req == nil {
 		return
 	}
-	ent := ecs.CreateEntity(w)
-	_ = ecs.Add(w, ent, component.MusicRequestComponent.Kind(), req)
+	ecs.Emit(w, *req)
 }
 
 func StopMusic(w *ecs.World) {
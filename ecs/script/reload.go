@@ -0,0 +1,158 @@
+package script
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// ReloadScripts recompiles the runtimes that use any of the changed script
+// files in place, keeping each entity's state, subscriptions and timers. A
+// runtime whose new source fails to compile, or that has a callback pending
+// it can't carry over, keeps running its old bytecode. It returns how many
+// runtimes were reloaded.
+func (r *Runtime) ReloadScripts(w *ecs.World, changed []string) int {
+	if r == nil || w == nil || len(changed) == 0 {
+		return 0
+	}
+	r.world = w
+
+	changedSet := make(map[string]bool, len(changed))
+	for _, name := range changed {
+		changedSet[normalizeScriptName(name)] = true
+	}
+	usesChanged := func(paths []string) bool {
+		for _, path := range paths {
+			if changedSet[normalizeScriptName(path)] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for key, cached := range r.compileCache {
+		if usesChanged(cached.paths) {
+			delete(r.compileCache, key)
+		}
+	}
+
+	reloaded := 0
+	for ent, rt := range r.runtimes {
+		if rt == nil || !usesChanged(strings.Split(rt.scriptPath, ";")) {
+			continue
+		}
+		scriptComp, ok := ecs.Get(w, ent, component.ScriptComponent.Kind())
+		if !ok || scriptComp == nil {
+			continue
+		}
+
+		cached, err := r.compiledFor(scriptComp)
+		if err != nil {
+			r.reportError(ent, fmt.Errorf("reload error, keeping the old script: %w", err))
+			continue
+		}
+		if err := r.reload(ent, rt, cached); err != nil {
+			r.reportError(ent, fmt.Errorf("reload error, keeping the old script: %w", err))
+			continue
+		}
+		reloaded++
+	}
+	return reloaded
+}
+
+// reload moves rt onto a new compile of its scripts. Compiled callbacks only
+// run in the program they came from, so each one is looked up again by the
+// top-level name it had in the old program. rt is left as it was if any
+// callback has none, like a function literal, since dropping it would
+// change what the script does.
+func (r *Runtime) reload(ent ecs.Entity, rt *entityRuntime, cached *compiledScript) error {
+	compiled := cached.compiled.Clone()
+	if err := compiled.Set("__modules", r.buildEntityModules(ent, rt, cached.modules)); err != nil {
+		return err
+	}
+	if err := compiled.Set("__state", rt.state); err != nil {
+		return err
+	}
+	// With no phase set, a run only defines the top-level names.
+	if err := runBudgeted(compiled); err != nil {
+		return err
+	}
+
+	names := topLevelFunctions(rt.compiled)
+	remap := func(callback tengo.Object) (tengo.Object, bool) {
+		if _, ok := callback.(*tengo.CompiledFunction); !ok {
+			return callback, true
+		}
+		name, ok := names[callback]
+		if !ok || !compiled.IsDefined(name) {
+			return nil, false
+		}
+		fn := compiled.Get(name).Object()
+		return fn, isCallableObject(fn)
+	}
+
+	var unbound []string
+	subscriptions := make(map[string][]subscription, len(rt.subscriptions))
+	for signal, subs := range rt.subscriptions {
+		for _, sub := range subs {
+			callback, ok := remap(sub.callback)
+			if !ok {
+				unbound = append(unbound, "signal "+signal)
+				continue
+			}
+			sub.callback = callback
+			subscriptions[signal] = append(subscriptions[signal], sub)
+		}
+	}
+	callbacks := make([]tengo.Object, len(rt.timers))
+	for i, timer := range rt.timers {
+		if timer.done {
+			continue
+		}
+		callback, ok := remap(timer.callback)
+		if !ok {
+			unbound = append(unbound, fmt.Sprintf("timer %d", timer.id))
+			continue
+		}
+		callbacks[i] = callback
+	}
+	if len(unbound) > 0 {
+		sort.Strings(unbound)
+		return fmt.Errorf("callbacks for %s are not top-level functions in the new source", strings.Join(unbound, ", "))
+	}
+
+	for i, timer := range rt.timers {
+		if callbacks[i] != nil {
+			timer.callback = callbacks[i]
+		}
+	}
+	rt.compiled = compiled
+	rt.hasOnStart = cached.hasOnStart
+	rt.hasOnUpdate = cached.hasOnUpdate
+	rt.sources = cached.sources
+	rt.subscriptions = subscriptions
+	// A fixed script gets another chance after blowing its budget.
+	rt.halted = false
+	return nil
+}
+
+// topLevelFunctions maps each function compiled has a top-level name for to
+// that name.
+func topLevelFunctions(compiled *tengo.Compiled) map[tengo.Object]string {
+	names := map[tengo.Object]string{}
+	for _, variable := range compiled.GetAll() {
+		if fn, ok := variable.Object().(*tengo.CompiledFunction); ok {
+			names[fn] = variable.Name()
+		}
+	}
+	return names
+}
+
+func normalizeScriptName(name string) string {
+	return filepath.ToSlash(strings.TrimSpace(name))
+}
@@ -1,13 +1,16 @@
 package script
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/stdlib"
+	"github.com/milk9111/sidescroller/common"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
 	scriptmodule "github.com/milk9111/sidescroller/ecs/script/module"
@@ -20,18 +23,50 @@ var scriptImportCallLinePattern = regexp.MustCompile(`(?m)^[ \t]*import\([ \t]*"
 
 type Runtime struct {
 	runtimes       map[ecs.Entity]*entityRuntime
+	compileCache   map[string]*compiledScript
+	sandbox        Sandbox
 	modules        map[string]scriptmodule.Module
 	world          *ecs.World
 	byGameEntityID map[string]ecs.Entity
+	errorHandler   func(ent ecs.Entity, err error)
+	frame          uint64
+	loggedErrors   map[string]*loggedError
+	scripted       *ecs.Query
+	// signalObserver, when set, is handed every signal queued on a script
+	// as the runtime first sees it. observedSignals counts the signals at
+	// the front of each queue it has already handed over.
+	signalObserver  SignalObserver
+	observedSignals map[ecs.Entity]int
+}
+
+// compiledScript is a script set compiled once and cloned for every entity
+// that runs it.
+type compiledScript struct {
+	paths       []string
+	compiled    *tengo.Compiled
+	hasOnStart  bool
+	hasOnUpdate bool
+	// modules are the entity-bound modules the script imports.
+	modules []string
+	sources []scriptSource
+	// modTimes stamps the disk copies compiled, so an edited script set
+	// compiles again in place of this.
+	modTimes string
 }
 
 type entityRuntime struct {
-	scriptPath    string
-	compiled      *tengo.Compiled
+	scriptPath string
+	compiled   *tengo.Compiled
+	// halted is set once a run blows the sandbox's allocation or step cap;
+	// the script is not run again for this entity.
+	halted        bool
 	state         *tengo.Map
 	hasOnStart    bool
 	hasOnUpdate   bool
 	subscriptions map[string][]subscription
+	sources       []scriptSource
+	timers        []*scriptTimer
+	nextTimerID   int64
 }
 
 type subscription struct {
@@ -39,6 +74,9 @@ type subscription struct {
 	callback         tengo.Object
 }
 
+// scriptSignalsModule is bound per entity like the registered modules.
+const scriptSignalsModule = "signals"
+
 const lifecycleStartDispatch = `
 if __phase == "start" {
 	on_start(__state)
@@ -53,7 +91,11 @@ if __phase == "update" {
 
 const lifecycleSignalDispatch = `
 if __phase == "signal" {
-	__signal_callback(__state)
+	if __signal_takes_payload {
+		__signal_callback(__state, __signal_payload)
+	} else {
+		__signal_callback(__state)
+	}
 }
 `
 
@@ -62,6 +104,8 @@ func NewRuntime() *Runtime {
 		runtimes:       map[ecs.Entity]*entityRuntime{},
 		modules:        map[string]scriptmodule.Module{},
 		byGameEntityID: map[string]ecs.Entity{},
+		sandbox:        DefaultSandbox(),
+		scripted:       ecs.NewQuery(component.ScriptComponent.Kind()),
 	}
 	r.RegisterBuiltinModules()
 	return r
@@ -93,11 +137,82 @@ func (r *Runtime) RegisterModule(module scriptmodule.Module) {
 	r.modules[name] = module
 }
 
+// SetErrorHandler routes script failures, each an *Error, to fn instead of
+// the log.
+func (r *Runtime) SetErrorHandler(fn func(ent ecs.Entity, err error)) {
+	if r == nil {
+		return
+	}
+	r.errorHandler = fn
+}
+
+// SignalObserver sees every signal queued on a script, as it is queued.
+type SignalObserver func(target ecs.Entity, event component.ScriptSignalEvent)
+
+// SetSignalObserver hands every signal queued on a script to fn, or stops
+// when fn is nil. It is for tests that check what scripts emit. Signals are
+// queued by functions that don't know the runtime, so it sees them on its
+// update: the ones queued since the last one as it starts, the ones a script
+// gets as it drains them, and the ones still queued as it ends.
+func (r *Runtime) SetSignalObserver(fn SignalObserver) {
+	if r == nil {
+		return
+	}
+	r.signalObserver = fn
+	r.observedSignals = nil
+	if fn != nil {
+		r.observedSignals = map[ecs.Entity]int{}
+	}
+}
+
+// observeSignals hands the observer the signals queued in w it hasn't seen.
+func (r *Runtime) observeSignals(w *ecs.World) {
+	if r.signalObserver == nil {
+		return
+	}
+	for ent := range r.observedSignals {
+		if !ecs.IsAlive(w, ent) {
+			delete(r.observedSignals, ent)
+		}
+	}
+	ecs.ForEach(w, component.ScriptSignalQueueComponent.Kind(), func(ent ecs.Entity, queue *component.ScriptSignalQueue) {
+		if queue != nil {
+			r.observeQueue(ent, queue.Events)
+		}
+	})
+}
+
+// observeQueue hands the observer the events of ent's queue past the ones it
+// has seen. A queue shorter than that was drained by something else.
+func (r *Runtime) observeQueue(ent ecs.Entity, events []component.ScriptSignalEvent) {
+	seen := r.observedSignals[ent]
+	if seen > len(events) {
+		seen = 0
+	}
+	for _, event := range events[seen:] {
+		r.signalObserver(ent, event)
+	}
+	r.observedSignals[ent] = len(events)
+}
+
+// reportError locates a script failure, adds it to the world's error log
+// and hands it to the error handler or the rate-limited log.
+func (r *Runtime) reportError(ent ecs.Entity, err error) {
+	diag := r.newError(ent, err)
+	recordError(r.world, diag)
+	if r.errorHandler != nil {
+		r.errorHandler(ent, diag)
+		return
+	}
+	r.logError(diag)
+}
+
 func (r *Runtime) Update(w *ecs.World) {
 	if r == nil || w == nil {
 		return
 	}
 
+	r.frame++
 	for ent := range r.runtimes {
 		if !ecs.IsAlive(w, ent) {
 			delete(r.runtimes, ent)
@@ -106,60 +221,85 @@ func (r *Runtime) Update(w *ecs.World) {
 
 	r.rebuildEntityIndex(w)
 	globalHitEvents := GlobalHitSignalEvents(w)
+	timeScale := gameplayTimeScale(w)
+	r.observeSignals(w)
 
-	ecs.ForEach(w, component.ScriptComponent.Kind(), func(ent ecs.Entity, scriptComp *component.Script) {
-		if scriptComp == nil || (strings.TrimSpace(scriptComp.Path) == "" && len(scriptComp.Paths) == 0) {
-			return
-		}
+	// Scripts create and destroy entities, scripted ones included, as they
+	// run, so walk a copy of the scripted entities rather than their store.
+	for _, ent := range r.scripted.Entities(w) {
+		r.updateEntity(w, ent, globalHitEvents, timeScale)
+	}
+	r.observeSignals(w)
+}
 
-		rt, err := r.getRuntime(ent, scriptComp)
-		if err != nil {
-			fmt.Printf("script: entity=%d load runtime error: %v\n", ent, err)
-			return
-		}
+func (r *Runtime) updateEntity(w *ecs.World, ent ecs.Entity, globalHitEvents []component.ScriptSignalEvent, timeScale float64) {
+	scriptComp, ok := ecs.Get(w, ent, component.ScriptComponent.Kind())
+	if !ok || scriptComp == nil || (strings.TrimSpace(scriptComp.Path) == "" && len(scriptComp.Paths) == 0) {
+		return
+	}
 
-		runtimeComp, ok := ecs.Get(w, ent, component.ScriptRuntimeComponent.Kind())
-		if !ok || runtimeComp == nil {
-			runtimeComp = &component.ScriptRuntime{}
-		}
+	rt, err := r.getRuntime(ent, scriptComp)
+	if err != nil {
+		r.reportError(ent, fmt.Errorf("load runtime error: %w", err))
+		return
+	}
+	if rt.halted {
+		return
+	}
 
-		if !runtimeComp.Started {
-			if err := rt.runPhase("start", r.buildEngineContext(ent)); err != nil {
-				fmt.Printf("script: entity=%d on_start error: %v\n", ent, err)
-				return
-			}
-			runtimeComp.Started = true
-			_ = ecs.Add(w, ent, component.ScriptRuntimeComponent.Kind(), runtimeComp)
-		}
+	// Timers tick first, so one scheduled anywhere in a frame runs its
+	// callback at the start of the frame it comes due.
+	report := func(err error) { r.reportError(ent, err) }
+	rt.tickTimers(timeScale, report)
+	if rt.halted {
+		return
+	}
 
-		events := drainScriptSignalQueue(w, ent)
-		for _, event := range events {
-			rt.dispatchSignal(event)
-		}
-		for _, event := range globalHitEvents {
-			if event.ExcludedEntity != 0 && event.ExcludedEntity == uint64(ent) {
-				continue
-			}
-			rt.dispatchSignal(event)
+	runtimeComp, ok := ecs.Get(w, ent, component.ScriptRuntimeComponent.Kind())
+	if !ok || runtimeComp == nil {
+		runtimeComp = &component.ScriptRuntime{}
+	}
+
+	if !runtimeComp.Started {
+		if err := rt.runPhase("start", r.buildEngineContext(ent)); err != nil {
+			r.reportError(ent, fmt.Errorf("on_start error: %w", err))
+			return
 		}
+		runtimeComp.Started = true
+		_ = ecs.Add(w, ent, component.ScriptRuntimeComponent.Kind(), runtimeComp)
+	}
 
-		if err := rt.runPhase("update", r.buildEngineContext(ent)); err != nil {
-			fmt.Printf("script: entity=%d on_update error: %v\n", ent, err)
+	events := drainScriptSignalQueue(w, ent)
+	if r.signalObserver != nil {
+		r.observeQueue(ent, events)
+		r.observedSignals[ent] = 0
+	}
+	for _, event := range events {
+		rt.dispatchSignal(event, report)
+	}
+	for _, event := range globalHitEvents {
+		if event.ExcludedEntity != 0 && event.ExcludedEntity == uint64(ent) {
+			continue
 		}
+		rt.dispatchSignal(event, report)
+	}
 
-		// Expose script state (e.g. state["current_state"]) via a component
-		if rt.state != nil {
-			if obj, ok := rt.state.Value["current_state"]; ok {
-				stateStr := objectAsString(obj)
-				_ = ecs.Add(w, ent, component.ScriptStateComponent.Kind(), &component.ScriptState{Current: stateStr})
-			} else {
-				// If no current_state present, ensure the component exists with empty value
-				if _, exists := ecs.Get(w, ent, component.ScriptStateComponent.Kind()); exists {
-					_ = ecs.Add(w, ent, component.ScriptStateComponent.Kind(), &component.ScriptState{Current: ""})
-				}
+	if err := rt.runPhase("update", r.buildEngineContext(ent)); err != nil {
+		r.reportError(ent, fmt.Errorf("on_update error: %w", err))
+	}
+
+	// Expose script state (e.g. state["current_state"]) via a component
+	if rt.state != nil {
+		if obj, ok := rt.state.Value["current_state"]; ok {
+			stateStr := objectAsString(obj)
+			_ = ecs.Add(w, ent, component.ScriptStateComponent.Kind(), &component.ScriptState{Current: stateStr})
+		} else {
+			// If no current_state present, ensure the component exists with empty value
+			if _, exists := ecs.Get(w, ent, component.ScriptStateComponent.Kind()); exists {
+				_ = ecs.Add(w, ent, component.ScriptStateComponent.Kind(), &component.ScriptState{Current: ""})
 			}
 		}
-	})
+	}
 }
 
 func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*entityRuntime, error) {
@@ -170,31 +310,114 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 		r.runtimes = map[ecs.Entity]*entityRuntime{}
 	}
 
-	// Determine script paths (support both legacy single Path and new Paths list)
-	var paths []string
-	if len(scriptComp.Paths) > 0 {
-		paths = append([]string(nil), scriptComp.Paths...)
-	} else if strings.TrimSpace(scriptComp.Path) != "" {
-		paths = []string{strings.TrimSpace(scriptComp.Path)}
-	}
-
+	paths := scriptPaths(scriptComp)
 	joinedPaths := strings.Join(paths, ";")
 	if rt, ok := r.runtimes[ent]; ok && rt != nil && rt.scriptPath == joinedPaths {
 		return rt, nil
 	}
 
+	rt, err := r.newEntityRuntime(ent, scriptComp)
+	if err != nil {
+		return nil, err
+	}
+
+	r.runtimes[ent] = rt
+	return rt, nil
+}
+
+// newEntityRuntime sets up a runtime for ent's scripts without running them.
+func (r *Runtime) newEntityRuntime(ent ecs.Entity, scriptComp *component.Script) (*entityRuntime, error) {
+	cached, err := r.compiledFor(scriptComp)
+	if err != nil {
+		return nil, err
+	}
+
 	rt := &entityRuntime{
-		scriptPath:    joinedPaths,
+		scriptPath:    strings.Join(scriptPaths(scriptComp), ";"),
+		compiled:      cached.compiled.Clone(),
 		state:         &tengo.Map{Value: map[string]tengo.Object{}},
+		hasOnStart:    cached.hasOnStart,
+		hasOnUpdate:   cached.hasOnUpdate,
 		subscriptions: map[string][]subscription{},
+		sources:       cached.sources,
+	}
+	if err := rt.compiled.Set("__modules", r.buildEntityModules(ent, rt, cached.modules)); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+// compiledFor returns the compile of a script component's script set,
+// compiling it on first use.
+func (r *Runtime) compiledFor(scriptComp *component.Script) (*compiledScript, error) {
+	paths := scriptPaths(scriptComp)
+	key := compileCacheKey(paths, scriptComp.Modules, scriptComp.Stdlib)
+	modTimes := scriptModTimes(paths)
+	if cached, ok := r.compileCache[key]; ok && cached.modTimes == modTimes {
+		return cached, nil
 	}
 
+	cached, err := r.compileScript(paths, scriptComp.Modules, scriptComp.Stdlib)
+	if err != nil {
+		return nil, err
+	}
+	cached.modTimes = modTimes
+	if r.compileCache == nil {
+		r.compileCache = map[string]*compiledScript{}
+	}
+	r.compileCache[key] = cached
+	return cached, nil
+}
+
+// scriptPaths lists a script component's files, supporting both the legacy
+// single Path and the Paths list.
+func scriptPaths(scriptComp *component.Script) []string {
+	if len(scriptComp.Paths) > 0 {
+		return append([]string(nil), scriptComp.Paths...)
+	}
+	if path := strings.TrimSpace(scriptComp.Path); path != "" {
+		return []string{path}
+	}
+	return nil
+}
+
+// compileCacheKey identifies a compiled script set by its paths, configured
+// modules and stdlib.
+func compileCacheKey(paths []string, modules []string, stdlibNames []string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(paths, ";"))
+	sb.WriteString("|")
+	sb.WriteString(strings.Join(modules, ";"))
+	sb.WriteString("|")
+	sb.WriteString(strings.Join(stdlibNames, ";"))
+	return sb.String()
+}
+
+// scriptModTimes stamps the disk mod time of each path, when scripts are
+// loaded from disk, so a cached compile can tell it is out of date.
+func scriptModTimes(paths []string) string {
+	var sb strings.Builder
+	for _, p := range paths {
+		if modTime, ok := prefabs.ScriptModTime(p); ok {
+			sb.WriteString(strconv.FormatInt(modTime.UnixNano(), 10))
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// compileScript concatenates and compiles a script set once. Entity-bound
+// modules are not compiled in; the import block reads them from __modules,
+// which each entity's clone sets to modules built for that entity.
+func (r *Runtime) compileScript(paths []string, configured []string, stdlibNames []string) (*compiledScript, error) {
 	// Load and concatenate script file contents in order.
 	// While doing so, extract any import(...) declarations, remove them
 	// from each source file, deduplicate the imports, and place a single
 	// import block at the top of the final script.
 	var sb strings.Builder
 	var rawSB strings.Builder
+	var sources []scriptSource
+	bodyLines := 0
 	imports := map[string]string{}
 	for _, p := range paths {
 		if strings.TrimSpace(p) == "" {
@@ -247,7 +470,23 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 		cleaned = scriptImportCallLinePattern.ReplaceAllString(cleaned, "")
 		sb.WriteString(cleaned)
 		sb.WriteString("\n")
+
+		// Removed imports leave their lines empty, so each file keeps its
+		// own line numbering after the ones before it.
+		lines := strings.Count(cleaned, "\n") + 1
+		sources = append(sources, scriptSource{path: p, first: bodyLines + 1, lines: lines})
+		bodyLines += lines
+	}
+
+	// Use the raw script content when resolving requested modules so
+	// we detect any import(...) usages even though we've removed them
+	// from the per-file sources and centralized them above.
+	requestedModules := r.resolveRequestedModules(rawSB.String(), configured)
+	allowed := map[string]bool{scriptSignalsModule: true, scriptTimerModule: true}
+	for _, name := range r.entityModuleNames(requestedModules) {
+		allowed[name] = true
 	}
+	allowedStdlib := r.stdlibAllowlist(stdlibNames)
 
 	// build deduplicated import block (if any)
 	importNames := make([]string, 0, len(imports))
@@ -257,6 +496,7 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 	sort.Strings(importNames)
 
 	importBlock := ""
+	var entityModules []string
 	if len(importNames) > 0 {
 		var ib strings.Builder
 		for _, mod := range importNames {
@@ -265,6 +505,22 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 				varName = mod
 			}
 			ib.WriteString(varName)
+			if r.isEntityModule(mod) {
+				if !allowed[mod] {
+					return nil, fmt.Errorf("module '%s' not found", mod)
+				}
+				entityModules = append(entityModules, mod)
+				ib.WriteString(" := __modules[\"")
+				ib.WriteString(mod)
+				ib.WriteString("\"]\n")
+				continue
+			}
+			if isStdlibModule(mod) && !allowedStdlib[mod] {
+				if !r.sandboxStdlib()[mod] {
+					return nil, fmt.Errorf("stdlib module %q is not allowed by the sandbox", mod)
+				}
+				return nil, fmt.Errorf("stdlib module %q is not allowed; add it to the script component's stdlib list", mod)
+			}
 			ib.WriteString(" := import(\"")
 			ib.WriteString(mod)
 			ib.WriteString("\")\n")
@@ -273,9 +529,13 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 		importBlock = ib.String()
 	}
 
+	importLines := strings.Count(importBlock, "\n")
+	for i := range sources {
+		sources[i].first += importLines
+	}
+
 	// final script string has a single import block (assignment-style) followed by cleaned sources
 	scriptString := importBlock + sb.String()
-	rawScriptString := rawSB.String()
 
 	startDispatch := ""
 	if strings.Contains(scriptString, "on_start") {
@@ -289,27 +549,32 @@ func (r *Runtime) getRuntime(ent ecs.Entity, scriptComp *component.Script) (*ent
 
 	src := scriptString + startDispatch + updateDispatch + "\n" + lifecycleSignalDispatch
 
-	script := tengo.NewScript([]byte(src))
+	script := tengo.NewScript([]byte(countSteps(src)))
+	_ = script.Add(stepCounterName, &stepCounter{max: r.sandbox.MaxSteps})
 	_ = script.Add("__phase", "")
 	_ = script.Add("__state", map[string]any{})
 	_ = script.Add("__signal_callback", tengo.UndefinedValue)
-	// Use the raw script content when resolving requested modules so
-	// we detect any import(...) usages even though we've removed them
-	// from the per-file sources and centralized them above.
-	requestedModules := r.resolveRequestedModules(rawScriptString, scriptComp.Modules)
-	script.SetImports(r.buildModuleMap(ent, rt, requestedModules))
+	_ = script.Add("__signal_payload", tengo.UndefinedValue)
+	_ = script.Add("__signal_takes_payload", false)
+	_ = script.Add("__modules", &tengo.ImmutableMap{Value: map[string]tengo.Object{}})
+	script.SetImports(buildModuleMap(allowedStdlib))
+	if r.sandbox.MaxAllocs > 0 {
+		script.SetMaxAllocs(r.sandbox.MaxAllocs)
+	}
 
 	compiled, err := script.Compile()
 	if err != nil {
-		return nil, err
+		return nil, &compileError{err: err, sources: sources}
 	}
 
-	rt.compiled = compiled
-	rt.hasOnStart = startDispatch != ""
-	rt.hasOnUpdate = updateDispatch != ""
-
-	r.runtimes[ent] = rt
-	return rt, nil
+	return &compiledScript{
+		paths:       paths,
+		compiled:    compiled,
+		hasOnStart:  startDispatch != "",
+		hasOnUpdate: updateDispatch != "",
+		modules:     entityModules,
+		sources:     sources,
+	}, nil
 }
 
 func (r *Runtime) resolveRequestedModules(scriptString string, configured []string) []string {
@@ -354,21 +619,43 @@ func (r *Runtime) resolveRequestedModules(scriptString string, configured []stri
 	return names
 }
 
-func (r *Runtime) buildModuleMap(owner ecs.Entity, rt *entityRuntime, modules []string) *tengo.ModuleMap {
-	moduleMap := stdlib.GetModuleMap(stdlib.AllModuleNames()...).Copy()
+// buildModuleMap holds the modules compiled into a shared script: the allowed
+// Tengo stdlib modules, with rand swapped for the seeded one. None of them
+// depend on the entity.
+func buildModuleMap(allowedStdlib map[string]bool) *tengo.ModuleMap {
+	names := make([]string, 0, len(allowedStdlib))
+	for name := range allowedStdlib {
+		names = append(names, name)
+	}
+	moduleMap := stdlib.GetModuleMap(names...)
+	if allowedStdlib["rand"] {
+		moduleMap.AddBuiltinModule("rand", seededRandModule())
+	}
+	return moduleMap
+}
+
+// isEntityModule reports whether a module is bound to the entity running the
+// script, so it is handed to each clone instead of compiled in.
+func (r *Runtime) isEntityModule(name string) bool {
+	if name == scriptSignalsModule || name == scriptTimerModule {
+		return true
+	}
+	_, ok := r.modules[name]
+	return ok
+}
+
+// entityModuleNames lists the registered modules a script may import: the
+// requested ones, or all of them when none were requested.
+func (r *Runtime) entityModuleNames(requested []string) []string {
 	allowed := map[string]bool{}
-	if len(modules) > 0 {
-		for _, name := range modules {
-			name = strings.TrimSpace(name)
-			if name == "" {
-				continue
-			}
-			allowed[name] = true
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
+		allowed[name] = true
 	}
 
-	moduleMap.AddBuiltinModule("signals", r.buildSignalsModule(owner, rt))
-
 	names := make([]string, 0, len(r.modules))
 	for name := range r.modules {
 		if len(allowed) > 0 && !allowed[name] {
@@ -377,13 +664,46 @@ func (r *Runtime) buildModuleMap(owner ecs.Entity, rt *entityRuntime, modules []
 		names = append(names, name)
 	}
 	sort.Strings(names)
+	return names
+}
 
-	for _, name := range names {
-		plugin := r.modules[name]
-		moduleMap.AddBuiltinModule(name, r.buildPluginModule(plugin, owner, owner))
+// buildEntityModules builds the entity-bound modules a compiled script
+// imports, for one owner.
+func (r *Runtime) buildEntityModules(owner ecs.Entity, rt *entityRuntime, modules []string) *tengo.ImmutableMap {
+	values := make(map[string]tengo.Object, len(modules))
+	for _, name := range modules {
+		if name == scriptSignalsModule {
+			values[name] = &tengo.ImmutableMap{Value: r.buildSignalsModule(owner, rt)}
+			continue
+		}
+		if name == scriptTimerModule {
+			values[name] = &tengo.ImmutableMap{Value: r.buildTimerModule(rt)}
+			continue
+		}
+		plugin, ok := r.modules[name]
+		if !ok {
+			continue
+		}
+		values[name] = &tengo.ImmutableMap{Value: r.buildPluginModule(plugin, owner, owner)}
 	}
+	return &tengo.ImmutableMap{Value: values}
+}
 
-	return moduleMap
+// seededRandModule replaces the stdlib rand functions scripts roll with so
+// they draw from the shared gameplay source and replay deterministically.
+func seededRandModule() map[string]tengo.Object {
+	values := make(map[string]tengo.Object, len(stdlib.BuiltinModules["rand"]))
+	for name, value := range stdlib.BuiltinModules["rand"] {
+		values[name] = value
+	}
+
+	values["int"] = &tengo.UserFunction{Name: "int", Value: stdlib.FuncARI64(func() int64 { return common.Rand().Int63() })}
+	values["float"] = &tengo.UserFunction{Name: "float", Value: stdlib.FuncARF(func() float64 { return common.Rand().Float64() })}
+	values["intn"] = &tengo.UserFunction{Name: "intn", Value: stdlib.FuncAI64RI64(func(n int64) int64 { return common.Rand().Int63n(n) })}
+	values["exp_float"] = &tengo.UserFunction{Name: "exp_float", Value: stdlib.FuncARF(func() float64 { return common.Rand().ExpFloat64() })}
+	values["norm_float"] = &tengo.UserFunction{Name: "norm_float", Value: stdlib.FuncARF(func() float64 { return common.Rand().NormFloat64() })}
+	values["perm"] = &tengo.UserFunction{Name: "perm", Value: stdlib.FuncAIRIs(func(n int) []int { return common.Rand().Perm(n) })}
+	return values
 }
 
 func (r *Runtime) buildEngineContext(owner ecs.Entity) *tengo.ImmutableMap {
@@ -406,7 +726,11 @@ func (r *Runtime) buildEngineContext(owner ecs.Entity) *tengo.ImmutableMap {
 			}
 			target = resolved
 		}
-		if EmitEntitySignal(r.world, target, owner, signal) {
+		payload, err := signalDataArg(args, 2)
+		if err != nil {
+			return tengo.FalseValue, err
+		}
+		if EmitSignalEvent(r.world, target, owner, component.ScriptSignalEvent{Name: signal, Payload: payload}) {
 			return tengo.TrueValue, nil
 		}
 		return tengo.FalseValue, nil
@@ -490,7 +814,11 @@ func (r *Runtime) buildSignalsModule(owner ecs.Entity, rt *entityRuntime) map[st
 			}
 			target = resolved
 		}
-		if EmitEntitySignal(r.world, target, owner, signal) {
+		payload, err := signalDataArg(args, 2)
+		if err != nil {
+			return tengo.FalseValue, err
+		}
+		if EmitSignalEvent(r.world, target, owner, component.ScriptSignalEvent{Name: signal, Payload: payload}) {
 			return tengo.TrueValue, nil
 		}
 		return tengo.FalseValue, nil
@@ -563,10 +891,20 @@ func (rt *entityRuntime) runPhase(phase string, engineCtx *tengo.ImmutableMap) e
 	if err := rt.compiled.Set("__state", rt.state); err != nil {
 		return err
 	}
-	return rt.compiled.Run()
+	return rt.run()
+}
+
+// run executes the script within its budget, halting it when it overruns.
+func (rt *entityRuntime) run() error {
+	err := runBudgeted(rt.compiled)
+	if errors.Is(err, errBudgetExceeded) {
+		rt.halted = true
+		return fmt.Errorf("%w; script halted", err)
+	}
+	return err
 }
 
-func (rt *entityRuntime) dispatchSignal(event component.ScriptSignalEvent) {
+func (rt *entityRuntime) dispatchSignal(event component.ScriptSignalEvent, report func(error)) {
 	if rt == nil || len(rt.subscriptions) == 0 || strings.TrimSpace(event.Name) == "" {
 		return
 	}
@@ -592,22 +930,66 @@ func (rt *entityRuntime) dispatchSignal(event component.ScriptSignalEvent) {
 		}
 
 		if err := rt.compiled.Set("__phase", "signal"); err != nil {
-			fmt.Printf("script: signal set phase error (%s): %v\n", event.Name, err)
+			report(fmt.Errorf("signal set phase error (%s): %w", event.Name, err))
 			continue
 		}
 		if err := rt.compiled.Set("__signal_callback", sub.callback); err != nil {
-			fmt.Printf("script: signal set callback error (%s): %v\n", event.Name, err)
+			report(fmt.Errorf("signal set callback error (%s): %w", event.Name, err))
 			continue
 		}
+		takesPayload := callbackTakesPayload(sub.callback)
+		if takesPayload {
+			payload, err := signalPayloadObject(event.Payload)
+			if err != nil {
+				report(fmt.Errorf("signal payload error (%s): %w", event.Name, err))
+				continue
+			}
+			_ = rt.compiled.Set("__signal_payload", payload)
+		}
+		_ = rt.compiled.Set("__signal_takes_payload", takesPayload)
 
-		if err := rt.compiled.Run(); err != nil {
-			fmt.Printf("script: signal handler error (%s): %v\n", event.Name, err)
+		if err := rt.run(); err != nil {
+			report(fmt.Errorf("signal handler error (%s): %w", event.Name, err))
+		}
+		if rt.halted {
+			return
 		}
 
 		_ = rt.compiled.Set("__signal_callback", tengo.UndefinedValue)
+		_ = rt.compiled.Set("__signal_payload", tengo.UndefinedValue)
 	}
 }
 
+// callbackTakesPayload reports whether a signal callback accepts the payload
+// after state. Go functions take any number of arguments.
+func callbackTakesPayload(callback tengo.Object) bool {
+	if fn, ok := callback.(*tengo.CompiledFunction); ok {
+		return fn.VarArgs || fn.NumParameters >= 2
+	}
+	return true
+}
+
+// signalPayloadObject converts a payload into a fresh Tengo map, so each
+// receiver gets its own copy. A signal without a payload gets an empty map.
+func signalPayloadObject(payload component.SignalPayload) (tengo.Object, error) {
+	if payload == nil {
+		return &tengo.Map{Value: map[string]tengo.Object{}}, nil
+	}
+	return tengo.FromInterface(payload.SignalData())
+}
+
+// signalDataArg reads the optional payload map passed to emit from a script.
+func signalDataArg(args []tengo.Object, index int) (component.SignalPayload, error) {
+	if len(args) <= index || args[index] == tengo.UndefinedValue {
+		return nil, nil
+	}
+	data, ok := tengo.ToInterface(args[index]).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("signal payload must be a map, got %s", args[index].TypeName())
+	}
+	return component.SignalData(data), nil
+}
+
 func isCallableObject(obj tengo.Object) bool {
 	switch obj.(type) {
 	case *tengo.CompiledFunction, *tengo.UserFunction, *tengo.BuiltinFunction:
@@ -686,16 +1068,9 @@ func QueueGlobalHitSignalWithPosition(w *ecs.World, source ecs.Entity, excludeTa
 		return false
 	}
 
-	sourceGameEntity := ""
-	if ecs.IsAlive(w, source) {
-		if id, ok := ecs.Get(w, source, component.GameEntityIDComponent.Kind()); ok && id != nil {
-			sourceGameEntity = strings.TrimSpace(id.Value)
-		}
-	}
-
 	event := component.ScriptSignalEvent{
 		Name:             "on_any_hit",
-		SourceGameEntity: sourceGameEntity,
+		SourceGameEntity: signalSourceGameEntity(w, source),
 		ExcludedEntity:   uint64(excludeTarget),
 		HasPosition:      hasPosition,
 		PositionX:        positionX,
@@ -716,38 +1091,45 @@ func EmitEntitySignal(w *ecs.World, target ecs.Entity, source ecs.Entity, signal
 }
 
 func EmitEntitySignalWithPosition(w *ecs.World, target ecs.Entity, source ecs.Entity, signalName string, positionX, positionY float64, hasPosition bool) bool {
+	return EmitSignalEvent(w, target, source, component.ScriptSignalEvent{
+		Name:        signalName,
+		HasPosition: hasPosition,
+		PositionX:   positionX,
+		PositionY:   positionY,
+	})
+}
+
+// EmitSignalEvent queues event on target's script, filling in the source's
+// game entity id. Use it to attach a payload.
+func EmitSignalEvent(w *ecs.World, target ecs.Entity, source ecs.Entity, event component.ScriptSignalEvent) bool {
 	if w == nil || !ecs.IsAlive(w, target) {
 		return false
 	}
-	signalName = strings.TrimSpace(signalName)
-	if signalName == "" {
+	event.Name = strings.TrimSpace(event.Name)
+	if event.Name == "" {
 		return false
 	}
-
-	sourceGameEntity := ""
-	if ecs.IsAlive(w, source) {
-		if id, ok := ecs.Get(w, source, component.GameEntityIDComponent.Kind()); ok && id != nil {
-			sourceGameEntity = strings.TrimSpace(id.Value)
-		}
-	}
-
-	event := component.ScriptSignalEvent{
-		Name:             signalName,
-		SourceGameEntity: sourceGameEntity,
-		HasPosition:      hasPosition,
-		PositionX:        positionX,
-		PositionY:        positionY,
-	}
+	event.SourceGameEntity = signalSourceGameEntity(w, source)
 
 	return enqueueScriptSignalEvent(w, target, event)
 }
 
 func BroadcastSignalWithPosition(w *ecs.World, source ecs.Entity, signalName string, positionX, positionY float64, hasPosition bool, excludeTargets ...ecs.Entity) int {
+	return BroadcastSignalEvent(w, source, component.ScriptSignalEvent{
+		Name:        signalName,
+		HasPosition: hasPosition,
+		PositionX:   positionX,
+		PositionY:   positionY,
+	}, excludeTargets...)
+}
+
+// BroadcastSignalEvent queues event on every script except excludeTargets.
+func BroadcastSignalEvent(w *ecs.World, source ecs.Entity, event component.ScriptSignalEvent, excludeTargets ...ecs.Entity) int {
 	if w == nil {
 		return 0
 	}
-	signalName = strings.TrimSpace(signalName)
-	if signalName == "" {
+	event.Name = strings.TrimSpace(event.Name)
+	if event.Name == "" {
 		return 0
 	}
 
@@ -756,20 +1138,7 @@ func BroadcastSignalWithPosition(w *ecs.World, source ecs.Entity, signalName str
 		excluded[ent] = struct{}{}
 	}
 
-	sourceGameEntity := ""
-	if ecs.IsAlive(w, source) {
-		if id, ok := ecs.Get(w, source, component.GameEntityIDComponent.Kind()); ok && id != nil {
-			sourceGameEntity = strings.TrimSpace(id.Value)
-		}
-	}
-
-	event := component.ScriptSignalEvent{
-		Name:             signalName,
-		SourceGameEntity: sourceGameEntity,
-		HasPosition:      hasPosition,
-		PositionX:        positionX,
-		PositionY:        positionY,
-	}
+	event.SourceGameEntity = signalSourceGameEntity(w, source)
 
 	delivered := 0
 	ecs.ForEach(w, component.ScriptComponent.Kind(), func(ent ecs.Entity, _ *component.Script) {
@@ -784,6 +1153,16 @@ func BroadcastSignalWithPosition(w *ecs.World, source ecs.Entity, signalName str
 	return delivered
 }
 
+func signalSourceGameEntity(w *ecs.World, source ecs.Entity) string {
+	if !ecs.IsAlive(w, source) {
+		return ""
+	}
+	if id, ok := ecs.Get(w, source, component.GameEntityIDComponent.Kind()); ok && id != nil {
+		return strings.TrimSpace(id.Value)
+	}
+	return ""
+}
+
 func objectAsString(obj tengo.Object) string {
 	if obj == nil {
 		return ""
@@ -0,0 +1,502 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/prefabs"
+)
+
+func TestRuntimeCompilesSharedScriptOncePerScriptSet(t *testing.T) {
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	r.SetErrorHandler(func(ent ecs.Entity, err error) {
+		t.Fatalf("script error on entity %d: %v", ent, err)
+	})
+
+	entities := make([]ecs.Entity, 3)
+	for i := range entities {
+		entities[i] = ecs.CreateEntity(w)
+		_ = ecs.Add(w, entities[i], component.ScriptComponent.Kind(), &component.Script{Paths: []string{"triggers/disposal_8.tengo"}})
+	}
+
+	r.Update(w)
+
+	if len(r.compileCache) != 1 {
+		t.Fatalf("expected one compiled script set, got %d", len(r.compileCache))
+	}
+	seen := map[any]bool{}
+	for _, ent := range entities {
+		rt := r.runtimes[ent]
+		if rt == nil {
+			t.Fatalf("expected a runtime for entity %d", ent)
+		}
+		if seen[rt.compiled] {
+			t.Fatal("expected every entity to get its own clone")
+		}
+		seen[rt.compiled] = true
+		if len(rt.subscriptions["on_trigger_entered"]) != 1 {
+			t.Fatalf("expected on_start to subscribe through the entity's own signals module, got %v", rt.subscriptions)
+		}
+	}
+}
+
+func TestRuntimeReplacesTheCompileOfAnEditedScript(t *testing.T) {
+	writeTestScript(t, "edited/main.tengo", "on_start := func(state) {\n    state[\"version\"] = 1\n}\n")
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	r.SetErrorHandler(func(ent ecs.Entity, err error) {
+		t.Fatalf("script error on entity %d: %v", ent, err)
+	})
+	spawn := func() ecs.Entity {
+		ent := ecs.CreateEntity(w)
+		_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"edited/main.tengo"}})
+		return ent
+	}
+	first := spawn()
+	r.Update(w)
+
+	path := filepath.Join("prefabs", "scripts", "edited", "main.tengo")
+	if err := os.WriteFile(path, []byte("on_start := func(state) {\n    state[\"version\"] = 2\n}\n"), 0o644); err != nil {
+		t.Fatalf("edit script: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("touch script: %v", err)
+	}
+	second := spawn()
+	r.Update(w)
+
+	if len(r.compileCache) != 1 {
+		t.Fatalf("expected the edited compile to replace the old one, got %d entries", len(r.compileCache))
+	}
+	for ent, want := range map[ecs.Entity]int64{first: 1, second: 2} {
+		if got, ok := r.runtimes[ent].state.Value["version"].(*tengo.Int); !ok || got.Value != want {
+			t.Fatalf("expected entity %d to run version %d, got %v", ent, want, r.runtimes[ent].state.Value["version"])
+		}
+	}
+}
+
+func TestRuntimeRejectsStdlibModuleOutsideAllowlist(t *testing.T) {
+	writeTestScript(t, "sandbox/uses_os.tengo", "os := import(\"os\")\n\non_start := func(state) {\n    os.exit(1)\n}\n")
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	var loadErr error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { loadErr = err })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"sandbox/uses_os.tengo"}})
+	r.Update(w)
+
+	if loadErr == nil || !strings.Contains(loadErr.Error(), `stdlib module "os" is not allowed`) {
+		t.Fatalf("expected os import to be rejected, got %v", loadErr)
+	}
+}
+
+func TestRuntimeRejectsStdlibModuleTheSandboxDenies(t *testing.T) {
+	writeTestScript(t, "sandbox/widens.tengo", "os := import(\"os\")\n")
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	var loadErr error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { loadErr = err })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"sandbox/widens.tengo"}, Stdlib: []string{"fmt", "os"}})
+	r.Update(w)
+
+	if loadErr == nil || !strings.Contains(loadErr.Error(), `stdlib module "os" is not allowed by the sandbox`) {
+		t.Fatalf("expected a script's stdlib list not to re-enable os, got %v", loadErr)
+	}
+}
+
+func TestRuntimeHaltsScriptThatOverrunsItsBudget(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		loop string
+	}{
+		{"bare_loop", "for {}"},
+		{"allocating_loop", "for i := 0; true; i++ {}"},
+		{"tail_call", "spin := undefined\n    spin = func() { return spin() }\n    spin()"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			writeTestScript(t, "sandbox/runaway.tengo", "on_update := func(state) {\n    "+tc.loop+"\n}\n")
+
+			w := ecs.NewWorld()
+			r := NewRuntime()
+			sandbox := DefaultSandbox()
+			sandbox.MaxAllocs = 10_000
+			sandbox.MaxSteps = 10_000
+			r.SetSandbox(sandbox)
+			var errs []error
+			r.SetErrorHandler(func(_ ecs.Entity, err error) { errs = append(errs, err) })
+
+			ent := ecs.CreateEntity(w)
+			_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"sandbox/runaway.tengo"}})
+			r.Update(w)
+			r.Update(w)
+
+			if len(errs) != 1 || !errors.Is(errs[0], errBudgetExceeded) {
+				t.Fatalf("expected one budget error before the script halts, got %v", errs)
+			}
+			if rt := r.runtimes[ent]; rt == nil || !rt.halted {
+				t.Fatal("expected the runaway script to be halted")
+			}
+		})
+	}
+}
+
+func TestRuntimeCountsStepsPerRun(t *testing.T) {
+	writeTestScript(t, "sandbox/busy.tengo", "on_update := func(state) {\n    for i := 0; i < 100; i++ {}\n}\n")
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	sandbox := DefaultSandbox()
+	sandbox.MaxSteps = 150
+	r.SetSandbox(sandbox)
+	var errs []error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { errs = append(errs, err) })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"sandbox/busy.tengo"}})
+	for i := 0; i < 5; i++ {
+		r.Update(w)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("expected each frame's run to get its own step budget, got %v", errs)
+	}
+}
+
+// writeTestScript places a script where prefabs.LoadScript looks for disk
+// copies, under a temporary working directory, and turns that lookup on.
+func writeTestScript(t *testing.T, name, src string) {
+	t.Helper()
+	writeTestScripts(t, map[string]string{name: src})
+}
+
+// writeTestScripts is writeTestScript for a set of scripts sharing one
+// working directory.
+func writeTestScripts(t *testing.T, scripts map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range scripts {
+		path := filepath.Join(dir, "prefabs", "scripts", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create script dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+	t.Chdir(dir)
+	prefabs.LoadScriptsFromDisk(true)
+	t.Cleanup(func() { prefabs.LoadScriptsFromDisk(false) })
+}
+
+func TestRuntimePassesSignalPayloadToCallbacksThatTakeIt(t *testing.T) {
+	writeTestScript(t, "signals/payload.tengo", `signals := import("signals")
+
+on_ping := func(state, data) {
+    state["amount"] = data.amount
+}
+
+on_hit := func(state, data) {
+    state["damage"] = data.damage
+}
+
+on_pong := func(state) {
+    state["ponged"] = true
+}
+
+on_start := func(state) {
+    signals.on("ping", on_ping, "*")
+    signals.on("on_hit", on_hit, "*")
+    signals.on("pong", on_pong, "*")
+    signals.emit("ping", "", {amount: 3})
+}
+`)
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	r.SetErrorHandler(func(ent ecs.Entity, err error) {
+		t.Fatalf("script error on entity %d: %v", ent, err)
+	})
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"signals/payload.tengo"}})
+	r.Update(w)
+
+	EmitSignalEvent(w, ent, 0, component.ScriptSignalEvent{Name: "on_hit", Payload: component.HitSignalPayload{Damage: 2, Health: 1}})
+	EmitSignalEvent(w, ent, 0, component.ScriptSignalEvent{Name: "pong", Payload: component.SignalData{"ignored": true}})
+	r.Update(w)
+
+	state := r.runtimes[ent].state.Value
+	if amount, ok := state["amount"].(*tengo.Int); !ok || amount.Value != 3 {
+		t.Fatalf("expected the script payload to arrive, got %v", state["amount"])
+	}
+	if damage, ok := state["damage"].(*tengo.Int); !ok || damage.Value != 2 {
+		t.Fatalf("expected the typed hit payload to arrive, got %v", state["damage"])
+	}
+	if state["ponged"] != tengo.TrueValue {
+		t.Fatal("expected a one-argument callback to still run")
+	}
+}
+
+func TestRuntimeObservesEachQueuedSignalOnce(t *testing.T) {
+	writeTestScript(t, "signals/echo.tengo", `signals := import("signals")
+
+on_ping := func(state) {
+    signals.emit("pong")
+}
+
+on_start := func(state) {
+    signals.on("ping", on_ping, "*")
+}
+`)
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	r.SetErrorHandler(func(ent ecs.Entity, err error) {
+		t.Fatalf("script error on entity %d: %v", ent, err)
+	})
+	var seen []string
+	r.SetSignalObserver(func(_ ecs.Entity, event component.ScriptSignalEvent) {
+		seen = append(seen, event.Name)
+	})
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"signals/echo.tengo"}})
+	r.Update(w)
+	EmitSignalEvent(w, ent, 0, component.ScriptSignalEvent{Name: "ping"})
+	for i := 0; i < 3; i++ {
+		r.Update(w)
+	}
+	if !reflect.DeepEqual(seen, []string{"ping", "pong"}) {
+		t.Fatalf("expected ping and the script's pong once each, got %v", seen)
+	}
+
+	r.SetSignalObserver(nil)
+	EmitSignalEvent(w, ent, 0, component.ScriptSignalEvent{Name: "ping"})
+	r.Update(w)
+	if len(seen) != 2 {
+		t.Fatalf("expected no signals seen once the observer is cleared, got %v", seen)
+	}
+}
+
+func TestRuntimeTimersRunOnScaledGameplayFrames(t *testing.T) {
+	writeTestScript(t, "timers/main.tengo", `timer := import("timer")
+
+on_start := func(state) {
+    state["after"] = 0
+    state["every"] = 0
+    timer.after(2, func(state) {
+        state["after"] += 1
+    })
+    state["every_id"] = timer.every(1, func(state) {
+        state["every"] += 1
+        if state["every"] == 2 {
+            timer.cancel(state["every_id"])
+        }
+    })
+}
+`)
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	r.SetErrorHandler(func(ent ecs.Entity, err error) {
+		t.Fatalf("script error on entity %d: %v", ent, err)
+	})
+
+	clock := ecs.CreateEntity(w)
+	_ = ecs.Add(w, clock, component.GameplayTimeComponent.Kind(), &component.GameplayTime{Scale: 0.5})
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"timers/main.tengo"}})
+
+	counts := func() (int64, int64) {
+		state := r.runtimes[ent].state.Value
+		after, _ := tengo.ToInt64(state["after"])
+		every, _ := tengo.ToInt64(state["every"])
+		return after, every
+	}
+
+	for range 4 {
+		r.Update(w)
+	}
+	if after, every := counts(); after != 0 || every != 1 {
+		t.Fatalf("expected half-speed timers to have fired every once and after not yet, got after=%d every=%d", after, every)
+	}
+
+	for range 3 {
+		r.Update(w)
+	}
+	if after, every := counts(); after != 1 || every != 2 {
+		t.Fatalf("expected after once and every cancelled at 2, got after=%d every=%d", after, every)
+	}
+	if pending := len(r.runtimes[ent].timers); pending != 0 {
+		t.Fatalf("expected fired and cancelled timers to be dropped, got %d", pending)
+	}
+}
+
+func TestRuntimeReportsErrorsAtTheirOriginalFileAndLine(t *testing.T) {
+	writeTestScripts(t, map[string]string{
+		"broken/constants.tengo": "SPEED := 2\nLIMIT := 4\n",
+		"broken/main.tengo": `fmt := import("fmt")
+
+on_update := func(state) {
+    count := SPEED
+    count()
+}
+`,
+	})
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	var errs []error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { errs = append(errs, err) })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.GameEntityIDComponent.Kind(), &component.GameEntityID{Value: "broken_1"})
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"broken/constants.tengo", "broken/main.tengo"}})
+	r.Update(w)
+	r.Update(w)
+
+	if len(errs) != 2 {
+		t.Fatalf("expected an error each update, got %v", errs)
+	}
+	var diag *Error
+	if !errors.As(errs[0], &diag) {
+		t.Fatalf("expected a located script error, got %T", errs[0])
+	}
+	if diag.GameEntityID != "broken_1" || diag.ScriptPath != "broken/constants.tengo;broken/main.tengo" {
+		t.Fatalf("unexpected script error origin %+v", diag)
+	}
+	if diag.File != "broken/main.tengo" || diag.Line != 5 {
+		t.Fatalf("expected the failure at broken/main.tengo:5, got %s:%d (%v)", diag.File, diag.Line, diag)
+	}
+	if !strings.Contains(diag.Error(), "broken/main.tengo:5:") || strings.Contains(diag.Error(), "(main)") {
+		t.Fatalf("expected positions mapped to the original file, got %q", diag.Error())
+	}
+
+	logEnt, ok := ecs.First(w, component.ScriptErrorLogComponent.Kind())
+	if !ok {
+		t.Fatal("expected the failure in the world's script error log")
+	}
+	log, _ := ecs.Get(w, logEnt, component.ScriptErrorLogComponent.Kind())
+	if len(log.Entries) != 1 || log.Entries[0].Count != 2 || log.Entries[0].Line != 5 {
+		t.Fatalf("expected one repeated log entry, got %+v", log.Entries)
+	}
+}
+
+func TestRuntimeReloadScriptsKeepsStateAndSubscriptions(t *testing.T) {
+	source := func(step int) string {
+		return fmt.Sprintf(`signals := import("signals")
+
+on_ping := func(state) {
+    state["count"] += %d
+}
+
+on_start := func(state) {
+    state["count"] = 0
+    signals.on("ping", on_ping, "*")
+}
+`, step)
+	}
+	writeTestScript(t, "reload/main.tengo", source(1))
+	rewrite := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join("prefabs", "scripts", "reload", "main.tengo"), []byte(src), 0o644); err != nil {
+			t.Fatalf("rewrite script: %v", err)
+		}
+	}
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	var errs []error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { errs = append(errs, err) })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"reload/main.tengo"}})
+	ping := func() {
+		EmitEntitySignal(w, ent, 0, "ping")
+		r.Update(w)
+	}
+	count := func() int64 {
+		value, _ := tengo.ToInt64(r.runtimes[ent].state.Value["count"])
+		return value
+	}
+
+	r.Update(w)
+	ping()
+
+	rewrite(source(10))
+	if reloaded := r.ReloadScripts(w, []string{"reload/main.tengo"}); reloaded != 1 {
+		t.Fatalf("expected one runtime reloaded, got %d", reloaded)
+	}
+	ping()
+	if got := count(); got != 11 {
+		t.Fatalf("expected the reloaded callback to add to the kept state, got %d", got)
+	}
+
+	rewrite("on_ping := func(state) {\n")
+	if reloaded := r.ReloadScripts(w, []string{"reload/main.tengo"}); reloaded != 0 {
+		t.Fatalf("expected a broken script not to reload, got %d", reloaded)
+	}
+	ping()
+	if got := count(); got != 21 {
+		t.Fatalf("expected the old bytecode to keep running, got %d", got)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "keeping the old script") {
+		t.Fatalf("expected one reload error, got %v", errs)
+	}
+}
+
+func TestRuntimeReloadScriptsKeepsTheOldScriptForAnonymousCallbacks(t *testing.T) {
+	source := func(step int) string {
+		return fmt.Sprintf(`signals := import("signals")
+
+on_start := func(state) {
+    state["count"] = 0
+    signals.on("ping", func(state) {
+        state["count"] += %d
+    }, "*")
+}
+`, step)
+	}
+	writeTestScript(t, "reload/anonymous.tengo", source(1))
+
+	w := ecs.NewWorld()
+	r := NewRuntime()
+	var errs []error
+	r.SetErrorHandler(func(_ ecs.Entity, err error) { errs = append(errs, err) })
+
+	ent := ecs.CreateEntity(w)
+	_ = ecs.Add(w, ent, component.ScriptComponent.Kind(), &component.Script{Paths: []string{"reload/anonymous.tengo"}})
+	r.Update(w)
+
+	if err := os.WriteFile(filepath.Join("prefabs", "scripts", "reload", "anonymous.tengo"), []byte(source(10)), 0o644); err != nil {
+		t.Fatalf("rewrite script: %v", err)
+	}
+	if reloaded := r.ReloadScripts(w, []string{"reload/anonymous.tengo"}); reloaded != 0 {
+		t.Fatalf("expected a runtime with an anonymous subscription not to reload, got %d", reloaded)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "signal ping") {
+		t.Fatalf("expected one reload error naming the ping subscription, got %v", errs)
+	}
+
+	EmitEntitySignal(w, ent, 0, "ping")
+	r.Update(w)
+	if got, _ := tengo.ToInt64(r.runtimes[ent].state.Value["count"]); got != 1 {
+		t.Fatalf("expected the old callback to keep running, got %d", got)
+	}
+}
@@ -0,0 +1,193 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/milk9111/sidescroller/ecs"
+)
+
+const (
+	DefaultMaxAllocs = 1_000_000
+	DefaultMaxSteps  = 1_000_000
+)
+
+// Sandbox limits what scripts can import and how much one run may do.
+type Sandbox struct {
+	// Stdlib is the Tengo stdlib allowlist. A script component's own
+	// stdlib list can only narrow it.
+	Stdlib []string
+	// MaxAllocs caps the objects a single run may allocate.
+	MaxAllocs int64
+	// MaxSteps caps the loop iterations and function calls a single run may
+	// make, which stops loops that allocate nothing, like `for {}`. Unlike a
+	// wall time limit it stops a script on the same frame in every replay.
+	MaxSteps int64
+}
+
+// DefaultSandbox allows every stdlib module except os.
+func DefaultSandbox() Sandbox {
+	names := make([]string, 0, len(stdlib.AllModuleNames()))
+	for _, name := range stdlib.AllModuleNames() {
+		if name != "os" {
+			names = append(names, name)
+		}
+	}
+	return Sandbox{Stdlib: names, MaxAllocs: DefaultMaxAllocs, MaxSteps: DefaultMaxSteps}
+}
+
+// SetSandbox replaces the runtime's sandbox. Scripts compiled under the old
+// one are recompiled on their next run.
+func (r *Runtime) SetSandbox(sandbox Sandbox) {
+	if r == nil {
+		return
+	}
+	r.sandbox = sandbox
+	r.compileCache = nil
+	r.runtimes = map[ecs.Entity]*entityRuntime{}
+}
+
+// stdlibAllowlist resolves a script's own stdlib list against the sandbox's,
+// falling back to the sandbox's when the script lists none. A script can't
+// allow what the sandbox doesn't.
+func (r *Runtime) stdlibAllowlist(configured []string) map[string]bool {
+	allowed := r.sandboxStdlib()
+	if len(configured) == 0 {
+		return allowed
+	}
+	narrowed := make(map[string]bool, len(configured))
+	for _, name := range configured {
+		if name = strings.TrimSpace(name); allowed[name] {
+			narrowed[name] = true
+		}
+	}
+	return narrowed
+}
+
+func (r *Runtime) sandboxStdlib() map[string]bool {
+	allowed := make(map[string]bool, len(r.sandbox.Stdlib))
+	for _, name := range r.sandbox.Stdlib {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+func isStdlibModule(name string) bool {
+	_, ok := stdlib.BuiltinModules[name]
+	if !ok {
+		_, ok = stdlib.SourceModules[name]
+	}
+	return ok
+}
+
+// errBudgetExceeded marks a run stopped by the sandbox; the script is halted
+// so it doesn't stall every following frame.
+var errBudgetExceeded = errors.New("script budget exceeded")
+
+// runBudgeted runs a compiled script within the sandbox's allocation and
+// step caps.
+func runBudgeted(compiled *tengo.Compiled) error {
+	if steps, ok := compiled.Get(stepCounterName).Object().(*stepCounter); ok {
+		steps.steps = 0
+	}
+	return checkBudget(compiled.Run())
+}
+
+// runWithTimeout is runBudgeted that also gives up after timeout, for
+// one-off runs like console input. RunContext watches the run from another
+// goroutine, too much to pay on every script run of every frame.
+func runWithTimeout(compiled *tengo.Compiled, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := compiled.RunContext(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: ran longer than %s", errBudgetExceeded, timeout)
+	}
+	return checkBudget(err)
+}
+
+func checkBudget(err error) error {
+	if errors.Is(err, tengo.ErrObjectAllocLimit) || errors.Is(err, errStepLimit) {
+		return fmt.Errorf("%w: %v", errBudgetExceeded, err)
+	}
+	return err
+}
+
+// stepCounterName is the global countSteps calls into.
+const stepCounterName = "__step"
+
+var errStepLimit = errors.New("step limit exceeded")
+
+// stepCounter is called at the top of every loop body and function, and
+// fails the run past max calls. Each clone of a compiled script copies it,
+// so entities count their own steps.
+type stepCounter struct {
+	tengo.ObjectImpl
+	steps, max int64
+}
+
+func (c *stepCounter) TypeName() string { return "step-counter" }
+
+func (c *stepCounter) String() string { return "<step-counter>" }
+
+func (c *stepCounter) Copy() tengo.Object { return &stepCounter{max: c.max} }
+
+func (c *stepCounter) CanCall() bool { return true }
+
+func (c *stepCounter) Call(...tengo.Object) (tengo.Object, error) {
+	c.steps++
+	if c.max > 0 && c.steps > c.max {
+		return nil, fmt.Errorf("%w: more than %d loop iterations and calls", errStepLimit, c.max)
+	}
+	return nil, nil
+}
+
+// countSteps puts a call to the step counter at the top of every loop body
+// and function body in src. Each call goes right after the opening brace,
+// so line numbers still match. Source that doesn't parse is returned as is
+// for the compiler to report.
+func countSteps(src string) string {
+	file := parser.NewFileSet().AddFile("", -1, len(src))
+	parsed, err := parser.NewParser(file, []byte(src), nil).ParseFile()
+	if err != nil {
+		return src
+	}
+
+	var offsets []int
+	for _, stmt := range parsed.Stmts {
+		walkNode(stmt, func(node parser.Node) {
+			var body *parser.BlockStmt
+			switch node := node.(type) {
+			case *parser.ForStmt:
+				body = node.Body
+			case *parser.ForInStmt:
+				body = node.Body
+			case *parser.FuncLit:
+				body = node.Body
+			}
+			if body != nil {
+				offsets = append(offsets, file.Offset(body.LBrace)+1)
+			}
+		})
+	}
+	sort.Ints(offsets)
+
+	var counted strings.Builder
+	last := 0
+	for _, offset := range offsets {
+		counted.WriteString(src[last:offset])
+		counted.WriteString(stepCounterName + "();")
+		last = offset
+	}
+	counted.WriteString(src[last:])
+	return counted.String()
+}
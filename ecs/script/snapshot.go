@@ -0,0 +1,404 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// The runtime is an ecs.SnapshotSection: world snapshots taken with it keep
+// each script's state, subscriptions and timers, so an AI or boss picks up in
+// the state and phase it was in. Callbacks are saved by their top-level name,
+// like a hot reload rebinds them, and top-level variables aren't saved at
+// all; scripts keep what changes in state.
+var _ ecs.SnapshotSection = (*Runtime)(nil)
+
+type runtimeSnapshot struct {
+	Entity ecs.Entity `json:"entity"`
+	// Scripts is the script set the runtime ran, as joined in scriptPath.
+	Scripts       string                            `json:"scripts"`
+	Halted        bool                              `json:"halted,omitempty"`
+	State         map[string]scriptValue            `json:"state"`
+	Subscriptions map[string][]subscriptionSnapshot `json:"subscriptions,omitempty"`
+	Timers        []timerSnapshot                   `json:"timers,omitempty"`
+	NextTimerID   int64                             `json:"next_timer_id,omitempty"`
+}
+
+type subscriptionSnapshot struct {
+	Source   string `json:"source,omitempty"`
+	Callback string `json:"callback"`
+}
+
+type timerSnapshot struct {
+	ID        int64   `json:"id"`
+	Remaining float64 `json:"remaining"`
+	Interval  float64 `json:"interval,omitempty"`
+	Callback  string  `json:"callback"`
+}
+
+// scriptValue is a Tengo value tagged with its type, so ints stay ints.
+type scriptValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (r *Runtime) SnapshotName() string {
+	return "scripts"
+}
+
+// EncodeSnapshot saves the runtime of every live entity. A callback or state
+// value that can't be saved, like a function literal, fails the snapshot.
+func (r *Runtime) EncodeSnapshot(w *ecs.World) (json.RawMessage, error) {
+	entities := make([]ecs.Entity, 0, len(r.runtimes))
+	for ent, rt := range r.runtimes {
+		if rt != nil && ecs.IsAlive(w, ent) {
+			entities = append(entities, ent)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i] < entities[j] })
+
+	snapshots := make([]runtimeSnapshot, 0, len(entities))
+	for _, ent := range entities {
+		snap, err := r.runtimes[ent].snapshot(ent)
+		if err != nil {
+			return nil, fmt.Errorf("entity %v: %w", ent, err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return json.Marshal(snapshots)
+}
+
+func (rt *entityRuntime) snapshot(ent ecs.Entity) (runtimeSnapshot, error) {
+	names := topLevelFunctions(rt.compiled)
+	callbackName := func(callback tengo.Object) (string, error) {
+		name, ok := names[callback]
+		if !ok {
+			return "", fmt.Errorf("callback %s is not bound to a top-level name", callback.TypeName())
+		}
+		return name, nil
+	}
+
+	snap := runtimeSnapshot{
+		Entity:      ent,
+		Scripts:     rt.scriptPath,
+		Halted:      rt.halted,
+		State:       make(map[string]scriptValue, len(rt.state.Value)),
+		NextTimerID: rt.nextTimerID,
+	}
+	for key, value := range rt.state.Value {
+		encoded, err := encodeScriptValue(value, names)
+		if err != nil {
+			return snap, fmt.Errorf("state[%q]: %w", key, err)
+		}
+		snap.State[key] = encoded
+	}
+	for signal, subs := range rt.subscriptions {
+		if snap.Subscriptions == nil {
+			snap.Subscriptions = map[string][]subscriptionSnapshot{}
+		}
+		for _, sub := range subs {
+			name, err := callbackName(sub.callback)
+			if err != nil {
+				return snap, fmt.Errorf("signal %s: %w", signal, err)
+			}
+			snap.Subscriptions[signal] = append(snap.Subscriptions[signal], subscriptionSnapshot{Source: sub.sourceGameEntity, Callback: name})
+		}
+	}
+	for _, timer := range rt.timers {
+		if timer.done {
+			continue
+		}
+		name, err := callbackName(timer.callback)
+		if err != nil {
+			return snap, fmt.Errorf("timer %d: %w", timer.id, err)
+		}
+		snap.Timers = append(snap.Timers, timerSnapshot{ID: timer.id, Remaining: timer.remaining, Interval: timer.interval, Callback: name})
+	}
+	return snap, nil
+}
+
+// DecodeSnapshot rebuilds the saved runtimes. Entities destroyed since the
+// snapshot get theirs once the restore brings them back, and live ones the
+// snapshot has no runtime for start their scripts over.
+func (r *Runtime) DecodeSnapshot(w *ecs.World, data json.RawMessage) (func(), error) {
+	var snapshots []runtimeSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, err
+	}
+	r.rebuildEntityIndex(w)
+
+	restored := make(map[ecs.Entity]*entityRuntime, len(snapshots))
+	applies := make([]func(), 0, len(snapshots))
+	var revived []runtimeSnapshot
+	for _, snap := range snapshots {
+		if !ecs.IsAlive(w, snap.Entity) {
+			revived = append(revived, snap)
+			continue
+		}
+		rt, apply, err := r.restoreRuntime(w, snap)
+		if err != nil {
+			return nil, fmt.Errorf("entity %v: %w", snap.Entity, err)
+		}
+		restored[snap.Entity] = rt
+		applies = append(applies, apply)
+	}
+
+	return func() {
+		for _, apply := range applies {
+			apply()
+		}
+		// Entities brought back only have their script component now. One
+		// whose runtime can't be restored starts its scripts over.
+		if len(revived) > 0 {
+			r.rebuildEntityIndex(w)
+		}
+		for _, snap := range revived {
+			if !ecs.IsAlive(w, snap.Entity) {
+				continue
+			}
+			rt, apply, err := r.restoreRuntime(w, snap)
+			if err != nil {
+				r.reportError(snap.Entity, fmt.Errorf("restore runtime error: %w", err))
+				ecs.Remove(w, snap.Entity, component.ScriptRuntimeComponent.Kind())
+				continue
+			}
+			apply()
+			restored[snap.Entity] = rt
+		}
+		for ent := range r.runtimes {
+			if _, ok := restored[ent]; !ok {
+				delete(r.runtimes, ent)
+			}
+		}
+		for ent, rt := range restored {
+			r.runtimes[ent] = rt
+		}
+	}, nil
+}
+
+// restoreRuntime decodes snap and returns the runtime it goes into, with a
+// func that fills it in. The live runtime is reused when it runs the same
+// scripts, since its modules are bound to it; otherwise a fresh one is set up.
+func (r *Runtime) restoreRuntime(w *ecs.World, snap runtimeSnapshot) (*entityRuntime, func(), error) {
+	scriptComp, ok := ecs.Get(w, snap.Entity, component.ScriptComponent.Kind())
+	if !ok || scriptComp == nil {
+		return nil, nil, fmt.Errorf("no script component")
+	}
+	if paths := strings.Join(scriptPaths(scriptComp), ";"); paths != snap.Scripts {
+		return nil, nil, fmt.Errorf("runs %s, the snapshot has %s", paths, snap.Scripts)
+	}
+
+	rt, ok := r.runtimes[snap.Entity]
+	if !ok || rt == nil || rt.scriptPath != snap.Scripts {
+		var err error
+		if rt, err = r.newEntityRuntime(snap.Entity, scriptComp); err != nil {
+			return nil, nil, err
+		}
+		if err := rt.compiled.Set("__state", rt.state); err != nil {
+			return nil, nil, err
+		}
+		// With no phase set, a run only defines the top-level names.
+		if err := runBudgeted(rt.compiled); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	lookup := func(name string) (tengo.Object, error) {
+		if !rt.compiled.IsDefined(name) {
+			return nil, fmt.Errorf("%s is not defined", name)
+		}
+		fn := rt.compiled.Get(name).Object()
+		if !isCallableObject(fn) {
+			return nil, fmt.Errorf("%s is not a function", name)
+		}
+		return fn, nil
+	}
+
+	state := make(map[string]tengo.Object, len(snap.State))
+	for key, value := range snap.State {
+		decoded, err := decodeScriptValue(value, lookup)
+		if err != nil {
+			return nil, nil, fmt.Errorf("state[%q]: %w", key, err)
+		}
+		state[key] = decoded
+	}
+	subscriptions := make(map[string][]subscription, len(snap.Subscriptions))
+	for signal, subs := range snap.Subscriptions {
+		for _, sub := range subs {
+			callback, err := lookup(sub.Callback)
+			if err != nil {
+				return nil, nil, fmt.Errorf("signal %s: %w", signal, err)
+			}
+			subscriptions[signal] = append(subscriptions[signal], subscription{sourceGameEntity: sub.Source, callback: callback})
+		}
+	}
+	timers := make([]*scriptTimer, 0, len(snap.Timers))
+	for _, timer := range snap.Timers {
+		callback, err := lookup(timer.Callback)
+		if err != nil {
+			return nil, nil, fmt.Errorf("timer %d: %w", timer.ID, err)
+		}
+		timers = append(timers, &scriptTimer{id: timer.ID, remaining: timer.Remaining, interval: timer.Interval, callback: callback})
+	}
+
+	return rt, func() {
+		// The state map is shared with the compiled program, so it is
+		// refilled rather than replaced.
+		rt.state.Value = state
+		rt.subscriptions = subscriptions
+		rt.timers = timers
+		rt.nextTimerID = snap.NextTimerID
+		rt.halted = snap.Halted
+	}, nil
+}
+
+func encodeScriptValue(obj tengo.Object, names map[tengo.Object]string) (scriptValue, error) {
+	switch v := obj.(type) {
+	case *tengo.Undefined:
+		return scriptValue{Type: "undefined"}, nil
+	case *tengo.Int:
+		return typedScriptValue("int", v.Value)
+	case *tengo.Float:
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return scriptValue{}, fmt.Errorf("can't save %v", v.Value)
+		}
+		return typedScriptValue("float", v.Value)
+	case *tengo.String:
+		return typedScriptValue("string", v.Value)
+	case *tengo.Bool:
+		return typedScriptValue("bool", !v.IsFalsy())
+	case *tengo.Char:
+		return typedScriptValue("char", string(v.Value))
+	case *tengo.Bytes:
+		return typedScriptValue("bytes", v.Value)
+	case *tengo.Array:
+		return encodeScriptArray("array", v.Value, names)
+	case *tengo.ImmutableArray:
+		return encodeScriptArray("immutable_array", v.Value, names)
+	case *tengo.Map:
+		return encodeScriptMap("map", v.Value, names)
+	case *tengo.ImmutableMap:
+		return encodeScriptMap("immutable_map", v.Value, names)
+	case *tengo.CompiledFunction:
+		name, ok := names[v]
+		if !ok {
+			return scriptValue{}, fmt.Errorf("function is not bound to a top-level name")
+		}
+		return typedScriptValue("func", name)
+	}
+	return scriptValue{}, fmt.Errorf("can't save a %s", obj.TypeName())
+}
+
+func encodeScriptArray(kind string, values []tengo.Object, names map[tengo.Object]string) (scriptValue, error) {
+	encoded := make([]scriptValue, len(values))
+	for i, value := range values {
+		var err error
+		if encoded[i], err = encodeScriptValue(value, names); err != nil {
+			return scriptValue{}, fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return typedScriptValue(kind, encoded)
+}
+
+func encodeScriptMap(kind string, values map[string]tengo.Object, names map[tengo.Object]string) (scriptValue, error) {
+	encoded := make(map[string]scriptValue, len(values))
+	for key, value := range values {
+		var err error
+		if encoded[key], err = encodeScriptValue(value, names); err != nil {
+			return scriptValue{}, fmt.Errorf("[%q]: %w", key, err)
+		}
+	}
+	return typedScriptValue(kind, encoded)
+}
+
+func typedScriptValue(kind string, value any) (scriptValue, error) {
+	data, err := json.Marshal(value)
+	return scriptValue{Type: kind, Value: data}, err
+}
+
+func decodeScriptValue(v scriptValue, lookup func(name string) (tengo.Object, error)) (tengo.Object, error) {
+	switch v.Type {
+	case "undefined":
+		return tengo.UndefinedValue, nil
+	case "int":
+		var n int64
+		err := json.Unmarshal(v.Value, &n)
+		return &tengo.Int{Value: n}, err
+	case "float":
+		var f float64
+		err := json.Unmarshal(v.Value, &f)
+		return &tengo.Float{Value: f}, err
+	case "string":
+		var s string
+		err := json.Unmarshal(v.Value, &s)
+		return &tengo.String{Value: s}, err
+	case "bool":
+		var b bool
+		if err := json.Unmarshal(v.Value, &b); err != nil {
+			return nil, err
+		}
+		if b {
+			return tengo.TrueValue, nil
+		}
+		return tengo.FalseValue, nil
+	case "char":
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, err
+		}
+		runes := []rune(s)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("char %q is not one character", s)
+		}
+		return &tengo.Char{Value: runes[0]}, nil
+	case "bytes":
+		var b []byte
+		err := json.Unmarshal(v.Value, &b)
+		return &tengo.Bytes{Value: b}, err
+	case "array", "immutable_array":
+		var encoded []scriptValue
+		if err := json.Unmarshal(v.Value, &encoded); err != nil {
+			return nil, err
+		}
+		values := make([]tengo.Object, len(encoded))
+		for i, value := range encoded {
+			var err error
+			if values[i], err = decodeScriptValue(value, lookup); err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		if v.Type == "immutable_array" {
+			return &tengo.ImmutableArray{Value: values}, nil
+		}
+		return &tengo.Array{Value: values}, nil
+	case "map", "immutable_map":
+		var encoded map[string]scriptValue
+		if err := json.Unmarshal(v.Value, &encoded); err != nil {
+			return nil, err
+		}
+		values := make(map[string]tengo.Object, len(encoded))
+		for key, value := range encoded {
+			var err error
+			if values[key], err = decodeScriptValue(value, lookup); err != nil {
+				return nil, fmt.Errorf("[%q]: %w", key, err)
+			}
+		}
+		if v.Type == "immutable_map" {
+			return &tengo.ImmutableMap{Value: values}, nil
+		}
+		return &tengo.Map{Value: values}, nil
+	case "func":
+		var name string
+		if err := json.Unmarshal(v.Value, &name); err != nil {
+			return nil, err
+		}
+		return lookup(name)
+	}
+	return nil, fmt.Errorf("unknown value type %q", v.Type)
+}
@@ -0,0 +1,153 @@
+package script
+
+import (
+	"fmt"
+
+	"github.com/d5/tengo/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// scriptTimerModule is bound per entity like signals. Its timers live on the
+// entity runtime, so they go away when the entity dies.
+const scriptTimerModule = "timer"
+
+type scriptTimer struct {
+	id int64
+	// remaining counts down in gameplay frames, scaled by GameplayTime.
+	remaining float64
+	// interval is the period of an every timer; after timers have none.
+	interval float64
+	callback tengo.Object
+	done     bool
+}
+
+func (r *Runtime) buildTimerModule(rt *entityRuntime) map[string]tengo.Object {
+	values := map[string]tengo.Object{}
+	values["after"] = &tengo.UserFunction{Name: "after", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		return rt.addTimer("after", args, false)
+	}}
+	values["every"] = &tengo.UserFunction{Name: "every", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		return rt.addTimer("every", args, true)
+	}}
+	values["cancel"] = &tengo.UserFunction{Name: "cancel", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) < 1 {
+			return tengo.FalseValue, fmt.Errorf("cancel requires 1 argument: id")
+		}
+		id, ok := tengo.ToInt64(args[0])
+		if !ok {
+			return tengo.FalseValue, nil
+		}
+		for _, timer := range rt.timers {
+			if timer.id == id && !timer.done {
+				timer.done = true
+				return tengo.TrueValue, nil
+			}
+		}
+		return tengo.FalseValue, nil
+	}}
+	return values
+}
+
+// addTimer schedules callback to run frames gameplay frames from now, and
+// every frames after that when repeat is set. It returns the timer's id.
+func (rt *entityRuntime) addTimer(name string, args []tengo.Object, repeat bool) (tengo.Object, error) {
+	if len(args) < 2 {
+		return tengo.UndefinedValue, fmt.Errorf("%s requires 2 arguments: frames, fn", name)
+	}
+	frames, ok := tengo.ToFloat64(args[0])
+	if !ok {
+		return tengo.UndefinedValue, fmt.Errorf("%s frames must be a number, got %s", name, args[0].TypeName())
+	}
+	if repeat && frames < 1 {
+		return tengo.UndefinedValue, fmt.Errorf("every frames must be at least 1, got %v", frames)
+	}
+	if !isCallableObject(args[1]) {
+		return tengo.UndefinedValue, fmt.Errorf("%s fn must be a function, got %s", name, args[1].TypeName())
+	}
+
+	rt.nextTimerID++
+	timer := &scriptTimer{id: rt.nextTimerID, remaining: frames, callback: args[1]}
+	if repeat {
+		timer.interval = frames
+	}
+	rt.timers = append(rt.timers, timer)
+	return &tengo.Int{Value: timer.id}, nil
+}
+
+// tickTimers advances the entity's timers by scale frames and runs the ones
+// that come due, in the order they were scheduled. Timers scheduled by a
+// callback start counting on the next tick.
+func (rt *entityRuntime) tickTimers(scale float64, report func(error)) {
+	if rt == nil || rt.halted || len(rt.timers) == 0 {
+		return
+	}
+
+	scheduled := rt.timers
+	for _, timer := range scheduled {
+		if timer.done {
+			continue
+		}
+		timer.remaining -= scale
+		if timer.remaining > 0 {
+			continue
+		}
+		if timer.interval > 0 {
+			timer.remaining += timer.interval
+		} else {
+			timer.done = true
+		}
+
+		if err := rt.runTimerCallback(timer.callback); err != nil {
+			report(fmt.Errorf("timer %d error: %w", timer.id, err))
+		}
+		if rt.halted {
+			return
+		}
+	}
+
+	pending := rt.timers[:0]
+	for _, timer := range rt.timers {
+		if !timer.done {
+			pending = append(pending, timer)
+		}
+	}
+	clear(rt.timers[len(pending):])
+	rt.timers = pending
+}
+
+// runTimerCallback calls a timer callback with state through the signal
+// dispatch block.
+func (rt *entityRuntime) runTimerCallback(callback tengo.Object) error {
+	if err := rt.compiled.Set("__phase", "signal"); err != nil {
+		return err
+	}
+	if err := rt.compiled.Set("__state", rt.state); err != nil {
+		return err
+	}
+	if err := rt.compiled.Set("__signal_callback", callback); err != nil {
+		return err
+	}
+	_ = rt.compiled.Set("__signal_takes_payload", false)
+
+	err := rt.run()
+	_ = rt.compiled.Set("__signal_callback", tengo.UndefinedValue)
+	return err
+}
+
+// gameplayTimeScale reads the world's gameplay time scale, treating a
+// missing or non-positive scale as normal speed.
+func gameplayTimeScale(w *ecs.World) float64 {
+	if w == nil {
+		return 1
+	}
+	ent, ok := ecs.First(w, component.GameplayTimeComponent.Kind())
+	if !ok {
+		return 1
+	}
+	time, ok := ecs.Get(w, ent, component.GameplayTimeComponent.Kind())
+	if !ok || time == nil || time.Scale <= 0 {
+		return 1
+	}
+	return time.Scale
+}
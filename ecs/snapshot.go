@@ -0,0 +1,466 @@
+package ecs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// snapshotVersion is bumped when the layout of a world snapshot changes.
+const snapshotVersion = 1
+
+// Codec turns one component type into JSON and back for world snapshots.
+type Codec[T any] struct {
+	Encode func(value *T) (json.RawMessage, error)
+	// Decode builds the value to restore from data. current is the entity's
+	// live component when it has one, so handles the snapshot skipped, like a
+	// physics body, can carry over.
+	Decode func(data json.RawMessage, current *T) (*T, error)
+	// Restored, when set, runs after every component of the snapshot is in
+	// place, for changes outside the world, like moving a physics body, that
+	// must not happen if a later component fails to decode.
+	Restored func(data json.RawMessage, value *T)
+}
+
+// JSONCodec encodes a component with encoding/json. Fields tagged `json:"-"`
+// and unexported fields are runtime-only: they are not written, and on
+// restore they keep the live component's values.
+func JSONCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Encode: func(value *T) (json.RawMessage, error) {
+			return json.Marshal(value)
+		},
+		Decode: func(data json.RawMessage, current *T) (*T, error) {
+			decoded := new(T)
+			if err := json.Unmarshal(data, decoded); err != nil {
+				return nil, err
+			}
+			if current == nil {
+				return decoded, nil
+			}
+			return keepRuntimeFields(decoded, current), nil
+		},
+	}
+}
+
+// keepRuntimeFields returns a copy of current with every field JSON carries
+// taken from decoded.
+func keepRuntimeFields[T any](decoded, current *T) *T {
+	merged := new(T)
+	*merged = *current
+	dst := reflect.ValueOf(merged).Elem()
+	if dst.Kind() != reflect.Struct {
+		return decoded
+	}
+	src := reflect.ValueOf(decoded).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+	}
+	return merged
+}
+
+// SnapshotRegistry lists the components a world snapshot keeps, each under a
+// stable name, since component kind ids depend on initialization order.
+// Components it doesn't list are left out of snapshots and left alone by
+// restores.
+type SnapshotRegistry struct {
+	codecs  []snapshotCodec
+	byName  map[string]snapshotCodec
+	reviver Reviver
+}
+
+// Reviver rebuilds an entity destroyed since the snapshot, before Restore
+// puts its registered components back. saved decodes the snapshot's
+// encoding of one of its components, by registered name, into into, and
+// reports whether it had one. A reviver that returns false leaves the entity
+// destroyed.
+type Reviver func(w *World, e Entity, saved func(name string, into any) bool) (bool, error)
+
+type snapshotCodec interface {
+	name() string
+	encode(w *World) ([]snapshotEntry, error)
+	// decode returns a function that puts the decoded components of the
+	// kept entities in place once the entity table is restored.
+	// Components of revived entities are decoded again then, against what
+	// the reviver built.
+	decode(w *World, entries []snapshotEntry, keep map[Entity]bool) (func(), error)
+}
+
+// SnapshotSection saves state kept outside the world's components, like a
+// script runtime's, in a world snapshot under its own name.
+type SnapshotSection interface {
+	SnapshotName() string
+	EncodeSnapshot(w *World) (json.RawMessage, error)
+	// DecodeSnapshot checks data against w, before anything is restored, and
+	// returns a function that puts it in place once the world's components
+	// are.
+	DecodeSnapshot(w *World, data json.RawMessage) (func(), error)
+}
+
+func NewSnapshotRegistry() *SnapshotRegistry {
+	return &SnapshotRegistry{byName: map[string]snapshotCodec{}}
+}
+
+// ReviveWith makes Restore hand the entities destroyed since the snapshot to
+// fn, rather than bring them back with only their registered components.
+// Registries that can't save everything an entity is built with want this.
+func (r *SnapshotRegistry) ReviveWith(fn Reviver) *SnapshotRegistry {
+	r.reviver = fn
+	return r
+}
+
+// RegisterSnapshot adds kind to the registry under name. It panics on a
+// name or kind registered twice.
+func RegisterSnapshot[T any](r *SnapshotRegistry, name string, kind component.ComponentKind[T], codec Codec[T]) {
+	if !kind.Valid() || codec.Encode == nil || codec.Decode == nil {
+		panic(fmt.Sprintf("ecs: snapshot codec %q needs a valid kind, Encode and Decode", name))
+	}
+	if _, ok := r.byName[name]; ok {
+		panic(fmt.Sprintf("ecs: snapshot codec %q registered twice", name))
+	}
+	for _, existing := range r.codecs {
+		if c, ok := existing.(*kindCodec[T]); ok && c.kind.ID() == kind.ID() {
+			panic(fmt.Sprintf("ecs: snapshot codecs %q and %q share a component kind", c.label, name))
+		}
+	}
+	c := &kindCodec[T]{label: name, kind: kind, codec: codec}
+	r.codecs = append(r.codecs, c)
+	r.byName[name] = c
+}
+
+type worldSnapshot struct {
+	Version     int                        `json:"version"`
+	NextID      entityID                   `json:"next_id"`
+	Generations []generation               `json:"generations"`
+	FreeIDs     []entityID                 `json:"free_ids"`
+	Entities    []Entity                   `json:"entities"`
+	Components  map[string][]snapshotEntry `json:"components"`
+	Sections    map[string]json.RawMessage `json:"sections,omitempty"`
+}
+
+type snapshotEntry struct {
+	Entity Entity          `json:"entity"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// Snapshot encodes every entity of w, alive or recycled, the registered
+// components of the live ones and the state of sections.
+func (r *SnapshotRegistry) Snapshot(w *World, sections ...SnapshotSection) ([]byte, error) {
+	if w == nil {
+		return nil, fmt.Errorf("ecs: snapshot: nil world")
+	}
+	snap := worldSnapshot{
+		Version:     snapshotVersion,
+		NextID:      w.nextID,
+		Generations: append([]generation{}, w.generations...),
+		FreeIDs:     append([]entityID{}, w.freeIDs...),
+		Entities:    Entities(w),
+		Components:  make(map[string][]snapshotEntry, len(r.codecs)),
+	}
+	sortEntities(snap.Entities)
+	for _, c := range r.codecs {
+		entries, err := c.encode(w)
+		if err != nil {
+			return nil, fmt.Errorf("ecs: snapshot %s: %w", c.name(), err)
+		}
+		if len(entries) > 0 {
+			snap.Components[c.name()] = entries
+		}
+	}
+	for _, section := range sections {
+		data, err := section.EncodeSnapshot(w)
+		if err != nil {
+			return nil, fmt.Errorf("ecs: snapshot %s: %w", section.SnapshotName(), err)
+		}
+		if snap.Sections == nil {
+			snap.Sections = map[string]json.RawMessage{}
+		}
+		snap.Sections[section.SnapshotName()] = data
+	}
+	return json.Marshal(snap)
+}
+
+// Restore makes w's entities those of the snapshot, with the same ids, so
+// entity references held in components stay valid. Entities the snapshot
+// doesn't have are destroyed, and ones destroyed since are brought back.
+// Registered components are replaced by the snapshot's; other components
+// stay as they are on entities that were alive in both, and entities brought
+// back get only registered ones, plus what the registry's reviver builds.
+// Components the registry doesn't know are ignored, as are sections not
+// passed in and sections the snapshot doesn't have. Nothing changes if the
+// snapshot fails to decode. Reviver errors are returned once the rest of the
+// snapshot is restored, with the entities they failed on left destroyed.
+func (r *SnapshotRegistry) Restore(w *World, data []byte, sections ...SnapshotSection) error {
+	if w == nil {
+		return fmt.Errorf("ecs: restore: nil world")
+	}
+	var snap worldSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("ecs: restore: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("ecs: restore: snapshot version %d, want %d", snap.Version, snapshotVersion)
+	}
+	if err := snap.validate(); err != nil {
+		return fmt.Errorf("ecs: restore: %w", err)
+	}
+
+	keep := make(map[Entity]bool, len(snap.Entities))
+	var revived []Entity
+	// seen is the generation w had for each revived id, which a handle to a
+	// later entity may hold.
+	seen := map[Entity]generation{}
+	for _, e := range snap.Entities {
+		if !IsAlive(w, e) {
+			revived = append(revived, e)
+			if id := e.id(); int(id) < len(w.generations) {
+				seen[e] = w.generations[id]
+			}
+		}
+		keep[e] = true
+	}
+
+	applies := make([]func(), 0, len(r.codecs)+len(sections))
+	for _, c := range r.codecs {
+		apply, err := c.decode(w, snap.Components[c.name()], keep)
+		if err != nil {
+			return fmt.Errorf("ecs: restore %s: %w", c.name(), err)
+		}
+		applies = append(applies, apply)
+	}
+	for _, section := range sections {
+		data, ok := snap.Sections[section.SnapshotName()]
+		if !ok {
+			continue
+		}
+		apply, err := section.DecodeSnapshot(w, data)
+		if err != nil {
+			return fmt.Errorf("ecs: restore %s: %w", section.SnapshotName(), err)
+		}
+		applies = append(applies, apply)
+	}
+
+	for _, e := range Entities(w) {
+		if !keep[e] {
+			DestroyEntity(w, e)
+		}
+	}
+	restoreEntityTable(w, &snap, keep)
+
+	var errs []error
+	if r.reviver != nil {
+		for _, e := range revived {
+			ok, err := r.reviver(w, e, snap.saved(e))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("ecs: restore: revive entity %v: %w", e, err))
+			}
+			if !ok || err != nil {
+				DestroyEntity(w, e)
+				if w.generations[e.id()] <= seen[e] {
+					w.generations[e.id()] = seen[e] + 1
+				}
+			}
+		}
+	}
+
+	for _, apply := range applies {
+		apply()
+	}
+	return errors.Join(errs...)
+}
+
+// saved returns a lookup of e's components in the snapshot for a Reviver.
+func (snap *worldSnapshot) saved(e Entity) func(name string, into any) bool {
+	return func(name string, into any) bool {
+		for _, entry := range snap.Components[name] {
+			if entry.Entity == e {
+				return json.Unmarshal(entry.Value, into) == nil
+			}
+		}
+		return false
+	}
+}
+
+// validate checks the entity table is one a world could have had: every id
+// below NextID is either alive or free, exactly once.
+func (snap *worldSnapshot) validate() error {
+	if len(snap.Generations) != int(snap.NextID) {
+		return fmt.Errorf("%d generations for next id %d", len(snap.Generations), snap.NextID)
+	}
+	used := make([]bool, snap.NextID)
+	alive := make(map[Entity]bool, len(snap.Entities))
+	for _, e := range snap.Entities {
+		id := e.id()
+		if id >= snap.NextID {
+			return fmt.Errorf("entity %v is past next id %d", e, snap.NextID)
+		}
+		if snap.Generations[id] != e.generation() {
+			return fmt.Errorf("entity %v does not match its generation", e)
+		}
+		if used[id] {
+			return fmt.Errorf("entity %v listed twice", e)
+		}
+		used[id] = true
+		alive[e] = true
+	}
+	for _, id := range snap.FreeIDs {
+		if id >= snap.NextID {
+			return fmt.Errorf("free id %d is past next id %d", id, snap.NextID)
+		}
+		if used[id] {
+			return fmt.Errorf("free id %d is alive or listed twice", id)
+		}
+		used[id] = true
+	}
+	for id, ok := range used {
+		if !ok {
+			return fmt.Errorf("id %d is neither alive nor free", id)
+		}
+	}
+	for name, entries := range snap.Components {
+		for _, entry := range entries {
+			if !alive[entry.Entity] {
+				return fmt.Errorf("%s component for entity %v, which the snapshot doesn't have", name, entry.Entity)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreEntityTable makes w's entity table the snapshot's, with keep alive.
+// Ids w has used past the snapshot stay free at a generation no handle to
+// them has had, so stale handles stay stale.
+func restoreEntityTable(w *World, snap *worldSnapshot, keep map[Entity]bool) {
+	nextID := snap.NextID
+	if w.nextID > nextID {
+		nextID = w.nextID
+	}
+	generations := make([]generation, nextID)
+	copy(generations, snap.Generations)
+	for id := range generations {
+		if id < len(w.generations) && w.generations[id] > generations[id] {
+			generations[id] = w.generations[id]
+		}
+	}
+	for e := range keep {
+		generations[e.id()] = e.generation()
+	}
+
+	freeIDs := append(make([]entityID, 0, int(nextID)-len(keep)), snap.FreeIDs...)
+	for id := snap.NextID; id < nextID; id++ {
+		freeIDs = append(freeIDs, id)
+	}
+
+	w.nextID = nextID
+	w.generations = generations
+	w.freeIDs = freeIDs
+	for e := range keep {
+		if !w.alive.has(e.id()) {
+			w.alive.set(e.id(), &struct{}{})
+		}
+	}
+}
+
+func sortEntities(entities []Entity) {
+	sort.Slice(entities, func(i, j int) bool { return entities[i] < entities[j] })
+}
+
+type kindCodec[T any] struct {
+	label string
+	kind  component.ComponentKind[T]
+	codec Codec[T]
+}
+
+func (c *kindCodec[T]) name() string {
+	return c.label
+}
+
+func (c *kindCodec[T]) store(w *World) *sparseComponentStore[T] {
+	v, ok := w.components[c.kind.ID()]
+	if !ok {
+		return nil
+	}
+	store, _ := v.(*sparseComponentStore[T])
+	return store
+}
+
+func (c *kindCodec[T]) encode(w *World) ([]snapshotEntry, error) {
+	store := c.store(w)
+	if store == nil {
+		return nil, nil
+	}
+	entries := make([]snapshotEntry, 0, store.len())
+	for _, id := range store.ids() {
+		if !w.alive.has(id) {
+			continue
+		}
+		value, _ := store.get(id)
+		data, err := c.codec.Encode(value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, snapshotEntry{Entity: makeEntity(id, w.generations[id]), Value: data})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Entity < entries[j].Entity })
+	return entries, nil
+}
+
+func (c *kindCodec[T]) decode(w *World, all []snapshotEntry, keep map[Entity]bool) (func(), error) {
+	entries := make([]snapshotEntry, 0, len(all))
+	for _, entry := range all {
+		if keep[entry.Entity] {
+			entries = append(entries, entry)
+		}
+	}
+	values := make([]*T, len(entries))
+	// fresh marks entries decoded without a live component, which a reviver
+	// may have built by the time they are put in place.
+	fresh := make([]bool, len(entries))
+	restored := make(map[Entity]bool, len(entries))
+	for i, entry := range entries {
+		current, _ := Get(w, entry.Entity, c.kind)
+		value, err := c.codec.Decode(entry.Value, current)
+		if err != nil {
+			return nil, fmt.Errorf("entity %v: %w", entry.Entity, err)
+		}
+		values[i] = value
+		fresh[i] = current == nil
+		restored[entry.Entity] = true
+	}
+
+	return func() {
+		if store := c.store(w); store != nil {
+			// Walk a copy, since removing reorders the store.
+			for _, id := range append([]entityID(nil), store.ids()...) {
+				if !restored[makeEntity(id, w.generations[id])] {
+					store.remove(id)
+				}
+			}
+		}
+		for i, entry := range entries {
+			if fresh[i] {
+				// The data decoded once already, so this only fails if the
+				// codec can't merge into what the reviver built.
+				if current, ok := Get(w, entry.Entity, c.kind); ok && current != nil {
+					if value, err := c.codec.Decode(entry.Value, current); err == nil {
+						values[i] = value
+					}
+				}
+			}
+			_ = Add(w, entry.Entity, c.kind, values[i])
+		}
+		if c.codec.Restored != nil {
+			for i, entry := range entries {
+				c.codec.Restored(entry.Value, values[i])
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,352 @@
+package ecs
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+type snapshotBody struct {
+	X, Y   float64
+	Handle *int `json:"-"`
+	frames int
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	body := component.NewComponentKind[snapshotBody]()
+	scratch := component.NewComponentKind[string]()
+
+	r := NewSnapshotRegistry()
+	RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+	RegisterSnapshot(r, "body", body, JSONCodec[snapshotBody]())
+
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	handle := 7
+	_ = Add(w, a, body, &snapshotBody{X: 1, Y: 2, Handle: &handle, frames: 3})
+	_ = Add(w, a, scratch, stringPtr("kept"))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+	gone := CreateEntity(w)
+	DestroyEntity(w, gone)
+
+	data, err := r.Snapshot(w)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	// Move on from the snapshot: change, remove, destroy and create.
+	_ = Add(w, a, pos, intPtr(10))
+	v, _ := Get(w, a, body)
+	v.X, v.frames = 50, 9
+	Remove(w, b, pos)
+	DestroyEntity(w, b)
+	c := CreateEntity(w)
+	_ = Add(w, c, pos, intPtr(3))
+
+	if err := r.Restore(w, data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if got, ok := Get(w, a, pos); !ok || *got != 1 {
+		t.Fatalf("expected a's pos restored to 1, got %v %v", got, ok)
+	}
+	got, ok := Get(w, a, body)
+	if !ok || got.X != 1 || got.Y != 2 {
+		t.Fatalf("expected a's body restored, got %+v %v", got, ok)
+	}
+	if got.Handle != &handle || got.frames != 9 {
+		t.Fatalf("expected runtime fields kept from the live component, got %+v", got)
+	}
+	if s, ok := Get(w, a, scratch); !ok || *s != "kept" {
+		t.Fatalf("expected unregistered component left alone, got %v %v", s, ok)
+	}
+	if !IsAlive(w, b) {
+		t.Fatalf("expected b brought back under its old id")
+	}
+	if got, ok := Get(w, b, pos); !ok || *got != 2 {
+		t.Fatalf("expected b's pos restored, got %v %v", got, ok)
+	}
+	if IsAlive(w, c) {
+		t.Fatalf("expected entity created after the snapshot destroyed")
+	}
+	if len(Entities(w)) != 2 {
+		t.Fatalf("expected 2 entities, got %v", Entities(w))
+	}
+
+	// The entity table comes back too, so ids are handed out as before.
+	if next, want := CreateEntity(w), makeEntity(gone.id(), gone.generation()+1); next != want {
+		t.Fatalf("expected the next entity to reuse %v's id as %v, got %v", gone, want, next)
+	}
+}
+
+func TestSnapshotIsStable(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	r := NewSnapshotRegistry()
+	RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+
+	w := NewWorld()
+	for i := 0; i < 5; i++ {
+		e := CreateEntity(w)
+		_ = Add(w, e, pos, intPtr(i))
+	}
+	DestroyEntity(w, Entities(w)[1])
+
+	first, err := r.Snapshot(w)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	restored := NewWorld()
+	if err := r.Restore(restored, first); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	second, err := r.Snapshot(restored)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if string(first) != string(second) {
+		t.Fatalf("expected a restored world to snapshot the same\n%s\n%s", first, second)
+	}
+}
+
+func TestRestoreRejectsBadSnapshot(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	r := NewSnapshotRegistry()
+	RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+
+	w := NewWorld()
+	e := CreateEntity(w)
+	_ = Add(w, e, pos, intPtr(1))
+
+	bad := map[string]any{
+		"version":     snapshotVersion,
+		"next_id":     1,
+		"generations": []int{0},
+		"entities":    []Entity{e},
+		"components": map[string]any{
+			"pos":     []map[string]any{{"entity": e, "value": "not a number"}},
+			"unknown": []map[string]any{{"entity": e, "value": true}},
+		},
+	}
+	data, _ := json.Marshal(bad)
+	if err := r.Restore(w, data); err == nil {
+		t.Fatalf("expected an error for a component that doesn't decode")
+	}
+	if got, ok := Get(w, e, pos); !ok || *got != 1 {
+		t.Fatalf("expected a failed restore to leave the world alone, got %v %v", got, ok)
+	}
+
+	delete(bad["components"].(map[string]any), "pos")
+	data, _ = json.Marshal(bad)
+	if err := r.Restore(w, data); err != nil {
+		t.Fatalf("expected unknown components to be skipped, got %v", err)
+	}
+	if Has(w, e, pos) {
+		t.Fatalf("expected pos removed, since the snapshot has none")
+	}
+
+	bad["version"] = snapshotVersion + 1
+	data, _ = json.Marshal(bad)
+	if err := r.Restore(w, data); err == nil {
+		t.Fatalf("expected an error for another snapshot version")
+	}
+}
+
+func TestRegisterSnapshotPanicsOnDuplicates(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	other := component.NewComponentKind[int]()
+
+	for _, tc := range []struct {
+		name     string
+		register func(r *SnapshotRegistry)
+	}{
+		{"name", func(r *SnapshotRegistry) { RegisterSnapshot(r, "pos", other, JSONCodec[int]()) }},
+		{"kind", func(r *SnapshotRegistry) { RegisterSnapshot(r, "pos_again", pos, JSONCodec[int]()) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewSnapshotRegistry()
+			RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected RegisterSnapshot to panic")
+				}
+			}()
+			tc.register(r)
+		})
+	}
+}
+
+func TestRestoreRevivesDestroyedEntities(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	body := component.NewComponentKind[snapshotBody]()
+	tag := component.NewComponentKind[string]()
+	handle := 7
+	r := NewSnapshotRegistry().ReviveWith(func(w *World, e Entity, saved func(string, any) bool) (bool, error) {
+		var p int
+		if !saved("pos", &p) || p == 3 {
+			return false, nil
+		}
+		_ = Add(w, e, tag, stringPtr("rebuilt"))
+		_ = Add(w, e, body, &snapshotBody{Handle: &handle})
+		return true, nil
+	})
+	RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+	RegisterSnapshot(r, "body", body, JSONCodec[snapshotBody]())
+
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+	_ = Add(w, b, body, &snapshotBody{X: 4, Handle: &handle})
+	c := CreateEntity(w)
+	_ = Add(w, c, pos, intPtr(3))
+	data, err := r.Snapshot(w)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	_ = Add(w, a, pos, intPtr(10))
+	DestroyEntity(w, b)
+	DestroyEntity(w, c)
+	recycled := CreateEntity(w)
+	later := CreateEntity(w)
+
+	if err := r.Restore(w, data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got, ok := Get(w, a, pos); !ok || *got != 1 {
+		t.Fatalf("expected a's pos restored to 1, got %v %v", got, ok)
+	}
+	if got, ok := Get(w, b, pos); !ok || *got != 2 {
+		t.Fatalf("expected b brought back with pos 2, got %v %v", got, ok)
+	}
+	if got, ok := Get(w, b, tag); !ok || *got != "rebuilt" {
+		t.Fatalf("expected the reviver's tag on b, got %v %v", got, ok)
+	}
+	if got, ok := Get(w, b, body); !ok || got.X != 4 || got.Handle != &handle {
+		t.Fatalf("expected b's body restored over what the reviver built, got %+v %v", got, ok)
+	}
+	if IsAlive(w, c) || IsAlive(w, recycled) || IsAlive(w, later) {
+		t.Fatalf("expected c left destroyed and the later entities destroyed")
+	}
+	if got := Entities(w); len(got) != 2 {
+		t.Fatalf("expected a and b, got %v", got)
+	}
+
+	// Handles from before the restore must not come back to life.
+	seen := map[Entity]bool{c: true, recycled: true, later: true}
+	for i := 0; i < 4; i++ {
+		if e := CreateEntity(w); seen[e] {
+			t.Fatalf("expected a fresh handle, got %v again", e)
+		}
+	}
+}
+
+func TestRestoreReturnsReviverErrors(t *testing.T) {
+	pos := component.NewComponentKind[int]()
+	r := NewSnapshotRegistry().ReviveWith(func(*World, Entity, func(string, any) bool) (bool, error) {
+		return true, errors.New("no prefab")
+	})
+	RegisterSnapshot(r, "pos", pos, JSONCodec[int]())
+
+	w := NewWorld()
+	a := CreateEntity(w)
+	_ = Add(w, a, pos, intPtr(1))
+	b := CreateEntity(w)
+	_ = Add(w, b, pos, intPtr(2))
+	data, err := r.Snapshot(w)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	_ = Add(w, a, pos, intPtr(10))
+	DestroyEntity(w, b)
+
+	if err := r.Restore(w, data); err == nil || !strings.Contains(err.Error(), "no prefab") {
+		t.Fatalf("expected the reviver's error, got %v", err)
+	}
+	if got, ok := Get(w, a, pos); !ok || *got != 1 {
+		t.Fatalf("expected a restored despite the error, got %v %v", got, ok)
+	}
+	if IsAlive(w, b) {
+		t.Fatalf("expected b left destroyed")
+	}
+}
+
+type countSection struct {
+	count    *int
+	restored bool
+}
+
+func (s *countSection) SnapshotName() string { return "count" }
+
+func (s *countSection) EncodeSnapshot(*World) (json.RawMessage, error) {
+	return json.Marshal(*s.count)
+}
+
+func (s *countSection) DecodeSnapshot(_ *World, data json.RawMessage) (func(), error) {
+	var count int
+	if err := json.Unmarshal(data, &count); err != nil {
+		return nil, err
+	}
+	return func() {
+		*s.count = count
+		s.restored = true
+	}, nil
+}
+
+func TestSnapshotSections(t *testing.T) {
+	r := NewSnapshotRegistry()
+	w := NewWorld()
+	count := 3
+	section := &countSection{count: &count}
+
+	data, err := r.Snapshot(w, section)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	count = 7
+	if err := r.Restore(w, data, section); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected the section restored to 3, got %d", count)
+	}
+
+	// A snapshot taken without the section leaves it alone.
+	section.restored = false
+	data, _ = r.Snapshot(w)
+	if err := r.Restore(w, data, section); err != nil || section.restored {
+		t.Fatalf("expected a missing section skipped, got %v %v", err, section.restored)
+	}
+}
+
+func TestRestoreChecksEntityTable(t *testing.T) {
+	r := NewSnapshotRegistry()
+	e := makeEntity(1, 0)
+
+	for _, tc := range []struct {
+		name string
+		snap map[string]any
+	}{
+		{"entity_past_next_id", map[string]any{"next_id": 1, "generations": []int{0}, "entities": []Entity{e}}},
+		{"generations_short", map[string]any{"next_id": 3, "generations": []int{0, 0}, "entities": []Entity{e}, "free_ids": []int{0}}},
+		{"id_unaccounted", map[string]any{"next_id": 2, "generations": []int{0, 0}, "entities": []Entity{e}}},
+		{"free_id_alive", map[string]any{"next_id": 2, "generations": []int{0, 0}, "entities": []Entity{e}, "free_ids": []int{0, 1}}},
+		{"component_for_missing_entity", map[string]any{"next_id": 2, "generations": []int{0, 0}, "entities": []Entity{e}, "free_ids": []int{0},
+			"components": map[string]any{"pos": []map[string]any{{"entity": makeEntity(0, 0), "value": 1}}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.snap["version"] = snapshotVersion
+			data, _ := json.Marshal(tc.snap)
+			if err := r.Restore(NewWorld(), data); err == nil {
+				t.Fatalf("expected the entity table to be rejected")
+			}
+		})
+	}
+}
@@ -259,9 +259,8 @@ func (a *AimSystem) Update(w *ecs.World) {
 		cursorWorldX = startX + dirX*anchorMaxDistance
 		cursorWorldY = startY + dirY*anchorMaxDistance
 	} else {
-		sx, sy := ebiten.CursorPosition()
-		rawCursorWorldX := camX + float64(sx)/zoom
-		rawCursorWorldY := camY + float64(sy)/zoom
+		rawCursorWorldX := camX + inputComp.CursorX/zoom
+		rawCursorWorldY := camY + inputComp.CursorY/zoom
 		rawDX := rawCursorWorldX - startX
 		rawDY := rawCursorWorldY - startY
 		rawDistance := math.Hypot(rawDX, rawDY)
@@ -5,10 +5,12 @@ import (
 
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/savegame"
 )
 
 type AudioSystem struct {
-	muted bool
+	muted  bool
+	volume float64
 }
 
 const (
@@ -18,7 +20,16 @@ const (
 )
 
 func NewAudioSystem(muted bool) *AudioSystem {
-	return &AudioSystem{muted: muted}
+	return &AudioSystem{muted: muted, volume: 1}
+}
+
+// SetVolume scales every sound effect started from now on. It takes the
+// combined master and effects level from the player's settings.
+func (a *AudioSystem) SetVolume(volume float64) {
+	if a == nil {
+		return
+	}
+	a.volume = savegame.ClampUnit(volume)
 }
 
 func (a *AudioSystem) Update(w *ecs.World) {
@@ -51,7 +62,7 @@ func (a *AudioSystem) Update(w *ecs.World) {
 
 			player := audioComp.Players[i]
 			if player != nil && !player.IsPlaying() {
-				player.SetVolume(audioVolumeForEntity(w, e, audioComp.Volume[i]))
+				player.SetVolume(audioVolumeForEntity(w, e, audioComp.Volume[i]) * a.volume)
 				player.Rewind()
 				player.Play()
 			}
@@ -74,6 +85,29 @@ func (a *AudioSystem) Update(w *ecs.World) {
 	})
 }
 
+// SilenceAudio immediately pauses every sound effect and music track in the
+// world. Scenes call it before they are discarded, since ebiten players keep
+// playing until paused.
+func SilenceAudio(w *ecs.World) {
+	if w == nil {
+		return
+	}
+	ecs.ForEach(w, component.AudioComponent.Kind(), func(_ ecs.Entity, audioComp *component.Audio) {
+		for _, player := range audioComp.Players {
+			if player != nil && player.IsPlaying() {
+				player.Pause()
+			}
+		}
+	})
+	ecs.ForEach(w, component.MusicPlayerComponent.Kind(), func(_ ecs.Entity, music *component.MusicPlayer) {
+		for _, player := range music.Players {
+			if player != nil && player.IsPlaying() {
+				player.Pause()
+			}
+		}
+	})
+}
+
 func audioVolumeForEntity(w *ecs.World, ent ecs.Entity, baseVolume float64) float64 {
 	if baseVolume <= 0 {
 		return 0
@@ -24,10 +24,25 @@ type CameraSystem struct {
 	lastShakeY           float64
 	// lastLookOffset stores the previous applied look offset for smoothing.
 	lastLookOffset float64
+	// shakeScale scales requested shake intensity; 0 turns screen shake off.
+	shakeScale float64
+	shakes     ecs.EventReader[component.CameraShakeRequest]
 }
 
 func NewCameraSystem() *CameraSystem {
-	return &CameraSystem{}
+	return &CameraSystem{shakeScale: 1}
+}
+
+// SetShakeScale applies the player's screen-shake intensity setting to
+// shakes requested from now on.
+func (cs *CameraSystem) SetShakeScale(scale float64) {
+	if cs == nil {
+		return
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	cs.shakeScale = scale
 }
 
 // SetScreenSize updates the screen dimensions used for view calculations.
@@ -78,12 +93,13 @@ func (cs *CameraSystem) Update(w *ecs.World) {
 		return
 	}
 
-	if req, ok := ecs.Get(w, cs.camEntity, component.CameraShakeRequestComponent.Kind()); ok && req != nil {
+	// Overlapping shakes keep the longest and the strongest.
+	for _, req := range cs.shakes.Read(w) {
 		frames := req.Frames
 		if frames <= 0 {
 			frames = 8
 		}
-		intensity := req.Intensity
+		intensity := req.Intensity * cs.shakeScale
 		if intensity < 0 {
 			intensity = 0
 		}
@@ -94,7 +110,6 @@ func (cs *CameraSystem) Update(w *ecs.World) {
 		if intensity > cs.shakeIntensity {
 			cs.shakeIntensity = intensity
 		}
-		_ = ecs.Remove(w, cs.camEntity, component.CameraShakeRequestComponent.Kind())
 	}
 
 	if cs.targetEntity.Valid() && ecs.IsAlive(w, cs.targetEntity) {
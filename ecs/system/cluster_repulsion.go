@@ -2,9 +2,9 @@ package system
 
 import (
 	"math"
-	"math/rand"
 
 	"github.com/jakecoffman/cp"
+	"github.com/milk9111/sidescroller/common"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
 )
@@ -84,8 +84,8 @@ func (cr *ClusterRepulsionSystem) Update(w *ecs.World) {
 			dy := b1.Body.Position().Y - b2.Body.Position().Y
 			dist := math.Hypot(dx, dy)
 			if dist == 0 {
-				dx = (rand.Float64() - 0.5) * 1e-3
-				dy = (rand.Float64() - 0.5) * 1e-3
+				dx = (common.Rand().Float64() - 0.5) * 1e-3
+				dy = (common.Rand().Float64() - 0.5) * 1e-3
 				dist = math.Hypot(dx, dy)
 			}
 
@@ -7,9 +7,28 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type CombatSystem struct{}
+type CombatSystem struct {
+	attackers *ecs.Query
+	// targets are what a hitbox can damage, and aiTargets those of them an
+	// enemy can, since enemies don't hurt each other.
+	targets   *ecs.Query
+	aiTargets *ecs.Query
+	levers    *ecs.Query
+}
 
-func NewCombatSystem() *CombatSystem { return &CombatSystem{} }
+func NewCombatSystem() *CombatSystem {
+	target := func() *ecs.Query {
+		return ecs.NewQuery(component.HurtboxComponent.Kind(), component.TransformComponent.Kind(), component.HealthComponent.Kind()).
+			Optional(component.InvulnerableComponent.Kind(), component.PlayerStateMachineComponent.Kind(), component.PlayerTagComponent.Kind(), component.AITagComponent.Kind())
+	}
+	return &CombatSystem{
+		attackers: ecs.NewQuery(component.HitboxComponent.Kind(), component.TransformComponent.Kind(), component.AnimationComponent.Kind()).
+			Optional(component.PlayerTagComponent.Kind(), component.AITagComponent.Kind()),
+		targets:   target(),
+		aiTargets: target().Without(component.AITagComponent.Kind()),
+		levers:    ecs.NewQuery(component.HurtboxComponent.Kind(), component.TransformComponent.Kind(), component.LeverComponent.Kind()),
+	}
+}
 
 func ensureDeathSignal(w *ecs.World, target ecs.Entity, source ecs.Entity) {
 	if w == nil || !ecs.IsAlive(w, target) {
@@ -103,182 +122,180 @@ func (s *CombatSystem) Update(w *ecs.World) {
 	})
 
 	// For each entity that has hitboxes, check configured frames and test against all hurtboxes
-	ecs.ForEach3(
-		w,
-		component.HitboxComponent.Kind(),
-		component.TransformComponent.Kind(),
-		component.AnimationComponent.Kind(),
-		func(e ecs.Entity, hitboxes *[]component.Hitbox, transform *component.Transform, anim *component.Animation) {
-			playerAttacker := ecs.Has(w, e, component.PlayerTagComponent.Kind())
-
-			// iterate by index so we can clear/mark per-hit state on the stored hitbox
-			for i := range *hitboxes {
-				hb := &(*hitboxes)[i]
-
-				// Determine whether this hitbox is currently active for the entity's animation/frame.
-				active := true
-				if hb.Anim != "" && hb.Anim != anim.Current {
-					active = false
-				}
-				if len(hb.Frames) > 0 && !frameActive(hb.Frames, anim.Frame) {
-					active = false
-				}
+	s.attackers.Each(w, func(e ecs.Entity) {
+		hitboxes, _ := ecs.Fetch(s.attackers, e, component.HitboxComponent.Kind())
+		transform, _ := ecs.Fetch(s.attackers, e, component.TransformComponent.Kind())
+		anim, _ := ecs.Fetch(s.attackers, e, component.AnimationComponent.Kind())
+		_, playerAttacker := ecs.Fetch(s.attackers, e, component.PlayerTagComponent.Kind())
+		targets := s.targets
+		if _, aiAttacker := ecs.Fetch(s.attackers, e, component.AITagComponent.Kind()); aiAttacker {
+			targets = s.aiTargets
+		}
 
-				// If hitbox is not active, clear the runtime hit tracking so it can hit again
-				if !active {
-					if hb.HitTargets != nil && len(hb.HitTargets) > 0 {
-						hb.HitTargets = nil
-					}
-					continue
+		// iterate by index so we can clear/mark per-hit state on the stored hitbox
+		for i := range *hitboxes {
+			hb := &(*hitboxes)[i]
+
+			// Determine whether this hitbox is currently active for the entity's animation/frame.
+			active := true
+			if hb.Anim != "" && hb.Anim != anim.Current {
+				active = false
+			}
+			if len(hb.Frames) > 0 && !frameActive(hb.Frames, anim.Frame) {
+				active = false
+			}
+
+			// If hitbox is not active, clear the runtime hit tracking so it can hit again
+			if !active {
+				if hb.HitTargets != nil && len(hb.HitTargets) > 0 {
+					hb.HitTargets = nil
 				}
+				continue
+			}
 
-				// Compute hitbox world AABB using centered local offsets.
-				hx := aabbTopLeftX(w, e, transform.X, hb.OffsetX, hb.Width, false)
-				hy := aabbTopLeftY(transform.Y, hb.OffsetY, hb.Height, false)
-				hw := hb.Width
-				hh := hb.Height
+			// Compute hitbox world AABB using centered local offsets.
+			hx := aabbTopLeftX(w, e, transform.X, hb.OffsetX, hb.Width, false)
+			hy := aabbTopLeftY(transform.Y, hb.OffsetY, hb.Height, false)
+			hw := hb.Width
+			hh := hb.Height
 
-				ecs.ForEach3(w, component.HurtboxComponent.Kind(), component.TransformComponent.Kind(), component.HealthComponent.Kind(), func(et ecs.Entity, hurtboxes *[]component.Hurtbox, tTransform *component.Transform, health *component.Health) {
-					if et == e {
-						return
-					}
-					// Don't allow AI (enemies) to damage other AI — skip friendly fire between enemies.
-					if ecs.Has(w, e, component.AITagComponent.Kind()) && ecs.Has(w, et, component.AITagComponent.Kind()) {
-						return
-					}
-					for _, hurt := range *hurtboxes {
-						tx := aabbTopLeftX(w, et, tTransform.X, hurt.OffsetX, hurt.Width, false)
-						ty := aabbTopLeftY(tTransform.Y, hurt.OffsetY, hurt.Height, false)
-						tw := hurt.Width
-						th := hurt.Height
-
-						if intersectionX, intersectionY, hit := intersects(hx, hy, hw, hh, tx, ty, tw, th); hit {
-							blocked := blockedBeforeHurtbox(w, e, transform.X, transform.Y, intersectionX, intersectionY, tx, ty, tw, th)
-
-							// Skip if target is temporarily invulnerable or if blocked by an earlier static obstacle
-							if ecs.Has(w, et, component.InvulnerableComponent.Kind()) || blocked {
-								continue
-							}
+			targets.Each(w, func(et ecs.Entity) {
+				if et == e {
+					return
+				}
+				hurtboxes, _ := ecs.Fetch(targets, et, component.HurtboxComponent.Kind())
+				tTransform, _ := ecs.Fetch(targets, et, component.TransformComponent.Kind())
+				health, _ := ecs.Fetch(targets, et, component.HealthComponent.Kind())
+				for _, hurt := range *hurtboxes {
+					tx := aabbTopLeftX(w, et, tTransform.X, hurt.OffsetX, hurt.Width, false)
+					ty := aabbTopLeftY(tTransform.Y, hurt.OffsetY, hurt.Height, false)
+					tw := hurt.Width
+					th := hurt.Height
+
+					if intersectionX, intersectionY, hit := intersects(hx, hy, hw, hh, tx, ty, tw, th); hit {
+						blocked := blockedBeforeHurtbox(w, e, transform.X, transform.Y, intersectionX, intersectionY, tx, ty, tw, th)
+
+						// Skip if target is temporarily invulnerable or if blocked by an earlier static obstacle
+						if _, invulnerable := ecs.Fetch(targets, et, component.InvulnerableComponent.Kind()); invulnerable || blocked {
+							continue
+						}
 
-							// Skip if target is already in the death state (no further damage)
-							if sm, ok := ecs.Get(w, et, component.PlayerStateMachineComponent.Kind()); ok && sm.State != nil && sm.State.Name() == "death" {
-								continue
-							}
+						// Skip if target is already in the death state (no further damage)
+						if sm, ok := ecs.Fetch(targets, et, component.PlayerStateMachineComponent.Kind()); ok && sm.State != nil && sm.State.Name() == "death" {
+							continue
+						}
 
-							// Prevent this hitbox from damaging the same entity multiple times
-							if hitboxAlreadyHitTarget(hb, et) {
-								continue
-							}
+						// Prevent this hitbox from damaging the same entity multiple times
+						if hitboxAlreadyHitTarget(hb, et) {
+							continue
+						}
 
-							previousHealth := health.Current
-							health.Current -= hb.Damage
-							if health.Current < 0 {
-								health.Current = 0
-							}
+						previousHealth := health.Current
+						health.Current -= hb.Damage
+						if health.Current < 0 {
+							health.Current = 0
+						}
 
-							sourceX := hx + hw/2
-							sourceY := hy + hh/2
+						sourceX := hx + hw/2
+						sourceY := hy + hh/2
 
-							// mark entity as already hit by this hitbox during its current activation
-							markHitboxTarget(hb, et)
+						// mark entity as already hit by this hitbox during its current activation
+						markHitboxTarget(hb, et)
 
-							if previousHealth > 0 && health.Current <= 0 {
-								ensureDeathSignal(w, et, e)
-							}
+						if previousHealth > 0 && health.Current <= 0 {
+							ensureDeathSignal(w, et, e)
+						}
 
-							if previousHealth > 0 {
-								EmitEntitySignalWithPosition(w, et, e, "on_hit", intersectionX, intersectionY, true)
-								QueueGlobalHitSignalWithPosition(w, e, et, intersectionX, intersectionY, true)
-							}
+						if previousHealth > 0 {
+							EmitSignalEvent(w, et, e, component.ScriptSignalEvent{
+								Name:        "on_hit",
+								HasPosition: true,
+								PositionX:   intersectionX,
+								PositionY:   intersectionY,
+								Payload:     component.HitSignalPayload{Damage: hb.Damage, Health: health.Current},
+							})
+							QueueGlobalHitSignalWithPosition(w, e, et, intersectionX, intersectionY, true)
+						}
 
-							if ecs.Has(w, et, component.PlayerTagComponent.Kind()) {
-								req := &component.DamageKnockback{SourceX: sourceX, SourceY: sourceY, SourceEntity: uint64(e)}
-								_ = ecs.Add(w, et, component.DamageKnockbackRequestComponent.Kind(), req)
-								err := ecs.Add(w, et, component.PlayerStateInterruptComponent.Kind(), &component.PlayerStateInterrupt{State: "hit"})
-								if err != nil {
-									panic("combat: add player state interrupt: " + err.Error())
-								}
-
-								shakeFrames := 8
-								shakeIntensity := 3.0
-								if p, ok := ecs.Get(w, et, component.PlayerComponent.Kind()); ok && p != nil && p.DamageShakeIntensity > 0 {
-									shakeIntensity = p.DamageShakeIntensity
-								}
-
-								if camEntity, ok := ecs.First(w, component.CameraComponent.Kind()); ok {
-									if existing, ok := ecs.Get(w, camEntity, component.CameraShakeRequestComponent.Kind()); ok && existing != nil {
-										if existing.Frames > shakeFrames {
-											shakeFrames = existing.Frames
-										}
-										if existing.Intensity > shakeIntensity {
-											shakeIntensity = existing.Intensity
-										}
-									}
-									_ = ecs.Add(w, camEntity, component.CameraShakeRequestComponent.Kind(), &component.CameraShakeRequest{Frames: shakeFrames, Intensity: shakeIntensity})
-								}
+						if _, ok := ecs.Fetch(targets, et, component.PlayerTagComponent.Kind()); ok {
+							req := &component.DamageKnockback{SourceX: sourceX, SourceY: sourceY, SourceEntity: uint64(e)}
+							_ = ecs.Add(w, et, component.DamageKnockbackRequestComponent.Kind(), req)
+							err := ecs.Add(w, et, component.PlayerStateInterruptComponent.Kind(), &component.PlayerStateInterrupt{State: "hit"})
+							if err != nil {
+								panic("combat: add player state interrupt: " + err.Error())
 							}
 
-							if ecs.Has(w, et, component.AITagComponent.Kind()) {
-								strongKnockback := ecs.Has(w, e, component.PlayerTagComponent.Kind())
-								req := &component.DamageKnockback{SourceX: sourceX, SourceY: sourceY, Strong: strongKnockback, SourceEntity: uint64(e)}
-								_ = ecs.Add(w, et, component.DamageKnockbackRequestComponent.Kind(), req)
-								err := ecs.Add(w, et, component.AIStateInterruptComponent.Kind(), &component.AIStateInterrupt{Event: "hit"})
-								if err != nil {
-									panic("combat: add ai state interrupt: " + err.Error())
-								}
+							shakeFrames := 8
+							shakeIntensity := 3.0
+							if p, ok := ecs.Get(w, et, component.PlayerComponent.Kind()); ok && p != nil && p.DamageShakeIntensity > 0 {
+								shakeIntensity = p.DamageShakeIntensity
 							}
 
-							// If the player dealt damage to an enemy, request a short global hit-freeze.
-							// if ecs.Has(w, e, component.PlayerTagComponent.Kind()) && ecs.Has(w, et, component.AITagComponent.Kind()) {
-							// 	// Determine freeze frames from the attacker's player config if available.
-							// 	freezeFrames := 5
-							// 	if p, ok := ecs.Get(w, e, component.PlayerComponent.Kind()); ok && p != nil && p.HitFreezeFrames > 0 {
-							// 		freezeFrames = p.HitFreezeFrames
-							// 	}
-							// 	if existing, ok := ecs.Get(w, e, component.HitFreezeRequestComponent.Kind()); ok && existing != nil && existing.Frames > freezeFrames {
-							// 		freezeFrames = existing.Frames
-							// 	}
-							// 	_ = ecs.Add(w, e, component.HitFreezeRequestComponent.Kind(), &component.HitFreezeRequest{Frames: freezeFrames})
-
-							// 	// Add a transient HitEvent on the attacker so the player's
-							// 	// attack state can detect the successful hit and play
-							// 	// the local 'hit' SFX. This avoids directly manipulating
-							// 	// audio here and keeps the attack-state logic in one place.
-							// 	_ = ecs.Add(w, e, component.HitEventComponent.Kind(), &component.HitEvent{})
-							// }
+							ecs.Emit(w, component.CameraShakeRequest{Frames: shakeFrames, Intensity: shakeIntensity})
 						}
-					}
-				})
 
-				if !playerAttacker {
-					continue
-				}
+						if _, ok := ecs.Fetch(targets, et, component.AITagComponent.Kind()); ok {
+							req := &component.DamageKnockback{SourceX: sourceX, SourceY: sourceY, Strong: playerAttacker, SourceEntity: uint64(e)}
+							_ = ecs.Add(w, et, component.DamageKnockbackRequestComponent.Kind(), req)
+							err := ecs.Add(w, et, component.AIStateInterruptComponent.Kind(), &component.AIStateInterrupt{Event: "hit"})
+							if err != nil {
+								panic("combat: add ai state interrupt: " + err.Error())
+							}
+						}
 
-				ecs.ForEach3(w, component.HurtboxComponent.Kind(), component.TransformComponent.Kind(), component.LeverComponent.Kind(), func(et ecs.Entity, hurtboxes *[]component.Hurtbox, tTransform *component.Transform, lever *component.Lever) {
-					if et == e || lever == nil || lever.State != component.LeverStateOpen {
-						return
+						// If the player dealt damage to an enemy, request a short global hit-freeze.
+						// if ecs.Has(w, e, component.PlayerTagComponent.Kind()) && ecs.Has(w, et, component.AITagComponent.Kind()) {
+						// 	// Determine freeze frames from the attacker's player config if available.
+						// 	freezeFrames := 5
+						// 	if p, ok := ecs.Get(w, e, component.PlayerComponent.Kind()); ok && p != nil && p.HitFreezeFrames > 0 {
+						// 		freezeFrames = p.HitFreezeFrames
+						// 	}
+						// 	if existing, ok := ecs.Get(w, e, component.HitFreezeRequestComponent.Kind()); ok && existing != nil && existing.Frames > freezeFrames {
+						// 		freezeFrames = existing.Frames
+						// 	}
+						// 	_ = ecs.Add(w, e, component.HitFreezeRequestComponent.Kind(), &component.HitFreezeRequest{Frames: freezeFrames})
+
+						// 	// Add a transient HitEvent on the attacker so the player's
+						// 	// attack state can detect the successful hit and play
+						// 	// the local 'hit' SFX. This avoids directly manipulating
+						// 	// audio here and keeps the attack-state logic in one place.
+						// 	_ = ecs.Add(w, e, component.HitEventComponent.Kind(), &component.HitEvent{})
+						// }
 					}
+				}
+			})
 
-					for _, hurt := range *hurtboxes {
-						tx := aabbTopLeftX(w, et, tTransform.X, hurt.OffsetX, hurt.Width, false)
-						ty := aabbTopLeftY(tTransform.Y, hurt.OffsetY, hurt.Height, false)
-						tw := hurt.Width
-						th := hurt.Height
-
-						if intersectionX, intersectionY, hit := intersects(hx, hy, hw, hh, tx, ty, tw, th); hit {
-							blocked := blockedBeforeHurtbox(w, e, transform.X, transform.Y, intersectionX, intersectionY, tx, ty, tw, th)
-							if blocked || hitboxAlreadyHitTarget(hb, et) {
-								continue
-							}
+			if !playerAttacker {
+				continue
+			}
 
-							markHitboxTarget(hb, et)
-							_ = ecs.Add(w, et, component.LeverHitRequestComponent.Kind(), &component.LeverHitRequest{SourceEntity: uint64(e)})
-							EmitEntitySignalWithPosition(w, et, e, "on_hit", intersectionX, intersectionY, true)
-							QueueGlobalHitSignalWithPosition(w, e, et, intersectionX, intersectionY, true)
+			s.levers.Each(w, func(et ecs.Entity) {
+				lever, _ := ecs.Fetch(s.levers, et, component.LeverComponent.Kind())
+				if et == e || lever == nil || lever.State != component.LeverStateOpen {
+					return
+				}
+				hurtboxes, _ := ecs.Fetch(s.levers, et, component.HurtboxComponent.Kind())
+				tTransform, _ := ecs.Fetch(s.levers, et, component.TransformComponent.Kind())
+
+				for _, hurt := range *hurtboxes {
+					tx := aabbTopLeftX(w, et, tTransform.X, hurt.OffsetX, hurt.Width, false)
+					ty := aabbTopLeftY(tTransform.Y, hurt.OffsetY, hurt.Height, false)
+					tw := hurt.Width
+					th := hurt.Height
+
+					if intersectionX, intersectionY, hit := intersects(hx, hy, hw, hh, tx, ty, tw, th); hit {
+						blocked := blockedBeforeHurtbox(w, e, transform.X, transform.Y, intersectionX, intersectionY, tx, ty, tw, th)
+						if blocked || hitboxAlreadyHitTarget(hb, et) {
+							continue
 						}
+
+						markHitboxTarget(hb, et)
+						ecs.Emit(w, component.LeverHitRequest{Lever: uint64(et), SourceEntity: uint64(e)})
+						EmitEntitySignalWithPosition(w, et, e, "on_hit", intersectionX, intersectionY, true)
+						QueueGlobalHitSignalWithPosition(w, e, et, intersectionX, intersectionY, true)
 					}
-				})
-			}
-		},
-	)
+				}
+			})
+		}
+	})
 }
@@ -457,12 +457,12 @@ func TestCombatQueuesLeverHitRequestWithoutHealth(t *testing.T) {
 
 	NewCombatSystem().Update(w)
 
-	req, ok := ecs.Get(w, lever, component.LeverHitRequestComponent.Kind())
-	if !ok || req == nil {
-		t.Fatal("expected combat to enqueue a lever hit request")
+	hits := ecs.Events[component.LeverHitRequest](w)
+	if len(hits) != 1 {
+		t.Fatalf("expected combat to emit one lever hit request, got %+v", hits)
 	}
-	if req.SourceEntity != uint64(attacker) {
-		t.Fatalf("expected lever request source %d, got %+v", attacker, req)
+	if hits[0].Lever != uint64(lever) || hits[0].SourceEntity != uint64(attacker) {
+		t.Fatalf("expected lever %d hit by %d, got %+v", lever, attacker, hits[0])
 	}
 	if !hasHitTarget(w, attacker, lever) {
 		t.Fatal("expected attacker hitbox to record the lever hit")
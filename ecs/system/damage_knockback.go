@@ -14,7 +14,9 @@ const damageKnockbackMaxDeltaV = 28.0
 const strongDamageKnockbackImpulse = 8.0
 const strongDamageKnockbackMaxDeltaV = 32.0
 
-type DamageKnockbackSystem struct{}
+type DamageKnockbackSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewDamageKnockbackSystem() *DamageKnockbackSystem { return &DamageKnockbackSystem{} }
 
@@ -29,13 +31,13 @@ func (s *DamageKnockbackSystem) Update(w *ecs.World) {
 	// Only entities that are explicitly Knockbackable will be affected.
 	ecs.ForEach4(w, component.DamageKnockbackRequestComponent.Kind(), component.KnockbackableComponent.Kind(), component.TransformComponent.Kind(), component.PhysicsBodyComponent.Kind(), func(e ecs.Entity, req *component.DamageKnockback, _ *component.Knockbackable, t *component.Transform, body *component.PhysicsBody) {
 		if req == nil || t == nil || body == nil || body.Body == nil || body.Static {
-			_ = ecs.Remove(w, e, component.DamageKnockbackRequestComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.DamageKnockbackRequestComponent.Kind())
 			return
 		}
 
 		// If the target has a health component and is out of health, skip.
 		if h, hok := ecs.Get(w, e, component.HealthComponent.Kind()); hok && h != nil && h.Current == 0 {
-			_ = ecs.Remove(w, e, component.DamageKnockbackRequestComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.DamageKnockbackRequestComponent.Kind())
 			return
 		}
 
@@ -151,6 +153,7 @@ func (s *DamageKnockbackSystem) Update(w *ecs.World) {
 		}
 
 		// Remove processed request
-		_ = ecs.Remove(w, e, component.DamageKnockbackRequestComponent.Kind())
+		ecs.DeferRemove(&s.commands, e, component.DamageKnockbackRequestComponent.Kind())
 	})
+	s.commands.Apply(w)
 }
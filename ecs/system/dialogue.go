@@ -1,11 +1,13 @@
 package system
 
 import (
+	"image/color"
 	"strings"
 
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
 )
 
 type DialogueSystem struct{}
@@ -34,22 +36,38 @@ func (s *DialogueSystem) Update(w *ecs.World) {
 		}
 
 		dialogueEntity := ecs.Entity(state.DialogueEntity)
-		dialogue, lineCount, ok := activeDialogue(w, dialogueEntity)
-		if !ok {
+		dialogue, nodes, ok := activeDialogue(w, dialogueEntity)
+		if !ok || state.NodeIndex < 0 || state.NodeIndex >= len(nodes) {
 			closeDialogue(state, ui)
 			return
 		}
 
+		node := nodes[state.NodeIndex]
+		if move := dialogueInputMove(w); move != 0 && len(state.Choices) > 0 {
+			state.ChoiceIndex = (state.ChoiceIndex + move + len(state.Choices)) % len(state.Choices)
+		}
+
 		if pressed {
-			nextLine := state.LineIndex + 1
-			if nextLine >= lineCount {
-				closeDialogue(state, ui)
-				return
+			if state.LineIndex+1 < len(node.Lines) {
+				state.LineIndex++
+				refreshDialogueChoices(w, state, node)
+			} else {
+				var next string
+				if len(state.Choices) > 0 {
+					choice := node.Choices[state.Choices[state.ChoiceIndex]]
+					runDialogueActions(w, dialogueEntity, choice.Actions)
+					next = choice.Next
+				} else {
+					next = routeDialogueNode(w, node)
+				}
+				if !enterDialogueNode(w, state, dialogueEntity, nodes, dialogueNodeIndex(nodes, next)) {
+					closeDialogue(state, ui)
+					return
+				}
 			}
-			state.LineIndex = nextLine
 		}
 
-		showDialogueLine(ui, dialogue, state.LineIndex)
+		showDialogueNode(ui, dialogue, nodes[state.NodeIndex], state)
 		_ = ecs.Add(w, stateEnt, component.DialogueStateComponent.Kind(), state)
 		return
 	}
@@ -65,15 +83,23 @@ func (s *DialogueSystem) Update(w *ecs.World) {
 	}
 
 	dialogueEntity := ecs.Entity(popup.TargetDialogueEntity)
-	dialogue, lineCount, ok := activeDialogue(w, dialogueEntity)
-	if !ok || lineCount == 0 {
+	dialogue, nodes, ok := activeDialogue(w, dialogueEntity)
+	if !ok {
+		return
+	}
+
+	start := 0
+	if dialogue.Start != "" {
+		start = dialogueNodeIndex(nodes, dialogue.Start)
+	}
+	if !enterDialogueNode(w, state, dialogueEntity, nodes, start) {
+		closeDialogue(state, ui)
 		return
 	}
 
 	state.Active = true
 	state.DialogueEntity = uint64(dialogueEntity)
-	state.LineIndex = 0
-	showDialogueLine(ui, dialogue, state.LineIndex)
+	showDialogueNode(ui, dialogue, nodes[state.NodeIndex], state)
 	if popupSprite != nil {
 		popupSprite.Disabled = true
 	}
@@ -143,24 +169,262 @@ func dialogueInputPressed(w *ecs.World) (bool, bool) {
 	return input.Pressed, input.UsingGamepad
 }
 
-func activeDialogue(w *ecs.World, dialogueEntity ecs.Entity) (*component.Dialogue, int, bool) {
+func dialogueInputMove(w *ecs.World) int {
+	if w == nil {
+		return 0
+	}
+
+	ent, ok := ecs.First(w, component.DialogueInputComponent.Kind())
+	if !ok {
+		return 0
+	}
+
+	input, ok := ecs.Get(w, ent, component.DialogueInputComponent.Kind())
+	if !ok || input == nil {
+		return 0
+	}
+
+	return input.Move
+}
+
+// activeDialogue returns the speaker's dialogue and its nodes. A dialogue
+// with only Lines is treated as a single node.
+func activeDialogue(w *ecs.World, dialogueEntity ecs.Entity) (*component.Dialogue, []component.DialogueNode, bool) {
 	if w == nil || !dialogueEntity.Valid() || !ecs.IsAlive(w, dialogueEntity) {
-		return nil, 0, false
+		return nil, nil, false
 	}
 
 	dialogue, ok := ecs.Get(w, dialogueEntity, component.DialogueComponent.Kind())
-	if !ok || dialogue == nil || len(dialogue.Lines) == 0 {
-		return nil, 0, false
+	if !ok || dialogue == nil {
+		return nil, nil, false
+	}
+
+	if len(dialogue.Nodes) > 0 {
+		return dialogue, dialogue.Nodes, true
+	}
+	if len(dialogue.Lines) == 0 {
+		return nil, nil, false
+	}
+
+	return dialogue, []component.DialogueNode{{Lines: dialogue.Lines}}, true
+}
+
+// enterDialogueNode moves the conversation to nodes[index], running its
+// actions. Nodes without lines or choices route straight on. It reports false
+// when the conversation should end.
+func enterDialogueNode(w *ecs.World, state *component.DialogueState, speaker ecs.Entity, nodes []component.DialogueNode, index int) bool {
+	// A chain of line-less nodes longer than the graph can only be a loop.
+	for steps := 0; steps <= len(nodes); steps++ {
+		if index < 0 || index >= len(nodes) {
+			return false
+		}
+
+		node := nodes[index]
+		runDialogueActions(w, speaker, node.Actions)
+
+		state.NodeIndex = index
+		state.LineIndex = 0
+		refreshDialogueChoices(w, state, node)
+		if len(node.Lines) > 0 || len(state.Choices) > 0 {
+			return true
+		}
+
+		index = dialogueNodeIndex(nodes, routeDialogueNode(w, node))
+	}
+
+	return false
+}
+
+// dialogueNodeIndex returns -1 for an empty id or "end" as well as for ids
+// that are not in nodes.
+func dialogueNodeIndex(nodes []component.DialogueNode, id string) int {
+	if id == "" || id == component.DialogueEnd {
+		return -1
+	}
+	for index := range nodes {
+		if nodes[index].ID == id {
+			return index
+		}
+	}
+	return -1
+}
+
+// routeDialogueNode picks where a node without a chosen choice goes next.
+func routeDialogueNode(w *ecs.World, node component.DialogueNode) string {
+	for _, branch := range node.Branches {
+		if dialogueConditionsPass(w, branch.Conditions) {
+			return branch.Next
+		}
+	}
+	return node.Next
+}
+
+// refreshDialogueChoices lists the node's visible choices once its last line
+// is showing.
+func refreshDialogueChoices(w *ecs.World, state *component.DialogueState, node component.DialogueNode) {
+	state.Choices = state.Choices[:0]
+	state.ChoiceIndex = 0
+	if state.LineIndex+1 < len(node.Lines) {
+		return
+	}
+	for index, choice := range node.Choices {
+		if dialogueConditionsPass(w, choice.Conditions) {
+			state.Choices = append(state.Choices, index)
+		}
+	}
+}
+
+func dialogueConditionsPass(w *ecs.World, conditions []component.DialogueCondition) bool {
+	for _, condition := range conditions {
+		if !dialogueConditionPasses(w, condition) {
+			return false
+		}
+	}
+	return true
+}
+
+func dialogueConditionPasses(w *ecs.World, condition component.DialogueCondition) bool {
+	if condition.Item != "" && inventoryItemCount(w, condition.Item) < max(1, condition.ItemCount) {
+		return false
+	}
+	if condition.NoItem != "" && inventoryItemCount(w, condition.NoItem) > 0 {
+		return false
+	}
+	if condition.Ability != "" && !playerHasAbility(w, condition.Ability) {
+		return false
+	}
+	if condition.NoAbility != "" && playerHasAbility(w, condition.NoAbility) {
+		return false
+	}
+	if condition.Entity != "" {
+		state, ok := recordedLevelEntityState(w, condition.Entity)
+		if !ok || (condition.EntityState != "" && state != condition.EntityState) {
+			return false
+		}
+	}
+	if condition.Flag != "" && !flagConditionPasses(w, condition.Flag, condition.FlagValue, condition.FlagAtLeast) {
+		return false
+	}
+	if condition.NoFlag != "" && playerFlag(w, condition.NoFlag) {
+		return false
+	}
+	return true
+}
+
+func inventoryItemCount(w *ecs.World, prefab string) int {
+	inventory := currentPlayerInventory(w)
+	if inventory == nil {
+		return 0
+	}
+	count := 0
+	for _, item := range inventory.Items {
+		if strings.TrimSpace(item.Prefab) == prefab {
+			count += item.Count
+		}
+	}
+	return count
+}
+
+func playerHasAbility(w *ecs.World, name string) bool {
+	ent, ok := ecs.First(w, component.AbilitiesComponent.Kind())
+	if !ok {
+		return false
+	}
+	abilities, ok := ecs.Get(w, ent, component.AbilitiesComponent.Kind())
+	if !ok || abilities == nil {
+		return false
+	}
+	switch name {
+	case "double_jump":
+		return abilities.DoubleJump
+	case "wall_grab":
+		return abilities.WallGrab
+	case "anchor":
+		return abilities.Anchor
+	case "heal":
+		return abilities.Heal
+	}
+	return false
+}
+
+// recordedLevelEntityState looks up a persisted entity state by game entity
+// id in the current level, or by a full "level#id" key.
+func recordedLevelEntityState(w *ecs.World, entity string) (component.PersistedLevelEntityState, bool) {
+	key := entity
+	if !strings.Contains(key, "#") {
+		key = levelEntityStateKey(currentLevelName(w), entity)
+	}
+	if key == "" {
+		return "", false
+	}
+
+	player, ok := ecs.First(w, component.PlayerTagComponent.Kind())
+	if !ok {
+		return "", false
+	}
+	stateMap, ok := ecs.Get(w, player, component.LevelEntityStateMapComponent.Kind())
+	if !ok || stateMap == nil {
+		return "", false
+	}
+
+	state, ok := stateMap.States[key]
+	return state, ok
+}
+
+func runDialogueActions(w *ecs.World, speaker ecs.Entity, actions []component.DialogueAction) {
+	for _, action := range actions {
+		if action.SetFlag != "" {
+			value := component.BoolFlag(true)
+			if action.FlagValue != nil {
+				value = *action.FlagValue
+			}
+			entity.SetPlayerFlag(w, action.SetFlag, value)
+		}
+		if action.ClearFlag != "" {
+			entity.ClearPlayerFlag(w, action.ClearFlag)
+		}
+		if action.OpenShop {
+			shopEntity := speaker
+			if action.Target != "" {
+				shopEntity, _ = entityByGameEntityID(w, action.Target)
+			}
+			openShop(w, shopEntity)
+		}
+		if action.Signal == "" {
+			continue
+		}
+
+		switch action.Target {
+		case "":
+			EmitEntitySignal(w, speaker, speaker, action.Signal)
+		case "*":
+			BroadcastSignalWithPosition(w, speaker, action.Signal, 0, 0, false)
+		default:
+			if target, ok := entityByGameEntityID(w, action.Target); ok {
+				EmitEntitySignal(w, target, speaker, action.Signal)
+			}
+		}
 	}
+}
 
-	return dialogue, len(dialogue.Lines), true
+func entityByGameEntityID(w *ecs.World, id string) (ecs.Entity, bool) {
+	found := ecs.Entity(0)
+	ecs.ForEach(w, component.GameEntityIDComponent.Kind(), func(e ecs.Entity, gameID *component.GameEntityID) {
+		if !found.Valid() && gameID != nil && gameID.Value == id {
+			found = e
+		}
+	})
+	return found, found.Valid()
 }
 
 func closeDialogue(state *component.DialogueState, ui *component.DialogueUI) {
 	if state != nil {
 		state.Active = false
 		state.DialogueEntity = 0
+		state.NodeIndex = 0
 		state.LineIndex = 0
+		state.Choices = nil
+		state.ChoiceIndex = 0
 	}
 	hideDialogueUI(ui)
 }
@@ -172,6 +436,16 @@ func hideDialogueUI(ui *component.DialogueUI) {
 	if ui.Text != nil {
 		ui.Text.Label = ""
 	}
+	if ui.Speaker != nil {
+		ui.Speaker.Label = ""
+		setWidgetVisible(ui.Speaker, false)
+	}
+	for _, choice := range ui.Choices {
+		if choice != nil {
+			choice.Label = ""
+			setWidgetVisible(choice, false)
+		}
+	}
 	if ui.PortraitBox != nil {
 		setWidgetVisible(ui.PortraitBox, false)
 	}
@@ -182,26 +456,61 @@ func hideDialogueUI(ui *component.DialogueUI) {
 	requestDialogueUIRelayout(ui)
 }
 
-func showDialogueLine(ui *component.DialogueUI, dialogue *component.Dialogue, lineIndex int) {
-	if ui == nil {
+var (
+	dialogueChoiceColor         = color.NRGBA{R: 168, G: 176, B: 196, A: 255}
+	dialogueSelectedChoiceColor = color.NRGBA{R: 255, G: 214, B: 120, A: 255}
+)
+
+// showDialogueNode draws the current line of node, with its speaker and
+// portrait falling back to the dialogue's, and the visible choices.
+func showDialogueNode(ui *component.DialogueUI, dialogue *component.Dialogue, node component.DialogueNode, state *component.DialogueState) {
+	if ui == nil || dialogue == nil || state == nil {
 		return
 	}
-	if dialogue == nil || len(dialogue.Lines) == 0 {
-		return
+
+	line := ""
+	if len(node.Lines) > 0 {
+		lineIndex := min(max(state.LineIndex, 0), len(node.Lines)-1)
+		line = strings.TrimSpace(node.Lines[lineIndex])
 	}
-	if lineIndex < 0 {
-		lineIndex = 0
+	speaker := node.Speaker
+	if speaker == "" {
+		speaker = dialogue.Speaker
 	}
-	if lineIndex >= len(dialogue.Lines) {
-		lineIndex = len(dialogue.Lines) - 1
+	portrait := node.Portrait
+	if portrait == nil {
+		portrait = dialogue.Portrait
 	}
 
 	if ui.Text != nil {
-		ui.Text.Label = strings.TrimSpace(dialogue.Lines[lineIndex])
+		ui.Text.Label = line
+	}
+	if ui.Speaker != nil {
+		ui.Speaker.Label = speaker
+		setWidgetVisible(ui.Speaker, speaker != "")
+	}
+	for index, choiceText := range ui.Choices {
+		if choiceText == nil {
+			continue
+		}
+		if index >= len(state.Choices) {
+			choiceText.Label = ""
+			setWidgetVisible(choiceText, false)
+			continue
+		}
+		label := node.Choices[state.Choices[index]].Text
+		if index == state.ChoiceIndex {
+			choiceText.Label = "> " + label
+			choiceText.SetColor(dialogueSelectedChoiceColor)
+		} else {
+			choiceText.Label = "  " + label
+			choiceText.SetColor(dialogueChoiceColor)
+		}
+		setWidgetVisible(choiceText, true)
 	}
 	if ui.Portrait != nil {
-		if dialogue.Portrait != nil {
-			ui.Portrait.Image = dialogue.Portrait
+		if portrait != nil {
+			ui.Portrait.Image = portrait
 			if ui.PortraitBox != nil {
 				setWidgetVisible(ui.PortraitBox, true)
 			}
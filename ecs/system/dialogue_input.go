@@ -1,16 +1,36 @@
 package system
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/controls"
+	"github.com/milk9111/sidescroller/internal/replay"
 )
 
-type DialogueInputSystem struct{}
+// dialogueChoiceAxisThreshold is how far the stick must be pushed to step
+// through dialogue choices.
+const dialogueChoiceAxisThreshold = 0.5
+
+type DialogueInputSystem struct {
+	feed         *replay.Feed
+	bindings     *controls.Map
+	axisUpHeld   bool
+	axisDownHeld bool
+}
 
 func NewDialogueInputSystem() *DialogueInputSystem {
-	return &DialogueInputSystem{}
+	return &DialogueInputSystem{bindings: controls.DefaultMap()}
+}
+
+func (s *DialogueInputSystem) SetBindings(bindings *controls.Map) {
+	if bindings == nil {
+		bindings = controls.DefaultMap()
+	}
+	s.bindings = bindings
+}
+
+func (s *DialogueInputSystem) SetFeed(feed *replay.Feed) {
+	s.feed = feed
 }
 
 func (s *DialogueInputSystem) Update(w *ecs.World) {
@@ -18,13 +38,20 @@ func (s *DialogueInputSystem) Update(w *ecs.World) {
 		return
 	}
 
-	pressed := inpututil.IsKeyJustPressed(ebiten.KeyZ)
-	usingGamepad := false
-
-	if gamepads := ebiten.GamepadIDs(); len(gamepads) > 0 {
-		usingGamepad = true
-		id := gamepads[0]
-		pressed = pressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightLeft)
+	var pressed, usingGamepad bool
+	var move int
+	if s.feed != nil && s.feed.Replaying {
+		pressed = s.feed.Frame.Dialogue.Pressed
+		usingGamepad = s.feed.Frame.Dialogue.UsingGamepad
+		move = s.feed.Frame.Dialogue.Move
+	} else {
+		pad := controls.FirstGamepad()
+		usingGamepad = pad.Connected
+		pressed = s.bindings.JustPressed(controls.ActionInteract, pad)
+		move = s.choiceMove(pad)
+		if s.feed != nil {
+			s.feed.Frame.Dialogue = component.DialogueInput{Pressed: pressed, UsingGamepad: usingGamepad, Move: move}
+		}
 	}
 
 	ecs.ForEach(w, component.DialogueInputComponent.Kind(), func(e ecs.Entity, input *component.DialogueInput) {
@@ -33,5 +60,30 @@ func (s *DialogueInputSystem) Update(w *ecs.World) {
 		}
 		input.Pressed = pressed
 		input.UsingGamepad = usingGamepad
+		input.Move = move
 	})
 }
+
+// choiceMove returns -1 or 1 on the frame up or down is pressed, counting a
+// stick push only when it crosses the threshold.
+func (s *DialogueInputSystem) choiceMove(pad controls.Gamepad) int {
+	move := 0
+	if s.bindings.JustPressed(controls.ActionMoveUp, pad) {
+		move = -1
+	}
+	if s.bindings.JustPressed(controls.ActionMoveDown, pad) {
+		move = 1
+	}
+
+	up := s.bindings.Axis(controls.ActionMoveUp, pad) > dialogueChoiceAxisThreshold
+	down := s.bindings.Axis(controls.ActionMoveDown, pad) > dialogueChoiceAxisThreshold
+	if up && !s.axisUpHeld {
+		move = -1
+	}
+	if down && !s.axisDownHeld {
+		move = 1
+	}
+	s.axisUpHeld = up
+	s.axisDownHeld = down
+	return move
+}
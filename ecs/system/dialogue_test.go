@@ -155,6 +155,152 @@ func TestDialogueSystemShowsPortraitWhenPresent(t *testing.T) {
 	}
 }
 
+func TestDialogueSystemPicksChoiceAndRunsActions(t *testing.T) {
+	w := ecs.NewWorld()
+
+	player := ecs.CreateEntity(w)
+	if err := ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{}); err != nil {
+		t.Fatalf("add player tag: %v", err)
+	}
+
+	speaker := addTestDialogueSpeaker(t, w, &component.Dialogue{
+		Speaker: "Shopkeep",
+		Nodes: []component.DialogueNode{
+			{
+				ID:    "menu",
+				Lines: []string{"What can I do for you?"},
+				Choices: []component.DialogueChoice{
+					{Text: "Hidden", Next: "secret", Conditions: []component.DialogueCondition{{Flag: "knows_secret"}}},
+					{Text: "Ask", Next: "answer", Actions: []component.DialogueAction{{SetFlag: "asked"}}},
+					{Text: "Leave"},
+				},
+			},
+			{ID: "secret", Lines: []string{"secret"}},
+			{ID: "answer", Lines: []string{"answer"}, Actions: []component.DialogueAction{{Signal: "answered"}}},
+		},
+	})
+
+	_, dialogueUI, dialogueState, dialogueInput := addTestDialogueUI(t, w)
+
+	system := NewDialogueSystem()
+	system.Update(w)
+
+	if dialogueUI.Speaker.Label != "Shopkeep" {
+		t.Fatalf("expected speaker name, got %q", dialogueUI.Speaker.Label)
+	}
+	if len(dialogueState.Choices) != 2 {
+		t.Fatalf("expected the flagged choice to be hidden, got choices %v", dialogueState.Choices)
+	}
+	if dialogueUI.Choices[0].Label != "> Ask" || dialogueUI.Choices[1].Label != "  Leave" {
+		t.Fatalf("expected Ask selected above Leave, got %q and %q", dialogueUI.Choices[0].Label, dialogueUI.Choices[1].Label)
+	}
+
+	dialogueInput.Pressed = false
+	dialogueInput.Move = 1
+	system.Update(w)
+	if dialogueState.ChoiceIndex != 1 {
+		t.Fatalf("expected moving down to select Leave, got %d", dialogueState.ChoiceIndex)
+	}
+
+	dialogueInput.Move = -1
+	system.Update(w)
+	dialogueInput.Move = 0
+	dialogueInput.Pressed = true
+	system.Update(w)
+
+	if !dialogueState.Active || dialogueUI.Text.Label != "answer" {
+		t.Fatalf("expected the answer node, got active=%v text=%q", dialogueState.Active, dialogueUI.Text.Label)
+	}
+	if !playerFlag(w, "asked") {
+		t.Fatal("expected the choice to set its flag")
+	}
+	queue, ok := ecs.Get(w, speaker, component.ScriptSignalQueueComponent.Kind())
+	if !ok || queue == nil || len(queue.Events) != 1 || queue.Events[0].Name != "answered" {
+		t.Fatalf("expected the answer node to signal the speaker, got %#v", queue)
+	}
+
+	system.Update(w)
+	if dialogueState.Active {
+		t.Fatal("expected dialogue to close after a node without next")
+	}
+}
+
+func TestDialogueSystemFollowsFirstPassingBranch(t *testing.T) {
+	w := ecs.NewWorld()
+
+	stateMap, player := addTestPlayerStateMap(t, w)
+	addTestLevelRuntime(t, w, "market")
+	stateMap.States["market#lever_1"] = component.PersistedLevelEntityStateUsed
+	if err := ecs.Add(w, player, component.InventoryComponent.Kind(), &component.Inventory{Items: []component.InventoryItem{{Prefab: "item_gear.yaml", Count: 2}}}); err != nil {
+		t.Fatalf("add inventory: %v", err)
+	}
+
+	addTestDialogueSpeaker(t, w, &component.Dialogue{
+		Start: "greeting",
+		Nodes: []component.DialogueNode{
+			{
+				ID: "greeting",
+				Branches: []component.DialogueBranch{
+					{Next: "rich", Conditions: []component.DialogueCondition{{Item: "item_gear.yaml", ItemCount: 3}}},
+					{Next: "lever", Conditions: []component.DialogueCondition{{Entity: "lever_1", EntityState: component.PersistedLevelEntityStateUsed}, {NoAbility: "anchor"}}},
+				},
+				Next: "fallback",
+			},
+			{ID: "rich", Lines: []string{"rich"}},
+			{ID: "lever", Lines: []string{"lever"}},
+			{ID: "fallback", Lines: []string{"fallback"}},
+		},
+	})
+
+	_, dialogueUI, dialogueState, _ := addTestDialogueUI(t, w)
+
+	NewDialogueSystem().Update(w)
+
+	if !dialogueState.Active || dialogueUI.Text.Label != "lever" {
+		t.Fatalf("expected the lever branch, got active=%v text=%q", dialogueState.Active, dialogueUI.Text.Label)
+	}
+}
+
+func TestDialogueSystemClosesOnRoutingLoop(t *testing.T) {
+	w := ecs.NewWorld()
+
+	player := ecs.CreateEntity(w)
+	if err := ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{}); err != nil {
+		t.Fatalf("add player tag: %v", err)
+	}
+
+	addTestDialogueSpeaker(t, w, &component.Dialogue{
+		Nodes: []component.DialogueNode{
+			{ID: "a", Next: "b"},
+			{ID: "b", Next: "a"},
+		},
+	})
+
+	_, _, dialogueState, _ := addTestDialogueUI(t, w)
+
+	NewDialogueSystem().Update(w)
+
+	if dialogueState.Active {
+		t.Fatal("expected a loop of empty nodes to leave the dialogue closed")
+	}
+}
+
+func addTestDialogueSpeaker(t *testing.T, w *ecs.World, dialogue *component.Dialogue) ecs.Entity {
+	t.Helper()
+
+	speaker := ecs.CreateEntity(w)
+	if err := ecs.Add(w, speaker, component.DialogueComponent.Kind(), dialogue); err != nil {
+		t.Fatalf("add dialogue: %v", err)
+	}
+
+	popup := ecs.CreateEntity(w)
+	if err := ecs.Add(w, popup, component.DialoguePopupComponent.Kind(), &component.DialoguePopup{TargetDialogueEntity: uint64(speaker)}); err != nil {
+		t.Fatalf("add popup: %v", err)
+	}
+
+	return speaker
+}
+
 func addTestDialogueUI(t *testing.T, w *ecs.World) (ecs.Entity, *component.DialogueUI, *component.DialogueState, *component.DialogueInput) {
 	t.Helper()
 
@@ -171,11 +317,23 @@ func addTestDialogueUI(t *testing.T, w *ecs.World) (ecs.Entity, *component.Dialo
 	text := widget.NewText(
 		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 255, G: 255, B: 255, A: 255}),
 	)
+	speakerText := widget.NewText(
+		widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 255, G: 255, B: 255, A: 255}),
+	)
 	overlay.AddChild(portrait)
+	overlay.AddChild(speakerText)
 	overlay.AddChild(text)
+	choices := make([]*widget.Text, 0, 2)
+	for range 2 {
+		choice := widget.NewText(
+			widget.TextOpts.Text("", &bodyFace, color.NRGBA{R: 255, G: 255, B: 255, A: 255}),
+		)
+		overlay.AddChild(choice)
+		choices = append(choices, choice)
+	}
 
 	ent := ecs.CreateEntity(w)
-	ui := &component.DialogueUI{Root: overlay, HUDLayer: overlay, OverlayLayer: overlay, Overlay: overlay, Portrait: portrait, Text: text}
+	ui := &component.DialogueUI{Root: overlay, HUDLayer: overlay, OverlayLayer: overlay, Overlay: overlay, Portrait: portrait, Speaker: speakerText, Text: text, Choices: choices}
 	state := &component.DialogueState{}
 	dialogueInput := &component.DialogueInput{Pressed: true}
 	if err := ecs.Add(w, ent, component.DialogueUIComponent.Kind(), ui); err != nil {
@@ -0,0 +1,28 @@
+package system
+
+import (
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
+)
+
+func playerFlag(w *ecs.World, name string) bool {
+	value, ok := entity.PlayerFlag(w, name)
+	return ok && value.Truthy()
+}
+
+// flagConditionPasses checks a named flag against an optional expected value
+// and an optional int minimum. With neither, the flag only has to be truthy.
+func flagConditionPasses(w *ecs.World, name string, value *component.FlagValue, atLeast *int) bool {
+	current, ok := entity.PlayerFlag(w, name)
+	if value == nil && atLeast == nil {
+		return ok && current.Truthy()
+	}
+	if value != nil && !current.Equal(*value) {
+		return false
+	}
+	if atLeast != nil && (current.Kind != component.FlagKindInt || current.Int < *atLeast) {
+		return false
+	}
+	return true
+}
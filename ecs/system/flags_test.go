@@ -0,0 +1,40 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
+)
+
+func TestDialogueFlagConditionsCompareTypedValues(t *testing.T) {
+	w := ecs.NewWorld()
+	player := ecs.CreateEntity(w)
+	_ = ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{})
+	entity.SetPlayerFlag(w, "quest_stage", component.IntFlag(2))
+	entity.SetPlayerFlag(w, "rival_name", component.StringFlag("Rust"))
+
+	stage := component.IntFlag(2)
+	rust := component.StringFlag("Rust")
+	two, three := 2, 3
+	cases := []struct {
+		name      string
+		condition component.DialogueCondition
+		want      bool
+	}{
+		{"truthy int", component.DialogueCondition{Flag: "quest_stage"}, true},
+		{"equal int", component.DialogueCondition{Flag: "quest_stage", FlagValue: &stage}, true},
+		{"equal string", component.DialogueCondition{Flag: "rival_name", FlagValue: &rust}, true},
+		{"wrong kind", component.DialogueCondition{Flag: "rival_name", FlagValue: &stage}, false},
+		{"at least met", component.DialogueCondition{Flag: "quest_stage", FlagAtLeast: &two}, true},
+		{"at least missed", component.DialogueCondition{Flag: "quest_stage", FlagAtLeast: &three}, false},
+		{"missing", component.DialogueCondition{Flag: "met_merchant"}, false},
+		{"no flag", component.DialogueCondition{NoFlag: "met_merchant"}, true},
+	}
+	for _, tc := range cases {
+		if got := dialogueConditionPasses(w, tc.condition); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
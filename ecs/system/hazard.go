@@ -11,9 +11,19 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type HazardSystem struct{}
+type HazardSystem struct {
+	hazards *ecs.Query
+	enemies *ecs.Query
+}
 
-func NewHazardSystem() *HazardSystem { return &HazardSystem{} }
+func NewHazardSystem() *HazardSystem {
+	return &HazardSystem{
+		hazards: ecs.NewQuery(component.HazardComponent.Kind(), component.TransformComponent.Kind()).
+			Optional(component.AITagComponent.Kind()),
+		enemies: ecs.NewQuery(component.AITagComponent.Kind(), component.TransformComponent.Kind(), component.PhysicsBodyComponent.Kind()).
+			Optional(component.HealthComponent.Kind()),
+	}
+}
 
 type hazardAABB struct {
 	x float64
@@ -27,6 +37,7 @@ type hazardHitSource struct {
 	centerX float64
 	centerY float64
 	entity  ecs.Entity
+	fromAI  bool
 }
 
 func overlapsAABB(a, b hazardAABB) bool {
@@ -158,7 +169,9 @@ func (s *HazardSystem) Update(w *ecs.World) {
 
 	hazards := make([]hazardHitSource, 0, 16)
 	seenHazards := make(map[ecs.Entity]struct{}, 16)
-	ecs.ForEach2(w, component.HazardComponent.Kind(), component.TransformComponent.Kind(), func(e ecs.Entity, h *component.Hazard, t *component.Transform) {
+	s.hazards.Each(w, func(e ecs.Entity) {
+		h, _ := ecs.Fetch(s.hazards, e, component.HazardComponent.Kind())
+		t, _ := ecs.Fetch(s.hazards, e, component.TransformComponent.Kind())
 		if h == nil || t == nil || h.Disabled {
 			return
 		}
@@ -168,7 +181,8 @@ func (s *HazardSystem) Update(w *ecs.World) {
 		seenHazards[e] = struct{}{}
 
 		if b, ok := hazardBounds(w, e, h, t); ok {
-			hazards = append(hazards, hazardHitSource{bounds: b, centerX: b.x + b.w/2, centerY: b.y + b.h/2, entity: e})
+			_, fromAI := ecs.Fetch(s.hazards, e, component.AITagComponent.Kind())
+			hazards = append(hazards, hazardHitSource{bounds: b, centerX: b.x + b.w/2, centerY: b.y + b.h/2, entity: e, fromAI: fromAI})
 		}
 	})
 
@@ -229,7 +243,9 @@ func (s *HazardSystem) Update(w *ecs.World) {
 	}
 
 	enemyHit := make(map[ecs.Entity]struct{}, 8)
-	ecs.ForEach3(w, component.AITagComponent.Kind(), component.TransformComponent.Kind(), component.PhysicsBodyComponent.Kind(), func(e ecs.Entity, _ *component.AITag, t *component.Transform, body *component.PhysicsBody) {
+	s.enemies.Each(w, func(e ecs.Entity) {
+		t, _ := ecs.Fetch(s.enemies, e, component.TransformComponent.Kind())
+		body, _ := ecs.Fetch(s.enemies, e, component.PhysicsBodyComponent.Kind())
 		if t == nil || body == nil {
 			return
 		}
@@ -237,7 +253,7 @@ func (s *HazardSystem) Update(w *ecs.World) {
 			return
 		}
 		// Skip enemies that are already dead so repeated overlap doesn't re-trigger hit state.
-		if h, ok := ecs.Get(w, e, component.HealthComponent.Kind()); ok && h != nil && h.Current <= 0 {
+		if h, ok := ecs.Fetch(s.enemies, e, component.HealthComponent.Kind()); ok && h != nil && h.Current <= 0 {
 			return
 		}
 		box, ok := physicsBodyAABB(w, e, t, body)
@@ -249,9 +265,8 @@ func (s *HazardSystem) Update(w *ecs.World) {
 				// don't let an enemy's own hazard kill itself
 				continue
 			}
-			// If both the hazard source and the target are AI, skip applying
-			// damage so enemies do not kill each other.
-			if hz.entity != 0 && ecs.Has(w, hz.entity, component.AITagComponent.Kind()) && ecs.Has(w, e, component.AITagComponent.Kind()) {
+			// Enemies don't kill each other with their own hazards.
+			if hz.fromAI {
 				continue
 			}
 			if overlapsAABB(box, hz.bounds) {
@@ -11,6 +11,8 @@ const defaultHealthDeathPostAnimationFrames = 60
 type HealthDeathFadeSystem struct {
 	FadeFrames          int
 	PostAnimationFrames int
+
+	commands ecs.CommandBuffer
 }
 
 func NewHealthDeathFadeSystem() *HealthDeathFadeSystem {
@@ -63,7 +65,7 @@ func (s *HealthDeathFadeSystem) Update(w *ecs.World) {
 				return
 			}
 			if !startHealthDeathFade(w, e, state) {
-				ecs.DestroyEntity(w, e)
+				s.commands.Destroy(e)
 				return
 			}
 		}
@@ -72,8 +74,9 @@ func (s *HealthDeathFadeSystem) Update(w *ecs.World) {
 			return
 		}
 
-		ecs.DestroyEntity(w, e)
+		s.commands.Destroy(e)
 	})
+	s.commands.Apply(w)
 }
 
 func shouldWaitForDeathAnimation(w *ecs.World, e ecs.Entity) (bool, bool) {
@@ -6,6 +6,7 @@ import (
 )
 
 type HitFreezeSystem struct {
+	commands ecs.CommandBuffer
 	onFreeze func(frames int)
 }
 
@@ -23,8 +24,9 @@ func (s *HitFreezeSystem) Update(w *ecs.World) {
 		if req != nil && req.Frames > maxFrames {
 			maxFrames = req.Frames
 		}
-		_ = ecs.Remove(w, e, component.HitFreezeRequestComponent.Kind())
+		ecs.DeferRemove(&s.commands, e, component.HitFreezeRequestComponent.Kind())
 	})
+	s.commands.Apply(w)
 
 	if maxFrames > 0 && s.onFreeze != nil {
 		s.onFreeze(maxFrames)
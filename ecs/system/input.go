@@ -5,8 +5,11 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/milk9111/sidescroller/common"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/controls"
+	"github.com/milk9111/sidescroller/internal/replay"
 )
 
 const autoAnchorDoubleClickWindowFrames = 20
@@ -15,10 +18,27 @@ const gamepadUpwardAttackThreshold = -0.6
 type InputSystem struct {
 	frame                    int
 	lastRightMousePressFrame int
+	feed                     *replay.Feed
+	bindings                 *controls.Map
 }
 
 func NewInputSystem() *InputSystem {
-	return &InputSystem{lastRightMousePressFrame: -1}
+	return &InputSystem{lastRightMousePressFrame: -1, bindings: controls.DefaultMap()}
+}
+
+// SetBindings swaps the action map used when polling devices. A nil map
+// restores the default bindings.
+func (i *InputSystem) SetBindings(bindings *controls.Map) {
+	if bindings == nil {
+		bindings = controls.DefaultMap()
+	}
+	i.bindings = bindings
+}
+
+// SetFeed routes polled input through feed for recording, or replays the
+// frames stored in it instead of polling ebiten.
+func (i *InputSystem) SetFeed(feed *replay.Feed) {
+	i.feed = feed
 }
 
 func registerDoublePress(frame, lastPressFrame, window int) (bool, int) {
@@ -48,36 +68,170 @@ func (i *InputSystem) Update(w *ecs.World) {
 
 	i.frame++
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+	var polled component.Input
+	var resetPressed bool
+	if i.feed != nil && i.feed.Replaying {
+		polled = i.feed.Frame.Input
+		resetPressed = i.feed.Frame.Reset
+	} else {
+		polled, resetPressed = i.poll(w)
+		if i.feed != nil {
+			i.feed.Frame.Input = polled
+			i.feed.Frame.Reset = resetPressed
+		}
+	}
+
+	if resetPressed {
 		if _, ok := ecs.First(w, component.ResetToInitialLevelRequestComponent.Kind()); !ok {
 			ent := ecs.CreateEntity(w)
 			_ = ecs.Add(w, ent, component.ResetToInitialLevelRequestComponent.Kind(), &component.ResetToInitialLevelRequest{})
 		}
 	}
 
-	const stickDeadzone = 0.2
+	ecs.ForEach(w, component.InputComponent.Kind(), func(e ecs.Entity, input *component.Input) {
+		if input.Disabled {
+			input.MoveX = 0
+			input.MoveY = 0
+			input.Jump = false
+			input.JumpPressed = false
+			input.Aim = false
+			input.AimX = 0
+			input.AimY = 0
+			input.LookY = 0
+			input.AnchorPressed = false
+			input.AutoAnchorPressed = false
+			input.AnchorReelIn = false
+			input.AnchorReelOut = false
+			input.AttackPressed = false
+			input.UpwardAttackPressed = false
+			input.HealPressed = false
+			input.AnchorReleasePressed = false
+			input.MenuPressed = false
+			input.UsingGamepad = false
+			input.CursorX = polled.CursorX
+			input.CursorY = polled.CursorY
+			return
+		}
 
-	left := ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyArrowLeft)
-	right := ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyArrowRight)
-	up := ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyArrowUp)
-	down := ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyArrowDown)
-	jump := ebiten.IsKeyPressed(ebiten.KeySpace)
-	keyboardUpPressed := up
-	// Keyboard look only supports looking down so W/Up can remain dedicated to upward attacks.
-	lookDown := down
+		input.MoveX = polled.MoveX
+		input.MoveY = polled.MoveY
+		input.Jump = polled.Jump
+		input.JumpPressed = polled.JumpPressed
+		input.Aim = polled.Aim
+		input.AimX = polled.AimX
+		input.AimY = polled.AimY
+		input.LookY = polled.LookY
+		input.AnchorPressed = polled.AnchorPressed
+		input.AutoAnchorPressed = polled.AutoAnchorPressed
+		input.AnchorReelIn = polled.AnchorReelIn
+		input.AnchorReelOut = polled.AnchorReelOut
+		input.AttackPressed = polled.AttackPressed
+		input.UpwardAttackPressed = polled.UpwardAttackPressed
+		input.HealPressed = polled.HealPressed
+		input.AnchorReleasePressed = polled.AnchorReleasePressed
+		input.MenuPressed = polled.MenuPressed
+		input.UsingGamepad = polled.UsingGamepad
+		input.CursorX = polled.CursorX
+		input.CursorY = polled.CursorY
+	})
+}
+
+// reelInputs are one device's reel and attack inputs.
+type reelInputs struct {
+	reelIn, reelOut, attack bool
+}
+
+// resolveReel combines the keyboard's and gamepad's reel and attack inputs.
+// The keyboard reels whenever its keys are held. The gamepad's reel buttons
+// double as attack, so they only reel while an anchor is out, reel-in
+// first, and attack the rest of the time.
+func resolveReel(desktop, gamepad reelInputs, anchorExists bool) (reelIn, reelOut, attack bool) {
+	reelIn, reelOut, attack = desktop.reelIn, desktop.reelOut, desktop.attack
+	switch {
+	case anchorExists && gamepad.reelIn:
+		reelIn = true
+	case anchorExists && gamepad.reelOut:
+		reelOut = true
+	default:
+		attack = attack || gamepad.attack
+	}
+	return reelIn, reelOut, attack
+}
+
+// resolveAnchor turns a press of the contextual anchor action into what it
+// does: release the anchor that is out, fire one along the aim while aiming,
+// or auto-anchor otherwise.
+func resolveAnchor(pressed, anchorExists, aim bool) (fire, auto, release bool) {
+	switch {
+	case !pressed:
+		return false, false, false
+	case anchorExists:
+		return false, false, true
+	case aim:
+		return true, false, false
+	default:
+		return false, true, false
+	}
+}
+
+// digitalLook is the look direction of held look keys or buttons, -1 up to
+// 1 down. The sticks override it.
+func digitalLook(up, down bool) float64 {
 	lookY := 0.0
-	if lookDown {
+	if up {
+		lookY -= 1
+	}
+	if down {
 		lookY += 1
 	}
-	jumpPressed := inpututil.IsKeyJustPressed(ebiten.KeySpace)
-	aim := ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
-	anchorPressed := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && aim
-	autoAnchorPressed := inpututil.IsKeyJustPressed(ebiten.KeyControlLeft) || inpututil.IsKeyJustPressed(ebiten.KeyControlRight)
-	anchorReelIn := ebiten.IsKeyPressed(ebiten.KeyQ)
-	anchorReelOut := ebiten.IsKeyPressed(ebiten.KeyE)
-	attackPressed := (inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || inpututil.IsKeyJustPressed(ebiten.KeyZ)) && !aim
-	healPressed := inpututil.IsKeyJustPressed(ebiten.KeyC)
-	menuPressed := inpututil.IsKeyJustPressed(ebiten.KeyEscape)
+	return lookY
+}
+
+// poll reads the keyboard, mouse and first gamepad through the action map
+// into a single Input value.
+func (i *InputSystem) poll(w *ecs.World) (component.Input, bool) {
+	resetPressed := inpututil.IsKeyJustPressed(ebiten.KeyF11)
+
+	const stickDeadzone = 0.2
+
+	bindings := i.bindings
+	pad := controls.FirstGamepad()
+	usingGamepad := pad.Connected
+
+	_, anchorExists := ecs.First(w, component.AnchorTagComponent.Kind())
+
+	left := bindings.Held(controls.ActionMoveLeft, pad)
+	right := bindings.Held(controls.ActionMoveRight, pad)
+	up := bindings.Held(controls.ActionMoveUp, pad)
+	down := bindings.Held(controls.ActionMoveDown, pad)
+	jump := bindings.Held(controls.ActionJump, pad)
+	keyboardUpPressed := up
+	// Look up has no default key, so move up stays dedicated to upward attacks.
+	lookY := digitalLook(bindings.Held(controls.ActionLookUp, pad), bindings.Held(controls.ActionLookDown, pad))
+	jumpPressed := bindings.JustPressed(controls.ActionJump, pad)
+	aim := bindings.Held(controls.ActionAim, pad)
+	anchorPressed := bindings.JustPressed(controls.ActionFireAnchor, pad) && aim
+	autoAnchorPressed := bindings.JustPressed(controls.ActionAutoAnchor, pad)
+	// The gamepad shares its reel buttons with attack and interact, so
+	// they are read apart from the keyboard's, which a disconnected
+	// Gamepad reads alone; see resolveReel.
+	desktop := controls.Gamepad{}
+	anchorReelIn, anchorReelOut, attackPressed := resolveReel(
+		reelInputs{
+			reelIn:  bindings.Held(controls.ActionReelIn, desktop),
+			reelOut: bindings.Held(controls.ActionReelOut, desktop),
+			attack:  bindings.JustPressed(controls.ActionAttack, desktop),
+		},
+		reelInputs{
+			reelIn:  bindings.GamepadHeld(controls.ActionReelIn, pad),
+			reelOut: bindings.GamepadHeld(controls.ActionReelOut, pad),
+			attack:  bindings.GamepadJustPressed(controls.ActionAttack, pad),
+		},
+		anchorExists,
+	)
+	attackPressed = attackPressed && !aim
+	healPressed := bindings.JustPressed(controls.ActionHeal, pad)
+	menuPressed := bindings.JustPressed(controls.ActionMenu, pad)
 	aimX := 0.0
 	aimY := 0.0
 
@@ -96,65 +250,28 @@ func (i *InputSystem) Update(w *ecs.World) {
 		moveY += 1
 	}
 
-	var usingGamepad bool
-	var anchorReleasePressed bool
-	if gamepads := ebiten.GamepadIDs(); len(gamepads) > 0 {
-		usingGamepad = true
-		id := gamepads[0]
-
-		anchorExists := false
-		if _, ok := ecs.First(w, component.AnchorTagComponent.Kind()); ok {
-			anchorExists = true
-		}
-
-		leftX := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
-		leftY := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
-		if math.Abs(leftX) > stickDeadzone {
-			moveX = leftX
-		}
-		if math.Abs(leftY) > stickDeadzone {
-			moveY = leftY
-		}
-
-		jump = jump || ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightBottom)
-		jumpPressed = jumpPressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightBottom)
-		if anchorExists && ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightLeft) {
-			anchorReelIn = true
-		} else if anchorExists && ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightTop) {
-			anchorReelOut = true
-		} else {
-			attackPressed = attackPressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightLeft)
-		}
+	anchorFired, anchorAuto, anchorReleasePressed := resolveAnchor(bindings.JustPressed(controls.ActionAnchor, pad), anchorExists, aim)
+	anchorPressed = anchorPressed || anchorFired
+	autoAnchorPressed = autoAnchorPressed || anchorAuto
 
-		if ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonFrontBottomLeft) {
-			aim = true
+	if usingGamepad {
+		stickX := bindings.Axis(controls.ActionMoveRight, pad) - bindings.Axis(controls.ActionMoveLeft, pad)
+		stickY := bindings.Axis(controls.ActionMoveDown, pad) - bindings.Axis(controls.ActionMoveUp, pad)
+		if math.Abs(stickX) > stickDeadzone {
+			moveX = stickX
 		}
-
-		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonFrontBottomRight) {
-			if _, ok := ecs.First(w, component.AnchorTagComponent.Kind()); ok { // if an anchor exists, this button releases it instead of firing a new one
-				anchorReleasePressed = true
-			} else if aim {
-				anchorPressed = true
-			} else {
-				autoAnchorPressed = true
-			}
+		if math.Abs(stickY) > stickDeadzone {
+			moveY = stickY
 		}
-
-		healPressed = healPressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonFrontTopRight)
-		menuPressed = menuPressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight)
-
-		lx := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
-		ly := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
-		if math.Hypot(lx, ly) > stickDeadzone {
-			aimX = lx
-			aimY = ly
+		if math.Hypot(stickX, stickY) > stickDeadzone {
+			aimX = stickX
+			aimY = stickY
 		}
 
-		// allow left stick vertical to control look if it's being used
-		ry := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickVertical)
-		if math.Abs(ry) > stickDeadzone {
+		lookStick := bindings.Axis(controls.ActionLookDown, pad) - bindings.Axis(controls.ActionLookUp, pad)
+		if math.Abs(lookStick) > stickDeadzone {
 			// Gamepad axis vertical is typically -1 = up, +1 = down
-			lookY = float64(ry)
+			lookY = lookStick
 		}
 	}
 
@@ -164,46 +281,28 @@ func (i *InputSystem) Update(w *ecs.World) {
 		attackPressed = false
 	}
 
-	ecs.ForEach(w, component.InputComponent.Kind(), func(e ecs.Entity, input *component.Input) {
-		if input.Disabled {
-			input.MoveX = 0
-			input.MoveY = 0
-			input.Jump = false
-			input.JumpPressed = false
-			input.Aim = false
-			input.AimX = 0
-			input.AimY = 0
-			input.LookY = 0
-			input.AnchorPressed = false
-			input.AutoAnchorPressed = false
-			input.AnchorReelIn = false
-			input.AnchorReelOut = false
-			input.AttackPressed = false
-			input.UpwardAttackPressed = false
-			input.HealPressed = false
-			input.AnchorReleasePressed = false
-			input.MenuPressed = false
-			input.UsingGamepad = false
-			return
-		}
+	cursorX, cursorY := common.CursorPosition()
 
-		input.MoveX = moveX
-		input.MoveY = moveY
-		input.Jump = jump
-		input.JumpPressed = jumpPressed
-		input.Aim = aim
-		input.AimX = aimX
-		input.AimY = aimY
-		input.LookY = lookY
-		input.AnchorPressed = anchorPressed
-		input.AutoAnchorPressed = autoAnchorPressed
-		input.AnchorReelIn = anchorReelIn
-		input.AnchorReelOut = anchorReelOut
-		input.AttackPressed = attackPressed
-		input.UpwardAttackPressed = upwardAttackPressed
-		input.HealPressed = healPressed
-		input.AnchorReleasePressed = anchorReleasePressed
-		input.MenuPressed = menuPressed
-		input.UsingGamepad = usingGamepad
-	})
+	return component.Input{
+		MoveX:                moveX,
+		MoveY:                moveY,
+		Jump:                 jump,
+		JumpPressed:          jumpPressed,
+		Aim:                  aim,
+		AimX:                 aimX,
+		AimY:                 aimY,
+		LookY:                lookY,
+		AnchorPressed:        anchorPressed,
+		AutoAnchorPressed:    autoAnchorPressed,
+		AnchorReelIn:         anchorReelIn,
+		AnchorReelOut:        anchorReelOut,
+		AttackPressed:        attackPressed,
+		UpwardAttackPressed:  upwardAttackPressed,
+		HealPressed:          healPressed,
+		AnchorReleasePressed: anchorReleasePressed,
+		MenuPressed:          menuPressed,
+		UsingGamepad:         usingGamepad,
+		CursorX:              float64(cursorX),
+		CursorY:              float64(cursorY),
+	}, resetPressed
 }
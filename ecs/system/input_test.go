@@ -1,6 +1,12 @@
 package system
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/replay"
+)
 
 func TestShouldTriggerUpwardAttack(t *testing.T) {
 	tests := []struct {
@@ -31,3 +37,100 @@ func TestShouldTriggerUpwardAttack(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveReelGatesTheGamepadOnAnAnchor(t *testing.T) {
+	tests := []struct {
+		name                    string
+		desktop, gamepad        reelInputs
+		anchorExists            bool
+		reelIn, reelOut, attack bool
+	}{
+		{name: "gamepad button attacks without an anchor", gamepad: reelInputs{reelIn: true, attack: true}, attack: true},
+		{name: "gamepad button reels in with an anchor", gamepad: reelInputs{reelIn: true, attack: true}, anchorExists: true, reelIn: true},
+		{name: "gamepad reel out waits on reel in", gamepad: reelInputs{reelIn: true, reelOut: true}, anchorExists: true, reelIn: true},
+		{name: "gamepad reel out without an anchor does nothing", gamepad: reelInputs{reelOut: true}},
+		{name: "keyboard reels without an anchor", desktop: reelInputs{reelIn: true}, reelIn: true},
+		{name: "keyboard reel leaves attack alone", desktop: reelInputs{reelIn: true, attack: true}, anchorExists: true, reelIn: true, attack: true},
+		{name: "keyboard attack while the gamepad reels", desktop: reelInputs{attack: true}, gamepad: reelInputs{reelIn: true, attack: true}, anchorExists: true, reelIn: true, attack: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reelIn, reelOut, attack := resolveReel(test.desktop, test.gamepad, test.anchorExists)
+			if reelIn != test.reelIn || reelOut != test.reelOut || attack != test.attack {
+				t.Fatalf("resolveReel = in %t out %t attack %t, want in %t out %t attack %t", reelIn, reelOut, attack, test.reelIn, test.reelOut, test.attack)
+			}
+		})
+	}
+}
+
+func TestResolveAnchorAndLookReadAnyDevice(t *testing.T) {
+	// The contextual anchor and look actions used to be read only with a
+	// gamepad connected; these resolve the same whatever they are bound to.
+	anchorTests := []struct {
+		name                 string
+		pressed, exists, aim bool
+		fire, auto, release  bool
+	}{
+		{name: "not pressed does nothing", exists: true, aim: true},
+		{name: "releases an anchor that is out", pressed: true, exists: true, aim: true, release: true},
+		{name: "fires along the aim", pressed: true, aim: true, fire: true},
+		{name: "auto anchors without aim", pressed: true, auto: true},
+	}
+	for _, test := range anchorTests {
+		t.Run(test.name, func(t *testing.T) {
+			fire, auto, release := resolveAnchor(test.pressed, test.exists, test.aim)
+			if fire != test.fire || auto != test.auto || release != test.release {
+				t.Fatalf("resolveAnchor = fire %t auto %t release %t, want fire %t auto %t release %t", fire, auto, release, test.fire, test.auto, test.release)
+			}
+		})
+	}
+
+	for _, test := range []struct {
+		up, down bool
+		want     float64
+	}{
+		{want: 0},
+		{up: true, want: -1},
+		{down: true, want: 1},
+		{up: true, down: true, want: 0},
+	} {
+		if got := digitalLook(test.up, test.down); got != test.want {
+			t.Fatalf("digitalLook(%t, %t) = %v, want %v", test.up, test.down, got, test.want)
+		}
+	}
+}
+
+func TestInputSystemAppliesReplayedFrame(t *testing.T) {
+	w := ecs.NewWorld()
+	active := ecs.CreateEntity(w)
+	if err := ecs.Add(w, active, component.InputComponent.Kind(), &component.Input{}); err != nil {
+		t.Fatalf("add input: %v", err)
+	}
+	disabled := ecs.CreateEntity(w)
+	if err := ecs.Add(w, disabled, component.InputComponent.Kind(), &component.Input{Disabled: true}); err != nil {
+		t.Fatalf("add disabled input: %v", err)
+	}
+
+	feed := &replay.Feed{Replaying: true}
+	feed.Frame.Input = component.Input{MoveX: 1, JumpPressed: true, Aim: true, CursorX: 320, CursorY: 180}
+	feed.Frame.Reset = true
+
+	system := NewInputSystem()
+	system.SetFeed(feed)
+	system.Update(w)
+
+	input, _ := ecs.Get(w, active, component.InputComponent.Kind())
+	if input.MoveX != 1 || !input.JumpPressed || !input.Aim || input.CursorX != 320 || input.CursorY != 180 {
+		t.Fatalf("expected replayed input to be applied, got %+v", *input)
+	}
+
+	disabledInput, _ := ecs.Get(w, disabled, component.InputComponent.Kind())
+	if disabledInput.MoveX != 0 || disabledInput.JumpPressed || disabledInput.Aim {
+		t.Fatalf("expected disabled input to stay neutral during replay, got %+v", *disabledInput)
+	}
+
+	if _, ok := ecs.First(w, component.ResetToInitialLevelRequestComponent.Kind()); !ok {
+		t.Fatal("expected replayed reset to queue a reset request")
+	}
+}
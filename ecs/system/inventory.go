@@ -314,6 +314,20 @@ func showInventory(ui *component.InventoryUI, items []inventoryViewItem, selecte
 }
 
 func buildInventoryCell(item *inventoryViewItem, selected bool) *widget.Container {
+	if item == nil {
+		return buildItemCell(nil, "", selected, false)
+	}
+
+	countLabel := ""
+	if item.Count > 1 {
+		countLabel = strings.TrimSpace("x" + itoa(item.Count))
+	}
+	return buildItemCell(item.Image, countLabel, selected, true)
+}
+
+// buildItemCell draws one grid slot with an icon and a caption under it.
+// Empty slots only draw the background.
+func buildItemCell(image *ebiten.Image, label string, selected, filled bool) *widget.Container {
 	background := color.NRGBA{R: 28, G: 35, B: 45, A: 255}
 	underlineColor := color.NRGBA{R: 236, G: 191, B: 99, A: 255}
 	countColor := color.NRGBA{R: 240, G: 232, B: 214, A: 255}
@@ -331,26 +345,22 @@ func buildInventoryCell(item *inventoryViewItem, selected bool) *widget.Containe
 	)
 	cell.GetWidget().MinWidth = inventoryCellSize
 	cell.GetWidget().MinHeight = inventoryCellSize
-	if item == nil {
+	if !filled {
 		return cell
 	}
 
 	icon := widget.NewGraphic(
-		widget.GraphicOpts.Image(imageOrPlaceholder(fitInventoryImage(item.Image, inventoryCellIconSize, inventoryCellIconSize))),
+		widget.GraphicOpts.Image(imageOrPlaceholder(fitInventoryImage(image, inventoryCellIconSize, inventoryCellIconSize))),
 		widget.GraphicOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
 	)
 	icon.GetWidget().MinWidth = inventoryCellIconSize
 	icon.GetWidget().MinHeight = inventoryCellIconSize
-	if item.Image == nil {
+	if image == nil {
 		setWidgetVisible(icon, false)
 	}
 
-	countLabel := ""
-	if item.Count > 1 {
-		countLabel = strings.TrimSpace("x" + itoa(item.Count))
-	}
 	count := widget.NewText(
-		widget.TextOpts.Text(countLabel, inventoryTextFaceRef(), countColor),
+		widget.TextOpts.Text(label, inventoryTextFaceRef(), countColor),
 		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
 		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
 	)
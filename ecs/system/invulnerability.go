@@ -7,7 +7,9 @@ import (
 
 // InvulnerabilitySystem decrements timed invulnerability frames and removes
 // the component when the timer expires.
-type InvulnerabilitySystem struct{}
+type InvulnerabilitySystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewInvulnerabilitySystem() *InvulnerabilitySystem { return &InvulnerabilitySystem{} }
 
@@ -23,10 +25,11 @@ func (s *InvulnerabilitySystem) Update(w *ecs.World) {
 		if inv.Frames > 0 {
 			inv.Frames--
 			if inv.Frames <= 0 {
-				_ = ecs.Remove(w, e, component.InvulnerableComponent.Kind())
+				ecs.DeferRemove(&s.commands, e, component.InvulnerableComponent.Kind())
 				return
 			}
 			_ = ecs.Add(w, e, component.InvulnerableComponent.Kind(), inv)
 		}
 	})
+	s.commands.Apply(w)
 }
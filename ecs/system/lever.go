@@ -5,7 +5,9 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type LeverSystem struct{}
+type LeverSystem struct {
+	hits ecs.EventReader[component.LeverHitRequest]
+}
 
 func NewLeverSystem() *LeverSystem { return &LeverSystem{} }
 
@@ -14,21 +16,24 @@ func (s *LeverSystem) Update(w *ecs.World) {
 		return
 	}
 
+	var hit map[ecs.Entity]bool
+	for _, req := range s.hits.Read(w) {
+		if hit == nil {
+			hit = map[ecs.Entity]bool{}
+		}
+		hit[ecs.Entity(req.Lever)] = true
+	}
+
 	ecs.ForEach2(w, component.LeverComponent.Kind(), component.AnimationComponent.Kind(), func(e ecs.Entity, lever *component.Lever, anim *component.Animation) {
 		if lever == nil || anim == nil {
 			return
 		}
 
-		req, hasHitRequest := ecs.Get(w, e, component.LeverHitRequestComponent.Kind())
-		if hasHitRequest && req != nil {
-			_ = ecs.Remove(w, e, component.LeverHitRequestComponent.Kind())
-		}
-
 		switch lever.State {
 		case "", component.LeverStateOpen:
 			lever.State = component.LeverStateOpen
 			setLeverAnimation(lever, anim, component.LeverStateOpen)
-			if hasHitRequest && req != nil {
+			if hit[e] {
 				lever.State = component.LeverStateClosing
 				setLeverAnimation(lever, anim, component.LeverStateClosing)
 				recordLevelEntityState(w, e, component.PersistedLevelEntityStateUsed)
@@ -39,9 +39,7 @@ func TestLeverSystemClosesAndRecordsPersistedState(t *testing.T) {
 	}, Current: "open", Playing: true}); err != nil {
 		t.Fatalf("add animation component: %v", err)
 	}
-	if err := ecs.Add(w, lever, component.LeverHitRequestComponent.Kind(), &component.LeverHitRequest{SourceEntity: 1}); err != nil {
-		t.Fatalf("add lever hit request: %v", err)
-	}
+	ecs.Emit(w, component.LeverHitRequest{Lever: uint64(lever), SourceEntity: 1})
 
 	system := NewLeverSystem()
 	system.Update(w)
@@ -54,9 +52,6 @@ func TestLeverSystemClosesAndRecordsPersistedState(t *testing.T) {
 	if anim == nil || anim.Current != "open_to_closed" || !anim.Playing {
 		t.Fatalf("expected closing animation to start, got %+v", anim)
 	}
-	if _, ok := ecs.Get(w, lever, component.LeverHitRequestComponent.Kind()); ok {
-		t.Fatal("expected lever hit request to be consumed")
-	}
 	if got := stateMap.States[levelEntityStateKey("disposal_1.json", "lever_1")]; got != component.PersistedLevelEntityStateUsed {
 		t.Fatalf("expected lever used state to be recorded, got %q", got)
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/milk9111/sidescroller/assets"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/savegame"
 )
 
 const (
@@ -16,11 +17,26 @@ const (
 )
 
 type MusicSystem struct {
-	muted bool
+	muted    bool
+	volume   float64
+	requests ecs.EventReader[component.MusicRequest]
 }
 
 func NewMusicSystem(muted bool) *MusicSystem {
-	return &MusicSystem{muted: muted}
+	return &MusicSystem{muted: muted, volume: 1}
+}
+
+// SetVolume scales every track, including the one already playing. Track
+// fades keep working in unscaled volumes and are scaled on output.
+func (m *MusicSystem) SetVolume(volume float64) {
+	if m == nil {
+		return
+	}
+	m.volume = savegame.ClampUnit(volume)
+}
+
+func (m *MusicSystem) setPlayerVolume(audioPlayer *audio.Player, volume float64) {
+	audioPlayer.SetVolume(volume * m.volume)
 }
 
 func RequestMusic(w *ecs.World, track string) {
@@ -31,8 +47,7 @@ func RequestMusicWithOptions(w *ecs.World, req *component.MusicRequest) {
 	if w == nil || req == nil {
 		return
 	}
-	ent := ecs.CreateEntity(w)
-	_ = ecs.Add(w, ent, component.MusicRequestComponent.Kind(), req)
+	ecs.Emit(w, *req)
 }
 
 func StopMusic(w *ecs.World) {
@@ -44,9 +59,10 @@ func (m *MusicSystem) Update(w *ecs.World) {
 		return
 	}
 
-	latest, requestEntities := m.consumeLatestRequest(w)
-	for _, ent := range requestEntities {
-		ecs.DestroyEntity(w, ent)
+	// Only the latest request matters; earlier ones would be cut off at once.
+	var latest *component.MusicRequest
+	if requests := m.requests.Read(w); len(requests) > 0 {
+		latest = &requests[len(requests)-1]
 	}
 
 	ent, ok := ecs.First(w, component.MusicPlayerComponent.Kind())
@@ -78,27 +94,15 @@ func (m *MusicSystem) Update(w *ecs.World) {
 	}
 
 	currentPlayer := m.currentPlayer(player)
-	if currentPlayer != nil && !currentPlayer.IsPlaying() && player.CurrentTrack != "" && player.CurrentLoop {
+	if currentPlayer == nil {
+		return
+	}
+	m.setPlayerVolume(currentPlayer, player.CurrentVolume)
+	if !currentPlayer.IsPlaying() && player.CurrentTrack != "" && player.CurrentLoop {
 		currentPlayer.Rewind()
-		currentPlayer.SetVolume(player.CurrentVolume)
 		currentPlayer.Play()
 	}
 }
-func (m *MusicSystem) consumeLatestRequest(w *ecs.World) (*component.MusicRequest, []ecs.Entity) {
-	var latest *component.MusicRequest
-	requestEntities := make([]ecs.Entity, 0)
-
-	ecs.ForEach(w, component.MusicRequestComponent.Kind(), func(ent ecs.Entity, req *component.MusicRequest) {
-		requestEntities = append(requestEntities, ent)
-		if req == nil {
-			return
-		}
-		copy := *req
-		latest = &copy
-	})
-
-	return latest, requestEntities
-}
 
 func (m *MusicSystem) applyRequest(player *component.MusicPlayer, req component.MusicRequest) {
 	if player == nil {
@@ -145,7 +149,7 @@ func (m *MusicSystem) applyRequest(player *component.MusicPlayer, req component.
 	currentPlayer := m.currentPlayer(player)
 	if !player.PendingActive && player.CurrentTrack == track && currentPlayer != nil {
 		player.CurrentVolume = volume
-		currentPlayer.SetVolume(player.CurrentVolume)
+		m.setPlayerVolume(currentPlayer, player.CurrentVolume)
 		if !currentPlayer.IsPlaying() {
 			currentPlayer.Rewind()
 			currentPlayer.Play()
@@ -181,12 +185,12 @@ func (m *MusicSystem) updateTransition(player *component.MusicPlayer) {
 
 	player.CurrentVolume -= player.FadeStep
 	if player.CurrentVolume > 0 {
-		currentPlayer.SetVolume(player.CurrentVolume)
+		m.setPlayerVolume(currentPlayer, player.CurrentVolume)
 		return
 	}
 
 	player.CurrentVolume = 0
-	currentPlayer.SetVolume(0)
+	m.setPlayerVolume(currentPlayer, 0)
 	currentPlayer.Pause()
 	currentPlayer.Rewind()
 	player.CurrentTrack = ""
@@ -229,7 +233,7 @@ func (m *MusicSystem) switchToPending(player *component.MusicPlayer) {
 	player.CurrentVolume = reqVolume
 	player.CurrentLoop = reqLoop
 	audioPlayer.Rewind()
-	audioPlayer.SetVolume(player.CurrentVolume)
+	m.setPlayerVolume(audioPlayer, player.CurrentVolume)
 	audioPlayer.Play()
 }
 
@@ -25,17 +25,10 @@ func TestMusicSystemMutedConsumesRequestWithoutPlaying(t *testing.T) {
 		t.Fatalf("add music player: %v", err)
 	}
 
-	requestEntity := ecs.CreateEntity(w)
-	if err := ecs.Add(w, requestEntity, component.MusicRequestComponent.Kind(), &component.MusicRequest{
-		Track:         "boss_music.wav",
-		Volume:        0.6,
-		Loop:          true,
-		FadeOutFrames: 15,
-	}); err != nil {
-		t.Fatalf("add music request: %v", err)
-	}
+	ecs.Emit(w, component.MusicRequest{Track: "boss_music.wav", Volume: 0.6, Loop: true, FadeOutFrames: 15})
 
-	NewMusicSystem(true).Update(w)
+	music := NewMusicSystem(true)
+	music.Update(w)
 
 	player, ok := ecs.Get(w, musicEntity, component.MusicPlayerComponent.Kind())
 	if !ok || player == nil {
@@ -47,7 +40,10 @@ func TestMusicSystemMutedConsumesRequestWithoutPlaying(t *testing.T) {
 	if trackPlayer.IsPlaying() {
 		t.Fatal("expected muted music system to avoid playback")
 	}
-	if _, ok := ecs.Get(w, requestEntity, component.MusicRequestComponent.Kind()); ok {
-		t.Fatal("expected request entity to be consumed")
+
+	player.CurrentTrack = ""
+	music.Update(w)
+	if player.CurrentTrack != "" {
+		t.Fatalf("expected the request to be read once, got %q", player.CurrentTrack)
 	}
 }
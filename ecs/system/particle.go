@@ -2,10 +2,10 @@ package system
 
 import (
 	"image/color"
-	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/milk9111/sidescroller/common"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
 )
@@ -31,8 +31,8 @@ func (s *ParticleSystem) Update(w *ecs.World) {
 
 			particle.X = t.X
 			particle.Y = t.Y
-			particle.VelX = rand.Float64()*2 - 1    // Random horizontal velocity between -1 and 1
-			particle.VelY = -(rand.Float64()*2 + 1) // Random upward velocity between -1 and -3
+			particle.VelX = common.Rand().Float64()*2 - 1    // Random horizontal velocity between -1 and 1
+			particle.VelY = -(common.Rand().Float64()*2 + 1) // Random upward velocity between -1 and -3
 			particle.Life = emitter.Lifetime
 
 			emitter.Particles = append(emitter.Particles, particle)
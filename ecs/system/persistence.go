@@ -34,6 +34,9 @@ type PersistenceSystem struct {
 	loadedSave       *savegame.File
 	initialized      bool
 	loadSequence     uint64
+	reloads          ecs.EventReader[component.ReloadRequest]
+	levelChanges     ecs.EventReader[component.LevelChangeRequest]
+	commands         ecs.CommandBuffer
 }
 
 func NewPersistenceSystem(initialLevelName string, allAbilities bool, initialAbilities *component.Abilities, initialFadeIn bool, physicsReset func(), saveStore *savegame.Store, loadedSave *savegame.File) *PersistenceSystem {
@@ -81,8 +84,9 @@ func (p *PersistenceSystem) Update(w *ecs.World) {
 	if _, ok := ecs.First(w, component.ResetToInitialLevelRequestComponent.Kind()); ok {
 		p.levelName = p.initialLevelName
 		ecs.ForEach(w, component.ResetToInitialLevelRequestComponent.Kind(), func(e ecs.Entity, _ *component.ResetToInitialLevelRequest) {
-			ecs.DestroyEntity(w, e)
+			p.commands.Destroy(e)
 		})
+		p.commands.Apply(w)
 		if err := p.reloadWorld(w, PersistenceOnReload); err != nil {
 			panic("persistence system: reset-to-initial failed: " + err.Error())
 		}
@@ -90,10 +94,7 @@ func (p *PersistenceSystem) Update(w *ecs.World) {
 		return
 	}
 
-	if _, ok := ecs.First(w, component.ReloadRequestComponent.Kind()); ok {
-		ecs.ForEach(w, component.ReloadRequestComponent.Kind(), func(e ecs.Entity, _ *component.ReloadRequest) {
-			ecs.DestroyEntity(w, e)
-		})
+	if len(p.reloads.Read(w)) > 0 {
 		if err := p.reloadWorld(w, PersistenceOnReload); err != nil {
 			panic("persistence system: reload failed: " + err.Error())
 		}
@@ -106,8 +107,9 @@ func (p *PersistenceSystem) Update(w *ecs.World) {
 			return
 		}
 		ecs.ForEach(w, component.CheckpointReloadRequestComponent.Kind(), func(e ecs.Entity, _ *component.CheckpointReloadRequest) {
-			ecs.DestroyEntity(w, e)
+			p.commands.Destroy(e)
 		})
+		p.commands.Apply(w)
 		if err := p.reloadCheckpoint(w, req); err != nil {
 			panic("persistence system: checkpoint reload failed: " + err.Error())
 		}
@@ -116,19 +118,17 @@ func (p *PersistenceSystem) Update(w *ecs.World) {
 
 	if _, ok := p.firstEnemyRespawnRequest(w); ok {
 		ecs.ForEach(w, component.EnemyRespawnRequestComponent.Kind(), func(e ecs.Entity, _ *component.EnemyRespawnRequest) {
-			ecs.DestroyEntity(w, e)
+			p.commands.Destroy(e)
 		})
+		p.commands.Apply(w)
 		if err := p.respawnCurrentLevelEnemies(w); err != nil {
 			panic("persistence system: enemy respawn reload failed: " + err.Error())
 		}
 		return
 	}
 
-	if req, ok := p.firstLevelChangeRequest(w); ok {
-		ecs.ForEach(w, component.LevelChangeRequestComponent.Kind(), func(e ecs.Entity, _ *component.LevelChangeRequest) {
-			ecs.DestroyEntity(w, e)
-		})
-
+	if changes := p.levelChanges.Read(w); len(changes) > 0 {
+		req := changes[0]
 		if req.TargetLevel != "" {
 			p.levelName = req.TargetLevel
 		}
@@ -256,18 +256,6 @@ func (p *PersistenceSystem) mergePersistentLevelScopedComponents(w *ecs.World, d
 	}
 }
 
-func (p *PersistenceSystem) firstLevelChangeRequest(w *ecs.World) (component.LevelChangeRequest, bool) {
-	ent, ok := ecs.First(w, component.LevelChangeRequestComponent.Kind())
-	if !ok {
-		return component.LevelChangeRequest{}, false
-	}
-	req, ok := ecs.Get(w, ent, component.LevelChangeRequestComponent.Kind())
-	if !ok || req == nil {
-		return component.LevelChangeRequest{}, false
-	}
-	return *req, true
-}
-
 func (p *PersistenceSystem) firstCheckpointReloadRequest(w *ecs.World) (component.CheckpointReloadRequest, bool) {
 	ent, ok := ecs.First(w, component.CheckpointReloadRequestComponent.Kind())
 	if !ok {
@@ -466,6 +454,10 @@ func applyCheckpointRespawn(w *ecs.World, checkpoint savegame.CheckpointState) {
 }
 
 func (p *PersistenceSystem) reloadWorld(w *ecs.World, mode PersistenceMode) error {
+	// Requests made in the world being replaced go with it.
+	p.reloads.Read(w)
+	p.levelChanges.Read(w)
+
 	preferredSingletons := p.snapshotPersistentSingletons(w, mode)
 	p.pruneForReload(w, mode)
 
@@ -775,18 +767,47 @@ func (p *PersistenceSystem) shouldKeep(persistent *component.Persistent, mode Pe
 	return persistent.KeepOnLevelChange
 }
 
-func (p *PersistenceSystem) queueAsyncSave(w *ecs.World) {
+// SaveNow queues a save of the current world on the background writer. It
+// reports whether there is a save slot to write to.
+func (p *PersistenceSystem) SaveNow(w *ecs.World) (bool, error) {
 	if p == nil || p.saveStore == nil || w == nil {
-		return
+		return false, nil
 	}
 
 	snapshot, err := savegame.CaptureWorld(w)
 	if err != nil {
-		log.Printf("save game: capture snapshot: %v", err)
-		return
+		return false, fmt.Errorf("save game: capture snapshot: %w", err)
 	}
 
 	p.saveStore.SaveAsync(snapshot)
+	return true, nil
+}
+
+// RequestCheckpointReload fades out and respawns the player at their last
+// checkpoint, the same way dying does.
+func RequestCheckpointReload(w *ecs.World) {
+	if w == nil {
+		return
+	}
+	if _, ok := ecs.First(w, component.CheckpointReloadRequestComponent.Kind()); !ok {
+		req := ecs.CreateEntity(w)
+		_ = ecs.Add(w, req, component.CheckpointReloadRequestComponent.Kind(), &component.CheckpointReloadRequest{})
+	}
+	if _, ok := ecs.First(w, component.TransitionRuntimeComponent.Kind()); !ok {
+		rtEnt := ecs.CreateEntity(w)
+		_ = ecs.Add(w, rtEnt, component.TransitionRuntimeComponent.Kind(), &component.TransitionRuntime{
+			Phase:   component.TransitionFadeOut,
+			Alpha:   0,
+			Timer:   transitionFadeFrames,
+			ReqSent: true,
+		})
+	}
+}
+
+func (p *PersistenceSystem) queueAsyncSave(w *ecs.World) {
+	if _, err := p.SaveNow(w); err != nil {
+		log.Print(err)
+	}
 }
 
 func (p *PersistenceSystem) armTransitionCooldownForCurrentOverlap(w *ecs.World) {
@@ -482,3 +482,37 @@ func TestApplyTransitionPopUsesUpSpawnTransitions(t *testing.T) {
 		t.Fatal("expected pop to preserve source facing")
 	}
 }
+
+func TestRequestCheckpointReloadQueuesOneRequestAndFade(t *testing.T) {
+	w := ecs.NewWorld()
+
+	RequestCheckpointReload(w)
+	RequestCheckpointReload(w)
+
+	requests := 0
+	ecs.ForEach(w, component.CheckpointReloadRequestComponent.Kind(), func(ecs.Entity, *component.CheckpointReloadRequest) {
+		requests++
+	})
+	if requests != 1 {
+		t.Fatalf("expected one checkpoint reload request, got %d", requests)
+	}
+	rtEnt, ok := ecs.First(w, component.TransitionRuntimeComponent.Kind())
+	if !ok {
+		t.Fatal("expected a fade out to be queued")
+	}
+	rt, ok := ecs.Get(w, rtEnt, component.TransitionRuntimeComponent.Kind())
+	if !ok || rt.Phase != component.TransitionFadeOut {
+		t.Fatalf("expected fade out, got %+v", rt)
+	}
+}
+
+func TestSaveNowWithoutStoreReportsNotSaved(t *testing.T) {
+	p := NewPersistenceSystem("disposal_3.json", false, nil, false, nil, nil, nil)
+	saved, err := p.SaveNow(ecs.NewWorld())
+	if err != nil {
+		t.Fatalf("SaveNow() error = %v", err)
+	}
+	if saved {
+		t.Fatal("expected no save without a store")
+	}
+}
@@ -54,6 +54,8 @@ type PhysicsSystem struct {
 	playerStates map[ecs.Entity]*playerContactState
 	// world is set at Update time so collision handlers can query components.
 	world *ecs.World
+	// commands holds anchor changes until the loops finding them are done.
+	commands ecs.CommandBuffer
 }
 
 type bodyInfo struct {
@@ -169,8 +171,9 @@ func (ps *PhysicsSystem) Update(w *ecs.World) {
 			}
 		}
 
-		ecs.DestroyEntity(w, e)
+		ps.commands.Destroy(e)
 	})
+	ps.commands.Apply(w)
 
 	ecs.ForEach(w, component.AnchorDetachRequestComponent.Kind(), func(e ecs.Entity, _ *component.AnchorDetachRequest) {
 		if !ecs.IsAlive(w, e) {
@@ -183,10 +186,11 @@ func (ps *PhysicsSystem) Update(w *ecs.World) {
 		}
 
 		ps.removeAnchorJoints(w, e)
-		_ = ecs.Remove(w, e, component.AnchorJointComponent.Kind())
-		_ = ecs.Remove(w, e, component.AnchorConstraintRequestComponent.Kind())
-		_ = ecs.Remove(w, e, component.AnchorDetachRequestComponent.Kind())
+		ecs.DeferRemove(&ps.commands, e, component.AnchorJointComponent.Kind())
+		ecs.DeferRemove(&ps.commands, e, component.AnchorConstraintRequestComponent.Kind())
+		ecs.DeferRemove(&ps.commands, e, component.AnchorDetachRequestComponent.Kind())
 	})
+	ps.commands.Apply(w)
 
 	if ps.space == nil {
 		ps.space = newPhysicsSpace()
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"image/color"
 	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -548,6 +550,47 @@ func DrawTransitionDebug(w *ecs.World, screen *ebiten.Image) {
 	})
 }
 
+// DrawScriptErrorDebug lists the latest script failures, newest first, along
+// the bottom-left of the screen.
+func DrawScriptErrorDebug(w *ecs.World, screen *ebiten.Image) {
+	if w == nil || screen == nil {
+		return
+	}
+	ent, ok := ecs.First(w, component.ScriptErrorLogComponent.Kind())
+	if !ok {
+		return
+	}
+	log, ok := ecs.Get(w, ent, component.ScriptErrorLogComponent.Kind())
+	if !ok || log == nil || len(log.Entries) == 0 {
+		return
+	}
+
+	const charW = 7
+	const lineH = 16
+	const margin = 10
+	maxChars := (screen.Bounds().Dx() - margin*2) / charW
+
+	lines := []string{"Script errors:"}
+	for _, entry := range log.Entries {
+		label := entry.GameEntityID
+		if label == "" {
+			label = "?"
+		}
+		if entry.Count > 1 {
+			label = fmt.Sprintf("%s x%d", label, entry.Count)
+		}
+		// The message already carries the mapped file positions.
+		line := fmt.Sprintf("[%s] %s", label, entry.Message)
+		if maxChars > 3 && len(line) > maxChars {
+			line = line[:maxChars-3] + "..."
+		}
+		lines = append(lines, line)
+	}
+
+	y := screen.Bounds().Dy() - margin - len(lines)*lineH
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), margin, y)
+}
+
 type physicsDebugDrawer struct {
 	screen *ebiten.Image
 	camX   float64
@@ -695,3 +738,41 @@ func debugCameraTransform(w *ecs.World) (float64, float64, float64) {
 	}
 	return camX, camY, zoom
 }
+
+// systemProfileDebugRows is how many of the slowest systems the overlay lists.
+const systemProfileDebugRows = 12
+
+// DrawSystemProfileDebug lists the systems with the slowest 95th percentile
+// frame times at the top-left of the screen.
+func DrawSystemProfileDebug(profile *ecs.SystemProfile, screen *ebiten.Image) {
+	if profile == nil || screen == nil {
+		return
+	}
+	stats := profile.Stats()
+	if len(stats) == 0 {
+		return
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].P95 > stats[j].P95
+	})
+
+	millis := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	lines := []string{"system                      p50ms  p95ms  p99ms allocs"}
+	for i, s := range stats {
+		if i == systemProfileDebugRows {
+			lines = append(lines, fmt.Sprintf("... %d more", len(stats)-i))
+			break
+		}
+		name := s.Name
+		if len(name) > 26 {
+			name = name[:25] + "~"
+		}
+		lines = append(lines, fmt.Sprintf("%-26s %6.2f %6.2f %6.2f %6.0f", name, millis(s.P50), millis(s.P95), millis(s.P99), s.MeanAllocs))
+	}
+
+	const margin = 10
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), margin, margin)
+}
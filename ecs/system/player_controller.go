@@ -33,7 +33,9 @@ func handleHealInput(input *component.Input, abilities *component.Abilities, sta
 	stateComp.Pending = playerStateHeal
 }
 
-type PlayerControllerSystem struct{}
+type PlayerControllerSystem struct {
+	shrineHeals ecs.EventReader[component.ShrineHealRequest]
+}
 
 func NewPlayerControllerSystem() *PlayerControllerSystem {
 	return &PlayerControllerSystem{}
@@ -98,6 +100,9 @@ func (p *PlayerControllerSystem) Update(w *ecs.World) {
 		return
 	}
 
+	// A hit on the same frame cancels the heal; the player can kneel again.
+	shrineHeal := len(p.shrineHeals.Read(w)) > 0
+
 	ecs.ForEach8(w,
 		component.PlayerComponent.Kind(),
 		component.InputComponent.Kind(),
@@ -139,10 +144,9 @@ func (p *PlayerControllerSystem) Update(w *ecs.World) {
 				_ = ecs.Remove(w, e, component.PlayerStateInterruptComponent.Kind())
 			}
 
-			if !interruptPending && ecs.Has(w, e, component.ShrineHealRequestComponent.Kind()) {
+			if !interruptPending && shrineHeal {
 				stateComp.Pending = playerStateShrine
 				interruptPending = true
-				_ = ecs.Remove(w, e, component.ShrineHealRequestComponent.Kind())
 			}
 
 			// helper ground check used by multiple closures
@@ -400,26 +404,10 @@ func (p *PlayerControllerSystem) Update(w *ecs.World) {
 					applyShrineEffects(w, e)
 				},
 				BeginCheckpointRespawn: func() {
-					if _, ok := ecs.First(w, component.CheckpointReloadRequestComponent.Kind()); !ok {
-						req := ecs.CreateEntity(w)
-						_ = ecs.Add(w, req, component.CheckpointReloadRequestComponent.Kind(), &component.CheckpointReloadRequest{})
-					}
-					if _, ok := ecs.First(w, component.TransitionRuntimeComponent.Kind()); !ok {
-						rtEnt := ecs.CreateEntity(w)
-						_ = ecs.Add(w, rtEnt, component.TransitionRuntimeComponent.Kind(), &component.TransitionRuntime{
-							Phase:   component.TransitionFadeOut,
-							Alpha:   0,
-							Timer:   transitionFadeFrames,
-							ReqSent: true,
-						})
-					}
+					RequestCheckpointReload(w)
 				},
 				RequestReload: func() {
-					// create a one-shot reload request entity
-					if _, ok := ecs.First(w, component.ReloadRequestComponent.Kind()); !ok {
-						req := ecs.CreateEntity(w)
-						_ = ecs.Add(w, req, component.ReloadRequestComponent.Kind(), &component.ReloadRequest{})
-					}
+					ecs.Emit(w, component.ReloadRequest{})
 				},
 				ConsumeHitEvent: func() bool {
 					if ecs.Has(w, e, component.HitEventComponent.Kind()) {
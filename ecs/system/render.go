@@ -22,6 +22,7 @@ type RenderSystem struct {
 	batch         staticTileBatch
 	lastLoadSeq   uint64
 	lastStaticSig uint64
+	sprites       *ecs.Query
 }
 
 type staticTileBatch struct {
@@ -56,7 +57,11 @@ type spriteSourceKey struct {
 }
 
 func NewRenderSystem() *RenderSystem {
-	return &RenderSystem{sourceCache: make(map[spriteSourceKey]*ebiten.Image)}
+	return &RenderSystem{
+		sourceCache: make(map[spriteSourceKey]*ebiten.Image),
+		sprites: ecs.NewQuery(component.TransformComponent.Kind(), component.SpriteComponent.Kind()).
+			Optional(component.StaticTileComponent.Kind(), component.TransitionComponent.Kind()),
+	}
 }
 
 func drawLine(target *ebiten.Image, line *component.LineRender, screenSpace bool, camX, camY, zoom float64) {
@@ -240,36 +245,32 @@ func (r *RenderSystem) Draw(w *ecs.World, screen *ebiten.Image) {
 	visibleChunksByLayer, visibleLayerOrder := groupChunksByLayer(visibleChunks)
 	drawnStaticLayers := make(map[int]bool, len(visibleChunksByLayer))
 
-	allEntities := ecs.Query2(w, component.TransformComponent.Kind(), component.SpriteComponent.Kind())
 	r.drawEntities = r.drawEntities[:0]
-	for _, e := range allEntities {
+	r.sprites.Each(w, func(e ecs.Entity) {
 		if e == r.camEntity {
-			continue
+			return
 		}
-		if ecs.Has(w, e, component.StaticTileComponent.Kind()) && !spriteNeedsDynamicDraw(w, e) {
-			continue
+		if _, static := ecs.Fetch(r.sprites, e, component.StaticTileComponent.Kind()); static && !spriteNeedsDynamicDraw(w, e) {
+			return
 		}
 
-		t, ok := ecs.Get(w, e, component.TransformComponent.Kind())
-		if !ok {
-			continue
-		}
-		s, ok := ecs.Get(w, e, component.SpriteComponent.Kind())
-		if !ok || s.Image == nil {
-			continue
+		t, _ := ecs.Fetch(r.sprites, e, component.TransformComponent.Kind())
+		s, _ := ecs.Fetch(r.sprites, e, component.SpriteComponent.Kind())
+		if t == nil || s == nil || s.Image == nil {
+			return
 		}
 
-		if tr, ok := ecs.Get(w, e, component.TransitionComponent.Kind()); ok {
+		if tr, ok := ecs.Fetch(r.sprites, e, component.TransitionComponent.Kind()); ok {
 			if transitionVisible(t, tr, viewLeft, viewTop, viewRight, viewBottom) {
 				r.drawEntities = append(r.drawEntities, e)
 			}
-			continue
+			return
 		}
 
 		// Keep dynamic entities always drawable. Aggressive culling can reject
 		// animated/offset sprites incorrectly and make entities disappear.
 		r.drawEntities = append(r.drawEntities, e)
-	}
+	})
 
 	sort.Slice(r.drawEntities, func(i, j int) bool {
 		li := drawLayerIndex(w, r.drawEntities[i])
@@ -6,7 +6,9 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type RespawnSystem struct{}
+type RespawnSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewRespawnSystem() *RespawnSystem { return &RespawnSystem{} }
 
@@ -22,13 +24,13 @@ func (s *RespawnSystem) Update(w *ecs.World) {
 		// Only handle player entity
 		if !ecs.Has(w, e, component.PlayerTagComponent.Kind()) {
 			// remove the request so it doesn't linger
-			_ = ecs.Remove(w, e, component.RespawnRequestComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.RespawnRequestComponent.Kind())
 			return
 		}
 
 		t, tok := ecs.Get(w, e, component.TransformComponent.Kind())
 		if !tok || t == nil {
-			_ = ecs.Remove(w, e, component.RespawnRequestComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.RespawnRequestComponent.Kind())
 			return
 		}
 
@@ -48,6 +50,7 @@ func (s *RespawnSystem) Update(w *ecs.World) {
 			}
 		}
 
-		_ = ecs.Remove(w, e, component.RespawnRequestComponent.Kind())
+		ecs.DeferRemove(&s.commands, e, component.RespawnRequestComponent.Kind())
 	})
+	s.commands.Apply(w)
 }
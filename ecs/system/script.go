@@ -2,6 +2,7 @@ package system
 
 import (
 	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
 	"github.com/milk9111/sidescroller/ecs/script"
 )
 
@@ -20,6 +21,51 @@ func (s *ScriptSystem) Update(w *ecs.World) {
 	s.runtime.Update(w)
 }
 
+// SetErrorHandler routes script load and run failures, each a
+// *script.Error, to fn.
+func (s *ScriptSystem) SetErrorHandler(fn func(ent ecs.Entity, err error)) {
+	if s == nil || s.runtime == nil {
+		return
+	}
+	s.runtime.SetErrorHandler(fn)
+}
+
+// SetSignalObserver hands every signal queued on a script to fn, or stops
+// when fn is nil.
+func (s *ScriptSystem) SetSignalObserver(fn script.SignalObserver) {
+	if s == nil || s.runtime == nil {
+		return
+	}
+	s.runtime.SetSignalObserver(fn)
+}
+
+// ReloadScripts recompiles the scripts using any of the changed files in
+// place, keeping their state. It returns how many were reloaded.
+func (s *ScriptSystem) ReloadScripts(w *ecs.World, changed []string) int {
+	if s == nil || s.runtime == nil {
+		return 0
+	}
+	return s.runtime.ReloadScripts(w, changed)
+}
+
+// SnapshotSection returns the script runtime as a world snapshot section, so
+// savegame.SnapshotWorld keeps each script's state and timers.
+func (s *ScriptSystem) SnapshotSection() ecs.SnapshotSection {
+	if s == nil || s.runtime == nil {
+		return nil
+	}
+	return s.runtime
+}
+
+// NewConsole returns a developer console that evaluates against this
+// system's script runtime.
+func (s *ScriptSystem) NewConsole() *script.Console {
+	if s == nil {
+		return nil
+	}
+	return script.NewConsole(s.runtime)
+}
+
 func EmitEntitySignal(w *ecs.World, target ecs.Entity, source ecs.Entity, signalName string) bool {
 	return script.EmitEntitySignal(w, target, source, signalName)
 }
@@ -28,6 +74,10 @@ func EmitEntitySignalWithPosition(w *ecs.World, target ecs.Entity, source ecs.En
 	return script.EmitEntitySignalWithPosition(w, target, source, signalName, positionX, positionY, hasPosition)
 }
 
+func EmitSignalEvent(w *ecs.World, target ecs.Entity, source ecs.Entity, event component.ScriptSignalEvent) bool {
+	return script.EmitSignalEvent(w, target, source, event)
+}
+
 func BroadcastSignalWithPosition(w *ecs.World, source ecs.Entity, signalName string, positionX, positionY float64, hasPosition bool, excludeTargets ...ecs.Entity) int {
 	return script.BroadcastSignalWithPosition(w, source, signalName, positionX, positionY, hasPosition, excludeTargets...)
 }
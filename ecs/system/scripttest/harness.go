@@ -0,0 +1,226 @@
+// Package scripttest runs prefab scripts in a minimal world so they can be
+// tested without loading a level. Go tests drive a Harness directly; the
+// _test.tengo files next to the scripts in prefabs/scripts drive one through
+// the "test" module, see Run.
+package scripttest
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
+	"github.com/milk9111/sidescroller/ecs/system"
+	"github.com/milk9111/sidescroller/internal/replay"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+// Harness is a world with a script system and whatever it needs to run
+// around it. Entities are spawned from prefabs and named by game entity id.
+type Harness struct {
+	World     *ecs.World
+	scheduler *ecs.Scheduler
+	scripts   *system.ScriptSystem
+	entities  map[string]ecs.Entity
+	spawned   map[string]int
+	// transitions is each entity's state history, one entry per change.
+	transitions map[string][]string
+	errors      []error
+	// signals are the ones queued on scripts, less those sent with Signal,
+	// which wait in sent until the script system sees them.
+	signals []RecordedSignal
+	sent    []RecordedSignal
+	// press is the input for the first frame after Input, held for the
+	// frames after it.
+	press, held component.Input
+	pressed     bool
+}
+
+// New returns a harness whose steps run systems and then the script system.
+// With no systems it runs physics, which most AI modules need.
+func New(systems ...ecs.System) *Harness {
+	if len(systems) == 0 {
+		systems = []ecs.System{system.NewPhysicsSystem()}
+	}
+	h := &Harness{
+		World:       ecs.NewWorld(),
+		entities:    map[string]ecs.Entity{},
+		spawned:     map[string]int{},
+		transitions: map[string][]string{},
+	}
+
+	h.scripts = system.NewScriptSystem()
+	h.scripts.SetErrorHandler(func(_ ecs.Entity, err error) {
+		h.errors = append(h.errors, err)
+	})
+	h.scripts.SetSignalObserver(h.recordSignal)
+	h.scheduler = ecs.NewScheduler(append(systems, h.scripts)...)
+	return h
+}
+
+// RecordedSignal is a signal queued on a script, with the game entity id of
+// the entity it was queued on.
+type RecordedSignal struct {
+	TargetGameEntity string
+	Event            component.ScriptSignalEvent
+}
+
+// Spawn builds prefab and returns the game entity id it is known by, which
+// is the prefab's name and a count, like "enemy_1".
+func (h *Harness) Spawn(prefab string) (string, error) {
+	e, err := entity.BuildEntity(h.World, prefab)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(path.Base(prefab), path.Ext(prefab))
+	h.spawned[name]++
+	id := fmt.Sprintf("%s_%d", name, h.spawned[name])
+	if err := ecs.Add(h.World, e, component.GameEntityIDComponent.Kind(), &component.GameEntityID{Value: id}); err != nil {
+		return "", err
+	}
+	h.entities[id] = e
+	return id, nil
+}
+
+// SpawnAt builds prefab at x, y.
+func (h *Harness) SpawnAt(prefab string, x, y float64) (string, error) {
+	id, err := h.Spawn(prefab)
+	if err != nil {
+		return "", err
+	}
+	if err := entity.SetEntityTransform(h.World, h.entities[id], x, y, 0); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Entity returns the entity spawned as id, if it is still alive.
+func (h *Harness) Entity(id string) (ecs.Entity, bool) {
+	e, ok := h.entities[id]
+	if !ok || !ecs.IsAlive(h.World, e) {
+		return 0, false
+	}
+	return e, true
+}
+
+// Input holds actions on every Input component from the next step on,
+// replacing the ones held before. Actions are named as in input scripts
+// (see replay.ParseScript): press actions like attack only fire on the
+// first frame.
+func (h *Harness) Input(actions ...string) error {
+	frames, err := replay.ParseScript(strings.NewReader("2 " + strings.Join(actions, " ")))
+	if err != nil {
+		return err
+	}
+	h.press, h.held, h.pressed = frames[0].Input, frames[1].Input, false
+	return nil
+}
+
+// Signal queues name on id's script as if id had emitted it, so
+// subscriptions that only listen to their own entity hear it too.
+func (h *Harness) Signal(id, name string) error {
+	e, ok := h.Entity(id)
+	if !ok {
+		return fmt.Errorf("no entity %s", id)
+	}
+	if !system.EmitSignalEvent(h.World, e, e, component.ScriptSignalEvent{Name: name}) {
+		return fmt.Errorf("could not signal %s to %s", name, id)
+	}
+	// Only signals the scripts and systems send are of interest.
+	h.sent = append(h.sent, RecordedSignal{TargetGameEntity: id, Event: component.ScriptSignalEvent{Name: name, SourceGameEntity: id}})
+	return nil
+}
+
+// Step runs frames updates. It stops at the first update a script fails in
+// and returns the failure.
+func (h *Harness) Step(frames int) error {
+	for i := 0; i < frames; i++ {
+		input := h.held
+		if !h.pressed {
+			input, h.pressed = h.press, true
+		}
+		ecs.ForEach(h.World, component.InputComponent.Kind(), func(_ ecs.Entity, current *component.Input) {
+			*current = input
+		})
+
+		ecs.AdvanceEvents(h.World)
+		h.scheduler.Update(h.World)
+		h.recordTransitions()
+
+		if len(h.errors) > 0 {
+			err := h.errors[0]
+			h.errors = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot saves the world and its scripts' state, like the console's
+// snapshot command.
+func (h *Harness) Snapshot() ([]byte, error) {
+	return savegame.SnapshotWorld(h.World, h.scripts.SnapshotSection())
+}
+
+// Restore puts the world and its scripts back to a Snapshot.
+func (h *Harness) Restore(data []byte) error {
+	return savegame.RestoreWorld(h.World, data, h.scripts.SnapshotSection())
+}
+
+// State returns id's current state: state["current_state"] for a script,
+// or the FSM state for Go AI.
+func (h *Harness) State(id string) string {
+	e, ok := h.Entity(id)
+	if !ok {
+		return ""
+	}
+	if state, ok := ecs.Get(h.World, e, component.ScriptStateComponent.Kind()); ok && state != nil && state.Current != "" {
+		return state.Current
+	}
+	if state, ok := ecs.Get(h.World, e, component.AIStateComponent.Kind()); ok && state != nil {
+		return string(state.Current)
+	}
+	return ""
+}
+
+// Transitions returns every state id has been in, in order, as seen at the
+// end of each step.
+func (h *Harness) Transitions(id string) []string {
+	return append([]string(nil), h.transitions[id]...)
+}
+
+// Signals returns the signals queued on any script so far, oldest first,
+// leaving out the ones sent with Signal.
+func (h *Harness) Signals() []RecordedSignal {
+	return append([]RecordedSignal(nil), h.signals...)
+}
+
+func (h *Harness) recordSignal(target ecs.Entity, event component.ScriptSignalEvent) {
+	signal := RecordedSignal{Event: event}
+	if id, ok := ecs.Get(h.World, target, component.GameEntityIDComponent.Kind()); ok && id != nil {
+		signal.TargetGameEntity = id.Value
+	}
+	for i, sent := range h.sent {
+		if sent.TargetGameEntity == signal.TargetGameEntity && sent.Event.Name == event.Name && sent.Event.SourceGameEntity == event.SourceGameEntity {
+			h.sent = append(h.sent[:i], h.sent[i+1:]...)
+			return
+		}
+	}
+	h.signals = append(h.signals, signal)
+}
+
+func (h *Harness) recordTransitions() {
+	for id := range h.entities {
+		state := h.State(id)
+		if state == "" {
+			continue
+		}
+		seen := h.transitions[id]
+		if len(seen) == 0 || seen[len(seen)-1] != state {
+			h.transitions[id] = append(seen, state)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package scripttest
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/prefabs"
+)
+
+// testFileSuffix marks the script tests that live next to the scripts.
+const testFileSuffix = "_test.tengo"
+
+var testPositionPattern = regexp.MustCompile(`\(main\):(\d+):(\d+)`)
+
+// Run runs every _test.tengo file under prefabs/scripts as a subtest.
+func Run(t *testing.T) {
+	t.Helper()
+	var names []string
+	err := fs.WalkDir(prefabs.ScriptsFS, "scripts", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(name, testFileSuffix) {
+			names = append(names, strings.TrimPrefix(name, "scripts/"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("find script tests: %v", err)
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			RunFile(t, name)
+		})
+	}
+}
+
+// RunFile runs one script test, named like the scripts a prefab lists, in a
+// fresh harness.
+func RunFile(t *testing.T, name string) {
+	t.Helper()
+	if err := New().RunScript(name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RunScript runs a script test against h. A failed assertion or script
+// error stops it, and the error points at the line of the test it came from.
+func (h *Harness) RunScript(name string) error {
+	src, err := prefabs.LoadScript(name)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", name, err)
+	}
+
+	modules := stdlib.GetModuleMap("fmt", "math", "text")
+	modules.AddBuiltinModule("test", h.module())
+	script := tengo.NewScript(src)
+	script.SetImports(modules)
+
+	compiled, err := script.Compile()
+	if err == nil {
+		err = compiled.Run()
+	}
+	if err != nil {
+		message := testPositionPattern.ReplaceAllString(err.Error(), name+":$1:$2")
+		return fmt.Errorf("%s", strings.NewReplacer("\n\t", " ", "\n", " ").Replace(message))
+	}
+	return nil
+}
+
+// module builds the "test" module a script test imports.
+func (h *Harness) module() map[string]tengo.Object {
+	values := map[string]tengo.Object{}
+
+	// sig: spawn(prefab, [x, y]) -> string
+	// doc: Build a prefab, optionally at x, y, and return its game entity id.
+	values["spawn"] = &tengo.UserFunction{Name: "spawn", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 && len(args) != 3 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		prefab, _ := tengo.ToString(args[0])
+		var id string
+		var err error
+		if len(args) == 3 {
+			x, _ := tengo.ToFloat64(args[1])
+			y, _ := tengo.ToFloat64(args[2])
+			id, err = h.SpawnAt(prefab, x, y)
+		} else {
+			id, err = h.Spawn(prefab)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &tengo.String{Value: id}, nil
+	}}
+
+	// sig: step([frames]) -> undefined
+	// doc: Run frames updates, one by default. A script error fails the test.
+	values["step"] = &tengo.UserFunction{Name: "step", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		frames := 1
+		if len(args) > 0 {
+			frames, _ = tengo.ToInt(args[0])
+		}
+		if err := h.Step(frames); err != nil {
+			return nil, fmt.Errorf("step: %w", err)
+		}
+		return tengo.UndefinedValue, nil
+	}}
+
+	// sig: input(actions...) -> undefined
+	// doc: Hold input script actions, like "right" or "jump", from the next step on.
+	values["input"] = &tengo.UserFunction{Name: "input", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		actions := make([]string, 0, len(args))
+		for _, arg := range args {
+			action, _ := tengo.ToString(arg)
+			actions = append(actions, action)
+		}
+		return tengo.UndefinedValue, h.Input(actions...)
+	}}
+
+	// sig: signal(id, name) -> undefined
+	// doc: Queue a signal on an entity's script, as if the entity emitted it.
+	values["signal"] = &tengo.UserFunction{Name: "signal", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 2 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		name, _ := tengo.ToString(args[1])
+		return tengo.UndefinedValue, h.Signal(id, name)
+	}}
+
+	// sig: emitted(id) -> [string]
+	// doc: Names of the signals an entity has sent so far, oldest first.
+	values["emitted"] = &tengo.UserFunction{Name: "emitted", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		return signalNames(h.Signals(), func(s RecordedSignal) bool {
+			return s.Event.SourceGameEntity == id
+		}), nil
+	}}
+
+	// sig: received(id) -> [string]
+	// doc: Names of the signals queued on an entity's script so far, oldest first.
+	values["received"] = &tengo.UserFunction{Name: "received", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		return signalNames(h.Signals(), func(s RecordedSignal) bool {
+			return s.TargetGameEntity == id
+		}), nil
+	}}
+
+	// sig: state(id) -> string
+	// doc: The entity's current AI state.
+	values["state"] = &tengo.UserFunction{Name: "state", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		return &tengo.String{Value: h.State(id)}, nil
+	}}
+
+	// sig: transitions(id) -> [string]
+	// doc: Every AI state the entity has been in, in order.
+	values["transitions"] = &tengo.UserFunction{Name: "transitions", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		states := &tengo.Array{}
+		for _, state := range h.Transitions(id) {
+			states.Value = append(states.Value, &tengo.String{Value: state})
+		}
+		return states, nil
+	}}
+
+	// sig: exists(id) -> bool
+	// doc: Whether the entity is still alive.
+	values["exists"] = &tengo.UserFunction{Name: "exists", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		if _, ok := h.Entity(id); ok {
+			return tengo.TrueValue, nil
+		}
+		return tengo.FalseValue, nil
+	}}
+
+	// sig: component(id, name) -> map
+	// doc: A component's plain fields, keyed in snake_case, or undefined if the entity lacks it.
+	values["component"] = &tengo.UserFunction{Name: "component", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 2 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		id, _ := tengo.ToString(args[0])
+		name, _ := tengo.ToString(args[1])
+		read, ok := componentReaders[name]
+		if !ok {
+			return nil, fmt.Errorf("component: unknown component %q", name)
+		}
+		e, ok := h.Entity(id)
+		if !ok {
+			return nil, fmt.Errorf("component: no entity %s", id)
+		}
+		value, ok := read(h.World, e)
+		if !ok {
+			return tengo.UndefinedValue, nil
+		}
+		return fieldsObject(reflect.ValueOf(value).Elem()), nil
+	}}
+
+	// sig: expect(cond, [message]) -> undefined
+	// doc: Fail the test unless cond is truthy.
+	values["expect"] = &tengo.UserFunction{Name: "expect", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) < 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		if args[0].IsFalsy() {
+			return nil, fmt.Errorf("expect failed%s", assertMessage(args, 1))
+		}
+		return tengo.UndefinedValue, nil
+	}}
+
+	// sig: equal(got, want, [message]) -> undefined
+	// doc: Fail the test unless got equals want.
+	values["equal"] = &tengo.UserFunction{Name: "equal", Value: func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) < 2 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		if !args[0].Equals(args[1]) {
+			return nil, fmt.Errorf("equal failed%s: got %s, want %s", assertMessage(args, 2), args[0], args[1])
+		}
+		return tengo.UndefinedValue, nil
+	}}
+
+	return values
+}
+
+func assertMessage(args []tengo.Object, index int) string {
+	if len(args) <= index {
+		return ""
+	}
+	message, _ := tengo.ToString(args[index])
+	return " (" + message + ")"
+}
+
+func signalNames(signals []RecordedSignal, keep func(RecordedSignal) bool) *tengo.Array {
+	names := &tengo.Array{}
+	for _, signal := range signals {
+		if keep(signal) {
+			names.Value = append(names.Value, &tengo.String{Value: signal.Event.Name})
+		}
+	}
+	return names
+}
+
+// componentReaders are the components a script test can read, by their
+// prefab names.
+var componentReaders = map[string]func(w *ecs.World, e ecs.Entity) (any, bool){
+	"ai":               readComponent(component.AIComponent),
+	"animation":        readComponent(component.AnimationComponent),
+	"hazard":           readComponent(component.HazardComponent),
+	"health":           readComponent(component.HealthComponent),
+	"input":            readComponent(component.InputComponent),
+	"invulnerable":     readComponent(component.InvulnerableComponent),
+	"particle_emitter": readComponent(component.ParticleEmitterComponent),
+	"physics_body":     readComponent(component.PhysicsBodyComponent),
+	"player":           readComponent(component.PlayerComponent),
+	"sprite":           readComponent(component.SpriteComponent),
+	"transform":        readComponent(component.TransformComponent),
+	"trigger":          readComponent(component.TriggerComponent),
+	"white_flash":      readComponent(component.WhiteFlashComponent),
+}
+
+func readComponent[T any](handle component.ComponentHandle[T]) func(w *ecs.World, e ecs.Entity) (any, bool) {
+	return func(w *ecs.World, e ecs.Entity) (any, bool) {
+		value, ok := ecs.Get(w, e, handle.Kind())
+		if !ok || value == nil {
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+// fieldsObject maps a component's bool, number and string fields to a Tengo
+// map. Other fields, like images and physics bodies, are left out.
+func fieldsObject(value reflect.Value) tengo.Object {
+	fields := map[string]tengo.Object{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		var obj tengo.Object
+		switch v := value.Field(i); v.Kind() {
+		case reflect.Bool:
+			obj = tengo.FalseValue
+			if v.Bool() {
+				obj = tengo.TrueValue
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			obj = &tengo.Int{Value: v.Int()}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			obj = &tengo.Int{Value: int64(v.Uint())}
+		case reflect.Float32, reflect.Float64:
+			obj = &tengo.Float{Value: v.Float()}
+		case reflect.String:
+			obj = &tengo.String{Value: v.String()}
+		default:
+			continue
+		}
+		fields[snakeCase(field.Name)] = obj
+	}
+	return &tengo.ImmutableMap{Value: fields}
+}
+
+func snakeCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A run of capitals like the ID in GameEntityID stays one word.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
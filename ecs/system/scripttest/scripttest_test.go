@@ -0,0 +1,349 @@
+package scripttest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/system"
+	"github.com/milk9111/sidescroller/levels"
+	"github.com/milk9111/sidescroller/prefabs"
+)
+
+func TestPrefabScripts(t *testing.T) {
+	Run(t)
+}
+
+const doorPrefab = `name: door
+components:
+  transform:
+    x: 0
+    y: 0
+  input: {}
+  script:
+    paths:
+      - door/main.tengo
+`
+
+const doorScript = `signals := import("signals")
+
+on_open := func(state) {
+    state["next_state"] = "open"
+}
+
+on_start := func(state) {
+    state["current_state"] = "closed"
+    signals.on("open", on_open)
+}
+
+on_update := func(state) {
+    if state["next_state"] == "open" && state["current_state"] != "open" {
+        state["current_state"] = "open"
+        signals.emit("opened")
+    }
+}
+`
+
+func writeTestPrefab(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, "prefabs", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create prefab dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatalf("write prefab file: %v", err)
+		}
+	}
+	t.Chdir(dir)
+	prefabs.LoadScriptsFromDisk(true)
+	t.Cleanup(func() { prefabs.LoadScriptsFromDisk(false) })
+}
+
+func TestHarnessTracksStatesSignalsAndInput(t *testing.T) {
+	writeTestPrefab(t, map[string]string{
+		"door.yaml":               doorPrefab,
+		"scripts/door/main.tengo": doorScript,
+	})
+
+	h := New()
+	id, err := h.Spawn("door.yaml")
+	if err != nil {
+		t.Fatalf("spawn door: %v", err)
+	}
+	if id != "door_1" {
+		t.Fatalf("expected the door to be named door_1, got %q", id)
+	}
+
+	if err := h.Input("right", "attack"); err != nil {
+		t.Fatalf("input: %v", err)
+	}
+	if err := h.Step(1); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if got := h.State(id); got != "closed" {
+		t.Fatalf("expected the door to start closed, got %q", got)
+	}
+	door, _ := h.Entity(id)
+	input, _ := ecs.Get(h.World, door, component.InputComponent.Kind())
+	if input.MoveX != 1 || !input.AttackPressed {
+		t.Fatalf("expected right and attack on the first frame, got %+v", input)
+	}
+
+	if err := h.Signal(id, "open"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if err := h.Step(1); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if input.MoveX != 1 || input.AttackPressed {
+		t.Fatalf("expected right held and attack released, got %+v", input)
+	}
+	if got := h.Transitions(id); !reflect.DeepEqual(got, []string{"closed", "open"}) {
+		t.Fatalf("expected closed then open, got %v", got)
+	}
+	signals := h.Signals()
+	if len(signals) != 1 || signals[0].Event.Name != "opened" || signals[0].Event.SourceGameEntity != id {
+		t.Fatalf("expected only the door's opened signal, got %+v", signals)
+	}
+}
+
+func TestRunScriptAssertsAndReportsTheFailingLine(t *testing.T) {
+	writeTestPrefab(t, map[string]string{
+		"door.yaml":               doorPrefab,
+		"scripts/door/main.tengo": doorScript,
+		"scripts/door/main_test.tengo": `test := import("test")
+
+door := test.spawn("door.yaml", 32, 64)
+test.step()
+test.equal(test.state(door), "closed")
+test.equal(test.component(door, "transform").x, 32.0)
+test.signal(door, "open")
+test.step()
+test.equal(test.transitions(door), ["closed", "open"])
+test.equal(test.emitted(door), ["opened"])
+test.equal(test.received(door), ["opened"])
+`,
+		"scripts/door/fail_test.tengo": `test := import("test")
+
+door := test.spawn("door.yaml")
+test.step()
+test.equal(test.state(door), "open", "starts open")
+`,
+	})
+
+	if err := New().RunScript("door/main_test.tengo"); err != nil {
+		t.Fatalf("expected the door test to pass, got %v", err)
+	}
+
+	err := New().RunScript("door/fail_test.tengo")
+	if err == nil {
+		t.Fatal("expected the failing assertion to fail the test")
+	}
+	if message := err.Error(); !strings.Contains(message, "door/fail_test.tengo:5:") || !strings.Contains(message, "starts open") {
+		t.Fatalf("expected the failure at door/fail_test.tengo:5, got %q", message)
+	}
+}
+
+// bossArena adds what the boss's phase_1 intro reaches for besides the
+// camera and player: a level with the spikes layers it toggles.
+func bossArena(t *testing.T, h *Harness) {
+	t.Helper()
+	active, hidden := true, false
+	runtimeEnt := ecs.CreateEntity(h.World)
+	if err := ecs.Add(h.World, runtimeEnt, component.LevelRuntimeComponent.Kind(), &component.LevelRuntime{
+		Name: "boss.json",
+		Level: &levels.Level{
+			Layers:    [][]int{{0}, {0}},
+			LayerMeta: []levels.LayerMeta{{Name: "Hidden Spikes", Active: &hidden}, {Name: "Spikes Hider", Active: &active}},
+		},
+		TileSize:     32,
+		LoadedLayers: []bool{true, true},
+	}); err != nil {
+		t.Fatalf("add level runtime: %v", err)
+	}
+	gridEnt := ecs.CreateEntity(h.World)
+	if err := ecs.Add(h.World, gridEnt, component.LevelGridComponent.Kind(), &component.LevelGrid{TileSize: 32}); err != nil {
+		t.Fatalf("add level grid: %v", err)
+	}
+	if err := ecs.Add(h.World, gridEnt, component.StaticTileBatchStateComponent.Kind(), &component.StaticTileBatchState{}); err != nil {
+		t.Fatalf("add static tile batch state: %v", err)
+	}
+}
+
+func TestSnapshotRestoresBossPhase(t *testing.T) {
+	h := New()
+	bossArena(t, h)
+	if _, err := h.Spawn("camera.yaml"); err != nil {
+		t.Fatalf("spawn camera: %v", err)
+	}
+	if _, err := h.SpawnAt("player.yaml", 100, 0); err != nil {
+		t.Fatalf("spawn player: %v", err)
+	}
+	boss, err := h.SpawnAt("boss.yaml", 0, 0)
+	if err != nil {
+		t.Fatalf("spawn boss: %v", err)
+	}
+
+	for i := 0; h.State(boss) != "phase_1"; i++ {
+		if i == 10 {
+			t.Fatalf("expected the boss to start phase_1 with the player in range, got %v", h.Transitions(boss))
+		}
+		if err := h.Step(1); err != nil {
+			t.Fatalf("step: %v", err)
+		}
+	}
+	if err := h.Step(20); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	data, err := h.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	// The intro runs out and the boss moves on; after the restore it has to
+	// run out on the same frame again.
+	run := func() []string {
+		var states []string
+		for i := 0; i < 200; i++ {
+			if err := h.Step(1); err != nil {
+				t.Fatalf("step: %v", err)
+			}
+			states = append(states, h.State(boss))
+		}
+		return states
+	}
+	before := run()
+	if before[len(before)-1] == "phase_1" {
+		t.Fatalf("expected the boss to leave phase_1 once the intro ran out")
+	}
+
+	if err := h.Restore(data); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := h.State(boss); got != "phase_1" {
+		t.Fatalf("expected the boss back in phase_1, got %q", got)
+	}
+	if after := run(); !reflect.DeepEqual(after, before) {
+		t.Fatalf("expected the restored boss to play out the same\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+func TestSnapshotBringsBackKilledEnemiesAndGates(t *testing.T) {
+	h := New(system.NewPhysicsSystem(), system.NewGateSystem())
+	bossArena(t, h)
+	if _, err := h.Spawn("camera.yaml"); err != nil {
+		t.Fatalf("spawn camera: %v", err)
+	}
+	if _, err := h.SpawnAt("player.yaml", 600, 0); err != nil {
+		t.Fatalf("spawn player: %v", err)
+	}
+	enemy, err := h.SpawnAt("enemy.yaml", 0, 0)
+	if err != nil {
+		t.Fatalf("spawn enemy: %v", err)
+	}
+	gate, err := h.SpawnAt("gate.yaml", 200, 0)
+	if err != nil {
+		t.Fatalf("spawn gate: %v", err)
+	}
+	if err := h.Step(5); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	gateSprite := func() *component.Sprite {
+		t.Helper()
+		e, ok := h.Entity(gate)
+		if !ok {
+			t.Fatalf("expected the gate alive")
+		}
+		sprite, _ := ecs.Get(h.World, e, component.SpriteComponent.Kind())
+		if sprite == nil || sprite.Image == nil {
+			t.Fatalf("expected the gate's sprite with its image, got %+v", sprite)
+		}
+		return sprite
+	}
+	if !gateSprite().Disabled {
+		t.Fatalf("expected the gate to start open")
+	}
+	enemyState := h.State(enemy)
+	data, err := h.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	// Kill the enemy and close the gate, then go back.
+	e, _ := h.Entity(enemy)
+	ecs.DestroyEntity(h.World, e)
+	if err := h.Signal(gate, "activate"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if gateSprite().Disabled {
+		t.Fatalf("expected the gate closed")
+	}
+	if err := h.Restore(data); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	e, ok := h.Entity(enemy)
+	if !ok {
+		t.Fatalf("expected the enemy back")
+	}
+	if !ecs.Has(h.World, e, component.ScriptComponent.Kind()) || !ecs.Has(h.World, e, component.HealthComponent.Kind()) {
+		t.Fatalf("expected the enemy rebuilt from its prefab")
+	}
+	if got := h.State(enemy); got != enemyState {
+		t.Fatalf("expected the enemy back in %q, got %q", enemyState, got)
+	}
+	if !gateSprite().Disabled {
+		t.Fatalf("expected the gate open again")
+	}
+
+	// A gate that opens is destroyed, so a closed one restored over that
+	// has to be built again, and still open when told to.
+	if err := h.Signal(gate, "activate"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	closed, err := h.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := h.Signal(gate, "deactivate"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if _, ok := h.Entity(gate); ok {
+		t.Fatalf("expected the opened gate destroyed")
+	}
+	if err := h.Restore(closed); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if gateSprite().Disabled {
+		t.Fatalf("expected the gate back closed")
+	}
+	if err := h.Signal(gate, "deactivate"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if err := h.Step(2); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if _, ok := h.Entity(gate); ok {
+		t.Fatalf("expected the restored gate to open when told to")
+	}
+}
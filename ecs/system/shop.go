@@ -0,0 +1,433 @@
+package system
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// ShopSystem runs the buy screen opened by a dialogue's open_shop action. It
+// is scheduled before the dialogue system so the press that picked the
+// choice does not also buy the first item.
+type ShopSystem struct{}
+
+type shopViewItem struct {
+	// Index is the item's position in the shop's Items.
+	Index       int
+	Prefab      string
+	Name        string
+	Description string
+	Image       *ebiten.Image
+	Price       int
+	// Remaining is -1 for items without a stock limit.
+	Remaining int
+}
+
+func NewShopSystem() *ShopSystem {
+	return &ShopSystem{}
+}
+
+func IsShopActive(w *ecs.World) bool {
+	_, state, _, ok := shopUIState(w)
+	return ok && state != nil && state.Active
+}
+
+func (s *ShopSystem) Update(w *ecs.World) {
+	stateEnt, state, ui, ok := shopUIState(w)
+	if !ok || state == nil || ui == nil {
+		return
+	}
+
+	// The dialogue that opened the shop is still on screen until it closes.
+	if !state.Active || IsDialogueActive(w) {
+		hideShopUI(ui)
+		return
+	}
+
+	shopEntity := ecs.Entity(state.ShopEntity)
+	shop, ok := activeShop(w, shopEntity)
+	if !ok {
+		closeShop(state, ui)
+		_ = ecs.Add(w, stateEnt, component.ShopStateComponent.Kind(), state)
+		return
+	}
+
+	items := shopViewItems(w, shopEntity, shop)
+	if len(items) == 0 || state.SelectedIndex < 0 {
+		state.SelectedIndex = 0
+	} else if state.SelectedIndex >= len(items) {
+		state.SelectedIndex = len(items) - 1
+	}
+
+	if input := activePlayerInput(w); input != nil {
+		if input.MenuPressed {
+			closeShop(state, ui)
+			_ = ecs.Add(w, stateEnt, component.ShopStateComponent.Kind(), state)
+			return
+		}
+
+		navigateShop(state, input, len(items))
+	}
+
+	if pressed, _ := dialogueInputPressed(w); pressed && len(items) > 0 {
+		state.Message = buyShopItem(w, shopEntity, shop, items[state.SelectedIndex].Index)
+		items = shopViewItems(w, shopEntity, shop)
+	}
+
+	showShop(ui, shopTitle(w, shopEntity, shop), currentPlayerGearCount(w), items, state)
+	_ = ecs.Add(w, stateEnt, component.ShopStateComponent.Kind(), state)
+}
+
+// openShop shows the shop on shopEntity. It reports false when the entity
+// has no shop.
+func openShop(w *ecs.World, shopEntity ecs.Entity) bool {
+	if _, ok := activeShop(w, shopEntity); !ok {
+		return false
+	}
+
+	stateEnt, state, _, ok := shopUIState(w)
+	if !ok || state == nil {
+		return false
+	}
+
+	state.Active = true
+	state.ShopEntity = uint64(shopEntity)
+	state.SelectedIndex = 0
+	state.LastMoveX = 0
+	state.LastMoveY = 0
+	state.Message = ""
+	_ = ecs.Add(w, stateEnt, component.ShopStateComponent.Kind(), state)
+	return true
+}
+
+func shopUIState(w *ecs.World) (ecs.Entity, *component.ShopState, *component.ShopUI, bool) {
+	if w == nil {
+		return 0, nil, nil, false
+	}
+
+	ent, ok := ecs.First(w, component.ShopStateComponent.Kind())
+	if !ok {
+		return 0, nil, nil, false
+	}
+
+	state, ok := ecs.Get(w, ent, component.ShopStateComponent.Kind())
+	if !ok || state == nil {
+		return 0, nil, nil, false
+	}
+
+	ui, ok := ecs.Get(w, ent, component.ShopUIComponent.Kind())
+	if !ok || ui == nil {
+		return 0, nil, nil, false
+	}
+
+	return ent, state, ui, true
+}
+
+func activeShop(w *ecs.World, shopEntity ecs.Entity) (*component.Shop, bool) {
+	if w == nil || !shopEntity.Valid() || !ecs.IsAlive(w, shopEntity) {
+		return nil, false
+	}
+
+	shop, ok := ecs.Get(w, shopEntity, component.ShopComponent.Kind())
+	if !ok || shop == nil {
+		return nil, false
+	}
+
+	return shop, true
+}
+
+func shopTitle(w *ecs.World, shopEntity ecs.Entity, shop *component.Shop) string {
+	if shop != nil && shop.Title != "" {
+		return shop.Title
+	}
+	if dialogue, ok := ecs.Get(w, shopEntity, component.DialogueComponent.Kind()); ok && dialogue != nil && dialogue.Speaker != "" {
+		return dialogue.Speaker
+	}
+	return "Shop"
+}
+
+func shopViewItems(w *ecs.World, shopEntity ecs.Entity, shop *component.Shop) []shopViewItem {
+	if shop == nil || len(shop.Items) == 0 {
+		return nil
+	}
+
+	items := make([]shopViewItem, 0, len(shop.Items))
+	for index := range shop.Items {
+		entry := &shop.Items[index]
+		definition, err := resolveInventoryItemDefinition(entry.Prefab)
+		if err != nil || definition == nil {
+			continue
+		}
+		items = append(items, shopViewItem{
+			Index:       index,
+			Prefab:      definition.Prefab,
+			Name:        strings.TrimSpace(definition.Name),
+			Description: strings.TrimSpace(definition.Description),
+			Image:       definition.Image,
+			Price:       entry.Price,
+			Remaining:   shopItemRemaining(w, shopEntity, entry, index),
+		})
+	}
+	return items
+}
+
+// buyShopItem spends gears on one of shop.Items[index] and returns the line
+// shown under the item details.
+func buyShopItem(w *ecs.World, shopEntity ecs.Entity, shop *component.Shop, index int) string {
+	if shop == nil || index < 0 || index >= len(shop.Items) {
+		return ""
+	}
+
+	item := &shop.Items[index]
+	if shopItemRemaining(w, shopEntity, item, index) == 0 {
+		return "Sold out."
+	}
+
+	gears := ensurePlayerGearCount(w)
+	if gears == nil {
+		return ""
+	}
+	if gears.Count < item.Price {
+		return "Not enough gears."
+	}
+
+	inventory := ensurePlayerInventory(w)
+	if inventory == nil {
+		return ""
+	}
+
+	gears.Count -= item.Price
+	addInventoryItem(inventory, &component.InventoryItem{Prefab: item.Prefab, Count: 1})
+	recordShopPurchase(w, shopEntity, item, index)
+
+	name := "item"
+	if definition, err := resolveInventoryItemDefinition(item.Prefab); err == nil && definition != nil && definition.Name != "" {
+		name = definition.Name
+	}
+	return "Bought " + name + "."
+}
+
+// shopPurchaseKey is the level entity state key of the shop followed by the
+// index of the entry in its item list, or empty when the shop has no game
+// entity id. Entries selling the same prefab keep separate stock.
+func shopPurchaseKey(w *ecs.World, shopEntity ecs.Entity, index int) string {
+	key := levelEntityStateKeyForEntity(w, shopEntity)
+	if key == "" || index < 0 {
+		return ""
+	}
+	return key + "#" + strconv.Itoa(index)
+}
+
+func shopItemSold(w *ecs.World, shopEntity ecs.Entity, item *component.ShopItem, index int) int {
+	key := shopPurchaseKey(w, shopEntity, index)
+	if key == "" {
+		return item.Sold
+	}
+
+	player, ok := ecs.First(w, component.PlayerTagComponent.Kind())
+	if !ok {
+		return 0
+	}
+	stateMap, ok := ecs.Get(w, player, component.LevelEntityStateMapComponent.Kind())
+	if !ok || stateMap == nil {
+		return 0
+	}
+	return stateMap.Purchased[key]
+}
+
+func shopItemRemaining(w *ecs.World, shopEntity ecs.Entity, item *component.ShopItem, index int) int {
+	if item.Stock <= 0 {
+		return -1
+	}
+	return max(0, item.Stock-shopItemSold(w, shopEntity, item, index))
+}
+
+func recordShopPurchase(w *ecs.World, shopEntity ecs.Entity, item *component.ShopItem, index int) {
+	key := shopPurchaseKey(w, shopEntity, index)
+	if key == "" {
+		item.Sold++
+		return
+	}
+
+	stateMap := ensurePlayerLevelEntityStateMap(w)
+	if stateMap == nil {
+		item.Sold++
+		return
+	}
+	if stateMap.Purchased == nil {
+		stateMap.Purchased = make(map[string]int)
+	}
+	stateMap.Purchased[key]++
+}
+
+func navigateShop(state *component.ShopState, input *component.Input, itemCount int) {
+	if itemCount <= 0 {
+		state.LastMoveX = 0
+		state.LastMoveY = 0
+		return
+	}
+
+	moveX := inventoryAxisDirection(input.MoveX)
+	moveY := inventoryAxisDirection(input.MoveY)
+
+	if moveX != 0 && moveX != state.LastMoveX {
+		state.SelectedIndex = moveInventorySelection(state.SelectedIndex, moveX, 0, itemCount)
+		state.Message = ""
+	}
+	if moveY != 0 && moveY != state.LastMoveY {
+		state.SelectedIndex = moveInventorySelection(state.SelectedIndex, 0, moveY, itemCount)
+		state.Message = ""
+	}
+
+	state.LastMoveX = moveX
+	state.LastMoveY = moveY
+}
+
+func closeShop(state *component.ShopState, ui *component.ShopUI) {
+	if state != nil {
+		state.Active = false
+		state.ShopEntity = 0
+		state.SelectedIndex = 0
+		state.LastMoveX = 0
+		state.LastMoveY = 0
+		state.Message = ""
+	}
+	hideShopUI(ui)
+}
+
+func hideShopUI(ui *component.ShopUI) {
+	if ui == nil {
+		return
+	}
+	if ui.DetailText != nil {
+		ui.DetailText.Label = ""
+	}
+	if ui.Message != nil {
+		ui.Message.Label = ""
+	}
+	if ui.DetailImage != nil {
+		setWidgetVisible(ui.DetailImage, false)
+	}
+	if ui.GridHost != nil {
+		ui.GridHost.RemoveChildren()
+	}
+	setWidgetVisible(ui.Overlay, false)
+	requestShopUIRelayout(ui)
+}
+
+func showShop(ui *component.ShopUI, title string, gears int, items []shopViewItem, state *component.ShopState) {
+	if ui == nil || state == nil {
+		return
+	}
+	if ui.Title != nil {
+		ui.Title.Label = title
+	}
+	if ui.Gears != nil {
+		ui.Gears.Label = "Gears: " + itoa(gears)
+	}
+	if ui.GridHost != nil {
+		ui.GridHost.RemoveChildren()
+		if len(items) == 0 {
+			empty := widget.NewText(
+				widget.TextOpts.Text("Nothing for sale.", inventoryTextFaceRef(), color.NRGBA{R: 181, G: 190, B: 198, A: 255}),
+				widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+				widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{HorizontalPosition: widget.AnchorLayoutPositionCenter, VerticalPosition: widget.AnchorLayoutPositionCenter})),
+			)
+			ui.GridHost.AddChild(empty)
+		} else {
+			totalSlots := max(len(items), inventoryGridVisibleSlots)
+			grid := widget.NewContainer(
+				widget.ContainerOpts.Layout(widget.NewGridLayout(
+					widget.GridLayoutOpts.Columns(inventoryGridColumns),
+					widget.GridLayoutOpts.Spacing(inventoryCellSpacing, inventoryCellSpacing),
+				)),
+			)
+			for index := 0; index < totalSlots; index++ {
+				if index < len(items) {
+					grid.AddChild(buildItemCell(items[index].Image, shopPriceLabel(items[index]), index == state.SelectedIndex, true))
+					continue
+				}
+				grid.AddChild(buildItemCell(nil, "", false, false))
+			}
+			ui.GridHost.AddChild(grid)
+		}
+	}
+
+	if state.SelectedIndex >= 0 && state.SelectedIndex < len(items) {
+		selected := items[state.SelectedIndex]
+		if ui.DetailImage != nil {
+			if selected.Image != nil {
+				ui.DetailImage.Image = scaleInventoryImage(selected.Image, 1.5)
+				setWidgetVisible(ui.DetailImage, true)
+			} else {
+				setWidgetVisible(ui.DetailImage, false)
+			}
+		}
+		if ui.DetailText != nil {
+			ui.DetailText.Label = shopDetailText(selected)
+		}
+	} else {
+		if ui.DetailImage != nil {
+			setWidgetVisible(ui.DetailImage, false)
+		}
+		if ui.DetailText != nil {
+			ui.DetailText.Label = ""
+		}
+	}
+	if ui.Message != nil {
+		ui.Message.Label = state.Message
+	}
+
+	setWidgetVisible(ui.Overlay, true)
+	requestShopUIRelayout(ui)
+}
+
+func shopPriceLabel(item shopViewItem) string {
+	switch {
+	case item.Remaining == 0:
+		return "Sold out"
+	case item.Price == 0:
+		return "Free"
+	case item.Price == 1:
+		return "1 gear"
+	}
+	return itoa(item.Price) + " gears"
+}
+
+func shopDetailText(item shopViewItem) string {
+	lines := []string{item.Name}
+	if item.Description != "" {
+		lines = append(lines, item.Description)
+	}
+	lines = append(lines, "", "Price: "+shopPriceLabel(item))
+	if item.Remaining > 0 {
+		lines = append(lines, "In stock: "+itoa(item.Remaining))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func requestShopUIRelayout(ui *component.ShopUI) {
+	if ui == nil {
+		return
+	}
+	if ui.Root != nil {
+		ui.Root.RequestRelayout()
+	}
+	if ui.Overlay != nil {
+		ui.Overlay.RequestRelayout()
+	}
+	if ui.Panel != nil {
+		ui.Panel.RequestRelayout()
+	}
+	if ui.GridHost != nil {
+		ui.GridHost.RequestRelayout()
+	}
+	if ui.DetailPanel != nil {
+		ui.DetailPanel.RequestRelayout()
+	}
+}
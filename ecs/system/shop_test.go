@@ -0,0 +1,194 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
+)
+
+func TestShopSystemBuysWithGearsAndRecordsStock(t *testing.T) {
+	w := ecs.NewWorld()
+
+	uiEnt, err := entity.NewUIRoot(w)
+	if err != nil {
+		t.Fatalf("new ui root: %v", err)
+	}
+
+	level := ecs.CreateEntity(w)
+	if err := ecs.Add(w, level, component.LevelRuntimeComponent.Kind(), &component.LevelRuntime{Name: "dump_market.json"}); err != nil {
+		t.Fatalf("add level runtime: %v", err)
+	}
+
+	player := ecs.CreateEntity(w)
+	if err := ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{}); err != nil {
+		t.Fatalf("add player tag: %v", err)
+	}
+	if err := ecs.Add(w, player, component.InventoryComponent.Kind(), &component.Inventory{}); err != nil {
+		t.Fatalf("add inventory: %v", err)
+	}
+	input := &component.Input{}
+	if err := ecs.Add(w, player, component.InputComponent.Kind(), input); err != nil {
+		t.Fatalf("add input: %v", err)
+	}
+	ensurePlayerGearCount(w).Count = 12
+
+	merchant := ecs.CreateEntity(w)
+	if err := ecs.Add(w, merchant, component.GameEntityIDComponent.Kind(), &component.GameEntityID{Value: "merchant"}); err != nil {
+		t.Fatalf("add game entity id: %v", err)
+	}
+	if err := ecs.Add(w, merchant, component.DialogueComponent.Kind(), &component.Dialogue{Speaker: "Shopkeep", Lines: []string{"Buy something."}}); err != nil {
+		t.Fatalf("add dialogue: %v", err)
+	}
+	if err := ecs.Add(w, merchant, component.ShopComponent.Kind(), &component.Shop{Items: []component.ShopItem{
+		{Prefab: "item_healing_flask.yaml", Price: 5, Stock: 1},
+		{Prefab: "item_wrench.yaml", Price: 50},
+	}}); err != nil {
+		t.Fatalf("add shop: %v", err)
+	}
+
+	runDialogueActions(w, merchant, []component.DialogueAction{{OpenShop: true}})
+	if !IsShopActive(w) {
+		t.Fatal("expected the open_shop action to open the shop")
+	}
+
+	ui, _ := ecs.Get(w, uiEnt, component.ShopUIComponent.Kind())
+	state, _ := ecs.Get(w, uiEnt, component.ShopStateComponent.Kind())
+	dialogueInput, _ := ecs.Get(w, uiEnt, component.DialogueInputComponent.Kind())
+	if ui == nil || state == nil || dialogueInput == nil {
+		t.Fatal("expected shop ui, shop state and dialogue input on the ui root")
+	}
+
+	system := NewShopSystem()
+	system.Update(w)
+	if ui.Overlay.GetWidget().Visibility != widget.Visibility_Show {
+		t.Fatal("expected shop overlay to be visible")
+	}
+	if ui.Title.Label != "Shopkeep" {
+		t.Fatalf("expected the shop to fall back to the speaker's name, got %q", ui.Title.Label)
+	}
+
+	dialogueInput.Pressed = true
+	system.Update(w)
+
+	if got := currentPlayerGearCount(w); got != 7 {
+		t.Fatalf("expected the flask to cost 5 of 12 gears, got %d left", got)
+	}
+	if got := inventoryItemCount(w, "item_healing_flask.yaml"); got != 1 {
+		t.Fatalf("expected one flask in the inventory, got %d", got)
+	}
+	stateMap, _ := ecs.Get(w, player, component.LevelEntityStateMapComponent.Kind())
+	if stateMap == nil || stateMap.Purchased["dump_market.json#merchant#0"] != 1 {
+		t.Fatalf("expected the purchase to be recorded on the player, got %+v", stateMap)
+	}
+
+	system.Update(w)
+	if state.Message != "Sold out." || currentPlayerGearCount(w) != 7 {
+		t.Fatalf("expected the second flask to be sold out, got message %q and %d gears", state.Message, currentPlayerGearCount(w))
+	}
+
+	dialogueInput.Pressed = false
+	input.MoveX = 1
+	system.Update(w)
+	if state.SelectedIndex != 1 {
+		t.Fatalf("expected moving right to select the wrench, got %d", state.SelectedIndex)
+	}
+
+	input.MoveX = 0
+	dialogueInput.Pressed = true
+	system.Update(w)
+	if state.Message != "Not enough gears." || inventoryItemCount(w, "item_wrench.yaml") != 0 {
+		t.Fatalf("expected the wrench to be unaffordable, got message %q", state.Message)
+	}
+
+	dialogueInput.Pressed = false
+	input.MenuPressed = true
+	system.Update(w)
+	if IsShopActive(w) {
+		t.Fatal("expected the menu button to close the shop")
+	}
+	if ui.Overlay.GetWidget().Visibility != widget.Visibility_Hide {
+		t.Fatal("expected shop overlay to be hidden after close")
+	}
+}
+
+func TestShopSystemWaitsForDialogueToClose(t *testing.T) {
+	w := ecs.NewWorld()
+
+	uiEnt, err := entity.NewUIRoot(w)
+	if err != nil {
+		t.Fatalf("new ui root: %v", err)
+	}
+
+	merchant := ecs.CreateEntity(w)
+	if err := ecs.Add(w, merchant, component.ShopComponent.Kind(), &component.Shop{Items: []component.ShopItem{{Prefab: "item_healing_flask.yaml", Price: 5}}}); err != nil {
+		t.Fatalf("add shop: %v", err)
+	}
+	if !openShop(w, merchant) {
+		t.Fatal("expected the shop to open")
+	}
+
+	dialogueState, _ := ecs.Get(w, uiEnt, component.DialogueStateComponent.Kind())
+	dialogueState.Active = true
+
+	NewShopSystem().Update(w)
+
+	ui, _ := ecs.Get(w, uiEnt, component.ShopUIComponent.Kind())
+	if ui.Overlay.GetWidget().Visibility != widget.Visibility_Hide {
+		t.Fatal("expected the shop to stay hidden behind an active dialogue")
+	}
+	if !IsShopActive(w) {
+		t.Fatal("expected the shop to stay open until the dialogue closes")
+	}
+}
+
+func TestShopSystemKeepsStockPerEntryForDuplicatePrefabs(t *testing.T) {
+	w := ecs.NewWorld()
+
+	level := ecs.CreateEntity(w)
+	if err := ecs.Add(w, level, component.LevelRuntimeComponent.Kind(), &component.LevelRuntime{Name: "dump_market.json"}); err != nil {
+		t.Fatalf("add level runtime: %v", err)
+	}
+
+	player := ecs.CreateEntity(w)
+	if err := ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{}); err != nil {
+		t.Fatalf("add player tag: %v", err)
+	}
+	if err := ecs.Add(w, player, component.InventoryComponent.Kind(), &component.Inventory{}); err != nil {
+		t.Fatalf("add inventory: %v", err)
+	}
+	ensurePlayerGearCount(w).Count = 20
+
+	merchant := ecs.CreateEntity(w)
+	if err := ecs.Add(w, merchant, component.GameEntityIDComponent.Kind(), &component.GameEntityID{Value: "merchant"}); err != nil {
+		t.Fatalf("add game entity id: %v", err)
+	}
+	shop := &component.Shop{Items: []component.ShopItem{
+		{Prefab: "item_healing_flask.yaml", Price: 5, Stock: 1},
+		{Prefab: "item_healing_flask.yaml", Price: 8, Stock: 2},
+	}}
+	if err := ecs.Add(w, merchant, component.ShopComponent.Kind(), shop); err != nil {
+		t.Fatalf("add shop: %v", err)
+	}
+
+	if got := buyShopItem(w, merchant, shop, 0); got == "Sold out." {
+		t.Fatalf("expected the first flask entry to sell, got %q", got)
+	}
+	if got := buyShopItem(w, merchant, shop, 0); got != "Sold out." {
+		t.Fatalf("expected the first flask entry to sell out, got %q", got)
+	}
+	if got := shopItemRemaining(w, merchant, &shop.Items[1], 1); got != 2 {
+		t.Fatalf("expected the second flask entry to keep its stock of 2, got %d", got)
+	}
+	if got := buyShopItem(w, merchant, shop, 1); got == "Sold out." {
+		t.Fatalf("expected the second flask entry to sell, got %q", got)
+	}
+	if got := shopItemRemaining(w, merchant, &shop.Items[1], 1); got != 1 {
+		t.Fatalf("expected one flask left on the second entry, got %d", got)
+	}
+	if got := currentPlayerGearCount(w); got != 7 {
+		t.Fatalf("expected 13 of 20 gears spent, got %d left", got)
+	}
+}
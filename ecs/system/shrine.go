@@ -56,14 +56,10 @@ func queueShrineHeal(w *ecs.World) {
 		return
 	}
 
-	player, ok := ecs.First(w, component.PlayerTagComponent.Kind())
-	if !ok {
-		return
-	}
-	if ecs.Has(w, player, component.ShrineHealRequestComponent.Kind()) {
+	if _, ok := ecs.First(w, component.PlayerTagComponent.Kind()); !ok {
 		return
 	}
-	_ = ecs.Add(w, player, component.ShrineHealRequestComponent.Kind(), &component.ShrineHealRequest{})
+	ecs.Emit(w, component.ShrineHealRequest{})
 }
 
 func applyShrineEffects(w *ecs.World, player ecs.Entity) {
@@ -94,9 +94,8 @@ func TestShrineSystemQueuesShrineHealRequest(t *testing.T) {
 		t.Fatal("expected safe respawn update to wait for shrine animation completion")
 	}
 
-	req, ok := ecs.Get(w, player, component.ShrineHealRequestComponent.Kind())
-	if !ok || req == nil {
-		t.Fatal("expected shrine heal request on the player")
+	if got := ecs.Events[component.ShrineHealRequest](w); len(got) != 1 {
+		t.Fatalf("expected one shrine heal request, got %d", len(got))
 	}
 
 	if _, ok := ecs.First(w, component.EnemyRespawnRequestComponent.Kind()); ok {
@@ -5,7 +5,9 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type SpriteFadeOutSystem struct{}
+type SpriteFadeOutSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewSpriteFadeOutSystem() *SpriteFadeOutSystem { return &SpriteFadeOutSystem{} }
 
@@ -24,7 +26,7 @@ func (s *SpriteFadeOutSystem) Update(w *ecs.World) {
 		}
 		if fade.TotalFrames <= 0 {
 			markStaticTileBatchDirty(w, e)
-			_ = ecs.Remove(w, e, component.SpriteFadeOutComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.SpriteFadeOutComponent.Kind())
 			return
 		}
 
@@ -32,10 +34,11 @@ func (s *SpriteFadeOutSystem) Update(w *ecs.World) {
 		fade.Frames--
 		if fade.Frames < 0 {
 			markStaticTileBatchDirty(w, e)
-			_ = ecs.Remove(w, e, component.SpriteFadeOutComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.SpriteFadeOutComponent.Kind())
 			return
 		}
 
 		fade.Alpha = clampColor01(float64(fade.Frames) / float64(fade.TotalFrames))
 	})
+	s.commands.Apply(w)
 }
@@ -1,15 +1,29 @@
 package system
 
 import (
-	"math/rand"
-
+	"github.com/milk9111/sidescroller/common"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type SpriteShakeSystem struct{}
+type SpriteShakeSystem struct {
+	commands       ecs.CommandBuffer
+	intensityScale float64
+}
+
+func NewSpriteShakeSystem() *SpriteShakeSystem { return &SpriteShakeSystem{intensityScale: 1} }
 
-func NewSpriteShakeSystem() *SpriteShakeSystem { return &SpriteShakeSystem{} }
+// SetIntensityScale applies the player's screen-shake intensity setting to
+// sprite shakes; 0 keeps shaken sprites still.
+func (s *SpriteShakeSystem) SetIntensityScale(scale float64) {
+	if s == nil {
+		return
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	s.intensityScale = scale
+}
 
 func (s *SpriteShakeSystem) Update(w *ecs.World) {
 	if w == nil {
@@ -23,19 +37,20 @@ func (s *SpriteShakeSystem) Update(w *ecs.World) {
 
 		if shake.Frames <= 0 || shake.Intensity <= 0 {
 			markStaticTileBatchDirty(w, e)
-			_ = ecs.Remove(w, e, component.SpriteShakeComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.SpriteShakeComponent.Kind())
 			return
 		}
 
 		markStaticTileBatchDirty(w, e)
-		shake.OffsetX = (rand.Float64()*2 - 1) * shake.Intensity
-		shake.OffsetY = (rand.Float64()*2 - 1) * shake.Intensity
+		shake.OffsetX = (common.Rand().Float64()*2 - 1) * shake.Intensity * s.intensityScale
+		shake.OffsetY = (common.Rand().Float64()*2 - 1) * shake.Intensity * s.intensityScale
 		shake.Frames--
 		if shake.Frames <= 0 {
 			markStaticTileBatchDirty(w, e)
-			_ = ecs.Remove(w, e, component.SpriteShakeComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.SpriteShakeComponent.Kind())
 		}
 	})
+	s.commands.Apply(w)
 }
 
 func markStaticTileBatchDirty(w *ecs.World, e ecs.Entity) {
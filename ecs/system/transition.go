@@ -33,12 +33,11 @@ func (ts *TransitionSystem) Update(w *ecs.World) {
 		case component.TransitionFadeOut:
 			rt.Alpha = 1 - float64(rt.Timer)/float64(transitionFadeFrames)
 			if rt.Timer <= 0 && !rt.ReqSent {
-				// Send the LevelChangeRequest into the world so the Game loop
-				// can perform the IO reload. Keep the runtime alive while we
+				// Emit the LevelChangeRequest so the persistence system can
+				// perform the IO reload. Keep the runtime alive while we
 				// wait for the outer loop to finish loading (signalled by
 				// LevelLoadedComponent.Kind()).
-				reqEnt := ecs.CreateEntity(w)
-				_ = ecs.Add(w, reqEnt, component.LevelChangeRequestComponent.Kind(), &rt.Req)
+				ecs.Emit(w, rt.Req)
 				rt.ReqSent = true
 			}
 
@@ -1,20 +1,33 @@
 package system
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/controls"
+	"github.com/milk9111/sidescroller/internal/replay"
 )
 
-const transitionUpAxisThreshold = -0.5
+const transitionUpAxisThreshold = 0.5
 
 type TransitionInputSystem struct {
 	stickUpLastFrame bool
+	feed             *replay.Feed
+	bindings         *controls.Map
 }
 
 func NewTransitionInputSystem() *TransitionInputSystem {
-	return &TransitionInputSystem{}
+	return &TransitionInputSystem{bindings: controls.DefaultMap()}
+}
+
+func (s *TransitionInputSystem) SetBindings(bindings *controls.Map) {
+	if bindings == nil {
+		bindings = controls.DefaultMap()
+	}
+	s.bindings = bindings
+}
+
+func (s *TransitionInputSystem) SetFeed(feed *replay.Feed) {
+	s.feed = feed
 }
 
 func (s *TransitionInputSystem) Update(w *ecs.World) {
@@ -22,22 +35,17 @@ func (s *TransitionInputSystem) Update(w *ecs.World) {
 		return
 	}
 
-	pressed := inpututil.IsKeyJustPressed(ebiten.KeyW) || inpututil.IsKeyJustPressed(ebiten.KeyArrowUp)
-	usingGamepad := false
-	stickUp := false
-
-	if gamepads := ebiten.GamepadIDs(); len(gamepads) > 0 {
-		usingGamepad = true
-		id := gamepads[0]
-		pressed = pressed || inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonLeftTop)
-		stickUp = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical) <= transitionUpAxisThreshold
-		if stickUp && !s.stickUpLastFrame {
-			pressed = true
+	var pressed, usingGamepad bool
+	if s.feed != nil && s.feed.Replaying {
+		pressed = s.feed.Frame.Transition.UpPressed
+		usingGamepad = s.feed.Frame.Transition.UsingGamepad
+	} else {
+		pressed, usingGamepad = s.poll()
+		if s.feed != nil {
+			s.feed.Frame.Transition = component.TransitionInput{UpPressed: pressed, UsingGamepad: usingGamepad}
 		}
 	}
 
-	s.stickUpLastFrame = stickUp
-
 	ecs.ForEach(w, component.TransitionInputComponent.Kind(), func(_ ecs.Entity, input *component.TransitionInput) {
 		if input == nil {
 			return
@@ -46,3 +54,20 @@ func (s *TransitionInputSystem) Update(w *ecs.World) {
 		input.UsingGamepad = usingGamepad
 	})
 }
+
+func (s *TransitionInputSystem) poll() (bool, bool) {
+	pad := controls.FirstGamepad()
+	pressed := s.bindings.JustPressed(controls.ActionEnter, pad)
+	usingGamepad := pad.Connected
+	stickUp := false
+
+	if usingGamepad {
+		stickUp = s.bindings.Axis(controls.ActionEnter, pad) >= transitionUpAxisThreshold
+		if stickUp && !s.stickUpLastFrame {
+			pressed = true
+		}
+	}
+
+	s.stickUpLastFrame = stickUp
+	return pressed, usingGamepad
+}
@@ -8,7 +8,9 @@ import (
 
 // TransitionPopSystem processes `TransitionPop` components, applies the launch
 // once, and keeps the component present until the player is grounded again.
-type TransitionPopSystem struct{}
+type TransitionPopSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewTransitionPopSystem() *TransitionPopSystem { return &TransitionPopSystem{} }
 
@@ -57,7 +59,8 @@ func (s *TransitionPopSystem) Update(w *ecs.World) {
 		}
 
 		if pop.Airborne {
-			_ = ecs.Remove(w, e, component.TransitionPopComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.TransitionPopComponent.Kind())
 		}
 	})
+	s.commands.Apply(w)
 }
@@ -3,6 +3,7 @@ package system
 import (
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
 )
 
 type TriggerSystem struct{}
@@ -32,7 +33,18 @@ func (ts *TriggerSystem) Update(w *ecs.World) {
 			return
 		}
 
-		if EmitEntitySignal(w, ent, player, "on_trigger_entered") {
+		if trigger.Flag != "" && !playerFlag(w, trigger.Flag) {
+			return
+		}
+		if trigger.NoFlag != "" && playerFlag(w, trigger.NoFlag) {
+			return
+		}
+
+		fired := EmitEntitySignal(w, ent, player, "on_trigger_entered")
+		if trigger.SetFlag != "" {
+			fired = entity.SetPlayerFlag(w, trigger.SetFlag, component.BoolFlag(true)) || fired
+		}
+		if fired {
 			recordLevelEntityState(w, ent, component.PersistedLevelEntityStateUsed)
 			trigger.Disabled = true
 		}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
 )
 
 func TestTriggerSystemEmitsSignalAndDisablesTrigger(t *testing.T) {
@@ -51,3 +52,39 @@ func TestTriggerSystemEmitsSignalAndDisablesTrigger(t *testing.T) {
 		t.Fatalf("expected disabled trigger to not enqueue again, got %d events", len(queue.Events))
 	}
 }
+
+func TestTriggerSystemWaitsForFlagAndSetsFlagWhenFired(t *testing.T) {
+	w := ecs.NewWorld()
+
+	player := ecs.CreateEntity(w)
+	_ = ecs.Add(w, player, component.PlayerTagComponent.Kind(), &component.PlayerTag{})
+	_ = ecs.Add(w, player, component.TransformComponent.Kind(), &component.Transform{X: 16, Y: 16})
+	_ = ecs.Add(w, player, component.PhysicsBodyComponent.Kind(), &component.PhysicsBody{Width: 32, Height: 32})
+
+	triggerEntity := ecs.CreateEntity(w)
+	_ = ecs.Add(w, triggerEntity, component.TransformComponent.Kind(), &component.Transform{})
+	_ = ecs.Add(w, triggerEntity, component.TriggerComponent.Kind(), &component.Trigger{
+		Name:    "gated",
+		Bounds:  component.AABB{W: 32, H: 32},
+		Flag:    "met_merchant",
+		SetFlag: "saw_spikes",
+	})
+
+	system := NewTriggerSystem()
+	system.Update(w)
+
+	trigger, _ := ecs.Get(w, triggerEntity, component.TriggerComponent.Kind())
+	if trigger.Disabled || playerFlag(w, "saw_spikes") {
+		t.Fatal("expected trigger to wait for met_merchant")
+	}
+
+	entity.SetPlayerFlag(w, "met_merchant", component.BoolFlag(true))
+	system.Update(w)
+
+	if !trigger.Disabled {
+		t.Fatal("expected trigger to fire once met_merchant is set")
+	}
+	if !playerFlag(w, "saw_spikes") {
+		t.Fatal("expected trigger to set saw_spikes")
+	}
+}
@@ -7,7 +7,9 @@ import (
 
 // TTLSystem decrements frame-based TTL components and destroys entities when
 // the TTL reaches zero.
-type TTLSystem struct{}
+type TTLSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewTTLSystem() *TTLSystem {
 	return &TTLSystem{}
@@ -32,6 +34,7 @@ func (s *TTLSystem) Update(w *ecs.World) {
 		}
 
 		// TTL expired: destroy the entity
-		ecs.DestroyEntity(w, e)
+		s.commands.Destroy(e)
 	})
+	s.commands.Apply(w)
 }
@@ -5,7 +5,9 @@ import (
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
-type WhiteFlashSystem struct{}
+type WhiteFlashSystem struct {
+	commands ecs.CommandBuffer
+}
 
 func NewWhiteFlashSystem() *WhiteFlashSystem { return &WhiteFlashSystem{} }
 
@@ -32,9 +34,10 @@ func (s *WhiteFlashSystem) Update(w *ecs.World) {
 		}
 
 		if wf.Frames <= 0 {
-			_ = ecs.Remove(w, e, component.WhiteFlashComponent.Kind())
+			ecs.DeferRemove(&s.commands, e, component.WhiteFlashComponent.Kind())
 		} else {
 			//_ = ecs.Add(w, e, component.WhiteFlashComponent.Kind(), wf)
 		}
 	})
+	s.commands.Apply(w)
 }
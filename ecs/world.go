@@ -2,16 +2,21 @@ package ecs
 
 import (
 	"errors"
+	"reflect"
 
 	"github.com/milk9111/sidescroller/ecs/component"
 )
 
 var errSparseComponentTypeMismatch = errors.New("ecs: component type mismatch")
 
+var errStoreChangedDuringIteration = errors.New("ecs: entity added to or removed from a store while iterating it; record the change in a CommandBuffer")
+
 type sparseSet[T any] struct {
 	sparse []int
 	dense  []entityID
 	data   []*T
+	// walkers counts the loops walking dense, when checkIteration is on.
+	walkers int
 }
 
 func (s *sparseSet[T]) len() int {
@@ -51,6 +56,7 @@ func (s *sparseSet[T]) set(id entityID, value *T) {
 		return
 	}
 
+	s.checkNotWalked()
 	s.sparse[int(id)] = len(s.dense)
 	s.dense = append(s.dense, id)
 	s.data = append(s.data, value)
@@ -65,6 +71,7 @@ func (s *sparseSet[T]) remove(id entityID) bool {
 	if denseIndex < 0 || denseIndex >= len(s.dense) || s.dense[denseIndex] != id {
 		return false
 	}
+	s.checkNotWalked()
 
 	last := len(s.dense) - 1
 	lastID := s.dense[last]
@@ -82,6 +89,26 @@ func (s *sparseSet[T]) remove(id entityID) bool {
 	return true
 }
 
+func (s *sparseSet[T]) beginWalk() {
+	if checkIteration {
+		s.walkers++
+	}
+}
+
+func (s *sparseSet[T]) endWalk() {
+	if checkIteration {
+		s.walkers--
+	}
+}
+
+// checkNotWalked panics when the set is about to gain or lose an entity under
+// a loop walking it, which would make the loop skip or repeat entities.
+func (s *sparseSet[T]) checkNotWalked() {
+	if checkIteration && s.walkers > 0 {
+		panic(errStoreChangedDuringIteration)
+	}
+}
+
 func (s *sparseSet[T]) ensureSparse(id entityID) {
 	required := int(id) + 1
 	if required <= len(s.sparse) {
@@ -123,11 +150,21 @@ func (s *sparseComponentStore[T]) set(id entityID, value *T) {
 	s.items.set(id, value)
 }
 
+func (s *sparseComponentStore[T]) beginWalk() {
+	s.items.beginWalk()
+}
+
+func (s *sparseComponentStore[T]) endWalk() {
+	s.items.endWalk()
+}
+
 type componentStore interface {
 	len() int
 	ids() []entityID
 	remove(entityID) bool
 	has(id entityID) bool
+	beginWalk()
+	endWalk()
 }
 
 type World struct {
@@ -136,6 +173,7 @@ type World struct {
 	generations []generation
 	alive       sparseSet[struct{}]
 	components  map[component.ComponentID]any
+	events      map[reflect.Type]eventBuffer
 }
 
 func NewWorld() *World {
@@ -296,6 +334,8 @@ func ForEach[A any](w *World, k component.ComponentKind[A], fn func(Entity, *A))
 		return
 	}
 
+	s.beginWalk()
+	defer s.endWalk()
 	for _, id := range s.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -330,6 +370,8 @@ func ForEach2[A, B any](w *World, k1 component.ComponentKind[A], k2 component.Co
 
 	// iterate the smaller store
 	if s2.len() < s1.len() {
+		s2.beginWalk()
+		defer s2.endWalk()
 		for _, id := range s2.ids() {
 			if !w.alive.has(id) {
 				continue
@@ -344,6 +386,8 @@ func ForEach2[A, B any](w *World, k1 component.ComponentKind[A], k2 component.Co
 		return
 	}
 
+	s1.beginWalk()
+	defer s1.endWalk()
 	for _, id := range s1.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -406,6 +450,8 @@ func ForEach3[A, B, C any](w *World, k1 component.ComponentKind[A], k2 component
 		others = append(others, stores[i])
 	}
 
+	base.beginWalk()
+	defer base.endWalk()
 	for _, id := range base.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -486,6 +532,8 @@ func ForEach4[A, B, C, D any](w *World, k1 component.ComponentKind[A], k2 compon
 		others = append(others, stores[i])
 	}
 
+	base.beginWalk()
+	defer base.endWalk()
 	for _, id := range base.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -595,6 +643,8 @@ func ForEach7[A, B, C, D, E, F, G any](w *World, k1 component.ComponentKind[A],
 		others = append(others, stores[i])
 	}
 
+	base.beginWalk()
+	defer base.endWalk()
 	for _, id := range base.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -716,6 +766,8 @@ func ForEach8[A, B, C, D, E, F, G, H any](w *World, k1 component.ComponentKind[A
 		others = append(others, stores[i])
 	}
 
+	base.beginWalk()
+	defer base.endWalk()
 	for _, id := range base.ids() {
 		if !w.alive.has(id) {
 			continue
@@ -0,0 +1,382 @@
+package controls
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Action names a gameplay input that can be bound to keys, mouse buttons and
+// gamepad buttons or stick directions.
+type Action string
+
+const (
+	ActionMoveLeft   Action = "move_left"
+	ActionMoveRight  Action = "move_right"
+	ActionMoveUp     Action = "move_up"
+	ActionMoveDown   Action = "move_down"
+	ActionLookUp     Action = "look_up"
+	ActionLookDown   Action = "look_down"
+	ActionJump       Action = "jump"
+	ActionAttack     Action = "attack"
+	ActionAim        Action = "aim"
+	ActionFireAnchor Action = "fire_anchor"
+	ActionAnchor     Action = "anchor"
+	ActionAutoAnchor Action = "auto_anchor"
+	ActionReelIn     Action = "reel_in"
+	ActionReelOut    Action = "reel_out"
+	ActionHeal       Action = "heal"
+	ActionMenu       Action = "menu"
+	ActionPause      Action = "pause"
+	ActionInteract   Action = "interact"
+	ActionEnter      Action = "enter"
+)
+
+// Actions lists every bindable action in the order the controls screen shows them.
+var Actions = []Action{
+	ActionMoveLeft,
+	ActionMoveRight,
+	ActionMoveUp,
+	ActionMoveDown,
+	ActionLookUp,
+	ActionLookDown,
+	ActionJump,
+	ActionAttack,
+	ActionAim,
+	ActionFireAnchor,
+	ActionAnchor,
+	ActionAutoAnchor,
+	ActionReelIn,
+	ActionReelOut,
+	ActionHeal,
+	ActionMenu,
+	ActionPause,
+	ActionInteract,
+	ActionEnter,
+}
+
+var actionLabels = map[Action]string{
+	ActionMoveLeft:   "Move left",
+	ActionMoveRight:  "Move right",
+	ActionMoveUp:     "Move up",
+	ActionMoveDown:   "Move down",
+	ActionLookUp:     "Look up",
+	ActionLookDown:   "Look down",
+	ActionJump:       "Jump",
+	ActionAttack:     "Attack",
+	ActionAim:        "Aim",
+	ActionFireAnchor: "Fire anchor",
+	ActionAnchor:     "Anchor (contextual)",
+	ActionAutoAnchor: "Auto anchor",
+	ActionReelIn:     "Reel in",
+	ActionReelOut:    "Reel out",
+	ActionHeal:       "Heal",
+	ActionMenu:       "Inventory",
+	ActionPause:      "Pause",
+	ActionInteract:   "Talk / advance",
+	ActionEnter:      "Enter door",
+}
+
+// Label returns the human readable name of action.
+func (a Action) Label() string {
+	if label, ok := actionLabels[a]; ok {
+		return label
+	}
+	return string(a)
+}
+
+func (a Action) valid() bool {
+	_, ok := actionLabels[a]
+	return ok
+}
+
+// Binding lists the inputs that trigger an action. Names are stored as
+// strings so profiles stay readable and survive ebiten renumbering its enums.
+type Binding struct {
+	Keys           []string `yaml:"keys,omitempty"`
+	MouseButtons   []string `yaml:"mouse,omitempty"`
+	GamepadButtons []string `yaml:"gamepad,omitempty"`
+	// GamepadAxes holds stick directions such as "left_x-" or "right_y+".
+	GamepadAxes []string `yaml:"axes,omitempty"`
+}
+
+func (b Binding) clone() Binding {
+	return Binding{
+		Keys:           append([]string(nil), b.Keys...),
+		MouseButtons:   append([]string(nil), b.MouseButtons...),
+		GamepadButtons: append([]string(nil), b.GamepadButtons...),
+		GamepadAxes:    append([]string(nil), b.GamepadAxes...),
+	}
+}
+
+// Describe formats the keyboard and mouse inputs, then the gamepad inputs,
+// for display on the controls screen.
+func (b Binding) Describe() string {
+	desktop := make([]string, 0, len(b.Keys)+len(b.MouseButtons))
+	desktop = append(desktop, b.Keys...)
+	for _, name := range b.MouseButtons {
+		desktop = append(desktop, "Mouse "+name)
+	}
+	pad := make([]string, 0, len(b.GamepadButtons)+len(b.GamepadAxes))
+	pad = append(pad, b.GamepadButtons...)
+	pad = append(pad, b.GamepadAxes...)
+
+	left := strings.Join(desktop, ", ")
+	if left == "" {
+		left = "-"
+	}
+	right := strings.Join(pad, ", ")
+	if right == "" {
+		right = "-"
+	}
+	return left + "  |  " + right
+}
+
+var mouseButtonNames = map[string]ebiten.MouseButton{
+	"left":    ebiten.MouseButtonLeft,
+	"right":   ebiten.MouseButtonRight,
+	"middle":  ebiten.MouseButtonMiddle,
+	"back":    ebiten.MouseButton3,
+	"forward": ebiten.MouseButton4,
+}
+
+var gamepadButtonNames = map[string]ebiten.StandardGamepadButton{
+	"right_bottom":       ebiten.StandardGamepadButtonRightBottom,
+	"right_right":        ebiten.StandardGamepadButtonRightRight,
+	"right_left":         ebiten.StandardGamepadButtonRightLeft,
+	"right_top":          ebiten.StandardGamepadButtonRightTop,
+	"front_top_left":     ebiten.StandardGamepadButtonFrontTopLeft,
+	"front_top_right":    ebiten.StandardGamepadButtonFrontTopRight,
+	"front_bottom_left":  ebiten.StandardGamepadButtonFrontBottomLeft,
+	"front_bottom_right": ebiten.StandardGamepadButtonFrontBottomRight,
+	"center_left":        ebiten.StandardGamepadButtonCenterLeft,
+	"center_right":       ebiten.StandardGamepadButtonCenterRight,
+	"left_stick":         ebiten.StandardGamepadButtonLeftStick,
+	"right_stick":        ebiten.StandardGamepadButtonRightStick,
+	"left_top":           ebiten.StandardGamepadButtonLeftTop,
+	"left_bottom":        ebiten.StandardGamepadButtonLeftBottom,
+	"left_left":          ebiten.StandardGamepadButtonLeftLeft,
+	"left_right":         ebiten.StandardGamepadButtonLeftRight,
+	"center_center":      ebiten.StandardGamepadButtonCenterCenter,
+}
+
+var gamepadAxisNames = map[string]ebiten.StandardGamepadAxis{
+	"left_x":  ebiten.StandardGamepadAxisLeftStickHorizontal,
+	"left_y":  ebiten.StandardGamepadAxisLeftStickVertical,
+	"right_x": ebiten.StandardGamepadAxisRightStickHorizontal,
+	"right_y": ebiten.StandardGamepadAxisRightStickVertical,
+}
+
+// MouseButtonName returns the profile name for button.
+func MouseButtonName(button ebiten.MouseButton) (string, bool) {
+	return reverseLookup(mouseButtonNames, button)
+}
+
+// GamepadButtonName returns the profile name for button.
+func GamepadButtonName(button ebiten.StandardGamepadButton) (string, bool) {
+	return reverseLookup(gamepadButtonNames, button)
+}
+
+// GamepadButtons lists every standard gamepad button a profile can reference.
+func GamepadButtons() []ebiten.StandardGamepadButton {
+	buttons := make([]ebiten.StandardGamepadButton, 0, len(gamepadButtonNames))
+	for _, button := range gamepadButtonNames {
+		buttons = append(buttons, button)
+	}
+	sort.Slice(buttons, func(i, j int) bool { return buttons[i] < buttons[j] })
+	return buttons
+}
+
+// MouseButtons lists every mouse button a profile can reference.
+func MouseButtons() []ebiten.MouseButton {
+	buttons := make([]ebiten.MouseButton, 0, len(mouseButtonNames))
+	for _, button := range mouseButtonNames {
+		buttons = append(buttons, button)
+	}
+	sort.Slice(buttons, func(i, j int) bool { return buttons[i] < buttons[j] })
+	return buttons
+}
+
+func reverseLookup[T comparable](names map[string]T, value T) (string, bool) {
+	for name, candidate := range names {
+		if candidate == value {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+type axisBinding struct {
+	axis ebiten.StandardGamepadAxis
+	sign float64
+}
+
+type resolvedBinding struct {
+	keys    []ebiten.Key
+	mouse   []ebiten.MouseButton
+	buttons []ebiten.StandardGamepadButton
+	axes    []axisBinding
+}
+
+func resolveBinding(action Action, binding Binding) (resolvedBinding, error) {
+	var resolved resolvedBinding
+	for _, name := range binding.Keys {
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return resolvedBinding{}, fmt.Errorf("action %q: unknown key %q", action, name)
+		}
+		resolved.keys = append(resolved.keys, key)
+	}
+	for _, name := range binding.MouseButtons {
+		button, ok := mouseButtonNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return resolvedBinding{}, fmt.Errorf("action %q: unknown mouse button %q", action, name)
+		}
+		resolved.mouse = append(resolved.mouse, button)
+	}
+	for _, name := range binding.GamepadButtons {
+		button, ok := gamepadButtonNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return resolvedBinding{}, fmt.Errorf("action %q: unknown gamepad button %q", action, name)
+		}
+		resolved.buttons = append(resolved.buttons, button)
+	}
+	for _, name := range binding.GamepadAxes {
+		axis, err := parseAxis(name)
+		if err != nil {
+			return resolvedBinding{}, fmt.Errorf("action %q: %w", action, err)
+		}
+		resolved.axes = append(resolved.axes, axis)
+	}
+	return resolved, nil
+}
+
+func parseAxis(name string) (axisBinding, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(name))
+	if len(trimmed) < 2 {
+		return axisBinding{}, fmt.Errorf("unknown gamepad axis %q", name)
+	}
+
+	var sign float64
+	switch trimmed[len(trimmed)-1] {
+	case '+':
+		sign = 1
+	case '-':
+		sign = -1
+	default:
+		return axisBinding{}, fmt.Errorf("gamepad axis %q needs a + or - direction", name)
+	}
+
+	axis, ok := gamepadAxisNames[trimmed[:len(trimmed)-1]]
+	if !ok {
+		return axisBinding{}, fmt.Errorf("unknown gamepad axis %q", name)
+	}
+	return axisBinding{axis: axis, sign: sign}, nil
+}
+
+// Gamepad identifies the controller an action map reads from. The zero
+// Gamepad is disconnected, so queries with it read the keyboard and mouse
+// alone.
+type Gamepad struct {
+	ID        ebiten.GamepadID
+	Connected bool
+}
+
+// FirstGamepad returns the first connected gamepad, matching how the game
+// has always picked a single controller.
+func FirstGamepad() Gamepad {
+	if gamepads := ebiten.GamepadIDs(); len(gamepads) > 0 {
+		return Gamepad{ID: gamepads[0], Connected: true}
+	}
+	return Gamepad{}
+}
+
+// Map is a validated profile ready to be queried every frame.
+type Map struct {
+	bindings map[Action]resolvedBinding
+}
+
+// Held reports whether any key, mouse button or gamepad button bound to
+// action is down. Stick directions are read separately through Axis.
+func (m *Map) Held(action Action, pad Gamepad) bool {
+	if m == nil {
+		return false
+	}
+	binding := m.bindings[action]
+	for _, key := range binding.keys {
+		if ebiten.IsKeyPressed(key) {
+			return true
+		}
+	}
+	for _, button := range binding.mouse {
+		if ebiten.IsMouseButtonPressed(button) {
+			return true
+		}
+	}
+	return m.GamepadHeld(action, pad)
+}
+
+// GamepadHeld is Held for pad's buttons alone.
+func (m *Map) GamepadHeld(action Action, pad Gamepad) bool {
+	if m == nil || !pad.Connected {
+		return false
+	}
+	for _, button := range m.bindings[action].buttons {
+		if ebiten.IsStandardGamepadButtonPressed(pad.ID, button) {
+			return true
+		}
+	}
+	return false
+}
+
+// JustPressed reports whether any key, mouse button or gamepad button bound
+// to action went down this frame.
+func (m *Map) JustPressed(action Action, pad Gamepad) bool {
+	if m == nil {
+		return false
+	}
+	binding := m.bindings[action]
+	for _, key := range binding.keys {
+		if inpututil.IsKeyJustPressed(key) {
+			return true
+		}
+	}
+	for _, button := range binding.mouse {
+		if inpututil.IsMouseButtonJustPressed(button) {
+			return true
+		}
+	}
+	return m.GamepadJustPressed(action, pad)
+}
+
+// GamepadJustPressed is JustPressed for pad's buttons alone.
+func (m *Map) GamepadJustPressed(action Action, pad Gamepad) bool {
+	if m == nil || !pad.Connected {
+		return false
+	}
+	for _, button := range m.bindings[action].buttons {
+		if inpututil.IsStandardGamepadButtonJustPressed(pad.ID, button) {
+			return true
+		}
+	}
+	return false
+}
+
+// Axis returns how far the sticks bound to action are pushed in the bound
+// direction, from 0 to 1. No deadzone is applied.
+func (m *Map) Axis(action Action, pad Gamepad) float64 {
+	if m == nil || !pad.Connected {
+		return 0
+	}
+	strongest := 0.0
+	for _, axis := range m.bindings[action].axes {
+		value := ebiten.StandardGamepadAxisValue(pad.ID, axis.axis) * axis.sign
+		if value > strongest {
+			strongest = value
+		}
+	}
+	return strongest
+}
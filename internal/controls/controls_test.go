@@ -0,0 +1,217 @@
+package controls
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestDefaultProfileCoversEveryAction(t *testing.T) {
+	profile := Default()
+	for _, action := range Actions {
+		if _, ok := profile.Actions[action]; !ok {
+			t.Fatalf("expected default binding for %q", action)
+		}
+		if action.Label() == string(action) {
+			t.Fatalf("expected label for %q", action)
+		}
+	}
+
+	m, err := profile.Map()
+	if err != nil {
+		t.Fatalf("resolve default profile: %v", err)
+	}
+	jump := m.bindings[ActionJump]
+	if !reflect.DeepEqual(jump.keys, []ebiten.Key{ebiten.KeySpace}) {
+		t.Fatalf("expected jump on space, got %v", jump.keys)
+	}
+	if !reflect.DeepEqual(jump.buttons, []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightBottom}) {
+		t.Fatalf("expected jump on right bottom, got %v", jump.buttons)
+	}
+	if got := m.bindings[ActionPause].keys; !reflect.DeepEqual(got, []ebiten.Key{ebiten.KeyEscape}) {
+		t.Fatalf("expected pause on escape, got %v", got)
+	}
+}
+
+func TestDecodeMergesPartialProfileOverDefaults(t *testing.T) {
+	profile, err := Decode([]byte(`
+version: 1
+actions:
+  move_left:
+    keys: [Q]
+    axes: [left_x-]
+  jump:
+    keys: [K]
+    gamepad: [right_right]
+`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got := profile.Binding(ActionMoveLeft).Keys; !reflect.DeepEqual(got, []string{"Q"}) {
+		t.Fatalf("expected move_left on Q, got %v", got)
+	}
+	if got := profile.Binding(ActionMoveRight).Keys; !reflect.DeepEqual(got, []string{"D", "ArrowRight"}) {
+		t.Fatalf("expected move_right to keep defaults, got %v", got)
+	}
+
+	m, err := profile.Map()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got := m.bindings[ActionJump].keys; !reflect.DeepEqual(got, []ebiten.Key{ebiten.KeyK}) {
+		t.Fatalf("expected jump on K, got %v", got)
+	}
+	if got := m.bindings[ActionMoveLeft].axes; !reflect.DeepEqual(got, []axisBinding{{axis: ebiten.StandardGamepadAxisLeftStickHorizontal, sign: -1}}) {
+		t.Fatalf("expected move_left on left stick, got %v", got)
+	}
+}
+
+func TestDecodeAcceptsJSON(t *testing.T) {
+	profile, err := Decode([]byte(`{"version": 1, "actions": {"heal": {"keys": ["H"], "mouse": ["middle"]}}}`))
+	if err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	binding := profile.Binding(ActionHeal)
+	if !reflect.DeepEqual(binding.Keys, []string{"H"}) || !reflect.DeepEqual(binding.MouseButtons, []string{"middle"}) {
+		t.Fatalf("unexpected heal binding %+v", binding)
+	}
+}
+
+func TestDecodeRejectsInvalidProfiles(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unknown action", data: "actions:\n  dance:\n    keys: [D]\n", want: `unknown action "dance"`},
+		{name: "unknown key", data: "actions:\n  jump:\n    keys: [NotAKey]\n", want: `unknown key "NotAKey"`},
+		{name: "unknown mouse button", data: "actions:\n  jump:\n    mouse: [thumb]\n", want: `unknown mouse button "thumb"`},
+		{name: "unknown gamepad button", data: "actions:\n  jump:\n    gamepad: [start]\n", want: `unknown gamepad button "start"`},
+		{name: "axis without direction", data: "actions:\n  jump:\n    axes: [left_x]\n", want: "needs a + or - direction"},
+		{name: "unknown axis", data: "actions:\n  jump:\n    axes: [trigger+]\n", want: `unknown gamepad axis "trigger+"`},
+		{name: "unknown field", data: "actions:\n  jump:\n    buttons: [A]\n", want: "buttons"},
+		{name: "newer version", data: "version: 3\n", want: "unsupported version 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode([]byte(test.data))
+			if err == nil {
+				t.Fatal("expected decode error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestDecodeMovesVersion1MenuOffPause(t *testing.T) {
+	profile, err := Decode([]byte(`
+version: 1
+actions:
+  menu:
+    keys: [Escape]
+    gamepad: [center_right]
+  heal:
+    keys: [Escape]
+`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if profile.Version != CurrentVersion {
+		t.Fatalf("expected version %d, got %d", CurrentVersion, profile.Version)
+	}
+	if got, want := profile.Binding(ActionMenu), Default().Actions[ActionMenu]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected menu moved to its default %+v, got %+v", want, got)
+	}
+	if got := profile.Binding(ActionPause).Keys; !reflect.DeepEqual(got, []string{"Escape"}) {
+		t.Fatalf("expected pause on Escape, got %v", got)
+	}
+	if got := profile.Binding(ActionHeal).Keys; !reflect.DeepEqual(got, []string{"Escape"}) {
+		t.Fatalf("expected only menu migrated, got heal on %v", got)
+	}
+
+	custom, err := Decode([]byte("version: 1\nactions:\n  menu:\n    keys: [M, Escape]\n    gamepad: [left_left]\n"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := custom.Binding(ActionMenu); !reflect.DeepEqual(got.Keys, []string{"M"}) || !reflect.DeepEqual(got.GamepadButtons, []string{"left_left"}) {
+		t.Fatalf("expected a custom menu binding kept without Escape, got %+v", got)
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	profile := Default()
+	profile.BindKey(ActionAttack, ebiten.KeyJ)
+	profile.BindGamepadButton(ActionMoveLeft, ebiten.StandardGamepadButtonLeftLeft)
+	if err := Save(path, profile); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, profile) {
+		t.Fatalf("expected round trip to match\nwant %+v\ngot  %+v", profile, loaded)
+	}
+}
+
+func TestLoadMissingProfileUsesDefaults(t *testing.T) {
+	loaded, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, Default()) {
+		t.Fatalf("expected defaults, got %+v", loaded)
+	}
+}
+
+func TestBindReplacesOnlyTheMatchingDevice(t *testing.T) {
+	profile := Default()
+
+	profile.BindKey(ActionAttack, ebiten.KeyJ)
+	attack := profile.Binding(ActionAttack)
+	if !reflect.DeepEqual(attack.Keys, []string{"J"}) || len(attack.MouseButtons) != 0 {
+		t.Fatalf("expected key bind to replace keyboard and mouse inputs, got %+v", attack)
+	}
+	if !reflect.DeepEqual(attack.GamepadButtons, []string{"right_left"}) {
+		t.Fatalf("expected key bind to keep gamepad inputs, got %+v", attack)
+	}
+
+	profile.BindMouseButton(ActionAttack, ebiten.MouseButtonMiddle)
+	attack = profile.Binding(ActionAttack)
+	if len(attack.Keys) != 0 || !reflect.DeepEqual(attack.MouseButtons, []string{"middle"}) {
+		t.Fatalf("expected mouse bind to replace keyboard and mouse inputs, got %+v", attack)
+	}
+
+	profile.BindGamepadButton(ActionMoveUp, ebiten.StandardGamepadButtonLeftTop)
+	moveUp := profile.Binding(ActionMoveUp)
+	if !reflect.DeepEqual(moveUp.GamepadButtons, []string{"left_top"}) || !reflect.DeepEqual(moveUp.GamepadAxes, []string{"left_y-"}) {
+		t.Fatalf("expected gamepad bind to keep stick directions, got %+v", moveUp)
+	}
+	if !reflect.DeepEqual(moveUp.Keys, []string{"W", "ArrowUp"}) {
+		t.Fatalf("expected gamepad bind to keep keys, got %+v", moveUp)
+	}
+
+	if got := Default().Binding(ActionAttack).Keys; !reflect.DeepEqual(got, []string{"Z"}) {
+		t.Fatalf("expected defaults to stay untouched, got %v", got)
+	}
+}
+
+func TestBindingDescribe(t *testing.T) {
+	got := Default().Binding(ActionAttack).Describe()
+	if got != "Z, Mouse left  |  right_left" {
+		t.Fatalf("unexpected description %q", got)
+	}
+	if got := (Binding{}).Describe(); got != "-  |  -" {
+		t.Fatalf("unexpected empty description %q", got)
+	}
+}
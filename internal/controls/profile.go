@@ -0,0 +1,292 @@
+package controls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/milk9111/sidescroller/internal/savegame"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CurrentVersion = 2
+	// FileName is the profile stored next to the save slots. It is YAML so
+	// the save slot listing, which reads every .json file, never picks it up.
+	FileName = "controls.yaml"
+)
+
+// Profile is the on-disk keybinding profile. Actions missing from the file
+// keep their default bindings. JSON profiles load as well, since JSON is
+// valid YAML.
+type Profile struct {
+	Version int                `yaml:"version"`
+	Actions map[Action]Binding `yaml:"actions"`
+}
+
+// Default returns the bindings the game ships with.
+func Default() *Profile {
+	return &Profile{
+		Version: CurrentVersion,
+		Actions: map[Action]Binding{
+			ActionMoveLeft:   {Keys: []string{"A", "ArrowLeft"}, GamepadAxes: []string{"left_x-"}},
+			ActionMoveRight:  {Keys: []string{"D", "ArrowRight"}, GamepadAxes: []string{"left_x+"}},
+			ActionMoveUp:     {Keys: []string{"W", "ArrowUp"}, GamepadAxes: []string{"left_y-"}},
+			ActionMoveDown:   {Keys: []string{"S", "ArrowDown"}, GamepadAxes: []string{"left_y+"}},
+			ActionLookUp:     {GamepadAxes: []string{"right_y-"}},
+			ActionLookDown:   {Keys: []string{"S", "ArrowDown"}, GamepadAxes: []string{"right_y+"}},
+			ActionJump:       {Keys: []string{"Space"}, GamepadButtons: []string{"right_bottom"}},
+			ActionAttack:     {Keys: []string{"Z"}, MouseButtons: []string{"left"}, GamepadButtons: []string{"right_left"}},
+			ActionAim:        {MouseButtons: []string{"right"}, GamepadButtons: []string{"front_bottom_left"}},
+			ActionFireAnchor: {MouseButtons: []string{"left"}},
+			ActionAnchor:     {GamepadButtons: []string{"front_bottom_right"}},
+			ActionAutoAnchor: {Keys: []string{"ControlLeft", "ControlRight"}},
+			ActionReelIn:     {Keys: []string{"Q"}, GamepadButtons: []string{"right_left"}},
+			ActionReelOut:    {Keys: []string{"E"}, GamepadButtons: []string{"right_top"}},
+			ActionHeal:       {Keys: []string{"C"}, GamepadButtons: []string{"front_top_right"}},
+			ActionMenu:       {Keys: []string{"Tab", "I"}, GamepadButtons: []string{"center_left"}},
+			ActionPause:      {Keys: []string{"Escape"}, GamepadButtons: []string{"center_right"}},
+			ActionInteract:   {Keys: []string{"Z"}, GamepadButtons: []string{"right_left"}},
+			ActionEnter:      {Keys: []string{"W", "ArrowUp"}, GamepadButtons: []string{"left_top"}, GamepadAxes: []string{"left_y-"}},
+		},
+	}
+}
+
+// DefaultMap returns the resolved default bindings.
+func DefaultMap() *Map {
+	m, err := Default().Map()
+	if err != nil {
+		panic(fmt.Sprintf("controls: default profile: %v", err))
+	}
+	return m
+}
+
+func (p *Profile) Clone() *Profile {
+	if p == nil {
+		return nil
+	}
+	cloned := &Profile{Version: p.Version, Actions: make(map[Action]Binding, len(p.Actions))}
+	for action, binding := range p.Actions {
+		cloned.Actions[action] = binding.clone()
+	}
+	return cloned
+}
+
+// Binding returns the inputs bound to action, falling back to the default.
+func (p *Profile) Binding(action Action) Binding {
+	if p != nil {
+		if binding, ok := p.Actions[action]; ok {
+			return binding.clone()
+		}
+	}
+	return Default().Actions[action].clone()
+}
+
+// Map validates every binding and resolves the names to ebiten inputs.
+func (p *Profile) Map() (*Map, error) {
+	if p == nil {
+		return nil, fmt.Errorf("controls: nil profile")
+	}
+
+	m := &Map{bindings: make(map[Action]resolvedBinding, len(Actions))}
+	for _, action := range Actions {
+		resolved, err := resolveBinding(action, p.Binding(action))
+		if err != nil {
+			return nil, fmt.Errorf("controls: %w", err)
+		}
+		m.bindings[action] = resolved
+	}
+	return m, nil
+}
+
+// BindKey replaces the keyboard and mouse inputs of action with key.
+func (p *Profile) BindKey(action Action, key ebiten.Key) {
+	binding := p.Binding(action)
+	binding.Keys = []string{key.String()}
+	binding.MouseButtons = nil
+	p.set(action, binding)
+}
+
+// BindMouseButton replaces the keyboard and mouse inputs of action with button.
+func (p *Profile) BindMouseButton(action Action, button ebiten.MouseButton) {
+	name, ok := MouseButtonName(button)
+	if !ok {
+		return
+	}
+	binding := p.Binding(action)
+	binding.Keys = nil
+	binding.MouseButtons = []string{name}
+	p.set(action, binding)
+}
+
+// BindGamepadButton replaces the gamepad buttons of action with button. Stick
+// directions are kept so movement still works on the analog sticks.
+func (p *Profile) BindGamepadButton(action Action, button ebiten.StandardGamepadButton) {
+	name, ok := GamepadButtonName(button)
+	if !ok {
+		return
+	}
+	binding := p.Binding(action)
+	binding.GamepadButtons = []string{name}
+	p.set(action, binding)
+}
+
+func (p *Profile) set(action Action, binding Binding) {
+	if p == nil {
+		return
+	}
+	if p.Actions == nil {
+		p.Actions = map[Action]Binding{}
+	}
+	p.Actions[action] = binding
+}
+
+// ResolvePath returns where the profile lives in the platform save directory.
+// Web builds have no save directory, so the path is empty there.
+func ResolvePath() (string, error) {
+	if runtime.GOOS == "js" && runtime.GOARCH == "wasm" {
+		return "", nil
+	}
+	return savegame.ResolvePath(FileName)
+}
+
+// Load reads the profile at path. A missing file or empty path yields the
+// defaults.
+func Load(path string) (*Profile, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Default(), nil
+		}
+		return nil, fmt.Errorf("load controls %q: %w", path, err)
+	}
+
+	profile, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("load controls %q: %w", path, err)
+	}
+	return profile, nil
+}
+
+// Decode parses a YAML or JSON profile and fills in defaults for any action
+// it does not mention.
+func Decode(data []byte) (*Profile, error) {
+	var decoded Profile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode profile: %w", err)
+	}
+	if decoded.Version == 0 {
+		decoded.Version = CurrentVersion
+	}
+	if decoded.Version < 1 || decoded.Version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported version %d", decoded.Version)
+	}
+	for version := decoded.Version; version < CurrentVersion; version++ {
+		migrations[version](decoded.Actions)
+	}
+
+	profile := Default()
+	for action, binding := range decoded.Actions {
+		if !action.valid() {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		profile.Actions[action] = binding.clone()
+	}
+	if _, err := profile.Map(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// migrations upgrade a profile's actions by one version, from the version
+// each is registered under to the next. Profiles are saved with every
+// action, so moving a default needs one, along with a CurrentVersion bump.
+var migrations = map[int]func(actions map[Action]Binding){
+	1: migrateMenuToPause,
+}
+
+// migrateMenuToPause upgrades a version 1 profile. Version 2 split pause off
+// menu: Escape and center_right went to pause, and menu moved to Tab, I and
+// center_left. Left on menu they would open the inventory and pause at
+// once, so they are dropped, and a device left without a menu input gets
+// the new default.
+func migrateMenuToPause(actions map[Action]Binding) {
+	binding, ok := actions[ActionMenu]
+	if !ok {
+		return
+	}
+	without := func(names []string, dropped string) []string {
+		kept := make([]string, 0, len(names))
+		for _, name := range names {
+			if name != dropped {
+				kept = append(kept, name)
+			}
+		}
+		return kept
+	}
+	defaults := Default().Actions[ActionMenu]
+	binding.Keys = without(binding.Keys, "Escape")
+	if len(binding.Keys) == 0 && len(binding.MouseButtons) == 0 {
+		binding.Keys = defaults.Keys
+	}
+	binding.GamepadButtons = without(binding.GamepadButtons, "center_right")
+	if len(binding.GamepadButtons) == 0 {
+		binding.GamepadButtons = defaults.GamepadButtons
+	}
+	actions[ActionMenu] = binding
+}
+
+// Save writes the profile to path through a temp file so a crash never
+// leaves a half-written profile behind.
+func Save(path string, profile *Profile) error {
+	if profile == nil {
+		return fmt.Errorf("save controls: nil profile")
+	}
+	if path == "" {
+		return nil
+	}
+	if _, err := profile.Map(); err != nil {
+		return fmt.Errorf("save controls: %w", err)
+	}
+
+	cloned := profile.Clone()
+	cloned.Version = CurrentVersion
+	data, err := yaml.Marshal(cloned)
+	if err != nil {
+		return fmt.Errorf("save controls: encode yaml: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("save controls: create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "controls-*.yaml")
+	if err != nil {
+		return fmt.Errorf("save controls: create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("save controls: write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save controls: close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("save controls: replace profile: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	httppprof "net/http/pprof"
@@ -24,6 +25,16 @@ type Config struct {
 	MemProfilePath     string
 	MemProfileRate     int
 	MemProfileInterval time.Duration
+	// SystemProfilePath is where SystemProfile is written on Stop, as JSON
+	// when the path ends in .json and CSV otherwise.
+	SystemProfilePath string
+	SystemProfile     SystemProfile
+}
+
+// SystemProfile is a per-system timing report, like ecs.SystemProfile.
+type SystemProfile interface {
+	WriteCSV(w io.Writer) error
+	WriteJSON(w io.Writer) error
 }
 
 type Profiler struct {
@@ -38,6 +49,8 @@ type Profiler struct {
 	memSnapshotIndex    int
 	stopCPUProfile      bool
 	stopTrace           bool
+	systemProfilePath   string
+	systemProfile       SystemProfile
 }
 
 func Start(cfg Config) (*Profiler, error) {
@@ -47,6 +60,15 @@ func Start(cfg Config) (*Profiler, error) {
 	}
 	p := &Profiler{label: label, memProfilePath: cfg.MemProfilePath}
 
+	if cfg.SystemProfilePath != "" {
+		if cfg.SystemProfile == nil {
+			return nil, errors.New("systemprofile has no system timings to write")
+		}
+		p.systemProfilePath = cfg.SystemProfilePath
+		p.systemProfile = cfg.SystemProfile
+		log.Printf("%s system profiling enabled: %s", p.label, cfg.SystemProfilePath)
+	}
+
 	if cfg.MemProfileRate > 0 {
 		previousRate := runtime.MemProfileRate
 		runtime.MemProfileRate = cfg.MemProfileRate
@@ -135,6 +157,15 @@ func (p *Profiler) Stop() error {
 		}
 	}
 
+	if p.systemProfilePath != "" {
+		if err := writeSystemProfile(p.systemProfilePath, p.systemProfile); err != nil {
+			errs = append(errs, fmt.Errorf("write systemprofile: %w", err))
+		} else {
+			log.Printf("%s system profile written: %s", p.label, p.systemProfilePath)
+		}
+		p.systemProfilePath = ""
+	}
+
 	if p.stopCPUProfile {
 		runtimepprof.StopCPUProfile()
 		p.stopCPUProfile = false
@@ -209,6 +240,23 @@ func writeHeapProfile(path string) error {
 	return nil
 }
 
+func writeSystemProfile(path string, profile SystemProfile) error {
+	profileFile, err := createProfileFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = profile.WriteJSON(profileFile)
+	} else {
+		err = profile.WriteCSV(profileFile)
+	}
+	if closeErr := profileFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 func createProfileFile(path string) (*os.File, error) {
 	dir := filepath.Dir(path)
 	if dir != "." {
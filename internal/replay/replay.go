@@ -0,0 +1,292 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+const CurrentVersion = 1
+
+// Header describes how a recorded session started. It is written as the
+// first line of a recording, followed by one Frame per line.
+type Header struct {
+	Version          int                  `json:"version"`
+	Seed             int64                `json:"seed"`
+	Level            string               `json:"level"`
+	AllAbilities     bool                 `json:"allAbilities,omitempty"`
+	InitialAbilities *component.Abilities `json:"initialAbilities,omitempty"`
+	Save             *savegame.File       `json:"save,omitempty"`
+	RecordedAt       time.Time            `json:"recordedAt"`
+}
+
+// Frame is the input produced by the polling input systems during a single
+// GameScene update, and what the player did to the scene around it.
+type Frame struct {
+	Input      component.Input           `json:"input"`
+	Dialogue   component.DialogueInput   `json:"dialogue"`
+	Transition component.TransitionInput `json:"transition"`
+	Reset      bool                      `json:"reset,omitempty"`
+	// LoadCheckpoint is the pause menu's Load checkpoint, picked while the
+	// game was paused before this frame.
+	LoadCheckpoint bool `json:"loadCheckpoint,omitempty"`
+	// Console lists the developer console lines run while the console held
+	// the game before this frame.
+	Console []string `json:"console,omitempty"`
+	// ToggleDebug is the debug overlay toggled on this frame.
+	ToggleDebug bool `json:"toggleDebug,omitempty"`
+}
+
+// Feed hands the current frame between GameScene and the input systems.
+// While Replaying is set the systems apply Frame instead of polling ebiten;
+// otherwise they write what they polled into Frame so it can be recorded.
+type Feed struct {
+	Frame     Frame
+	Replaying bool
+}
+
+// Recorder writes one recording per game session: the first to the path
+// given to Create and each later one, after End, to a numbered file next to
+// it, like session-2.jsonl.
+type Recorder struct {
+	base     string
+	path     string
+	file     *os.File
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+	started  bool
+	closed   bool
+	sessions int
+	frames   int
+}
+
+// Create opens path for writing a new recording. The header is written later
+// by Begin once the game scene knows which level and save it starts from.
+func Create(path string) (*Recorder, error) {
+	if path == "" {
+		return nil, fmt.Errorf("create recording: empty path")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create recording: create directory: %w", err)
+		}
+	}
+
+	r := &Recorder{base: path}
+	if err := r.open(path); err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	return r, nil
+}
+
+func (r *Recorder) open(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	r.path = path
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	r.encoder = json.NewEncoder(r.writer)
+	r.frames = 0
+	return nil
+}
+
+// sessionPath numbers the recording of the nth session after the first.
+func sessionPath(base string, n int) string {
+	ext := filepath.Ext(base)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext)
+}
+
+// Begin writes the header of a session. After End it first opens the
+// session's own file.
+func (r *Recorder) Begin(header Header) error {
+	if r == nil {
+		return nil
+	}
+	if r.closed {
+		return fmt.Errorf("record %q: recorder closed", r.base)
+	}
+	if r.started {
+		return fmt.Errorf("record %q: header already written", r.path)
+	}
+	if r.file == nil {
+		if err := r.open(sessionPath(r.base, r.sessions+1)); err != nil {
+			return fmt.Errorf("record %q: %w", r.base, err)
+		}
+	}
+
+	header.Version = CurrentVersion
+	if header.RecordedAt.IsZero() {
+		header.RecordedAt = time.Now().UTC()
+	}
+	if err := r.encoder.Encode(header); err != nil {
+		return fmt.Errorf("record %q: write header: %w", r.path, err)
+	}
+	r.started = true
+	r.sessions++
+	return r.writer.Flush()
+}
+
+// End finishes the recording of the current session, for when the game
+// scene it came from exits.
+func (r *Recorder) End() error {
+	if r == nil || !r.started {
+		return nil
+	}
+	r.started = false
+	return r.closeFile()
+}
+
+// Path is the file the current or last session was recorded to.
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.path
+}
+
+// Record appends a frame and flushes it so a crash still leaves a usable
+// recording up to the last completed frame.
+func (r *Recorder) Record(frame Frame) error {
+	if r == nil {
+		return nil
+	}
+	if !r.started {
+		return fmt.Errorf("record %q: frame written before header", r.path)
+	}
+
+	if err := r.encoder.Encode(frame); err != nil {
+		return fmt.Errorf("record %q: write frame %d: %w", r.path, r.frames, err)
+	}
+	r.frames++
+	return r.writer.Flush()
+}
+
+func (r *Recorder) Frames() int {
+	if r == nil {
+		return 0
+	}
+	return r.frames
+}
+
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.closed = true
+	r.started = false
+	return r.closeFile()
+}
+
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+
+	flushErr := r.writer.Flush()
+	closeErr := r.file.Close()
+	r.file = nil
+	if flushErr != nil {
+		return fmt.Errorf("close recording %q: %w", r.path, flushErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close recording %q: %w", r.path, closeErr)
+	}
+	return nil
+}
+
+type Player struct {
+	header Header
+	frames []Frame
+	next   int
+}
+
+func Load(path string) (*Player, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load replay %q: %w", path, err)
+	}
+	defer file.Close()
+
+	player, err := Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("load replay %q: %w", path, err)
+	}
+	return player, nil
+}
+
+func Decode(r io.Reader) (*Player, error) {
+	decoder := json.NewDecoder(r)
+
+	var header Header
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	if header.Version != CurrentVersion {
+		return nil, fmt.Errorf("unsupported version %d", header.Version)
+	}
+
+	frames := make([]Frame, 0)
+	for {
+		var frame Frame
+		err := decoder.Decode(&frame)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A recording cut short by a crash ends with a partial line;
+			// keep every complete frame before it.
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, fmt.Errorf("decode frame %d: %w", len(frames), err)
+		}
+		frames = append(frames, frame)
+	}
+
+	return &Player{header: header, frames: frames}, nil
+}
+
+// NewPlayer replays frames built in memory, for example from ParseScript.
+func NewPlayer(header Header, frames []Frame) *Player {
+	header.Version = CurrentVersion
+	return &Player{header: header, frames: append([]Frame(nil), frames...)}
+}
+
+func (p *Player) Header() Header {
+	if p == nil {
+		return Header{}
+	}
+	return p.header
+}
+
+// Next returns the next recorded frame, or false once the recording is exhausted.
+func (p *Player) Next() (Frame, bool) {
+	if p == nil || p.next >= len(p.frames) {
+		return Frame{}, false
+	}
+	frame := p.frames[p.next]
+	p.next++
+	return frame, true
+}
+
+func (p *Player) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.frames)
+}
+
+func (p *Player) Done() bool {
+	return p == nil || p.next >= len(p.frames)
+}
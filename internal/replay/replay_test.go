@@ -0,0 +1,201 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+func TestRecorderAndPlayerRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := Create(path)
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+
+	header := Header{
+		Seed:             42,
+		Level:            "long_fall.json",
+		InitialAbilities: &component.Abilities{Anchor: true},
+		Save:             &savegame.File{Level: "long_fall.json", Player: savegame.PlayerState{GearCount: 3}},
+	}
+	if err := recorder.Begin(header); err != nil {
+		t.Fatalf("begin recording: %v", err)
+	}
+	frames := []Frame{
+		{Input: component.Input{MoveX: 1, Jump: true, JumpPressed: true}},
+		{Input: component.Input{Aim: true, CursorX: 120, CursorY: 48}, Dialogue: component.DialogueInput{Pressed: true}},
+		{Transition: component.TransitionInput{UpPressed: true}, Reset: true},
+		{LoadCheckpoint: true, Console: []string{"tp 128 64", `flags.set("met_merchant")`}, ToggleDebug: true},
+	}
+	for _, frame := range frames {
+		if err := recorder.Record(frame); err != nil {
+			t.Fatalf("record frame: %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("close recorder: %v", err)
+	}
+
+	player, err := Load(path)
+	if err != nil {
+		t.Fatalf("load replay: %v", err)
+	}
+
+	loaded := player.Header()
+	if loaded.Version != CurrentVersion || loaded.Seed != 42 || loaded.Level != "long_fall.json" {
+		t.Fatalf("unexpected header %+v", loaded)
+	}
+	if loaded.InitialAbilities == nil || !loaded.InitialAbilities.Anchor {
+		t.Fatalf("expected initial abilities to round trip, got %+v", loaded.InitialAbilities)
+	}
+	if loaded.Save == nil || loaded.Save.Player.GearCount != 3 {
+		t.Fatalf("expected starting save to round trip, got %+v", loaded.Save)
+	}
+	if player.Len() != len(frames) {
+		t.Fatalf("expected %d frames, got %d", len(frames), player.Len())
+	}
+
+	for i, want := range frames {
+		got, ok := player.Next()
+		if !ok {
+			t.Fatalf("frame %d: expected frame", i)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("frame %d: got %+v, want %+v", i, got, want)
+		}
+	}
+	if _, ok := player.Next(); ok {
+		t.Fatal("expected replay to be exhausted")
+	}
+	if !player.Done() {
+		t.Fatal("expected replay to report done")
+	}
+}
+
+func TestRecorderWritesEachSessionToItsOwnFile(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := Create(filepath.Join(dir, "session.jsonl"))
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	levels := []string{"long_fall.json", "disposal_8.json", "long_fall.json"}
+	for i, level := range levels {
+		if err := recorder.Begin(Header{Level: level}); err != nil {
+			t.Fatalf("begin session %d: %v", i+1, err)
+		}
+		for frame := 0; frame <= i; frame++ {
+			if err := recorder.Record(Frame{}); err != nil {
+				t.Fatalf("record session %d: %v", i+1, err)
+			}
+		}
+		if err := recorder.End(); err != nil {
+			t.Fatalf("end session %d: %v", i+1, err)
+		}
+	}
+
+	for i, name := range []string{"session.jsonl", "session-2.jsonl", "session-3.jsonl"} {
+		player, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+		if player.Header().Level != levels[i] || player.Len() != i+1 {
+			t.Fatalf("expected %s to hold %s with %d frames, got %s with %d", name, levels[i], i+1, player.Header().Level, player.Len())
+		}
+	}
+
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("close recorder: %v", err)
+	}
+	if err := recorder.Begin(Header{}); err == nil {
+		t.Fatal("expected a closed recorder to refuse another session")
+	}
+}
+
+func TestRecordRequiresHeader(t *testing.T) {
+	recorder, err := Create(filepath.Join(t.TempDir(), "session.jsonl"))
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	if err := recorder.Record(Frame{}); err == nil {
+		t.Fatal("expected frame before header to fail")
+	}
+}
+
+func TestDecodeKeepsFramesBeforeTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := Create(path)
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	if err := recorder.Begin(Header{Level: "long_fall.json"}); err != nil {
+		t.Fatalf("begin recording: %v", err)
+	}
+	if err := recorder.Record(Frame{Input: component.Input{MoveX: -1}}); err != nil {
+		t.Fatalf("record frame: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("close recorder: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read recording: %v", err)
+	}
+	data = append(data, []byte(`{"input":{"MoveX":1`)...)
+
+	player, err := Decode(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("decode truncated recording: %v", err)
+	}
+	if player.Len() != 1 {
+		t.Fatalf("expected the complete frame to survive, got %d frames", player.Len())
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	if _, err := Decode(strings.NewReader(`{"version":99,"level":"long_fall.json"}`)); err == nil {
+		t.Fatal("expected unknown version to be rejected")
+	}
+}
+
+func TestParseScriptExpandsHeldAndPressedActions(t *testing.T) {
+	frames, err := ParseScript(strings.NewReader(`
+# comment
+2 right jump
+1 attack interact # trailing comment
+`))
+	if err != nil {
+		t.Fatalf("parse script: %v", err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(frames))
+	}
+	if frames[0].Input.MoveX != 1 || !frames[0].Input.Jump || !frames[0].Input.JumpPressed {
+		t.Fatalf("expected first frame to hold right and press jump, got %+v", frames[0].Input)
+	}
+	if frames[1].Input.MoveX != 1 || !frames[1].Input.Jump || frames[1].Input.JumpPressed {
+		t.Fatalf("expected second frame to hold jump without a new press, got %+v", frames[1].Input)
+	}
+	if !frames[2].Input.AttackPressed || !frames[2].Dialogue.Pressed || frames[2].Input.MoveX != 0 {
+		t.Fatalf("expected third frame to attack and interact, got %+v", frames[2])
+	}
+}
+
+func TestParseScriptRejectsUnknownAction(t *testing.T) {
+	if _, err := ParseScript(strings.NewReader("10 moonwalk\n")); err == nil {
+		t.Fatal("expected unknown action to be rejected")
+	}
+	if _, err := ParseScript(strings.NewReader("zero right\n")); err == nil {
+		t.Fatal("expected invalid frame count to be rejected")
+	}
+}
@@ -0,0 +1,114 @@
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+// ParseScript builds frames from a hand-written input script. Each non-empty
+// line is a frame count followed by the actions held for that many frames:
+//
+//	# walk right, then jump while still moving
+//	30 right
+//	20 right jump
+//	60
+//
+// Held actions (left, right, up, down, jump, aim, reel_in, reel_out) stay
+// active for every frame of the step. Press actions (attack, attack_up, heal,
+// anchor, auto_anchor, release_anchor, menu, interact, dialogue_up,
+// dialogue_down, enter) fire on the first frame only; jump also reports
+// JumpPressed on its first frame.
+func ParseScript(r io.Reader) ([]Frame, error) {
+	scanner := bufio.NewScanner(r)
+	frames := make([]Frame, 0)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		count, err := strconv.Atoi(fields[0])
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("input script line %d: expected a positive frame count, got %q", lineNumber, fields[0])
+		}
+
+		for i := 0; i < count; i++ {
+			var frame Frame
+			for _, action := range fields[1:] {
+				if err := applyScriptAction(&frame, strings.ToLower(action), i == 0); err != nil {
+					return nil, fmt.Errorf("input script line %d: %w", lineNumber, err)
+				}
+			}
+			frames = append(frames, frame)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read input script: %w", err)
+	}
+
+	return frames, nil
+}
+
+func applyScriptAction(frame *Frame, action string, first bool) error {
+	input := &frame.Input
+	switch action {
+	case "left":
+		input.MoveX -= 1
+	case "right":
+		input.MoveX += 1
+	case "up":
+		input.MoveY -= 1
+	case "down":
+		input.MoveY += 1
+		input.LookY = 1
+	case "jump":
+		input.Jump = true
+		input.JumpPressed = first
+	case "aim":
+		input.Aim = true
+	case "reel_in":
+		input.AnchorReelIn = true
+	case "reel_out":
+		input.AnchorReelOut = true
+	case "attack":
+		input.AttackPressed = first
+	case "attack_up":
+		input.UpwardAttackPressed = first
+	case "heal":
+		input.HealPressed = first
+	case "anchor":
+		input.AnchorPressed = first
+	case "auto_anchor":
+		input.AutoAnchorPressed = first
+	case "release_anchor":
+		input.AnchorReleasePressed = first
+	case "menu":
+		input.MenuPressed = first
+	case "interact":
+		frame.Dialogue.Pressed = first
+	case "dialogue_up":
+		if first {
+			frame.Dialogue.Move = -1
+		}
+	case "dialogue_down":
+		if first {
+			frame.Dialogue.Move = 1
+		}
+	case "enter":
+		frame.Transition = component.TransitionInput{UpPressed: first}
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package savegame
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreSaveRotatesBackupsAndLoadFallsBackOnCorruption(t *testing.T) {
+	root := t.TempDir()
+	store := &Store{path: filepath.Join(root, "slot.json")}
+
+	for gear := 1; gear <= BackupCount+2; gear++ {
+		if err := store.Save(&File{Level: "long_fall.json", Player: PlayerState{GearCount: gear}}); err != nil {
+			t.Fatalf("save %d: %v", gear, err)
+		}
+	}
+
+	for n := 1; n <= BackupCount; n++ {
+		backup, err := loadPath(rotatingBackupPath(store.path, n))
+		if err != nil {
+			t.Fatalf("load backup %d: %v", n, err)
+		}
+		if want := BackupCount + 2 - n; backup.Player.GearCount != want {
+			t.Fatalf("expected backup %d to hold gear %d, got %d", n, want, backup.Player.GearCount)
+		}
+	}
+	if _, err := os.Stat(rotatingBackupPath(store.path, BackupCount+1)); !os.IsNotExist(err) {
+		t.Fatalf("expected only %d backups, got err=%v", BackupCount, err)
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatalf("read save: %v", err)
+	}
+	tampered := strings.Replace(string(data), `"gearCount": 5`, `"gearCount": 99`, 1)
+	if tampered == string(data) {
+		t.Fatal("expected to tamper with the gear count")
+	}
+	writeRawFixture(t, root, "slot.json", []byte(tampered))
+
+	if _, err := loadPath(store.path); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("load with fallback: %v", err)
+	}
+	if loaded.Player.GearCount != 4 || loaded.RestoredFrom != "slot.json.1.bak" {
+		t.Fatalf("expected the newest backup, got gear %d from %q", loaded.Player.GearCount, loaded.RestoredFrom)
+	}
+
+	slots, err := listSlotsInDir(root, 4)
+	if err != nil {
+		t.Fatalf("list slots: %v", err)
+	}
+	if len(slots) != 1 || slots[0].Snapshot.RestoredFrom == "" {
+		t.Fatalf("expected the slot list to report the backup, got %+v", slots)
+	}
+
+	if err := store.Save(&File{Level: "long_fall.json", Player: PlayerState{GearCount: 6}}); err != nil {
+		t.Fatalf("save over corrupt slot: %v", err)
+	}
+	backup, err := loadPath(rotatingBackupPath(store.path, 1))
+	if err != nil || backup.Player.GearCount != 4 {
+		t.Fatalf("expected the corrupt save to be kept out of the backups, got %+v, %v", backup, err)
+	}
+}
+
+func TestLoadPathAcceptsSaveWithoutChecksum(t *testing.T) {
+	root := t.TempDir()
+	writeSaveFixture(t, root, "slot.json", "long_fall.json", 2)
+
+	file, err := loadPath(filepath.Join(root, "slot.json"))
+	if err != nil {
+		t.Fatalf("load save without checksum: %v", err)
+	}
+	if file.Player.GearCount != 2 {
+		t.Fatalf("unexpected gear count %d", file.Player.GearCount)
+	}
+}
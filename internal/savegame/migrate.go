@@ -0,0 +1,121 @@
+package savegame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown save version")
+	ErrNewerVersion   = errors.New("save is from a newer version of the game")
+)
+
+// Migration upgrades a raw save document by one version, from the version it
+// is registered under to the next. It edits the document in place.
+type Migration func(raw map[string]any) error
+
+// migrations holds the upgrade step out of each old version. Adding a field
+// that older saves can leave at its zero value needs no migration; renaming,
+// moving or reinterpreting one does, along with a CurrentVersion bump.
+var migrations = map[int]Migration{}
+
+// migrateSave verifies raw save JSON and upgrades it to target, returning the
+// upgraded JSON with the version it was written at. A save without a version
+// is treated as version 1, which predates versioning.
+func migrateSave(data []byte, target int, steps map[int]Migration) ([]byte, int, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, err
+	}
+	if err := verifyChecksum(raw); err != nil {
+		return nil, 0, err
+	}
+
+	version := 1
+	if value, ok := raw["version"]; ok && value != nil {
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) {
+			return nil, 0, fmt.Errorf("%w %v", ErrUnknownVersion, value)
+		}
+		if number != 0 {
+			version = int(number)
+		}
+	}
+	if version < 1 {
+		return nil, version, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	if version > target {
+		return nil, version, fmt.Errorf("%w (version %d, supported up to %d)", ErrNewerVersion, version, target)
+	}
+	if version == target {
+		return data, version, nil
+	}
+
+	for from := version; from < target; from++ {
+		step, ok := steps[from]
+		if !ok || step == nil {
+			return nil, version, fmt.Errorf("%w %d: no migration to version %d", ErrUnknownVersion, from, from+1)
+		}
+		if err := step(raw); err != nil {
+			return nil, version, fmt.Errorf("migrate version %d to %d: %w", from, from+1, err)
+		}
+		raw["version"] = from + 1
+	}
+	// The old checksum no longer matches; it is recomputed when the upgraded
+	// save is written.
+	delete(raw, "checksum")
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, err
+	}
+	return upgraded, version, nil
+}
+
+// upgradePath loads the save at path, migrating it to target. When it was
+// migrated, the original bytes are copied to a backup next to it before the
+// upgraded file replaces it.
+func upgradePath(path string, target int, steps map[int]Migration) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load save %q: %w", path, err)
+	}
+
+	upgraded, version, err := migrateSave(data, target, steps)
+	if err != nil {
+		return nil, fmt.Errorf("load save %q: %w", path, err)
+	}
+
+	file, err := decodeFile(path, upgraded, target)
+	if err != nil {
+		return nil, err
+	}
+	if version == target {
+		return file, nil
+	}
+
+	backup := backupPath(path, version)
+	if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(backup, "save-backup-*", data); err != nil {
+			return nil, fmt.Errorf("back up save %q: %w", path, err)
+		}
+	}
+
+	encoded, err := encodeFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("upgrade save %q: encode json: %w", path, err)
+	}
+	if err := writeFileAtomic(path, "save-*.json", encoded); err != nil {
+		return nil, fmt.Errorf("upgrade save %q: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// backupPath names the copy of a save kept from before it was upgraded. The
+// .bak extension keeps it out of the slot list.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
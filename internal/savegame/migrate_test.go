@@ -0,0 +1,72 @@
+package savegame
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpgradePathMigratesBacksUpAndRewritesSave(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "slot.json")
+	original := []byte(`{"version": 1, "map": "long_fall.json", "player": {"health": {"initial": 5, "current": 4}}}` + "\n")
+	writeRawFixture(t, root, "slot.json", original)
+
+	steps := map[int]Migration{
+		1: func(raw map[string]any) error {
+			raw["level"] = raw["map"]
+			delete(raw, "map")
+			return nil
+		},
+		2: func(raw map[string]any) error {
+			raw["flags"] = map[string]any{"migrated": map[string]any{"kind": "bool", "bool": true}}
+			return nil
+		},
+	}
+
+	file, err := upgradePath(path, 3, steps)
+	if err != nil {
+		t.Fatalf("upgrade save: %v", err)
+	}
+	if file.Version != 3 || file.Level != "long_fall.json" || file.Player.Health.Current != 4 || !file.Flags["migrated"].Bool {
+		t.Fatalf("unexpected upgraded save %+v", file)
+	}
+
+	backup, err := os.ReadFile(backupPath(path, 1))
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if string(backup) != string(original) {
+		t.Fatalf("expected backup to keep the original bytes, got %q", backup)
+	}
+
+	reloaded, err := upgradePath(path, 3, nil)
+	if err != nil {
+		t.Fatalf("reload upgraded save: %v", err)
+	}
+	if reloaded.Version != 3 || reloaded.Level != "long_fall.json" {
+		t.Fatalf("expected the upgraded save to be written back, got %+v", reloaded)
+	}
+}
+
+func TestUpgradePathRefusesNewerAndUnknownVersions(t *testing.T) {
+	root := t.TempDir()
+	writeRawFixture(t, root, "newer.json", []byte(`{"version": 4, "level": "long_fall.json"}`))
+	writeRawFixture(t, root, "negative.json", []byte(`{"version": -1, "level": "long_fall.json"}`))
+	writeRawFixture(t, root, "gap.json", []byte(`{"version": 1, "level": "long_fall.json"}`))
+
+	if _, err := upgradePath(filepath.Join(root, "newer.json"), 3, nil); !errors.Is(err, ErrNewerVersion) {
+		t.Fatalf("expected newer version error, got %v", err)
+	}
+	if _, err := upgradePath(filepath.Join(root, "negative.json"), 3, nil); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expected unknown version error, got %v", err)
+	}
+	if _, err := upgradePath(filepath.Join(root, "gap.json"), 3, map[int]Migration{1: func(map[string]any) error { return nil }}); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expected missing migration error, got %v", err)
+	}
+
+	if _, err := os.Stat(backupPath(filepath.Join(root, "gap.json"), 1)); !os.IsNotExist(err) {
+		t.Fatalf("expected a refused save to leave no backup, got err=%v", err)
+	}
+}
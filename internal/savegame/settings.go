@@ -0,0 +1,130 @@
+package savegame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+const (
+	SettingsVersion = 1
+	// SettingsFileName sits in the save directory next to the slots and is
+	// skipped when listing them.
+	SettingsFileName = "settings.json"
+)
+
+// Settings holds the player's audio, video and accessibility options. They
+// are shared by every save slot.
+type Settings struct {
+	Version        int     `json:"version"`
+	MasterVolume   float64 `json:"masterVolume"`
+	MusicVolume    float64 `json:"musicVolume"`
+	SFXVolume      float64 `json:"sfxVolume"`
+	Fullscreen     bool    `json:"fullscreen"`
+	IntegerScaling bool    `json:"integerScaling"`
+	ScreenShake    float64 `json:"screenShake"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Version:      SettingsVersion,
+		MasterVolume: 1,
+		MusicVolume:  1,
+		SFXVolume:    1,
+		ScreenShake:  1,
+	}
+}
+
+// Normalized clamps every level into the 0-1 range.
+func (s Settings) Normalized() Settings {
+	s.Version = SettingsVersion
+	s.MasterVolume = ClampUnit(s.MasterVolume)
+	s.MusicVolume = ClampUnit(s.MusicVolume)
+	s.SFXVolume = ClampUnit(s.SFXVolume)
+	s.ScreenShake = ClampUnit(s.ScreenShake)
+	return s
+}
+
+// MusicGain is the multiplier applied to music tracks.
+func (s Settings) MusicGain() float64 {
+	return ClampUnit(s.MasterVolume) * ClampUnit(s.MusicVolume)
+}
+
+// SFXGain is the multiplier applied to sound effects.
+func (s Settings) SFXGain() float64 {
+	return ClampUnit(s.MasterVolume) * ClampUnit(s.SFXVolume)
+}
+
+// LoadSettings reads the settings file from the platform save directory.
+// A missing file, or a web build, yields the defaults.
+func LoadSettings() (Settings, error) {
+	if isWebTarget(runtime.GOOS, runtime.GOARCH) {
+		return DefaultSettings(), nil
+	}
+
+	path, err := ResolvePath(SettingsFileName)
+	if err != nil {
+		return DefaultSettings(), err
+	}
+	return loadSettingsPath(path)
+}
+
+// SaveSettings writes settings to the platform save directory.
+func SaveSettings(settings Settings) error {
+	if isWebTarget(runtime.GOOS, runtime.GOARCH) {
+		return nil
+	}
+
+	path, err := ResolvePath(SettingsFileName)
+	if err != nil {
+		return err
+	}
+	return saveSettingsPath(path, settings)
+}
+
+func loadSettingsPath(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return DefaultSettings(), nil
+		}
+		return DefaultSettings(), fmt.Errorf("load settings %q: %w", path, err)
+	}
+
+	// Decode over the defaults so options added later keep their default
+	// value when an older file does not mention them.
+	settings := DefaultSettings()
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return DefaultSettings(), fmt.Errorf("decode settings %q: %w", path, err)
+	}
+	if settings.Version > SettingsVersion {
+		return DefaultSettings(), fmt.Errorf("load settings %q: unsupported version %d", path, settings.Version)
+	}
+	return settings.Normalized(), nil
+}
+
+func saveSettingsPath(path string, settings Settings) error {
+	data, err := json.MarshalIndent(settings.Normalized(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("save settings: encode json: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := writeFileAtomic(path, "settings-*.json", data); err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	return nil
+}
+
+// ClampUnit limits value to the 0 to 1 range volumes and scales use.
+func ClampUnit(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
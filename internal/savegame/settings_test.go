@@ -0,0 +1,82 @@
+package savegame
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSettingsMissingFileUsesDefaults(t *testing.T) {
+	settings, err := loadSettingsPath(filepath.Join(t.TempDir(), SettingsFileName))
+	if err != nil {
+		t.Fatalf("load settings: %v", err)
+	}
+	if settings != DefaultSettings() {
+		t.Fatalf("expected defaults, got %+v", settings)
+	}
+}
+
+func TestSettingsSaveAndLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", SettingsFileName)
+	want := Settings{
+		Version:        SettingsVersion,
+		MasterVolume:   0.8,
+		MusicVolume:    0.5,
+		SFXVolume:      0.25,
+		Fullscreen:     true,
+		IntegerScaling: true,
+		ScreenShake:    0.4,
+	}
+
+	if err := saveSettingsPath(path, want); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	got, err := loadSettingsPath(path)
+	if err != nil {
+		t.Fatalf("load settings: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLoadSettingsKeepsDefaultsForMissingFields(t *testing.T) {
+	root := t.TempDir()
+	writeRawFixture(t, root, SettingsFileName, []byte(`{"version":1,"musicVolume":0.3,"screenShake":4}`))
+
+	settings, err := loadSettingsPath(filepath.Join(root, SettingsFileName))
+	if err != nil {
+		t.Fatalf("load settings: %v", err)
+	}
+	if settings.MasterVolume != 1 || settings.SFXVolume != 1 {
+		t.Fatalf("expected unspecified volumes to keep defaults, got %+v", settings)
+	}
+	if settings.MusicVolume != 0.3 {
+		t.Fatalf("expected music volume 0.3, got %v", settings.MusicVolume)
+	}
+	if settings.ScreenShake != 1 {
+		t.Fatalf("expected screen shake to be clamped to 1, got %v", settings.ScreenShake)
+	}
+}
+
+func TestLoadSettingsRejectsNewerVersion(t *testing.T) {
+	root := t.TempDir()
+	writeRawFixture(t, root, SettingsFileName, []byte(`{"version":99}`))
+
+	settings, err := loadSettingsPath(filepath.Join(root, SettingsFileName))
+	if err == nil {
+		t.Fatal("expected newer settings version to fail")
+	}
+	if settings != DefaultSettings() {
+		t.Fatalf("expected defaults alongside the error, got %+v", settings)
+	}
+}
+
+func TestSettingsGains(t *testing.T) {
+	settings := Settings{MasterVolume: 0.5, MusicVolume: 0.5, SFXVolume: 2}
+	if got := settings.MusicGain(); got != 0.25 {
+		t.Fatalf("expected music gain 0.25, got %v", got)
+	}
+	if got := settings.SFXGain(); got != 0.5 {
+		t.Fatalf("expected sfx gain to clamp to 0.5, got %v", got)
+	}
+}
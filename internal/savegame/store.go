@@ -16,12 +16,18 @@ import (
 const CurrentVersion = 1
 
 type File struct {
-	Version           int               `json:"version"`
-	Level             string            `json:"level"`
-	Player            PlayerState       `json:"player"`
-	LevelLayerStates  map[string]bool   `json:"levelLayerStates,omitempty"`
-	LevelEntityStates map[string]string `json:"levelEntityStates,omitempty"`
-	SavedAt           time.Time         `json:"savedAt"`
+	Version           int                  `json:"version"`
+	Level             string               `json:"level"`
+	Player            PlayerState          `json:"player"`
+	LevelLayerStates  map[string]bool      `json:"levelLayerStates,omitempty"`
+	LevelEntityStates map[string]string    `json:"levelEntityStates,omitempty"`
+	ShopPurchases     map[string]int       `json:"shopPurchases,omitempty"`
+	Flags             map[string]FlagState `json:"flags,omitempty"`
+	SavedAt           time.Time            `json:"savedAt"`
+	Checksum          string               `json:"checksum,omitempty"`
+	// RestoredFrom names the backup this save was read from when the slot
+	// itself was missing or corrupt.
+	RestoredFrom string `json:"-"`
 }
 
 type PlayerState struct {
@@ -38,6 +44,14 @@ type PlayerState struct {
 	TransitionPop      *TransitionPopState      `json:"transitionPop,omitempty"`
 }
 
+// FlagState is a typed story flag; only the field named by Kind is set.
+type FlagState struct {
+	Kind   string `json:"kind"`
+	Bool   bool   `json:"bool,omitempty"`
+	Int    int    `json:"int,omitempty"`
+	String string `json:"string,omitempty"`
+}
+
 type HealthState struct {
 	Initial int `json:"initial"`
 	Current int `json:"current"`
@@ -149,7 +163,19 @@ func (s *Store) Load() (*File, error) {
 		return nil, nil
 	}
 
-	return loadPath(s.path)
+	file, err := upgradePath(s.path, CurrentVersion, migrations)
+	if err == nil || errors.Is(err, ErrNewerVersion) {
+		return file, err
+	}
+
+	backup, backupErr := loadNewestBackup(s.path)
+	if backupErr != nil {
+		return nil, err
+	}
+	if s.logf != nil {
+		s.logf("save game: %v; restored from %s", err, backup.RestoredFrom)
+	}
+	return backup, nil
 }
 
 func ListSlots(limit int) ([]SlotInfo, error) {
@@ -195,13 +221,20 @@ func listSlotsInDir(root string, limit int) ([]SlotInfo, error) {
 		if name == "" || strings.ToLower(filepath.Ext(name)) != ".json" {
 			continue
 		}
+		if strings.EqualFold(name, SettingsFileName) {
+			continue
+		}
 		fileNames = append(fileNames, name)
 	}
 	sort.Strings(fileNames)
 
 	slots := make([]SlotInfo, 0, min(limit, len(fileNames)))
 	for _, fileName := range fileNames {
-		snapshot, err := loadPath(filepath.Join(root, fileName))
+		path := filepath.Join(root, fileName)
+		snapshot, err := loadPath(path)
+		if err != nil && !errors.Is(err, ErrNewerVersion) {
+			snapshot, err = loadNewestBackup(path)
+		}
 		if err != nil {
 			continue
 		}
@@ -214,24 +247,31 @@ func listSlotsInDir(root string, limit int) ([]SlotInfo, error) {
 	return slots, nil
 }
 
+// loadPath reads a save for display, migrating it in memory only. The file
+// on disk is upgraded when the slot is actually loaded through a Store.
 func loadPath(path string) (*File, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("load save %q: %w", path, err)
 	}
 
-	return decodeFile(path, data)
+	upgraded, _, err := migrateSave(data, CurrentVersion, migrations)
+	if err != nil {
+		return nil, fmt.Errorf("load save %q: %w", path, err)
+	}
+
+	return decodeFile(path, upgraded, CurrentVersion)
 }
 
-func decodeFile(path string, data []byte) (*File, error) {
+func decodeFile(path string, data []byte, version int) (*File, error) {
 	var file File
 	if err := json.Unmarshal(data, &file); err != nil {
 		return nil, fmt.Errorf("decode save %q: %w", path, err)
 	}
 	if file.Version == 0 {
-		file.Version = CurrentVersion
+		file.Version = version
 	}
-	if file.Version != CurrentVersion {
+	if file.Version != version {
 		return nil, fmt.Errorf("load save %q: unsupported version %d", path, file.Version)
 	}
 	if strings.TrimSpace(file.Level) == "" {
@@ -247,6 +287,8 @@ func decodeFile(path string, data []byte) (*File, error) {
 	file.Player.Inventory = cloneInventoryItems(file.Player.Inventory)
 	file.LevelLayerStates = cloneLevelLayerStates(file.LevelLayerStates)
 	file.LevelEntityStates = cloneLevelEntityStates(file.LevelEntityStates)
+	file.ShopPurchases = cloneShopPurchases(file.ShopPurchases)
+	file.Flags = cloneFlags(file.Flags)
 	return &file, nil
 }
 
@@ -265,19 +307,31 @@ func (s *Store) Save(snapshot *File) error {
 	cloned.Version = CurrentVersion
 	cloned.SavedAt = time.Now().UTC()
 
-	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
-		return fmt.Errorf("save game: create directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(cloned, "", "  ")
+	data, err := encodeFile(cloned)
 	if err != nil {
 		return fmt.Errorf("save game: encode json: %w", err)
 	}
-	data = append(data, '\n')
 
-	tmp, err := os.CreateTemp(filepath.Dir(s.path), "save-*.json")
+	if err := rotateBackups(s.path); err != nil {
+		return fmt.Errorf("save game: rotate backups: %w", err)
+	}
+	if err := writeFileAtomic(s.path, "save-*.json", data); err != nil {
+		return fmt.Errorf("save game: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path, syncs it and
+// renames it into place, so readers never see a partially written file.
+func writeFileAtomic(path, tempPattern string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPattern)
 	if err != nil {
-		return fmt.Errorf("save game: create temp file: %w", err)
+		return fmt.Errorf("create temp file: %w", err)
 	}
 	tmpPath := tmp.Name()
 	defer func() {
@@ -286,18 +340,34 @@ func (s *Store) Save(snapshot *File) error {
 
 	if _, err := tmp.Write(data); err != nil {
 		_ = tmp.Close()
-		return fmt.Errorf("save game: write temp file: %w", err)
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
 	}
 	if err := tmp.Close(); err != nil {
-		return fmt.Errorf("save game: close temp file: %w", err)
+		return fmt.Errorf("close temp file: %w", err)
 	}
-	if err := os.Rename(tmpPath, s.path); err != nil {
-		return fmt.Errorf("save game: replace save file: %w", err)
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace %s: %w", filepath.Base(path), err)
 	}
+	syncDir(filepath.Dir(path))
 
 	return nil
 }
 
+// syncDir flushes a directory entry after a rename. Not every platform lets
+// a directory be synced, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
 func (s *Store) SaveAsync(snapshot *File) {
 	if s == nil || s.disabled || snapshot == nil {
 		return
@@ -388,6 +458,8 @@ func cloneFile(file *File) *File {
 	cloned.Player.Inventory = cloneInventoryItems(file.Player.Inventory)
 	cloned.LevelLayerStates = cloneLevelLayerStates(file.LevelLayerStates)
 	cloned.LevelEntityStates = cloneLevelEntityStates(file.LevelEntityStates)
+	cloned.ShopPurchases = cloneShopPurchases(file.ShopPurchases)
+	cloned.Flags = cloneFlags(file.Flags)
 	return &cloned
 }
 
@@ -422,6 +494,28 @@ func cloneLevelEntityStates(states map[string]string) map[string]string {
 	return cloned
 }
 
+func cloneShopPurchases(purchases map[string]int) map[string]int {
+	if len(purchases) == 0 {
+		return nil
+	}
+	cloned := make(map[string]int, len(purchases))
+	for key, value := range purchases {
+		cloned[key] = value
+	}
+	return cloned
+}
+
+func cloneFlags(flags map[string]FlagState) map[string]FlagState {
+	if len(flags) == 0 {
+		return nil
+	}
+	cloned := make(map[string]FlagState, len(flags))
+	for key, value := range flags {
+		cloned[key] = value
+	}
+	return cloned
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -49,6 +49,20 @@ func TestListSlotsInDirMissingDirectoryReturnsEmpty(t *testing.T) {
 	}
 }
 
+func TestListSlotsInDirSkipsSettingsFile(t *testing.T) {
+	root := t.TempDir()
+	writeSaveFixture(t, root, "slot_a.json", "long_fall.json", 1)
+	writeRawFixture(t, root, SettingsFileName, []byte(`{"version":1,"level":"long_fall.json"}`))
+
+	slots, err := listSlotsInDir(root, 4)
+	if err != nil {
+		t.Fatalf("list slots: %v", err)
+	}
+	if len(slots) != 1 || slots[0].FileName != "slot_a.json" {
+		t.Fatalf("expected only slot_a.json, got %+v", slots)
+	}
+}
+
 func writeSaveFixture(t *testing.T, root, name, level string, gear int) {
 	t.Helper()
 	writeRawFixture(t, root, name, []byte("{\n  \"version\": 1,\n  \"level\": \""+level+"\",\n  \"player\": {\n    \"health\": {\"initial\": 5, \"current\": 3},\n    \"abilities\": {\"doubleJump\": true, \"wallGrab\": false, \"anchor\": true, \"heal\": false},\n    \"gearCount\": "+itoa(gear)+",\n    \"transform\": {\"x\": 0, \"y\": 0, \"scaleX\": 1, \"scaleY\": 1, \"rotation\": 0},\n    \"safeRespawn\": {\"x\": 0, \"y\": 0, \"initialized\": false},\n    \"facingLeft\": false\n  },\n  \"savedAt\": \""+time.Date(2026, time.April, gear, 12, 0, 0, 0, time.UTC).Format(time.RFC3339)+"\"\n}\n"))
@@ -0,0 +1,162 @@
+package savegame
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/jakecoffman/cp"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/ecs/entity"
+)
+
+var (
+	worldSnapshotsOnce sync.Once
+	worldSnapshots     *ecs.SnapshotRegistry
+)
+
+// WorldSnapshots is the registry SnapshotWorld and RestoreWorld use. Unlike
+// CaptureWorld it covers every entity, so moving platform, arena and lever
+// state come back exactly as they were, not as the level file sets them up.
+// Enemy and boss AI lives in script state, which the script runtime saves as
+// a snapshot section.
+//
+// Only plain-data components are registered; the rest stay as they are. An
+// entity destroyed since the snapshot is built again from its prefab before
+// these are put back, and one that wasn't built from a prefab stays
+// destroyed.
+func WorldSnapshots() *ecs.SnapshotRegistry {
+	worldSnapshotsOnce.Do(func() {
+		r := ecs.NewSnapshotRegistry().ReviveWith(revivePrefab)
+		ecs.RegisterSnapshot(r, "prefab_source", component.PrefabSourceComponent.Kind(), ecs.JSONCodec[component.PrefabSource]())
+		ecs.RegisterSnapshot(r, "transform", component.TransformComponent.Kind(), ecs.JSONCodec[component.Transform]())
+		ecs.RegisterSnapshot(r, "physics_body", component.PhysicsBodyComponent.Kind(), physicsBodyCodec())
+		ecs.RegisterSnapshot(r, "health", component.HealthComponent.Kind(), ecs.JSONCodec[component.Health]())
+		ecs.RegisterSnapshot(r, "invulnerable", component.InvulnerableComponent.Kind(), ecs.JSONCodec[component.Invulnerable]())
+		ecs.RegisterSnapshot(r, "ttl", component.TTLComponent.Kind(), ecs.JSONCodec[component.TTL]())
+		ecs.RegisterSnapshot(r, "hazard", component.HazardComponent.Kind(), ecs.JSONCodec[component.Hazard]())
+		ecs.RegisterSnapshot(r, "game_entity_id", component.GameEntityIDComponent.Kind(), ecs.JSONCodec[component.GameEntityID]())
+		ecs.RegisterSnapshot(r, "safe_respawn", component.SafeRespawnComponent.Kind(), ecs.JSONCodec[component.SafeRespawn]())
+		ecs.RegisterSnapshot(r, "player_checkpoint", component.PlayerCheckpointComponent.Kind(), ecs.JSONCodec[component.PlayerCheckpoint]())
+		ecs.RegisterSnapshot(r, "abilities", component.AbilitiesComponent.Kind(), ecs.JSONCodec[component.Abilities]())
+		ecs.RegisterSnapshot(r, "inventory", component.InventoryComponent.Kind(), ecs.JSONCodec[component.Inventory]())
+		ecs.RegisterSnapshot(r, "flags", component.FlagsComponent.Kind(), ecs.JSONCodec[component.Flags]())
+		ecs.RegisterSnapshot(r, "player_gear_count", component.PlayerGearCountComponent.Kind(), ecs.JSONCodec[component.PlayerGearCount]())
+		ecs.RegisterSnapshot(r, "level_layer_state_map", component.LevelLayerStateMapComponent.Kind(), ecs.JSONCodec[component.LevelLayerStateMap]())
+		ecs.RegisterSnapshot(r, "level_entity_state_map", component.LevelEntityStateMapComponent.Kind(), ecs.JSONCodec[component.LevelEntityStateMap]())
+		ecs.RegisterSnapshot(r, "script_runtime", component.ScriptRuntimeComponent.Kind(), ecs.JSONCodec[component.ScriptRuntime]())
+		ecs.RegisterSnapshot(r, "script_state", component.ScriptStateComponent.Kind(), ecs.JSONCodec[component.ScriptState]())
+		ecs.RegisterSnapshot(r, "moving_platform", component.MovingPlatformComponent.Kind(), ecs.JSONCodec[component.MovingPlatform]())
+		ecs.RegisterSnapshot(r, "arena_node", component.ArenaNodeComponent.Kind(), ecs.JSONCodec[component.ArenaNode]())
+		ecs.RegisterSnapshot(r, "lever", component.LeverComponent.Kind(), ecs.JSONCodec[component.Lever]())
+		ecs.RegisterSnapshot(r, "sprite", component.SpriteComponent.Kind(), ecs.JSONCodec[component.Sprite]())
+		ecs.RegisterSnapshot(r, "gate_runtime", component.GateRuntimeComponent.Kind(), gateRuntimeCodec())
+		ecs.RegisterSnapshot(r, "ai_state", component.AIStateComponent.Kind(), ecs.JSONCodec[component.AIState]())
+		ecs.RegisterSnapshot(r, "spawn_children_runtime", component.SpawnChildrenRuntimeComponent.Kind(), ecs.JSONCodec[component.SpawnChildrenRuntime]())
+		worldSnapshots = r
+	})
+	return worldSnapshots
+}
+
+// SnapshotWorld encodes w with WorldSnapshots and the given sections, such as
+// the script runtime.
+func SnapshotWorld(w *ecs.World, sections ...ecs.SnapshotSection) ([]byte, error) {
+	return WorldSnapshots().Snapshot(w, sections...)
+}
+
+// RestoreWorld puts w and the given sections back to a SnapshotWorld snapshot.
+func RestoreWorld(w *ecs.World, data []byte, sections ...ecs.SnapshotSection) error {
+	return WorldSnapshots().Restore(w, data, sections...)
+}
+
+// revivePrefab builds an entity destroyed since the snapshot from the prefab
+// it was first built from.
+func revivePrefab(w *ecs.World, e ecs.Entity, saved func(name string, into any) bool) (bool, error) {
+	var source component.PrefabSource
+	if !saved("prefab_source", &source) || source.Path == "" {
+		return false, nil
+	}
+	if err := entity.RebuildEntity(w, e, source.Path, source.Overrides); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// gateRuntimeCodec keeps the live gate's sprite template image, which isn't
+// saved. A gate without a live runtime, like one brought back, caches its
+// templates again from the restored sprite and body.
+func gateRuntimeCodec() ecs.Codec[component.GateRuntime] {
+	return ecs.Codec[component.GateRuntime]{
+		Encode: func(value *component.GateRuntime) (json.RawMessage, error) {
+			return json.Marshal(value)
+		},
+		Decode: func(data json.RawMessage, current *component.GateRuntime) (*component.GateRuntime, error) {
+			var restored component.GateRuntime
+			if err := json.Unmarshal(data, &restored); err != nil {
+				return nil, err
+			}
+			if current == nil || !current.Initialized {
+				restored.Initialized = false
+				return &restored, nil
+			}
+			restored.SpriteTemplate.Image = current.SpriteTemplate.Image
+			return &restored, nil
+		},
+	}
+}
+
+// physicsBodyState is a physics body in a snapshot: the component's settings
+// and where the live body was and how it was moving.
+type physicsBodyState struct {
+	component.PhysicsBody
+	Motion *bodyMotion `json:"motion,omitempty"`
+}
+
+type bodyMotion struct {
+	X               float64 `json:"x"`
+	Y               float64 `json:"y"`
+	VX              float64 `json:"vx"`
+	VY              float64 `json:"vy"`
+	Angle           float64 `json:"angle"`
+	AngularVelocity float64 `json:"angular_velocity"`
+}
+
+// physicsBodyCodec keeps the live cp body and shape across a restore and moves
+// the body back to where it was. Entities without a live body get theirs from
+// the physics system on its next update.
+func physicsBodyCodec() ecs.Codec[component.PhysicsBody] {
+	return ecs.Codec[component.PhysicsBody]{
+		Encode: func(value *component.PhysicsBody) (json.RawMessage, error) {
+			state := physicsBodyState{PhysicsBody: *value}
+			if body := value.Body; body != nil {
+				pos := body.Position()
+				vel := body.Velocity()
+				state.Motion = &bodyMotion{X: pos.X, Y: pos.Y, VX: vel.X, VY: vel.Y, Angle: body.Angle(), AngularVelocity: body.AngularVelocity()}
+			}
+			return json.Marshal(state)
+		},
+		Decode: func(data json.RawMessage, current *component.PhysicsBody) (*component.PhysicsBody, error) {
+			var state physicsBodyState
+			if err := json.Unmarshal(data, &state); err != nil {
+				return nil, err
+			}
+			restored := state.PhysicsBody
+			if current != nil {
+				restored.Body = current.Body
+				restored.Shape = current.Shape
+			}
+			return &restored, nil
+		},
+		Restored: func(data json.RawMessage, value *component.PhysicsBody) {
+			var state physicsBodyState
+			if value.Body == nil || value.Static || json.Unmarshal(data, &state) != nil || state.Motion == nil {
+				return
+			}
+			motion := state.Motion
+			value.Body.SetPosition(cp.Vector{X: motion.X, Y: motion.Y})
+			value.Body.SetVelocity(motion.VX, motion.VY)
+			value.Body.SetAngle(motion.Angle)
+			value.Body.SetAngularVelocity(motion.AngularVelocity)
+		},
+	}
+}
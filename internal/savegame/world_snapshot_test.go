@@ -0,0 +1,77 @@
+package savegame
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/component"
+)
+
+func TestSnapshotWorldRestoresRuntimeState(t *testing.T) {
+	w := ecs.NewWorld()
+	boss := ecs.CreateEntity(w)
+	_ = ecs.Add(w, boss, component.TransformComponent.Kind(), &component.Transform{X: 10, Y: 20, ScaleX: 1, ScaleY: 1})
+	_ = ecs.Add(w, boss, component.HealthComponent.Kind(), &component.Health{Initial: 30, Current: 12})
+	_ = ecs.Add(w, boss, component.ScriptStateComponent.Kind(), &component.ScriptState{Current: "phase_2"})
+	body := cp.NewBody(1, cp.INFINITY)
+	body.SetPosition(cp.Vector{X: 10, Y: 20})
+	body.SetVelocity(3, -4)
+	_ = ecs.Add(w, boss, component.PhysicsBodyComponent.Kind(), &component.PhysicsBody{Body: body, Width: 32, Height: 48})
+	_ = ecs.Add(w, boss, component.SpriteComponent.Kind(), &component.Sprite{FacingLeft: true})
+
+	platform := ecs.CreateEntity(w)
+	_ = ecs.Add(w, platform, component.MovingPlatformComponent.Kind(), &component.MovingPlatform{Progress: 0.25, Direction: 1, Moving: true, Initialized: true})
+	gate := ecs.CreateEntity(w)
+	_ = ecs.Add(w, gate, component.ArenaNodeComponent.Kind(), &component.ArenaNode{Group: "boss", Active: true, HazardEnabled: true})
+
+	data, err := SnapshotWorld(w)
+	if err != nil {
+		t.Fatalf("snapshot world: %v", err)
+	}
+
+	// The fight goes on after the snapshot.
+	health, _ := ecs.Get(w, boss, component.HealthComponent.Kind())
+	health.Current = 0
+	state, _ := ecs.Get(w, boss, component.ScriptStateComponent.Kind())
+	state.Current = "phase_3"
+	body.SetPosition(cp.Vector{X: 200, Y: 20})
+	body.SetVelocity(0, 0)
+	moving, _ := ecs.Get(w, platform, component.MovingPlatformComponent.Kind())
+	moving.Progress = 0.9
+	sprite, _ := ecs.Get(w, boss, component.SpriteComponent.Kind())
+	sprite.FacingLeft = false
+	ecs.DestroyEntity(w, gate)
+	spawned := ecs.CreateEntity(w)
+	_ = ecs.Add(w, spawned, component.TTLComponent.Kind(), &component.TTL{Frames: 5})
+
+	if err := RestoreWorld(w, data); err != nil {
+		t.Fatalf("restore world: %v", err)
+	}
+
+	if got, _ := ecs.Get(w, boss, component.HealthComponent.Kind()); got == nil || got.Current != 12 {
+		t.Fatalf("expected boss health 12, got %+v", got)
+	}
+	if got, _ := ecs.Get(w, boss, component.ScriptStateComponent.Kind()); got == nil || got.Current != "phase_2" {
+		t.Fatalf("expected boss script state phase_2, got %+v", got)
+	}
+	restoredBody, _ := ecs.Get(w, boss, component.PhysicsBodyComponent.Kind())
+	if restoredBody == nil || restoredBody.Body != body || restoredBody.Width != 32 {
+		t.Fatalf("expected the live body kept with its settings, got %+v", restoredBody)
+	}
+	if pos, vel := body.Position(), body.Velocity(); pos.X != 10 || vel.X != 3 || vel.Y != -4 {
+		t.Fatalf("expected the body moved back to 10 at (3,-4), got %v at %v", pos, vel)
+	}
+	if sprite, _ := ecs.Get(w, boss, component.SpriteComponent.Kind()); sprite == nil || !sprite.FacingLeft {
+		t.Fatalf("expected the boss sprite facing left again, got %+v", sprite)
+	}
+	if got, _ := ecs.Get(w, platform, component.MovingPlatformComponent.Kind()); got == nil || got.Progress != 0.25 {
+		t.Fatalf("expected platform progress 0.25, got %+v", got)
+	}
+	if ecs.IsAlive(w, gate) {
+		t.Fatalf("expected the destroyed gate, built without a prefab, left destroyed")
+	}
+	if ecs.IsAlive(w, spawned) {
+		t.Fatalf("expected the entity spawned after the snapshot destroyed")
+	}
+}
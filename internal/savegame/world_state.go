@@ -95,6 +95,10 @@ func CaptureWorld(w *ecs.World) (*File, error) {
 	}
 	if stateMap, ok := ecs.Get(w, player, component.LevelEntityStateMapComponent.Kind()); ok && stateMap != nil {
 		snapshot.LevelEntityStates = captureLevelEntityStates(stateMap)
+		snapshot.ShopPurchases = cloneShopPurchases(stateMap.Purchased)
+	}
+	if flags, ok := ecs.Get(w, player, component.FlagsComponent.Kind()); ok && flags != nil {
+		snapshot.Flags = captureFlags(flags)
 	}
 
 	if abilitiesEntity, ok := ecs.First(w, component.AbilitiesComponent.Kind()); ok {
@@ -194,7 +198,11 @@ func ApplyWorld(w *ecs.World, file *File) error {
 	}
 	_ = ecs.Add(w, player, component.InventoryComponent.Kind(), &component.Inventory{Items: applyInventory(file.Player.Inventory)})
 	_ = ecs.Add(w, player, component.LevelLayerStateMapComponent.Kind(), &component.LevelLayerStateMap{States: applyLevelLayerStates(file.LevelLayerStates)})
-	_ = ecs.Add(w, player, component.LevelEntityStateMapComponent.Kind(), &component.LevelEntityStateMap{States: applyLevelEntityStates(file.LevelEntityStates)})
+	_ = ecs.Add(w, player, component.LevelEntityStateMapComponent.Kind(), &component.LevelEntityStateMap{
+		States:    applyLevelEntityStates(file.LevelEntityStates),
+		Purchased: cloneShopPurchases(file.ShopPurchases),
+	})
+	_ = ecs.Add(w, player, component.FlagsComponent.Kind(), &component.Flags{Values: applyFlags(file.Flags)})
 
 	abilitiesEntity := ensureAbilitiesEntity(w)
 	_ = ecs.Add(w, abilitiesEntity, component.AbilitiesComponent.Kind(), &component.Abilities{
@@ -327,3 +335,26 @@ func applyLevelEntityStates(states map[string]string) map[string]component.Persi
 	}
 	return copy
 }
+
+func captureFlags(flags *component.Flags) map[string]FlagState {
+	if flags == nil || len(flags.Values) == 0 {
+		return nil
+	}
+
+	copy := make(map[string]FlagState, len(flags.Values))
+	for key, value := range flags.Values {
+		copy[key] = FlagState{Kind: string(value.Kind), Bool: value.Bool, Int: value.Int, String: value.String}
+	}
+	return copy
+}
+
+func applyFlags(flags map[string]FlagState) map[string]component.FlagValue {
+	copy := make(map[string]component.FlagValue, len(flags))
+	for key, value := range flags {
+		switch kind := component.FlagKind(value.Kind); kind {
+		case component.FlagKindBool, component.FlagKindInt, component.FlagKindString:
+			copy[key] = component.FlagValue{Kind: kind, Bool: value.Bool, Int: value.Int, String: value.String}
+		}
+	}
+	return copy
+}
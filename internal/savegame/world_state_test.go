@@ -27,6 +27,11 @@ func TestCaptureWorldAndApplyWorldRoundTrip(t *testing.T) {
 	_ = ecs.Add(source, player, component.LevelEntityStateMapComponent.Kind(), &component.LevelEntityStateMap{States: map[string]component.PersistedLevelEntityState{
 		"disposal_1.json#trigger_1": component.PersistedLevelEntityStateUsed,
 		"disposal_1.json#enemy_1":   component.PersistedLevelEntityStateDefeated,
+	}, Purchased: map[string]int{"dump_market.json#merchant#0": 2}})
+	_ = ecs.Add(source, player, component.FlagsComponent.Kind(), &component.Flags{Values: map[string]component.FlagValue{
+		"met_merchant": component.BoolFlag(true),
+		"quest_stage":  component.IntFlag(2),
+		"rival_name":   component.StringFlag("Rust"),
 	}})
 
 	abilities := ecs.CreateEntity(source)
@@ -97,6 +102,13 @@ func TestCaptureWorldAndApplyWorldRoundTrip(t *testing.T) {
 	if stateMap == nil || stateMap.States["disposal_1.json#trigger_1"] != component.PersistedLevelEntityStateUsed {
 		t.Fatalf("unexpected state map %+v", stateMap)
 	}
+	if stateMap.Purchased["dump_market.json#merchant#0"] != 2 {
+		t.Fatalf("unexpected shop purchases %+v", stateMap.Purchased)
+	}
+	flags, _ := ecs.Get(target, player2, component.FlagsComponent.Kind())
+	if flags == nil || !flags.Values["met_merchant"].Bool || flags.Values["quest_stage"] != component.IntFlag(2) || flags.Values["rival_name"] != component.StringFlag("Rust") {
+		t.Fatalf("unexpected flags %+v", flags)
+	}
 
 	abilitiesEntity, ok := ecs.First(target, component.AbilitiesComponent.Kind())
 	if !ok {
@@ -10,8 +10,11 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/milk9111/sidescroller/assets"
+	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/controls"
 	sharedprofiler "github.com/milk9111/sidescroller/internal/profiler"
+	"github.com/milk9111/sidescroller/internal/replay"
 	"github.com/milk9111/sidescroller/internal/savegame"
 	"github.com/milk9111/sidescroller/scenes"
 )
@@ -31,8 +34,11 @@ func main() {
 	memProfilePath := flag.String("memprofile", "", "optional path to write a heap profile on exit")
 	memProfileRate := flag.Int("memprofilerate", 0, "optional runtime.MemProfileRate override; 0 keeps the Go default")
 	memProfileSample := flag.String("memprofile-sample", "", "optional interval for periodic heap snapshots, for example 30s")
+	systemProfilePath := flag.String("systemprofile", "", "optional path to write per-system frame timings on exit (.csv, or .json)")
 	sceneName := flag.String("scene", "", "scene name to load")
 	saveFileName := flag.String("save", "save.json", "save file name stored in the platform save directory")
+	recordPath := flag.String("record", "", "optional path to record every frame of input for later replay")
+	replayPath := flag.String("replay", "", "optional path to a recording to replay instead of reading devices")
 	flag.Parse()
 	levelProvided := flagWasProvided("level")
 	saveProvided := flagWasProvided("save")
@@ -49,6 +55,13 @@ func main() {
 		memProfileInterval = parsedInterval
 	}
 
+	// Systems are timed for the F3 overlay with -o, and for the file with
+	// -systemprofile.
+	var systemProfile *ecs.SystemProfile
+	if *overlay || *systemProfilePath != "" {
+		systemProfile = ecs.NewSystemProfile(ecs.DefaultProfileWindow)
+	}
+
 	profilerInstance, err := sharedprofiler.Start(sharedprofiler.Config{
 		Label:              "game",
 		PprofAddr:          *pprofAddr,
@@ -57,6 +70,8 @@ func main() {
 		MemProfilePath:     *memProfilePath,
 		MemProfileRate:     *memProfileRate,
 		MemProfileInterval: memProfileInterval,
+		SystemProfilePath:  *systemProfilePath,
+		SystemProfile:      systemProfile,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -128,6 +143,20 @@ func main() {
 		}
 	}
 
+	controlsProfile := controls.Default()
+	if controlsPath, err := controls.ResolvePath(); err != nil {
+		log.Printf("controls: %v; using default bindings", err)
+	} else if loaded, err := controls.Load(controlsPath); err != nil {
+		log.Printf("controls: %v; using default bindings", err)
+	} else {
+		controlsProfile = loaded
+	}
+
+	settings, err := savegame.LoadSettings()
+	if err != nil {
+		log.Printf("settings: %v; using defaults", err)
+	}
+
 	gameConfig := &scenes.GameConfig{
 		LevelName:        *levelName,
 		Debug:            *debug,
@@ -138,11 +167,44 @@ func main() {
 		InitialAbilities: initialAbilities,
 		SaveStore:        saveStore,
 		LoadedSave:       loadedSave,
+		Controls:         controlsProfile,
+		Settings:         &settings,
+		SystemProfile:    systemProfile,
 	}
 	if loadedSave != nil && strings.TrimSpace(loadedSave.Level) != "" {
 		gameConfig.LevelName = loadedSave.Level
 	}
 
+	if *recordPath != "" && *replayPath != "" {
+		log.Fatal("-record and -replay cannot be used together")
+	}
+	if *recordPath != "" {
+		recorder, err := replay.Create(*recordPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			if closeErr := recorder.Close(); closeErr != nil {
+				log.Printf("close recording: %v", closeErr)
+			}
+		}()
+		gameConfig.Recorder = recorder
+	}
+	if *replayPath != "" {
+		player, err := replay.Load(*replayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		header := player.Header()
+		gameConfig.LevelName = header.Level
+		gameConfig.AllAbilities = header.AllAbilities
+		gameConfig.InitialAbilities = header.InitialAbilities
+		gameConfig.LoadedSave = header.Save
+		// Replays never write to the player's save slot.
+		gameConfig.SaveStore = nil
+		gameConfig.Replay = player
+	}
+
 	initialScene := scenes.SceneStartMenu
 	requestedScene := strings.TrimSpace(*sceneName)
 	if requestedScene != "" {
@@ -155,6 +217,9 @@ func main() {
 	if saveProvided {
 		initialScene = scenes.SceneGame
 	}
+	if gameConfig.Replay != nil {
+		initialScene = scenes.SceneGame
+	}
 
 	gameConfig.InitialFadeIn = true
 
@@ -166,7 +231,9 @@ func main() {
 			return scenes.NewTestScene(), nil
 		},
 		scenes.SceneIntro: func() (scenes.Scene, error) {
-			return scenes.NewIntroScene(), nil
+			intro := scenes.NewIntroScene()
+			intro.SetVolume(gameConfig.Settings.SFXGain())
+			return intro, nil
 		},
 		scenes.SceneStartMenu: func() (scenes.Scene, error) {
 			return scenes.NewStartMenuScene(gameConfig)
@@ -175,6 +242,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	game.SetSettings(gameConfig.Settings)
 
 	// Hide the native OS cursor at game start; we draw a custom aim target when aiming.
 	ebiten.SetCursorMode(ebiten.CursorModeHidden)
@@ -275,6 +275,7 @@ type ScriptComponentSpec struct {
 	Path    string   `yaml:"path"`
 	Paths   []string `yaml:"paths"`
 	Modules []string `yaml:"modules"`
+	Stdlib  []string `yaml:"stdlib"`
 }
 
 type AABBComponentSpec struct {
@@ -302,6 +303,9 @@ type TriggerComponentSpec struct {
 	Bounds   AABBComponentSpec `yaml:"bounds"`
 	Name     string            `yaml:"name"`
 	Disabled bool              `yaml:"disabled"`
+	Flag     string            `yaml:"flag"`
+	NoFlag   string            `yaml:"no_flag"`
+	SetFlag  string            `yaml:"set_flag"`
 }
 
 type LeverComponentSpec struct {
@@ -429,9 +433,58 @@ type ShrinePopupComponentSpec struct {
 }
 
 type DialogueComponentSpec struct {
-	Lines    []string `yaml:"lines"`
-	Portrait string   `yaml:"portrait"`
-	Range    float64  `yaml:"range"`
+	Lines    []string           `yaml:"lines"`
+	Portrait string             `yaml:"portrait"`
+	Range    float64            `yaml:"range"`
+	Speaker  string             `yaml:"speaker"`
+	Start    string             `yaml:"start"`
+	Nodes    []DialogueNodeSpec `yaml:"nodes"`
+}
+
+type DialogueNodeSpec struct {
+	ID       string               `yaml:"id"`
+	Speaker  string               `yaml:"speaker"`
+	Portrait string               `yaml:"portrait"`
+	Lines    []string             `yaml:"lines"`
+	Actions  []DialogueActionSpec `yaml:"actions"`
+	Choices  []DialogueChoiceSpec `yaml:"choices"`
+	Branches []DialogueBranchSpec `yaml:"branches"`
+	Next     string               `yaml:"next"`
+}
+
+type DialogueChoiceSpec struct {
+	Text    string                  `yaml:"text"`
+	Next    string                  `yaml:"next"`
+	If      []DialogueConditionSpec `yaml:"if"`
+	Actions []DialogueActionSpec    `yaml:"actions"`
+}
+
+type DialogueBranchSpec struct {
+	If   []DialogueConditionSpec `yaml:"if"`
+	Next string                  `yaml:"next"`
+}
+
+type DialogueConditionSpec struct {
+	Item      string `yaml:"item"`
+	Count     int    `yaml:"count"`
+	NoItem    string `yaml:"no_item"`
+	Ability   string `yaml:"ability"`
+	NoAbility string `yaml:"no_ability"`
+	Entity    string `yaml:"entity"`
+	State     string `yaml:"state"`
+	Flag      string `yaml:"flag"`
+	Value     any    `yaml:"value"`
+	AtLeast   *int   `yaml:"at_least"`
+	NoFlag    string `yaml:"no_flag"`
+}
+
+type DialogueActionSpec struct {
+	Signal    string `yaml:"signal"`
+	Target    string `yaml:"target"`
+	SetFlag   string `yaml:"set_flag"`
+	Value     any    `yaml:"value"`
+	ClearFlag string `yaml:"clear_flag"`
+	OpenShop  bool   `yaml:"open_shop"`
 }
 
 type ShrineComponentSpec struct {
@@ -448,6 +501,17 @@ type ItemReferenceComponentSpec struct {
 	Prefab string `yaml:"prefab"`
 }
 
+type ShopItemComponentSpec struct {
+	Prefab string `yaml:"prefab"`
+	Price  int    `yaml:"price"`
+	Stock  int    `yaml:"stock"`
+}
+
+type ShopComponentSpec struct {
+	Title string                  `yaml:"title"`
+	Items []ShopItemComponentSpec `yaml:"items"`
+}
+
 type InventoryItemComponentSpec struct {
 	Prefab string `yaml:"prefab"`
 	Count  int    `yaml:"count"`
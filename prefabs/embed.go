@@ -12,11 +12,41 @@ import (
 //go:embed scripts/* scripts/*/*
 var ScriptsFS embed.FS
 
+// scriptsFromDisk makes LoadScript prefer the scripts under prefabs/ in the
+// working directory to the embedded ones, for hot reload.
+var scriptsFromDisk bool
+
+// LoadScriptsFromDisk makes LoadScript and ScriptModTime look at the disk
+// copies of scripts first, or only at the embedded ones when off, which is
+// the default.
+func LoadScriptsFromDisk(enabled bool) {
+	scriptsFromDisk = enabled
+}
+
 func LoadScript(name string) ([]byte, error) {
 	clean := cleanScriptPath(name)
+	if scriptsFromDisk {
+		if data, err := os.ReadFile(diskPrefabPath(clean)); err == nil {
+			return data, nil
+		}
+	}
 	return ScriptsFS.ReadFile(clean)
 }
 
+// ScriptModTime returns when the disk copy of a script last changed. It
+// reports false while scripts are only loaded from the embedded copies.
+func ScriptModTime(name string) (time.Time, bool) {
+	if !scriptsFromDisk {
+		return time.Time{}, false
+	}
+	clean := cleanScriptPath(name)
+	info, err := os.Stat(diskPrefabPath(clean))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
 //go:embed *.yaml
 var PrefabsFS embed.FS
 
@@ -1,6 +1,7 @@
 package prefabs
 
 import (
+	"io/fs"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -91,3 +92,28 @@ func isScriptFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	return ext == ".tengo" || ext == ".lua"
 }
+
+// ScriptDirs lists root and every directory below it. Watches don't recurse,
+// so each script directory has to be added on its own.
+func ScriptDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// ScriptName returns the name a script component uses for a watched script
+// file, or false when the path isn't a script.
+func ScriptName(path string) (string, bool) {
+	if !isScriptFile(path) {
+		return "", false
+	}
+	return strings.TrimPrefix(cleanScriptPath(path), "scripts/"), true
+}
@@ -0,0 +1,117 @@
+package scenes
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/milk9111/sidescroller/ecs"
+	"github.com/milk9111/sidescroller/ecs/script"
+)
+
+// devConsoleToggleKey opens and closes the developer console in debug mode.
+const devConsoleToggleKey = ebiten.KeyGraveAccent
+
+const (
+	devConsoleVisibleLines = 14
+	devConsoleLineHeight   = 16
+	devConsoleMargin       = 8
+	devConsoleRepeatDelay  = 24
+	devConsoleRepeatPeriod = 3
+)
+
+var devConsoleBackdropColor = color.NRGBA{R: 0x05, G: 0x08, B: 0x0c, A: 0xe0}
+
+// devConsole is the text view over a script console. While it is open the
+// game is held like the pause menu, so typing doesn't drive the player.
+type devConsole struct {
+	console *script.Console
+	open    bool
+	input   string
+	history []string
+	// recall is the history entry shown by up and down; len(history) is the
+	// fresh input line.
+	recall int
+	// ran, when set, hears every line run, for the session recording.
+	ran func(line string)
+}
+
+func newDevConsole(console *script.Console) *devConsole {
+	return &devConsole{console: console}
+}
+
+func (c *devConsole) Update(w *ecs.World) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		c.open = false
+		return
+	}
+
+	for _, value := range ebiten.AppendInputChars(nil) {
+		if value < 0x20 || value == 0x7f || value == '`' {
+			continue
+		}
+		c.input += string(value)
+	}
+	if devConsoleKeyTriggered(ebiten.KeyBackspace) && c.input != "" {
+		runes := []rune(c.input)
+		c.input = string(runes[:len(runes)-1])
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) && c.recall > 0 {
+		c.recall--
+		c.input = c.history[c.recall]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) && c.recall < len(c.history) {
+		c.recall++
+		c.input = ""
+		if c.recall < len(c.history) {
+			c.input = c.history[c.recall]
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter) {
+		line := strings.TrimSpace(c.input)
+		c.input = ""
+		if line != "" {
+			c.history = append(c.history, line)
+			c.console.Exec(w, line)
+			if c.ran != nil {
+				c.ran(line)
+			}
+		}
+		c.recall = len(c.history)
+	}
+}
+
+func (c *devConsole) Draw(screen *ebiten.Image) {
+	bounds := screen.Bounds()
+	height := devConsoleMargin*2 + (devConsoleVisibleLines+1)*devConsoleLineHeight
+	vector.DrawFilledRect(screen, float32(bounds.Min.X), float32(bounds.Min.Y), float32(bounds.Dx()), float32(height), devConsoleBackdropColor, false)
+
+	lines := c.console.Lines
+	if len(lines) > devConsoleVisibleLines {
+		lines = lines[len(lines)-devConsoleVisibleLines:]
+	}
+	target := c.console.Target
+	if target == "" {
+		target = "player"
+	}
+	lines = append(append([]string(nil), lines...), target+"> "+c.input+"_")
+
+	y := devConsoleMargin + (devConsoleVisibleLines+1-len(lines))*devConsoleLineHeight
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), devConsoleMargin, y)
+}
+
+func devConsoleKeyTriggered(key ebiten.Key) bool {
+	duration := inpututil.KeyPressDuration(key)
+	if duration == 1 {
+		return true
+	}
+	if duration < devConsoleRepeatDelay {
+		return false
+	}
+	return (duration-devConsoleRepeatDelay)%devConsoleRepeatPeriod == 0
+}
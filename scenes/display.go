@@ -0,0 +1,99 @@
+package scenes
+
+import (
+	"image"
+	"math"
+
+	euiinput "github.com/ebitenui/ebitenui/input"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/milk9111/sidescroller/common"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+// integerViewport describes where a scene's fixed-size screen is drawn
+// inside the window when integer scaling is on.
+type integerViewport struct {
+	screenW float64
+	screenH float64
+	offsetX int
+	offsetY int
+	scaled  bool
+}
+
+// computeIntegerViewport picks the largest whole-number scale at which a
+// sceneW x sceneH screen fits in the window and returns the logical screen
+// size that makes ebiten draw at exactly that scale. Windows smaller than
+// the scene fall back to regular scaling.
+func computeIntegerViewport(outsideW, outsideH, deviceScale, sceneW, sceneH float64) integerViewport {
+	fallback := integerViewport{screenW: sceneW, screenH: sceneH}
+	if outsideW <= 0 || outsideH <= 0 || sceneW <= 0 || sceneH <= 0 {
+		return fallback
+	}
+	if deviceScale <= 0 {
+		deviceScale = 1
+	}
+
+	pixelW := outsideW * deviceScale
+	pixelH := outsideH * deviceScale
+	scale := math.Floor(math.Min(pixelW/sceneW, pixelH/sceneH))
+	if scale < 1 {
+		return fallback
+	}
+
+	screenW := math.Floor(pixelW / scale)
+	screenH := math.Floor(pixelH / scale)
+	return integerViewport{
+		screenW: screenW,
+		screenH: screenH,
+		offsetX: int((screenW - sceneW) / 2),
+		offsetY: int((screenH - sceneH) / 2),
+		scaled:  true,
+	}
+}
+
+// applyWindowSettings pushes the display options that ebiten owns.
+func applyWindowSettings(settings *savegame.Settings) {
+	if settings == nil {
+		return
+	}
+	if ebiten.IsFullscreen() != settings.Fullscreen {
+		ebiten.SetFullscreen(settings.Fullscreen)
+	}
+}
+
+// setCursorOffset keeps gameplay and ebitenui cursor coordinates in game
+// screen space while the viewport is letterboxed.
+func setCursorOffset(x, y int) {
+	common.SetCursorOffset(x, y)
+	if x == 0 && y == 0 {
+		euiinput.SetCursorUpdater(nil)
+		return
+	}
+	euiinput.SetCursorUpdater(offsetCursorUpdater{})
+}
+
+// offsetCursorUpdater is an ebitenui cursor source that reports the cursor
+// relative to the letterboxed game screen. The game's menus only need mouse
+// buttons and position, so it reads ebiten directly without extra state.
+type offsetCursorUpdater struct{}
+
+func (offsetCursorUpdater) Update()                             {}
+func (offsetCursorUpdater) AfterUpdate()                        {}
+func (offsetCursorUpdater) Draw(*ebiten.Image)                  {}
+func (offsetCursorUpdater) AfterDraw(*ebiten.Image)             {}
+func (offsetCursorUpdater) CursorPosition() (int, int)          { return common.CursorPosition() }
+func (offsetCursorUpdater) GetCursorImage(string) *ebiten.Image { return nil }
+func (offsetCursorUpdater) GetCursorOffset(string) image.Point  { return image.Point{} }
+
+func (offsetCursorUpdater) MouseButtonPressed(b ebiten.MouseButton) bool {
+	return ebiten.IsMouseButtonPressed(b)
+}
+
+func (offsetCursorUpdater) MouseButtonJustPressed(b ebiten.MouseButton) bool {
+	return inpututil.IsMouseButtonJustPressed(b)
+}
+
+func (offsetCursorUpdater) MouseButtonJustReleased(b ebiten.MouseButton) bool {
+	return inpututil.IsMouseButtonJustReleased(b)
+}
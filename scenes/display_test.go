@@ -0,0 +1,53 @@
+package scenes
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeIntegerViewport(t *testing.T) {
+	tests := []struct {
+		name               string
+		outsideW, outsideH float64
+		deviceScale        float64
+		want               integerViewport
+	}{
+		{name: "exact fit", outsideW: 1600, outsideH: 900, deviceScale: 1, want: integerViewport{screenW: 1600, screenH: 900, scaled: true}},
+		{name: "letterboxed", outsideW: 1920, outsideH: 1080, deviceScale: 1, want: integerViewport{screenW: 1920, screenH: 1080, offsetX: 160, offsetY: 90, scaled: true}},
+		{name: "hidpi doubles", outsideW: 1600, outsideH: 900, deviceScale: 2, want: integerViewport{screenW: 1600, screenH: 900, scaled: true}},
+		{name: "too small falls back", outsideW: 1280, outsideH: 720, deviceScale: 1, want: integerViewport{screenW: 1600, screenH: 900}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := computeIntegerViewport(test.outsideW, test.outsideH, test.deviceScale, 1600, 900)
+			if got != test.want {
+				t.Fatalf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestStepSettingsLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level float64
+		delta int
+		wrap  bool
+		want  float64
+	}{
+		{name: "step up", level: 0.5, delta: 1, want: 0.6},
+		{name: "clamps at full", level: 1, delta: 1, want: 1},
+		{name: "clamps at zero", level: 0, delta: -1, want: 0},
+		{name: "wraps past full", level: 1, delta: 1, wrap: true, want: 0},
+		{name: "snaps odd levels", level: 0.34, delta: 1, want: 0.4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := stepSettingsLevel(test.level, test.delta, test.wrap); math.Abs(got-test.want) > 1e-9 {
+				t.Fatalf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package scenes
 
 import (
+	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/controls"
+	"github.com/milk9111/sidescroller/internal/replay"
 	"github.com/milk9111/sidescroller/internal/savegame"
 )
 
@@ -16,4 +19,19 @@ type GameConfig struct {
 	InitialAbilities *component.Abilities
 	SaveStore        *savegame.Store
 	LoadedSave       *savegame.File
+	// Controls is the keybinding profile; nil uses the default bindings.
+	Controls *controls.Profile
+	// Settings is shared by every scene so changes apply live; nil uses
+	// the defaults.
+	Settings *savegame.Settings
+	// Recorder, when set, captures every frame of input for later replay.
+	Recorder *replay.Recorder
+	// Replay, when set, feeds recorded input instead of polling devices.
+	Replay *replay.Player
+	// ScriptErrorHandler, when set, receives script failures instead of the
+	// rate-limited log. Each one is a *script.Error naming its file and line.
+	ScriptErrorHandler func(ent ecs.Entity, err error)
+	// SystemProfile, when set, times every system and draw pass; F3 shows
+	// the slowest.
+	SystemProfile *ecs.SystemProfile
 }
@@ -2,6 +2,8 @@ package scenes
 
 import (
 	"errors"
+	"log"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -10,6 +12,9 @@ import (
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
 	"github.com/milk9111/sidescroller/ecs/system"
+	"github.com/milk9111/sidescroller/internal/controls"
+	"github.com/milk9111/sidescroller/internal/replay"
+	"github.com/milk9111/sidescroller/internal/savegame"
 	"github.com/milk9111/sidescroller/prefabs"
 )
 
@@ -32,10 +37,30 @@ type GameScene struct {
 	render          *system.RenderSystem
 	physics         *system.PhysicsSystem
 	camera          *system.CameraSystem
+	audio           *system.AudioSystem
+	music           *system.MusicSystem
+	spriteShake     *system.SpriteShakeSystem
 	scriptRuntime   *system.ScriptSystem
 	debugPhysics    bool
 	debugOverlay    bool
+	profile         *ecs.SystemProfile
+	console         *devConsole
 	prefabWatcher   *prefabs.Watcher
+	inputFeed       *replay.Feed
+	recorder        *replay.Recorder
+	replay          *replay.Player
+	replayFinished  bool
+	bindings        *controls.Map
+	settings        *savegame.Settings
+	menuTheme       *startMenuTheme
+	paused          bool
+	pauseView       *pauseView
+	settingsView    *settingsView
+	settingsOpen    bool
+	nextScene       string
+	// heldActions are the checkpoint loads and console lines since the last
+	// gameplay frame, recorded with the next one.
+	heldActions replay.Frame
 }
 
 func NewGameScene(cfg GameConfig) *GameScene {
@@ -69,73 +94,127 @@ func NewGameScene(cfg GameConfig) *GameScene {
 		physics:       physicsSystem,
 		debugPhysics:  cfg.Debug,
 		debugOverlay:  cfg.Overlay,
+		profile:       cfg.SystemProfile,
 	}
+	gameplayScheduler.SetProfile(cfg.SystemProfile)
+	dialogueScheduler.SetProfile(cfg.SystemProfile)
 
 	cameraSystem := system.NewCameraSystem()
 	scriptSystem := system.NewScriptSystem()
+	if cfg.ScriptErrorHandler != nil {
+		scriptSystem.SetErrorHandler(cfg.ScriptErrorHandler)
+	}
 	musicSystem := system.NewMusicSystem(cfg.Mute)
-
-	game.dialogue.Add(musicSystem)
-	game.dialogue.Add(animationSystem)
-	game.dialogue.Add(system.NewDialogueSystem())
-	game.dialogue.Add(system.NewItemSystem())
-	game.dialogue.Add(system.NewInventorySystem())
-	game.dialogue.Add(debugMessageSystem)
-	game.dialogue.Add(tutorialSystem)
-	game.dialogue.Add(uiSystem)
-
-	game.gameplay.Add(system.NewAudioSystem(cfg.Mute))
-	game.gameplay.Add(musicSystem)
-	game.gameplay.Add(system.NewPlayerControllerSystem())
-	game.gameplay.Add(system.NewPathfindingSystem())
-	game.gameplay.Add(system.NewAINavigationSystem())
-	game.gameplay.Add(system.NewAimSystem())
-	game.gameplay.Add(animationSystem)
-	game.gameplay.Add(system.NewColorSystem())
-	game.gameplay.Add(system.NewWhiteFlashSystem())
-	game.gameplay.Add(system.NewSpriteShakeSystem())
-	game.gameplay.Add(system.NewSpriteFadeOutSystem())
-	game.gameplay.Add(system.NewInvulnerabilitySystem())
-	game.gameplay.Add(system.NewCombatSystem())
-	game.gameplay.Add(system.NewLeverSystem())
-	game.gameplay.Add(system.NewDamageKnockbackSystem())
-	game.gameplay.Add(system.NewArenaNodeSystem())
-	game.gameplay.Add(system.NewPlayerHealthBarSystem())
-	game.gameplay.Add(system.NewHazardSystem())
-	game.gameplay.Add(system.NewAnchorSystem())
-	game.gameplay.Add(system.NewClusterRepulsionSystem())
-	game.gameplay.Add(system.NewMovingPlatformSystem())
-	game.gameplay.Add(physicsSystem)
-	game.gameplay.Add(dialogueInputSystem)
-	game.gameplay.Add(transitionInputSystem)
-	game.gameplay.Add(dialoguePopupSystem)
-	game.gameplay.Add(itemPopupSystem)
-	game.gameplay.Add(shrinePopupSystem)
-	game.gameplay.Add(transitionPopupSystem)
-	game.gameplay.Add(system.NewShrineSystem())
-	game.gameplay.Add(system.NewTriggerSystem())
-	game.gameplay.Add(system.NewPickupHoverSystem())
-	game.gameplay.Add(system.NewPickupCollectSystem())
-	game.gameplay.Add(particleSystem)
-	game.gameplay.Add(scriptSystem)
-	game.gameplay.Add(system.NewGateSystem())
-	game.gameplay.Add(debugMessageSystem)
-	game.gameplay.Add(tutorialSystem)
-	game.gameplay.Add(system.NewHealthDeathFadeSystem())
-	game.gameplay.Add(system.NewTTLSystem())
-	game.gameplay.Add(system.NewRespawnSystem())
-	game.gameplay.Add(system.NewTransitionPopSystem())
-	game.gameplay.Add(system.NewTransitionSystem())
-	game.gameplay.Add(game.persistence)
-	game.gameplay.Add(system.NewSpawnChildrenSystem())
-	game.gameplay.Add(cameraSystem)
-	game.gameplay.Add(system.NewParallaxSystem())
+	audioSystem := system.NewAudioSystem(cfg.Mute)
+	spriteShakeSystem := system.NewSpriteShakeSystem()
+
+	// Systems run phase by phase and, within a phase, in the order they are
+	// registered here unless Before or After say otherwise.
+	gameplay, dialogue, both := game.gameplay, game.dialogue, []*ecs.Scheduler{game.gameplay, game.dialogue}
+
+	gameplay.Register(ecs.SystemSpec{System: audioSystem, Phase: ecs.PhaseInput})
+	ecs.Register(ecs.SystemSpec{System: musicSystem, Phase: ecs.PhaseInput}, both...)
+	gameplay.Register(ecs.SystemSpec{System: system.NewPlayerControllerSystem(), Phase: ecs.PhaseInput})
+
+	gameplay.Register(ecs.SystemSpec{System: system.NewPathfindingSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewAINavigationSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewAimSystem(), Phase: ecs.PhasePrePhysics})
+	ecs.Register(ecs.SystemSpec{System: animationSystem, Phase: ecs.PhasePrePhysics}, both...)
+	gameplay.Register(ecs.SystemSpec{System: system.NewColorSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewWhiteFlashSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: spriteShakeSystem, Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewSpriteFadeOutSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewInvulnerabilitySystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewCombatSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewLeverSystem(), Phase: ecs.PhasePrePhysics})
+	// Combat queues the knockback of this frame's hits.
+	gameplay.Register(ecs.SystemSpec{System: system.NewDamageKnockbackSystem(), Phase: ecs.PhasePrePhysics, After: []string{"CombatSystem"}})
+	gameplay.Register(ecs.SystemSpec{System: system.NewArenaNodeSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewPlayerHealthBarSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewHazardSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewAnchorSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewClusterRepulsionSystem(), Phase: ecs.PhasePrePhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewMovingPlatformSystem(), Phase: ecs.PhasePrePhysics})
+
+	gameplay.Register(ecs.SystemSpec{System: physicsSystem, Phase: ecs.PhasePhysics})
+
+	gameplay.Register(ecs.SystemSpec{System: dialogueInputSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: transitionInputSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: dialoguePopupSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: itemPopupSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: shrinePopupSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: transitionPopupSystem, Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewShrineSystem(), Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewTriggerSystem(), Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewPickupHoverSystem(), Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: system.NewPickupCollectSystem(), Phase: ecs.PhasePostPhysics})
+	gameplay.Register(ecs.SystemSpec{System: particleSystem, Phase: ecs.PhasePostPhysics})
+	// Scripts hear the trigger signals of the same frame.
+	gameplay.Register(ecs.SystemSpec{System: scriptSystem, Phase: ecs.PhasePostPhysics, After: []string{"TriggerSystem"}})
+	gameplay.Register(ecs.SystemSpec{System: system.NewGateSystem(), Phase: ecs.PhasePostPhysics})
+	dialogue.Register(ecs.SystemSpec{System: system.NewShopSystem(), Phase: ecs.PhasePostPhysics})
+	dialogue.Register(ecs.SystemSpec{System: system.NewDialogueSystem(), Phase: ecs.PhasePostPhysics})
+	dialogue.Register(ecs.SystemSpec{System: system.NewItemSystem(), Phase: ecs.PhasePostPhysics})
+	dialogue.Register(ecs.SystemSpec{System: system.NewInventorySystem(), Phase: ecs.PhasePostPhysics})
+
+	ecs.Register(ecs.SystemSpec{System: debugMessageSystem, Phase: ecs.PhaseLate}, both...)
+	ecs.Register(ecs.SystemSpec{System: tutorialSystem, Phase: ecs.PhaseLate}, both...)
+	gameplay.Register(ecs.SystemSpec{System: system.NewHealthDeathFadeSystem(), Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: system.NewTTLSystem(), Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: system.NewRespawnSystem(), Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: system.NewTransitionPopSystem(), Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: system.NewTransitionSystem(), Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: game.persistence, Phase: ecs.PhaseLate})
+	gameplay.Register(ecs.SystemSpec{System: system.NewSpawnChildrenSystem(), Phase: ecs.PhaseLate})
+
+	gameplay.Register(ecs.SystemSpec{System: cameraSystem, Phase: ecs.PhaseRenderPrep})
+	// Parallax layers follow where the camera ended up this frame.
+	gameplay.Register(ecs.SystemSpec{System: system.NewParallaxSystem(), Phase: ecs.PhaseRenderPrep, After: []string{"CameraSystem"}})
+	dialogue.Register(ecs.SystemSpec{System: uiSystem, Phase: ecs.PhaseRenderPrep})
+	for _, scheduler := range both {
+		if err := scheduler.Validate(); err != nil {
+			panic("game scene: " + err.Error())
+		}
+	}
 
 	game.camera = cameraSystem
+	game.audio = audioSystem
+	game.music = musicSystem
+	game.spriteShake = spriteShakeSystem
 	game.scriptRuntime = scriptSystem
+	if cfg.Debug {
+		game.console = newDevConsole(scriptSystem.NewConsole())
+	}
+
+	game.settings = cfg.Settings
+	if game.settings == nil {
+		settings := savegame.DefaultSettings()
+		game.settings = &settings
+	}
+	game.applySettings()
+
+	game.bindings = controls.DefaultMap()
+	if cfg.Controls != nil {
+		bindings, err := cfg.Controls.Map()
+		if err != nil {
+			log.Printf("controls: %v; using default bindings", err)
+		} else {
+			game.bindings = bindings
+			inputSystem.SetBindings(bindings)
+			dialogueInputSystem.SetBindings(bindings)
+			transitionInputSystem.SetBindings(bindings)
+		}
+	}
+
+	game.setupReplay(cfg, inputSystem, dialogueInputSystem, transitionInputSystem)
 
 	if cfg.WatchPrefabs {
-		watcher, err := prefabs.NewWatcher("prefabs", "prefabs/scripts")
+		prefabs.LoadScriptsFromDisk(true)
+		scriptDirs, err := prefabs.ScriptDirs("prefabs/scripts")
+		if err != nil {
+			panic("failed to list script directories: " + err.Error())
+		}
+		watcher, err := prefabs.NewWatcher(append([]string{"prefabs"}, scriptDirs...)...)
 		if err != nil {
 			panic("failed to create prefab watcher: " + err.Error())
 		}
@@ -146,6 +225,122 @@ func NewGameScene(cfg GameConfig) *GameScene {
 	return game
 }
 
+// World exposes the scene's world so headless tools can inspect it between updates.
+func (g *GameScene) World() *ecs.World {
+	if g == nil {
+		return nil
+	}
+	return g.world
+}
+
+// setupReplay seeds gameplay randomness and wires the input systems to a
+// shared feed when the session is being recorded or replayed.
+func (g *GameScene) setupReplay(cfg GameConfig, input *system.InputSystem, dialogueInput *system.DialogueInputSystem, transitionInput *system.TransitionInputSystem) {
+	if cfg.Replay == nil && cfg.Recorder == nil {
+		return
+	}
+
+	g.inputFeed = &replay.Feed{}
+	input.SetFeed(g.inputFeed)
+	dialogueInput.SetFeed(g.inputFeed)
+	transitionInput.SetFeed(g.inputFeed)
+
+	if cfg.Replay != nil {
+		g.replay = cfg.Replay
+		g.inputFeed.Replaying = true
+		common.SeedRand(cfg.Replay.Header().Seed)
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	common.SeedRand(seed)
+	if err := cfg.Recorder.Begin(replay.Header{
+		Seed:             seed,
+		Level:            cfg.LevelName,
+		AllAbilities:     cfg.AllAbilities,
+		InitialAbilities: cfg.InitialAbilities,
+		Save:             cfg.LoadedSave,
+	}); err != nil {
+		log.Printf("record input: %v", err)
+		return
+	}
+	g.recorder = cfg.Recorder
+	if g.console != nil {
+		g.console.ran = func(line string) {
+			g.heldActions.Console = append(g.heldActions.Console, line)
+		}
+	}
+}
+
+// endRecording finishes this scene's recording, so the next game scene
+// records a session of its own.
+func (g *GameScene) endRecording() {
+	if g.recorder == nil {
+		return
+	}
+	if err := g.recorder.End(); err != nil {
+		log.Printf("record input: %v", err)
+	}
+	g.recorder = nil
+}
+
+func (g *GameScene) beginInputFrame() {
+	if g.inputFeed == nil {
+		return
+	}
+
+	if g.replay == nil {
+		g.inputFeed.Frame = g.heldActions
+		g.heldActions = replay.Frame{}
+		return
+	}
+
+	frame, ok := g.replay.Next()
+	if !ok && !g.replayFinished {
+		g.replayFinished = true
+		log.Printf("replay finished after %d frames", g.replay.Len())
+	}
+	g.inputFeed.Frame = frame
+	g.replayHeldActions(frame)
+}
+
+// replayHeldActions does what the player did while the recorded session was
+// held before frame.
+func (g *GameScene) replayHeldActions(frame replay.Frame) {
+	if frame.LoadCheckpoint {
+		system.RequestCheckpointReload(g.world)
+	}
+	if len(frame.Console) > 0 && g.console == nil {
+		g.console = newDevConsole(g.scriptRuntime.NewConsole())
+	}
+	for _, line := range frame.Console {
+		g.console.console.Exec(g.world, line)
+	}
+}
+
+// debugToggled reports F3, which a replay takes from the recording.
+func (g *GameScene) debugToggled() bool {
+	if g.replay != nil {
+		return g.inputFeed.Frame.ToggleDebug
+	}
+	pressed := inpututil.IsKeyJustPressed(ebiten.KeyF3)
+	if g.inputFeed != nil {
+		g.inputFeed.Frame.ToggleDebug = pressed
+	}
+	return pressed
+}
+
+func (g *GameScene) endInputFrame() {
+	if g.recorder == nil || g.inputFeed == nil {
+		return
+	}
+
+	if err := g.recorder.Record(g.inputFeed.Frame); err != nil {
+		log.Printf("record input: %v", err)
+		g.recorder = nil
+	}
+}
+
 func (g *GameScene) gameplayUpdateScale() float64 {
 	if g == nil || g.world == nil {
 		return 1
@@ -200,10 +395,145 @@ func (g *GameScene) setGameplayTimeScale(scale float64) {
 	time.Scale = scale
 }
 
+// applySettings pushes the live player settings into the systems that use
+// them. It runs every frame so changes from the settings view apply at once.
+func (g *GameScene) applySettings() {
+	if g == nil || g.settings == nil {
+		return
+	}
+	g.audio.SetVolume(g.settings.SFXGain())
+	g.music.SetVolume(g.settings.MusicGain())
+	g.camera.SetShakeScale(g.settings.ScreenShake)
+	g.spriteShake.SetIntensityScale(g.settings.ScreenShake)
+}
+
+func (g *GameScene) theme() *startMenuTheme {
+	if g.menuTheme == nil {
+		theme, err := newStartMenuTheme()
+		if err != nil {
+			log.Printf("pause menu: %v", err)
+			return nil
+		}
+		g.menuTheme = theme
+	}
+	return g.menuTheme
+}
+
+func (g *GameScene) pausePressed() bool {
+	return g.bindings.JustPressed(controls.ActionPause, controls.FirstGamepad())
+}
+
+// pause freezes both schedulers and shows the pause menu over the game.
+func (g *GameScene) pause() {
+	if g.pauseView == nil {
+		theme := g.theme()
+		if theme == nil {
+			return
+		}
+		g.pauseView = newPauseView(theme, []pauseMenuItem{
+			{label: "Resume", activate: g.resume},
+			{label: "Save", activate: g.saveNow},
+			{label: "Load checkpoint", activate: g.loadCheckpoint},
+			{label: "Settings", activate: g.openSettings},
+			{label: "Quit to title", activate: g.quitToTitle},
+		})
+	}
+	g.pauseView.Reset()
+	g.paused = true
+}
+
+func (g *GameScene) resume() {
+	g.paused = false
+	g.settingsOpen = false
+}
+
+func (g *GameScene) saveNow() {
+	saved, err := g.persistence.SaveNow(g.world)
+	switch {
+	case err != nil:
+		g.pauseView.SetMessage(err.Error())
+	case !saved:
+		g.pauseView.SetMessage("This session has no save slot.")
+	default:
+		g.pauseView.SetMessage("Game saved.")
+	}
+}
+
+// loadCheckpoint resumes into the same fade and respawn the player gets on death.
+func (g *GameScene) loadCheckpoint() {
+	system.RequestCheckpointReload(g.world)
+	g.heldActions.LoadCheckpoint = g.recorder != nil
+	g.resume()
+}
+
+// openSettings shows the settings view in place of the pause menu.
+func (g *GameScene) openSettings() {
+	if g.settingsView == nil {
+		theme := g.theme()
+		if theme == nil {
+			return
+		}
+		g.settingsView = newSettingsView(g.settings, theme, func() {
+			g.settingsOpen = false
+		})
+	}
+	g.settingsOpen = true
+}
+
+// quitToTitle leaves the game for the start menu on the next update. Sounds
+// are stopped here since the scene is dropped without further updates.
+func (g *GameScene) quitToTitle() {
+	system.SilenceAudio(g.world)
+	g.endRecording()
+	if g.prefabWatcher != nil {
+		_ = g.prefabWatcher.Close()
+		g.prefabWatcher = nil
+	}
+	g.nextScene = SceneStartMenu
+}
+
+// updatePaused runs the pause menu in place of both schedulers. Gameplay
+// frames are neither counted nor recorded while paused.
+func (g *GameScene) updatePaused() string {
+	if g.settingsOpen {
+		g.settingsView.Update()
+		g.applySettings()
+		return g.nextScene
+	}
+	if g.pausePressed() || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.resume()
+		return g.nextScene
+	}
+	g.pauseView.Update()
+	return g.nextScene
+}
+
 func (g *GameScene) Update() (string, error) {
+	if g.paused {
+		return g.updatePaused(), nil
+	}
+	// The console holds the game like the pause menu while it is open. A
+	// replay can't pause or open it, and runs the recorded lines instead.
+	if g.console != nil && g.replay == nil {
+		if inpututil.IsKeyJustPressed(devConsoleToggleKey) {
+			g.console.open = !g.console.open
+			return "", nil
+		}
+		if g.console.open {
+			g.console.Update(g.world)
+			return "", nil
+		}
+	}
+	if g.replay == nil && g.pausePressed() && !system.IsInventoryActive(g.world) {
+		g.pause()
+		return "", nil
+	}
+	g.applySettings()
+
 	g.frames++
+	g.beginInputFrame()
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+	if g.debugToggled() {
 		g.debugPhysics = !g.debugPhysics
 		g.debugOverlay = !g.debugOverlay
 	}
@@ -215,7 +545,9 @@ func (g *GameScene) Update() (string, error) {
 	popupVisible := g.active == g.gameplay && g.interactionPopupRequested()
 	gameplayInputUpdated := false
 	if g.active == g.gameplay && g.input != nil {
-		g.input.Update(g.world)
+		g.profile.Measure("InputSystem", func() {
+			g.input.Update(g.world)
+		})
 		gameplayInputUpdated = true
 		if popupVisible {
 			g.clearAttackInputs()
@@ -233,6 +565,13 @@ func (g *GameScene) Update() (string, error) {
 				g.input.Update(g.world)
 			}
 			g.setDialogueInputPressed(false)
+		} else if system.IsShopActive(g.world) && !system.IsDialogueActive(g.world) {
+			if !gameplayInputUpdated && g.input != nil {
+				g.input.Update(g.world)
+			}
+			if g.dialogueInput != nil {
+				g.dialogueInput.Update(g.world)
+			}
 		} else if g.pendingPress {
 			g.setDialogueInputPressed(true)
 			g.pendingPress = false
@@ -242,6 +581,10 @@ func (g *GameScene) Update() (string, error) {
 	}
 	if g.active != nil {
 		if g.active == g.gameplay {
+			// Event frames are gameplay frames, so an event emitted as the
+			// game switches to the dialogue scheduler waits for gameplay to
+			// come back instead of expiring behind a popup.
+			ecs.AdvanceEvents(g.world)
 			g.setGameplayTimeScale(g.gameplayUpdateScale())
 		} else {
 			g.setGameplayTimeScale(1)
@@ -254,7 +597,7 @@ func (g *GameScene) Update() (string, error) {
 		g.interactionOpen = false
 		g.pendingPress = true
 	} else if g.active == g.dialogue {
-		if system.IsDialogueActive(g.world) || system.IsItemActive(g.world) || system.IsInventoryActive(g.world) {
+		if system.IsDialogueActive(g.world) || system.IsItemActive(g.world) || system.IsInventoryActive(g.world) || system.IsShopActive(g.world) {
 			g.interactionOpen = true
 		} else if g.interactionOpen {
 			g.active = g.gameplay
@@ -266,6 +609,8 @@ func (g *GameScene) Update() (string, error) {
 		}
 	}
 
+	g.endInputFrame()
+
 	if err := g.processPrefabEvents(); err != nil {
 		panic("failed to process prefab events: " + err.Error())
 	}
@@ -506,13 +851,31 @@ func (g *GameScene) clearAttackInputs() {
 
 func (g *GameScene) Draw(screen *ebiten.Image) {
 	if g.render != nil {
-		g.render.Draw(g.world, screen)
+		g.profile.Measure("RenderSystem.Draw", func() {
+			g.render.Draw(g.world, screen)
+		})
 	}
 	if g.particles != nil {
-		g.particles.Draw(g.world, screen)
+		g.profile.Measure("ParticleSystem.Draw", func() {
+			g.particles.Draw(g.world, screen)
+		})
 	}
 	if g.ui != nil {
-		g.ui.Draw(g.world, screen)
+		g.profile.Measure("UISystem.Draw", func() {
+			g.ui.Draw(g.world, screen)
+		})
+	}
+
+	if g.paused {
+		drawMenuBackdrop(screen)
+		ui := g.pauseView.UI()
+		if g.settingsOpen {
+			ui = g.settingsView.UI()
+		}
+		if ui != nil {
+			ui.Draw(screen)
+		}
+		return
 	}
 
 	if g.debugPhysics && g.physics != nil {
@@ -524,6 +887,13 @@ func (g *GameScene) Draw(screen *ebiten.Image) {
 		system.DrawTransitionDebug(g.world, screen)
 		system.DrawHazardDebug(g.world, screen)
 		system.DrawPlayerStateDebug(g.world, screen)
+		system.DrawScriptErrorDebug(g.world, screen)
+	}
+	if g.debugOverlay {
+		system.DrawSystemProfileDebug(g.profile, screen)
+	}
+	if g.console != nil && g.console.open {
+		g.console.Draw(screen)
 	}
 }
 
@@ -544,19 +914,28 @@ func (g *GameScene) processPrefabEvents() error {
 		return nil
 	}
 
+	// Script edits recompile just the scripts that use them; anything else
+	// rebuilds the level.
 	reload := false
+	var scripts []string
 	for {
 		select {
-		case <-g.prefabWatcher.Events:
+		case path := <-g.prefabWatcher.Events:
+			if name, ok := prefabs.ScriptName(path); ok {
+				scripts = append(scripts, name)
+				continue
+			}
 			reload = true
 		case <-g.prefabWatcher.Errors:
 			// Ignore errors for now; keep running.
 		default:
 			if reload {
-				ent := ecs.CreateEntity(g.world)
-				_ = ecs.Add(g.world, ent, component.ReloadRequestComponent.Kind(), &component.ReloadRequest{})
+				ecs.Emit(g.world, component.ReloadRequest{})
 				return nil
 			}
+			if len(scripts) > 0 && g.scriptRuntime != nil {
+				g.scriptRuntime.ReloadScripts(g.world, scripts)
+			}
 			return nil
 		}
 	}
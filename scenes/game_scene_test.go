@@ -1,10 +1,12 @@
 package scenes
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/milk9111/sidescroller/ecs"
 	"github.com/milk9111/sidescroller/ecs/component"
+	"github.com/milk9111/sidescroller/internal/replay"
 )
 
 type stubPlayerState string
@@ -83,3 +85,52 @@ func TestGameSceneSetGameplayTimeScaleStoresWorldScale(t *testing.T) {
 		t.Fatalf("expected gameplay scale to update in place to 1, got %v", time.Scale)
 	}
 }
+
+func TestGameSceneRecordsEverySessionAfterQuitToTitle(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := replay.Create(filepath.Join(dir, "session.jsonl"))
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	cfg := GameConfig{LevelName: "long_fall.json", Mute: true, Recorder: recorder}
+	for session := 1; session <= 2; session++ {
+		scene := NewGameScene(cfg)
+		for frame := 0; frame < session*2; frame++ {
+			if _, err := scene.Update(); err != nil {
+				t.Fatalf("session %d: update: %v", session, err)
+			}
+		}
+		scene.quitToTitle()
+	}
+
+	for i, name := range []string{"session.jsonl", "session-2.jsonl"} {
+		player, err := replay.Load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+		if want := (i + 1) * 2; player.Len() != want {
+			t.Fatalf("expected %s to hold %d frames, got %d", name, want, player.Len())
+		}
+	}
+}
+
+func TestGameSceneRecordsCheckpointLoadsWithTheNextFrame(t *testing.T) {
+	recorder, err := replay.Create(filepath.Join(t.TempDir(), "session.jsonl"))
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	scene := NewGameScene(GameConfig{LevelName: "long_fall.json", Mute: true, Recorder: recorder})
+	scene.loadCheckpoint()
+	scene.beginInputFrame()
+	if !scene.inputFeed.Frame.LoadCheckpoint {
+		t.Fatalf("expected the checkpoint load recorded with the next frame, got %+v", scene.inputFeed.Frame)
+	}
+	scene.beginInputFrame()
+	if scene.inputFeed.Frame.LoadCheckpoint {
+		t.Fatal("expected the checkpoint load recorded only once")
+	}
+}
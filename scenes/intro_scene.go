@@ -103,6 +103,19 @@ func NewIntroScene() *IntroScene {
 	}
 }
 
+// SetVolume scales the intro's sound effects by the player's settings.
+func (s *IntroScene) SetVolume(volume float64) {
+	if s == nil {
+		return
+	}
+	if s.windAudioPlayer != nil {
+		s.windAudioPlayer.SetVolume(volume)
+	}
+	if s.landingAudioPlayer != nil {
+		s.landingAudioPlayer.SetVolume(volume)
+	}
+}
+
 func (s *IntroScene) Update() (string, error) {
 	var nextState IntroSceneState
 	switch s.state {
@@ -2,15 +2,23 @@ package scenes
 
 import (
 	"fmt"
+	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/milk9111/sidescroller/common"
+	"github.com/milk9111/sidescroller/internal/savegame"
 )
 
 type Manager struct {
 	factories  map[string]Factory
 	active     Scene
 	activeName string
+	settings   *savegame.Settings
+	// fullscreen is the setting last pushed to the window, so the OS can
+	// change the window in between without being overridden.
+	fullscreen bool
+	viewport   integerViewport
+	canvas     *ebiten.Image
 }
 
 func NewManager(initialScene string, factories map[string]Factory) (*Manager, error) {
@@ -36,6 +44,16 @@ func NewManager(initialScene string, factories map[string]Factory) (*Manager, er
 	return manager, nil
 }
 
+// SetSettings shares the live player settings with the manager, which owns
+// window-level options such as fullscreen and integer scaling.
+func (m *Manager) SetSettings(settings *savegame.Settings) {
+	m.settings = settings
+	applyWindowSettings(settings)
+	if settings != nil {
+		m.fullscreen = settings.Fullscreen
+	}
+}
+
 func (m *Manager) SwitchTo(name string) error {
 	factory, ok := m.factories[name]
 	if !ok {
@@ -56,13 +74,13 @@ func (m *Manager) SwitchTo(name string) error {
 }
 
 func (m *Manager) Update() error {
-	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
-		return ErrQuit
-	}
-
 	if m.active == nil {
 		return fmt.Errorf("no active scene")
 	}
+	if m.settings != nil && m.settings.Fullscreen != m.fullscreen {
+		applyWindowSettings(m.settings)
+		m.fullscreen = m.settings.Fullscreen
+	}
 
 	nextScene, err := m.active.Update()
 	if err != nil {
@@ -76,17 +94,44 @@ func (m *Manager) Update() error {
 }
 
 func (m *Manager) Draw(screen *ebiten.Image) {
-	if m.active != nil {
+	if m.active == nil {
+		return
+	}
+	if !m.viewport.scaled {
 		m.active.Draw(screen)
+		return
 	}
+
+	// Integer scaling gives ebiten a logical screen a whole fraction of the
+	// window, so the scene renders to its usual size and is centered in it.
+	sceneW, sceneH := m.active.Layout(common.BaseWidth, common.BaseHeight)
+	if m.canvas == nil || m.canvas.Bounds().Dx() != sceneW || m.canvas.Bounds().Dy() != sceneH {
+		m.canvas = ebiten.NewImage(sceneW, sceneH)
+	}
+	m.canvas.Clear()
+	m.active.Draw(m.canvas)
+
+	screen.Fill(color.Black)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(m.viewport.offsetX), float64(m.viewport.offsetY))
+	screen.DrawImage(m.canvas, op)
 }
 
 func (m *Manager) LayoutF(outsideWidth, outsideHeight float64) (float64, float64) {
-	if m.active != nil {
-		return m.active.LayoutF(outsideWidth, outsideHeight)
+	if m.active == nil {
+		return outsideWidth, outsideHeight
 	}
 
-	return outsideWidth, outsideHeight
+	sceneW, sceneH := m.active.LayoutF(outsideWidth, outsideHeight)
+	viewport := integerViewport{screenW: sceneW, screenH: sceneH}
+	if m.settings != nil && m.settings.IntegerScaling {
+		viewport = computeIntegerViewport(outsideWidth, outsideHeight, ebiten.Monitor().DeviceScaleFactor(), sceneW, sceneH)
+	}
+	if viewport.offsetX != m.viewport.offsetX || viewport.offsetY != m.viewport.offsetY {
+		setCursorOffset(viewport.offsetX, viewport.offsetY)
+	}
+	m.viewport = viewport
+	return viewport.screenW, viewport.screenH
 }
 
 func (m *Manager) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -0,0 +1,148 @@
+package scenes
+
+import (
+	"strings"
+
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/milk9111/sidescroller/common"
+)
+
+// pauseMenuItem is one button of the pause menu.
+type pauseMenuItem struct {
+	label    string
+	activate func()
+}
+
+// pauseView is the menu shown over the frozen game. The game scene owns what
+// each item does; the view only lays the buttons out and handles navigation.
+type pauseView struct {
+	theme        *startMenuTheme
+	items        []pauseMenuItem
+	ui           *ebitenui.UI
+	entries      []startMenuEntry
+	focus        int
+	message      string
+	axisUpHeld   bool
+	axisDownHeld bool
+}
+
+func newPauseView(theme *startMenuTheme, items []pauseMenuItem) *pauseView {
+	view := &pauseView{theme: theme, items: items}
+	view.rebuild()
+	return view
+}
+
+func (v *pauseView) UI() *ebitenui.UI {
+	if v == nil {
+		return nil
+	}
+	return v.ui
+}
+
+// Reset puts focus back on the first item and clears any status message,
+// so each pause starts from Resume.
+func (v *pauseView) Reset() {
+	if v == nil {
+		return
+	}
+	v.focus = 0
+	v.SetMessage("")
+}
+
+// SetMessage shows a status line under the buttons, such as a save result.
+func (v *pauseView) SetMessage(message string) {
+	if v == nil {
+		return
+	}
+	v.message = message
+	v.rebuild()
+}
+
+// Update handles keyboard and gamepad navigation, then lets ebitenui process
+// the mouse.
+func (v *pauseView) Update() {
+	if v == nil {
+		return
+	}
+
+	if step := menuVerticalStep(&v.axisUpHeld, &v.axisDownHeld); step != 0 {
+		v.focus = nextMenuFocus(v.entries, v.focus, step)
+		applyEntryFocus(v.entries, v.focus)
+		return
+	}
+	if menuActivatePressed() && v.focus >= 0 && v.focus < len(v.entries) {
+		if entry := v.entries[v.focus]; entry.onActivate != nil {
+			entry.onActivate()
+		}
+		return
+	}
+
+	if v.ui != nil {
+		v.ui.Update()
+	}
+}
+
+func (v *pauseView) rebuild() {
+	root := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	content := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(14),
+		)),
+	)
+	content.GetWidget().LayoutData = widget.AnchorLayoutData{
+		HorizontalPosition: widget.AnchorLayoutPositionCenter,
+		VerticalPosition:   widget.AnchorLayoutPositionCenter,
+	}
+
+	header := widget.NewText(
+		widget.TextOpts.Text("Paused", &v.theme.LabelFace, v.theme.TextColor),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	content.AddChild(header)
+
+	v.entries = v.entries[:0]
+	for index, item := range v.items {
+		item := item
+		image, focusImage := v.theme.SecondaryButtonImage, v.theme.SecondaryFocusImage
+		if index == 0 {
+			image, focusImage = v.theme.PrimaryButtonImage, v.theme.PrimaryFocusImage
+		}
+		button := widget.NewButton(
+			widget.ButtonOpts.Image(image),
+			widget.ButtonOpts.Text(item.label, &v.theme.ButtonFace, v.theme.ButtonText),
+			widget.ButtonOpts.TextPadding(v.theme.ButtonPadding),
+			widget.ButtonOpts.WidgetOpts(
+				widget.WidgetOpts.MinSize(startMenuButtonWidth, startMenuButtonHeight),
+				widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter}),
+			),
+			widget.ButtonOpts.ClickedHandler(func(*widget.ButtonClickedEventArgs) {
+				if item.activate != nil {
+					item.activate()
+				}
+			}),
+		)
+		v.entries = append(v.entries, startMenuEntry{button: button, baseImage: image, focusImage: focusImage, onActivate: item.activate})
+		content.AddChild(button)
+	}
+
+	if strings.TrimSpace(v.message) != "" {
+		messageText := widget.NewText(
+			widget.TextOpts.Text(v.message, &v.theme.SlotFace, v.theme.MutedTextColor),
+			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+			widget.TextOpts.MaxWidth(common.BaseWidth-220),
+			widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+		)
+		content.AddChild(messageText)
+	}
+
+	root.AddChild(content)
+	v.focus = clampMenuFocus(v.entries, v.focus)
+	applyEntryFocus(v.entries, v.focus)
+	v.ui = &ebitenui.UI{Container: root}
+}
@@ -0,0 +1,308 @@
+package scenes
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/milk9111/sidescroller/common"
+	"github.com/milk9111/sidescroller/internal/savegame"
+)
+
+const (
+	settingsOptionWidth  = 560
+	settingsOptionHeight = 54
+	settingsLevelStep    = 0.1
+)
+
+// settingsOption is one row of the settings view. change receives -1 or +1
+// from left/right, and activating the row steps it forward with wrapping.
+type settingsOption struct {
+	label  string
+	value  func(settings *savegame.Settings) string
+	change func(settings *savegame.Settings, delta int, wrap bool)
+}
+
+var settingsOptions = []settingsOption{
+	levelOption("Master volume", func(s *savegame.Settings) *float64 { return &s.MasterVolume }),
+	levelOption("Music volume", func(s *savegame.Settings) *float64 { return &s.MusicVolume }),
+	levelOption("Sound effects volume", func(s *savegame.Settings) *float64 { return &s.SFXVolume }),
+	toggleOption("Fullscreen", func(s *savegame.Settings) *bool { return &s.Fullscreen }),
+	toggleOption("Integer scaling", func(s *savegame.Settings) *bool { return &s.IntegerScaling }),
+	levelOption("Screen shake", func(s *savegame.Settings) *float64 { return &s.ScreenShake }),
+}
+
+func levelOption(label string, field func(*savegame.Settings) *float64) settingsOption {
+	return settingsOption{
+		label: label,
+		value: func(settings *savegame.Settings) string {
+			return fmt.Sprintf("%d%%", int(math.Round(*field(settings)*100)))
+		},
+		change: func(settings *savegame.Settings, delta int, wrap bool) {
+			level := field(settings)
+			*level = stepSettingsLevel(*level, delta, wrap)
+		},
+	}
+}
+
+func toggleOption(label string, field func(*savegame.Settings) *bool) settingsOption {
+	return settingsOption{
+		label: label,
+		value: func(settings *savegame.Settings) string {
+			if *field(settings) {
+				return "On"
+			}
+			return "Off"
+		},
+		change: func(settings *savegame.Settings, _ int, _ bool) {
+			enabled := field(settings)
+			*enabled = !*enabled
+		},
+	}
+}
+
+// stepSettingsLevel moves a 0-1 level by one step. With wrap set, stepping
+// past full wraps back to zero so a single button can cycle through levels.
+func stepSettingsLevel(level float64, delta int, wrap bool) float64 {
+	steps := math.Round(level/settingsLevelStep) + float64(delta)
+	maxSteps := math.Round(1 / settingsLevelStep)
+	if wrap && steps > maxSteps {
+		steps = 0
+	}
+	if steps < 0 {
+		steps = 0
+	}
+	if steps > maxSteps {
+		steps = maxSteps
+	}
+	return steps * settingsLevelStep
+}
+
+// settingsView edits the shared live settings. Changes take effect right
+// away because scenes read the same Settings value every frame, and are
+// written to the settings file as they are made.
+type settingsView struct {
+	settings      *savegame.Settings
+	theme         *startMenuTheme
+	onBack        func()
+	ui            *ebitenui.UI
+	entries       []startMenuEntry
+	focus         int
+	message       string
+	axisUpHeld    bool
+	axisDownHeld  bool
+	axisLeftHeld  bool
+	axisRightHeld bool
+}
+
+func newSettingsView(settings *savegame.Settings, theme *startMenuTheme, onBack func()) *settingsView {
+	view := &settingsView{settings: settings, theme: theme, onBack: onBack}
+	view.rebuild()
+	return view
+}
+
+func (v *settingsView) UI() *ebitenui.UI {
+	if v == nil {
+		return nil
+	}
+	return v.ui
+}
+
+// Update handles keyboard and gamepad navigation, then lets ebitenui process
+// the mouse. Escape goes back.
+func (v *settingsView) Update() {
+	if v == nil || v.settings == nil {
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if v.onBack != nil {
+			v.onBack()
+		}
+		return
+	}
+
+	if step := menuVerticalStep(&v.axisUpHeld, &v.axisDownHeld); step != 0 {
+		v.focus = nextMenuFocus(v.entries, v.focus, step)
+		applyEntryFocus(v.entries, v.focus)
+		return
+	}
+	if step := menuHorizontalStep(&v.axisLeftHeld, &v.axisRightHeld); step != 0 {
+		if v.focus >= 0 && v.focus < len(settingsOptions) {
+			v.change(v.focus, step, false)
+		}
+		return
+	}
+	if menuActivatePressed() && v.focus >= 0 && v.focus < len(v.entries) {
+		if entry := v.entries[v.focus]; entry.onActivate != nil {
+			entry.onActivate()
+		}
+		return
+	}
+
+	if v.ui != nil {
+		v.ui.Update()
+	}
+}
+
+func (v *settingsView) change(index, delta int, wrap bool) {
+	settingsOptions[index].change(v.settings, delta, wrap)
+	*v.settings = v.settings.Normalized()
+	v.message = ""
+	if err := savegame.SaveSettings(*v.settings); err != nil {
+		v.message = err.Error()
+	}
+	v.rebuild()
+}
+
+func (v *settingsView) rebuild() {
+	root := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	content := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(14),
+		)),
+	)
+	content.GetWidget().LayoutData = widget.AnchorLayoutData{
+		HorizontalPosition: widget.AnchorLayoutPositionCenter,
+		VerticalPosition:   widget.AnchorLayoutPositionCenter,
+	}
+
+	header := widget.NewText(
+		widget.TextOpts.Text("Settings", &v.theme.LabelFace, v.theme.TextColor),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	content.AddChild(header)
+
+	hint := widget.NewText(
+		widget.TextOpts.Text("Left and right adjust the selected option.", &v.theme.SlotFace, v.theme.MutedTextColor),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	content.AddChild(hint)
+
+	panel := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(v.theme.PanelBackground),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(8),
+			widget.RowLayoutOpts.Padding(v.theme.PanelPadding),
+		)),
+	)
+	panel.GetWidget().LayoutData = widget.RowLayoutData{Position: widget.RowLayoutPositionCenter}
+
+	v.entries = v.entries[:0]
+	for index, option := range settingsOptions {
+		index := index
+		activate := func() {
+			v.change(index, 1, true)
+		}
+		button := widget.NewButton(
+			widget.ButtonOpts.Image(v.theme.SlotButtonImage),
+			widget.ButtonOpts.Text(fmt.Sprintf("%s: %s", option.label, option.value(v.settings)), &v.theme.SlotFace, v.theme.ButtonText),
+			widget.ButtonOpts.TextPadding(&widget.Insets{Left: 14, Right: 14, Top: 6, Bottom: 6}),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.MinSize(settingsOptionWidth, settingsOptionHeight)),
+			widget.ButtonOpts.ClickedHandler(func(*widget.ButtonClickedEventArgs) {
+				activate()
+			}),
+		)
+		v.entries = append(v.entries, startMenuEntry{button: button, baseImage: v.theme.SlotButtonImage, focusImage: v.theme.SlotFocusImage, onActivate: activate})
+		panel.AddChild(button)
+	}
+	content.AddChild(panel)
+
+	if strings.TrimSpace(v.message) != "" {
+		messageText := widget.NewText(
+			widget.TextOpts.Text(v.message, &v.theme.LabelFace, v.theme.ErrorTextColor),
+			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+			widget.TextOpts.MaxWidth(common.BaseWidth-220),
+			widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+		)
+		content.AddChild(messageText)
+	}
+
+	backAction := func() {
+		if v.onBack != nil {
+			v.onBack()
+		}
+	}
+	backButton := widget.NewButton(
+		widget.ButtonOpts.Image(v.theme.SecondaryButtonImage),
+		widget.ButtonOpts.Text("Back", &v.theme.ButtonFace, v.theme.ButtonText),
+		widget.ButtonOpts.TextPadding(v.theme.ButtonPadding),
+		widget.ButtonOpts.WidgetOpts(
+			widget.WidgetOpts.MinSize(startMenuButtonWidth, startMenuButtonHeight),
+			widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter}),
+		),
+		widget.ButtonOpts.ClickedHandler(func(*widget.ButtonClickedEventArgs) {
+			backAction()
+		}),
+	)
+	v.entries = append(v.entries, startMenuEntry{button: backButton, baseImage: v.theme.SecondaryButtonImage, focusImage: v.theme.SecondaryFocusImage, onActivate: backAction})
+	content.AddChild(backButton)
+
+	root.AddChild(content)
+	v.focus = clampMenuFocus(v.entries, v.focus)
+	applyEntryFocus(v.entries, v.focus)
+	v.ui = &ebitenui.UI{Container: root}
+}
+
+var menuBackdropColor = color.NRGBA{R: 0x05, G: 0x08, B: 0x0c, A: 0xc8}
+
+// drawMenuBackdrop dims the frozen game behind an in-game menu.
+func drawMenuBackdrop(screen *ebiten.Image) {
+	bounds := screen.Bounds()
+	vector.DrawFilledRect(screen, float32(bounds.Min.X), float32(bounds.Min.Y), float32(bounds.Dx()), float32(bounds.Dy()), menuBackdropColor, false)
+}
+
+// menuVerticalStep returns -1 or 1 when up or down was pressed this frame.
+func menuVerticalStep(upHeld, downHeld *bool) int {
+	step := 0
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) || inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		step = -1
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) || inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		step = 1
+	}
+	direction := menuGamepadVerticalDirection()
+	if direction < 0 && !*upHeld {
+		step = -1
+	}
+	if direction > 0 && !*downHeld {
+		step = 1
+	}
+	*upHeld = direction < 0
+	*downHeld = direction > 0
+	return step
+}
+
+// menuHorizontalStep returns -1 or 1 when left or right was pressed this frame.
+func menuHorizontalStep(leftHeld, rightHeld *bool) int {
+	step := 0
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) || inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		step = -1
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) || inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		step = 1
+	}
+	direction := menuGamepadHorizontalDirection()
+	if direction < 0 && !*leftHeld {
+		step = -1
+	}
+	if direction > 0 && !*rightHeld {
+		step = 1
+	}
+	*leftHeld = direction < 0
+	*rightHeld = direction > 0
+	return step
+}
@@ -0,0 +1,199 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/milk9111/sidescroller/common"
+	"github.com/milk9111/sidescroller/internal/controls"
+)
+
+const (
+	startMenuControlWidth  = 560
+	startMenuControlHeight = 54
+)
+
+func (s *StartMenuScene) buildControlsUI() (*ebitenui.UI, error) {
+	root := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	content := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(14),
+		)),
+	)
+	content.GetWidget().LayoutData = widget.AnchorLayoutData{
+		HorizontalPosition: widget.AnchorLayoutPositionCenter,
+		VerticalPosition:   widget.AnchorLayoutPositionCenter,
+	}
+
+	header := widget.NewText(
+		widget.TextOpts.Text("Controls", &s.theme.LabelFace, s.theme.TextColor),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	content.AddChild(header)
+
+	hint := "Select an action, then press a key, mouse button or gamepad button. Escape cancels."
+	if s.bindingAction != "" {
+		hint = fmt.Sprintf("Press a new input for %s. Escape cancels.", s.bindingAction.Label())
+	}
+	hintText := widget.NewText(
+		widget.TextOpts.Text(hint, &s.theme.SlotFace, s.theme.MutedTextColor),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	)
+	content.AddChild(hintText)
+
+	panel := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(s.theme.PanelBackground),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(12, 8),
+			widget.GridLayoutOpts.Padding(s.theme.PanelPadding),
+		)),
+	)
+	panel.GetWidget().LayoutData = widget.RowLayoutData{Position: widget.RowLayoutPositionCenter}
+
+	profile := s.controlsProfile()
+	s.controlsEntries = s.controlsEntries[:0]
+	for _, action := range controls.Actions {
+		action := action
+		label := fmt.Sprintf("%s\n%s", action.Label(), profile.Binding(action).Describe())
+		if action == s.bindingAction {
+			label = fmt.Sprintf("%s\nPress a key or button...", action.Label())
+		}
+		activate := func() {
+			s.bindingAction = action
+			s.controlsMessage = ""
+			_ = s.rebuildControlsUI()
+		}
+		button := widget.NewButton(
+			widget.ButtonOpts.Image(s.theme.SlotButtonImage),
+			widget.ButtonOpts.Text(label, &s.theme.SlotFace, s.theme.ButtonText),
+			widget.ButtonOpts.TextPadding(&widget.Insets{Left: 14, Right: 14, Top: 6, Bottom: 6}),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.MinSize(startMenuControlWidth, startMenuControlHeight)),
+			widget.ButtonOpts.ClickedHandler(func(*widget.ButtonClickedEventArgs) {
+				activate()
+			}),
+		)
+		s.controlsEntries = append(s.controlsEntries, startMenuEntry{
+			button:     button,
+			baseImage:  s.theme.SlotButtonImage,
+			focusImage: s.theme.SlotFocusImage,
+			onActivate: activate,
+		})
+		panel.AddChild(button)
+	}
+	content.AddChild(panel)
+
+	if strings.TrimSpace(s.controlsMessage) != "" {
+		messageText := widget.NewText(
+			widget.TextOpts.Text(s.controlsMessage, &s.theme.LabelFace, s.theme.ErrorTextColor),
+			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+			widget.TextOpts.MaxWidth(common.BaseWidth-220),
+			widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+		)
+		content.AddChild(messageText)
+	}
+
+	buttons := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(14),
+		)),
+	)
+	buttons.GetWidget().LayoutData = widget.RowLayoutData{Position: widget.RowLayoutPositionCenter}
+
+	resetAction := func() {
+		s.saveControls(controls.Default())
+	}
+	resetButton := s.newMenuButton("Reset", s.theme.SecondaryButtonImage, resetAction)
+	s.controlsEntries = append(s.controlsEntries, startMenuEntry{button: resetButton, baseImage: s.theme.SecondaryButtonImage, focusImage: s.theme.SecondaryFocusImage, onActivate: resetAction})
+	buttons.AddChild(resetButton)
+
+	backAction := func() {
+		s.slideTarget = 0
+	}
+	backButton := s.newMenuButton("Back", s.theme.SecondaryButtonImage, backAction)
+	s.controlsEntries = append(s.controlsEntries, startMenuEntry{button: backButton, baseImage: s.theme.SecondaryButtonImage, focusImage: s.theme.SecondaryFocusImage, onActivate: backAction})
+	buttons.AddChild(backButton)
+
+	content.AddChild(buttons)
+	root.AddChild(content)
+	s.controlsFocus = clampMenuFocus(s.controlsEntries, s.controlsFocus)
+	applyEntryFocus(s.controlsEntries, s.controlsFocus)
+
+	return &ebitenui.UI{Container: root}, nil
+}
+
+func (s *StartMenuScene) rebuildControlsUI() error {
+	controlsUI, err := s.buildControlsUI()
+	if err != nil {
+		return err
+	}
+	s.controlsUI = controlsUI
+	return nil
+}
+
+func (s *StartMenuScene) controlsProfile() *controls.Profile {
+	if s.config.Controls == nil {
+		return controls.Default()
+	}
+	return s.config.Controls
+}
+
+// captureBinding waits for the next key, mouse button or gamepad button and
+// binds it to the action picked on the controls screen.
+func (s *StartMenuScene) captureBinding() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.bindingAction = ""
+		_ = s.rebuildControlsUI()
+		return
+	}
+
+	profile := s.controlsProfile().Clone()
+	if keys := inpututil.AppendJustPressedKeys(nil); len(keys) > 0 {
+		profile.BindKey(s.bindingAction, keys[0])
+		s.bindingAction = ""
+		s.saveControls(profile)
+		return
+	}
+	for _, button := range controls.MouseButtons() {
+		if inpututil.IsMouseButtonJustPressed(button) {
+			profile.BindMouseButton(s.bindingAction, button)
+			s.bindingAction = ""
+			s.saveControls(profile)
+			return
+		}
+	}
+	if pad := controls.FirstGamepad(); pad.Connected {
+		for _, button := range controls.GamepadButtons() {
+			if inpututil.IsStandardGamepadButtonJustPressed(pad.ID, button) {
+				profile.BindGamepadButton(s.bindingAction, button)
+				s.bindingAction = ""
+				s.saveControls(profile)
+				return
+			}
+		}
+	}
+}
+
+// saveControls persists profile and hands it to the next game scene.
+func (s *StartMenuScene) saveControls(profile *controls.Profile) {
+	s.controlsMessage = ""
+	if err := controls.Save(s.controlsPath, profile); err != nil {
+		s.controlsMessage = err.Error()
+	} else {
+		s.config.Controls = profile
+	}
+	if err := s.rebuildControlsUI(); err != nil {
+		s.controlsMessage = err.Error()
+	}
+}
@@ -17,6 +17,7 @@ import (
 	textv2 "github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/milk9111/sidescroller/assets"
 	"github.com/milk9111/sidescroller/common"
+	"github.com/milk9111/sidescroller/internal/controls"
 	"github.com/milk9111/sidescroller/internal/savegame"
 	"golang.org/x/image/font/gofont/goregular"
 )
@@ -72,6 +73,15 @@ type startMenuEntry struct {
 	disabled   bool
 }
 
+// startMenuView picks which panel slides in beside the main menu.
+type startMenuView int
+
+const (
+	startMenuViewLoad startMenuView = iota
+	startMenuViewControls
+	startMenuViewSettings
+)
+
 type StartMenuScene struct {
 	config           *GameConfig
 	theme            *startMenuTheme
@@ -80,10 +90,13 @@ type StartMenuScene struct {
 	musicPlayer      *audio.Player
 	mainUI           *ebitenui.UI
 	loadUI           *ebitenui.UI
+	controlsUI       *ebitenui.UI
+	settingsView     *settingsView
 	mainSurface      *ebiten.Image
 	loadSurface      *ebiten.Image
 	slots            [startMenuSlots]startMenuSlot
 	loadError        string
+	secondary        startMenuView
 	slide            float64
 	slideTarget      float64
 	fadeAlpha        float64
@@ -91,8 +104,13 @@ type StartMenuScene struct {
 	quitOnFade       bool
 	mainEntries      []startMenuEntry
 	loadEntries      []startMenuEntry
+	controlsEntries  []startMenuEntry
 	mainFocus        int
 	loadFocus        int
+	controlsFocus    int
+	controlsPath     string
+	controlsMessage  string
+	bindingAction    controls.Action
 	musicStarted     bool
 	musicBreakFrames int
 	axisUpHeld       bool
@@ -138,6 +156,18 @@ func NewStartMenuScene(config *GameConfig) (*StartMenuScene, error) {
 	if err := scene.refreshSlots(); err != nil {
 		scene.loadError = err.Error()
 	}
+	if config.Settings == nil {
+		settings := savegame.DefaultSettings()
+		config.Settings = &settings
+	}
+	scene.settingsView = newSettingsView(config.Settings, theme, func() {
+		scene.slideTarget = 0
+	})
+	if path, err := controls.ResolvePath(); err != nil {
+		scene.controlsMessage = err.Error()
+	} else {
+		scene.controlsPath = path
+	}
 	if err := scene.buildUIs(); err != nil {
 		return nil, err
 	}
@@ -161,8 +191,13 @@ func (s *StartMenuScene) Update() (string, error) {
 
 	s.updateMusic()
 
+	if s.bindingAction != "" {
+		s.captureBinding()
+		return SceneStartMenu, nil
+	}
+
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		if s.isLoadView() {
+		if s.isSecondaryView() {
 			s.slideTarget = 0
 		} else {
 			s.stopMusic()
@@ -175,13 +210,18 @@ func (s *StartMenuScene) Update() (string, error) {
 		return SceneStartMenu, nil
 	}
 
+	if s.isSecondaryView() && s.secondary == startMenuViewSettings {
+		s.settingsView.Update()
+		return SceneStartMenu, nil
+	}
+
 	if s.handleMenuNavigation() {
 		return SceneStartMenu, nil
 	}
 
-	if s.isLoadView() {
-		if s.loadUI != nil {
-			s.loadUI.Update()
+	if s.isSecondaryView() {
+		if ui := s.secondaryUI(); ui != nil {
+			ui.Update()
 		}
 	} else if s.mainUI != nil {
 		s.mainUI.Update()
@@ -201,7 +241,7 @@ func (s *StartMenuScene) Draw(screen *ebiten.Image) {
 	loadX := (1 - eased) * common.BaseWidth
 
 	s.drawUI(screen, s.mainSurface, s.mainUI, mainX)
-	s.drawUI(screen, s.loadSurface, s.loadUI, loadX)
+	s.drawUI(screen, s.loadSurface, s.secondaryUI(), loadX)
 
 	if s.fadeAlpha > 0 {
 		overlay := ebiten.NewImage(1, 1)
@@ -230,8 +270,13 @@ func (s *StartMenuScene) buildUIs() error {
 	if err != nil {
 		return err
 	}
+	controlsUI, err := s.buildControlsUI()
+	if err != nil {
+		return err
+	}
 	s.mainUI = mainUI
 	s.loadUI = loadUI
+	s.controlsUI = controlsUI
 	return nil
 }
 
@@ -285,12 +330,33 @@ func (s *StartMenuScene) buildMainUI() (*ebitenui.UI, error) {
 		if err := s.rebuildLoadUI(); err != nil {
 			s.loadError = err.Error()
 		}
+		s.secondary = startMenuViewLoad
 		s.slideTarget = 1
 	}
 	loadButton := s.newMenuButton("Load", s.theme.SecondaryButtonImage, loadAction)
 	s.mainEntries = append(s.mainEntries, startMenuEntry{button: loadButton, baseImage: s.theme.SecondaryButtonImage, focusImage: s.theme.SecondaryFocusImage, onActivate: loadAction})
 	buttons.AddChild(loadButton)
 
+	controlsAction := func() {
+		s.controlsMessage = ""
+		if err := s.rebuildControlsUI(); err != nil {
+			s.controlsMessage = err.Error()
+		}
+		s.secondary = startMenuViewControls
+		s.slideTarget = 1
+	}
+	controlsButton := s.newMenuButton("Controls", s.theme.SecondaryButtonImage, controlsAction)
+	s.mainEntries = append(s.mainEntries, startMenuEntry{button: controlsButton, baseImage: s.theme.SecondaryButtonImage, focusImage: s.theme.SecondaryFocusImage, onActivate: controlsAction})
+	buttons.AddChild(controlsButton)
+
+	settingsAction := func() {
+		s.secondary = startMenuViewSettings
+		s.slideTarget = 1
+	}
+	settingsButton := s.newMenuButton("Settings", s.theme.SecondaryButtonImage, settingsAction)
+	s.mainEntries = append(s.mainEntries, startMenuEntry{button: settingsButton, baseImage: s.theme.SecondaryButtonImage, focusImage: s.theme.SecondaryFocusImage, onActivate: settingsAction})
+	buttons.AddChild(settingsButton)
+
 	exitAction := func() {
 		s.beginFade("", true)
 	}
@@ -302,7 +368,7 @@ func (s *StartMenuScene) buildMainUI() (*ebitenui.UI, error) {
 	content.AddChild(buttons)
 	root.AddChild(content)
 	s.mainFocus = clampMenuFocus(s.mainEntries, s.mainFocus)
-	s.applyFocusState(s.mainEntries, s.mainFocus)
+	applyEntryFocus(s.mainEntries, s.mainFocus)
 
 	return &ebitenui.UI{Container: root}, nil
 }
@@ -393,7 +459,7 @@ func (s *StartMenuScene) buildLoadUI() (*ebitenui.UI, error) {
 	content.AddChild(backButton)
 	root.AddChild(content)
 	s.loadFocus = clampMenuFocus(s.loadEntries, s.loadFocus)
-	s.applyFocusState(s.loadEntries, s.loadFocus)
+	applyEntryFocus(s.loadEntries, s.loadFocus)
 
 	return &ebitenui.UI{Container: root}, nil
 }
@@ -504,7 +570,13 @@ func (s *StartMenuScene) handleMenuNavigation() bool {
 }
 
 func (s *StartMenuScene) activeEntries() ([]startMenuEntry, int) {
-	if s.isLoadView() {
+	if s.isSecondaryView() && s.secondary == startMenuViewControls {
+		if len(s.controlsEntries) == 0 {
+			return nil, -1
+		}
+		return s.controlsEntries, clampMenuFocus(s.controlsEntries, s.controlsFocus)
+	}
+	if s.isSecondaryView() {
 		if len(s.loadEntries) == 0 {
 			return nil, -1
 		}
@@ -517,16 +589,21 @@ func (s *StartMenuScene) activeEntries() ([]startMenuEntry, int) {
 }
 
 func (s *StartMenuScene) setActiveFocus(index int) {
-	if s.isLoadView() {
+	if s.isSecondaryView() && s.secondary == startMenuViewControls {
+		s.controlsFocus = clampMenuFocus(s.controlsEntries, index)
+		applyEntryFocus(s.controlsEntries, s.controlsFocus)
+		return
+	}
+	if s.isSecondaryView() {
 		s.loadFocus = clampMenuFocus(s.loadEntries, index)
-		s.applyFocusState(s.loadEntries, s.loadFocus)
+		applyEntryFocus(s.loadEntries, s.loadFocus)
 		return
 	}
 	s.mainFocus = clampMenuFocus(s.mainEntries, index)
-	s.applyFocusState(s.mainEntries, s.mainFocus)
+	applyEntryFocus(s.mainEntries, s.mainFocus)
 }
 
-func (s *StartMenuScene) applyFocusState(entries []startMenuEntry, focused int) {
+func applyEntryFocus(entries []startMenuEntry, focused int) {
 	for index := range entries {
 		entry := entries[index]
 		if entry.button == nil {
@@ -555,6 +632,9 @@ func (s *StartMenuScene) updateMusic() {
 	if s == nil || s.config == nil || s.config.Mute || s.musicPlayer == nil {
 		return
 	}
+	if s.config.Settings != nil {
+		s.musicPlayer.SetVolume(s.config.Settings.MusicGain())
+	}
 	if s.musicPlayer.IsPlaying() {
 		return
 	}
@@ -580,10 +660,21 @@ func (s *StartMenuScene) stopMusic() {
 	s.musicPlayer = nil
 }
 
-func (s *StartMenuScene) isLoadView() bool {
+func (s *StartMenuScene) isSecondaryView() bool {
 	return s.slideTarget > 0 || s.slide > 0
 }
 
+func (s *StartMenuScene) secondaryUI() *ebitenui.UI {
+	switch s.secondary {
+	case startMenuViewControls:
+		return s.controlsUI
+	case startMenuViewSettings:
+		return s.settingsView.UI()
+	default:
+		return s.loadUI
+	}
+}
+
 func (s *StartMenuScene) isFading() bool {
 	return s.fadeAlpha > 0
 }
@@ -692,6 +783,10 @@ func formatSlotSummary(fileName string, save *savegame.File) string {
 		levelName = "Unknown level"
 	}
 
+	if save.RestoredFrom != "" {
+		fileName += "  |  Restored from backup"
+	}
+
 	return fmt.Sprintf(
 		"%s\nSaved %s  |  Level %s\nHealth %d/%d  |  Gear %d  |  Items %d\nAbilities %s",
 		fileName,